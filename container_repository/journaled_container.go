@@ -0,0 +1,218 @@
+package container_repository
+
+import (
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+)
+
+type journal interface {
+	Journal(linux_backend.Container)
+}
+
+// journaledContainer writes the container to the journal after every
+// successful operation which changes its persisted state.
+type journaledContainer struct {
+	linux_backend.Container
+
+	journal journal
+}
+
+func (c *journaledContainer) Stop(kill bool) error {
+	err := c.Container.Stop(kill)
+	c.journal.Journal(c.Container)
+	return err
+}
+
+func (c *journaledContainer) Pause() error {
+	if err := c.Container.Pause(); err != nil {
+		return err
+	}
+
+	c.journal.Journal(c.Container)
+	return nil
+}
+
+func (c *journaledContainer) Resume() error {
+	if err := c.Container.Resume(); err != nil {
+		return err
+	}
+
+	c.journal.Journal(c.Container)
+	return nil
+}
+
+func (c *journaledContainer) LimitBandwidth(limits garden.BandwidthLimits) error {
+	if err := c.Container.LimitBandwidth(limits); err != nil {
+		return err
+	}
+
+	c.journal.Journal(c.Container)
+	return nil
+}
+
+func (c *journaledContainer) LimitDetailedBandwidth(limits linux_backend.BandwidthLimits) error {
+	if err := c.Container.LimitDetailedBandwidth(limits); err != nil {
+		return err
+	}
+
+	c.journal.Journal(c.Container)
+	return nil
+}
+
+func (c *journaledContainer) LimitCPU(limits garden.CPULimits) error {
+	if err := c.Container.LimitCPU(limits); err != nil {
+		return err
+	}
+
+	c.journal.Journal(c.Container)
+	return nil
+}
+
+func (c *journaledContainer) LimitCPUQuota(limits linux_backend.CPUQuotaLimits) error {
+	if err := c.Container.LimitCPUQuota(limits); err != nil {
+		return err
+	}
+
+	c.journal.Journal(c.Container)
+	return nil
+}
+
+func (c *journaledContainer) LimitCPUSet(limits linux_backend.CPUSetLimits) error {
+	if err := c.Container.LimitCPUSet(limits); err != nil {
+		return err
+	}
+
+	c.journal.Journal(c.Container)
+	return nil
+}
+
+func (c *journaledContainer) LimitDisk(limits garden.DiskLimits) error {
+	if err := c.Container.LimitDisk(limits); err != nil {
+		return err
+	}
+
+	c.journal.Journal(c.Container)
+	return nil
+}
+
+func (c *journaledContainer) LimitIO(limits linux_backend.IOLimits) error {
+	if err := c.Container.LimitIO(limits); err != nil {
+		return err
+	}
+
+	c.journal.Journal(c.Container)
+	return nil
+}
+
+func (c *journaledContainer) LimitMemory(limits garden.MemoryLimits) error {
+	if err := c.Container.LimitMemory(limits); err != nil {
+		return err
+	}
+
+	c.journal.Journal(c.Container)
+	return nil
+}
+
+func (c *journaledContainer) LimitDetailedMemory(limits linux_backend.MemoryLimits) error {
+	if err := c.Container.LimitDetailedMemory(limits); err != nil {
+		return err
+	}
+
+	c.journal.Journal(c.Container)
+	return nil
+}
+
+func (c *journaledContainer) LimitPids(limits linux_backend.PidLimits) error {
+	if err := c.Container.LimitPids(limits); err != nil {
+		return err
+	}
+
+	c.journal.Journal(c.Container)
+	return nil
+}
+
+func (c *journaledContainer) NetIn(hostPort, containerPort uint32) (uint32, uint32, error) {
+	hostPort, containerPort, err := c.Container.NetIn(hostPort, containerPort)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	c.journal.Journal(c.Container)
+	return hostPort, containerPort, nil
+}
+
+func (c *journaledContainer) DetailedNetIn(spec linux_backend.NetInSpec) (linux_backend.NetInSpec, error) {
+	spec, err := c.Container.DetailedNetIn(spec)
+	if err != nil {
+		return linux_backend.NetInSpec{}, err
+	}
+
+	c.journal.Journal(c.Container)
+	return spec, nil
+}
+
+func (c *journaledContainer) RemoveNetIn(spec linux_backend.NetInSpec) error {
+	if err := c.Container.RemoveNetIn(spec); err != nil {
+		return err
+	}
+
+	c.journal.Journal(c.Container)
+	return nil
+}
+
+func (c *journaledContainer) NetOut(rule garden.NetOutRule) error {
+	if err := c.Container.NetOut(rule); err != nil {
+		return err
+	}
+
+	c.journal.Journal(c.Container)
+	return nil
+}
+
+func (c *journaledContainer) RemoveNetOut(rule garden.NetOutRule) error {
+	if err := c.Container.RemoveNetOut(rule); err != nil {
+		return err
+	}
+
+	c.journal.Journal(c.Container)
+	return nil
+}
+
+func (c *journaledContainer) Run(spec garden.ProcessSpec, io garden.ProcessIO) (garden.Process, error) {
+	process, err := c.Container.Run(spec, io)
+	if err != nil {
+		return nil, err
+	}
+
+	c.journal.Journal(c.Container)
+	return process, nil
+}
+
+func (c *journaledContainer) SetGraceTime(graceTime time.Duration) error {
+	if err := c.Container.SetGraceTime(graceTime); err != nil {
+		return err
+	}
+
+	c.journal.Journal(c.Container)
+	return nil
+}
+
+func (c *journaledContainer) SetProperty(name, value string) error {
+	if err := c.Container.SetProperty(name, value); err != nil {
+		return err
+	}
+
+	c.journal.Journal(c.Container)
+	return nil
+}
+
+func (c *journaledContainer) RemoveProperty(name string) error {
+	if err := c.Container.RemoveProperty(name); err != nil {
+		return err
+	}
+
+	c.journal.Journal(c.Container)
+	return nil
+}
@@ -0,0 +1,214 @@
+package container_repository
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	"github.com/pivotal-golang/lager"
+)
+
+// PersistentContainerRepository keeps containers in memory like
+// InMemoryContainerRepository, but also writes a snapshot of each container
+// to the journal directory whenever it is added or mutated, so that the
+// containers can be restored after garden-linux is killed without being
+// given the chance to save snapshots on Stop.
+type PersistentContainerRepository struct {
+	*InMemoryContainerRepository
+
+	journalPath  string
+	journalMutex *sync.Mutex
+
+	logger lager.Logger
+}
+
+func NewPersistent(journalPath string, logger lager.Logger) (*PersistentContainerRepository, error) {
+	if err := os.MkdirAll(journalPath, 0755); err != nil {
+		return nil, fmt.Errorf("container_repository: create journal directory: %s", err)
+	}
+
+	return &PersistentContainerRepository{
+		InMemoryContainerRepository: New(),
+
+		journalPath:  journalPath,
+		journalMutex: &sync.Mutex{},
+
+		logger: logger.Session("container-journal"),
+	}, nil
+}
+
+func (cr *PersistentContainerRepository) Add(container linux_backend.Container) {
+	if _, ok := container.(*journaledContainer); !ok {
+		container = &journaledContainer{
+			Container: container,
+			journal:   cr,
+		}
+	}
+
+	cr.InMemoryContainerRepository.Add(container)
+	cr.Journal(container)
+}
+
+func (cr *PersistentContainerRepository) Delete(container linux_backend.Container) {
+	cr.InMemoryContainerRepository.Delete(container)
+
+	cr.journalMutex.Lock()
+	defer cr.journalMutex.Unlock()
+
+	err := os.Remove(cr.entryPath(container.ID()))
+	if err != nil && !os.IsNotExist(err) {
+		cr.logger.Error("failed-to-remove-entry", err, lager.Data{
+			"handle": container.Handle(),
+		})
+	}
+}
+
+// Journal writes the current snapshot of the container to the journal.
+// Failures are logged rather than returned, as the change that triggered the
+// write has already been applied to the container.
+func (cr *PersistentContainerRepository) Journal(container linux_backend.Container) {
+	jLog := cr.logger.Session("journal", lager.Data{
+		"handle": container.Handle(),
+	})
+
+	cr.journalMutex.Lock()
+	defer cr.journalMutex.Unlock()
+
+	if err := cr.writeEntry(container); err != nil {
+		jLog.Error("failed-to-write-entry", err)
+	}
+}
+
+// EventEmitter returns an emitter which passes events on to next, having first
+// journaled the containers whose state changes without an API call, i.e. which
+// are stopped or frozen when they run out of memory or whose processes exit,
+// so that Replay does not restore them in their last journaled state. The
+// journal is written before the event is passed on, so that it is never
+// skipped when the subscribers of next fall behind.
+func (cr *PersistentContainerRepository) EventEmitter(next linux_backend.EventEmitter) linux_backend.EventEmitter {
+	return &journalingEmitter{
+		repository: cr,
+		next:       next,
+	}
+}
+
+type journalingEmitter struct {
+	repository *PersistentContainerRepository
+	next       linux_backend.EventEmitter
+}
+
+func (e *journalingEmitter) Emit(event linux_backend.Event) {
+	switch event.Type {
+	case linux_backend.EventOutOfMemory, linux_backend.EventStopped, linux_backend.EventProcessExited:
+		e.repository.journalHandle(event.Handle)
+	}
+
+	e.next.Emit(event)
+}
+
+func (cr *PersistentContainerRepository) journalHandle(handle string) {
+	jLog := cr.logger.Session("journal", lager.Data{
+		"handle": handle,
+	})
+
+	cr.journalMutex.Lock()
+	defer cr.journalMutex.Unlock()
+
+	// Delete removes the container before its entry, so checking for it with
+	// the journal locked never brings back the entry of a deleted container
+	container, err := cr.FindByHandle(handle)
+	if err != nil {
+		return
+	}
+
+	if err := cr.writeEntry(container); err != nil {
+		jLog.Error("failed-to-write-entry", err)
+	}
+}
+
+// Replay calls restore with the latest journaled snapshot of every container.
+// Entries which fail to restore are removed from the journal, as the resources
+// they refer to will be pruned.
+func (cr *PersistentContainerRepository) Replay(restore func(id string, snapshot io.Reader) error) error {
+	rLog := cr.logger.Session("replay")
+
+	entries, err := ioutil.ReadDir(cr.journalPath)
+	if err != nil {
+		rLog.Error("failed-to-read-journal", err)
+		return fmt.Errorf("container_repository: read journal: %s", err)
+	}
+
+	for _, entry := range entries {
+		id := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(id, ".") {
+			continue
+		}
+
+		eLog := rLog.Session("entry", lager.Data{"id": id})
+
+		file, err := os.Open(cr.entryPath(id))
+		if err != nil {
+			eLog.Error("failed-to-open", err)
+			continue
+		}
+
+		err = restore(id, file)
+		file.Close()
+
+		if err != nil {
+			eLog.Error("failed-to-restore", err)
+			os.Remove(cr.entryPath(id))
+		}
+	}
+
+	return nil
+}
+
+func (cr *PersistentContainerRepository) writeEntry(container linux_backend.Container) error {
+	// write to a temporary file and rename it over the entry, so that a crash
+	// mid-write never leaves a truncated snapshot behind
+	tmp, err := ioutil.TempFile(cr.journalPath, "."+container.ID()+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := container.Snapshot(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), cr.entryPath(container.ID())); err != nil {
+		return err
+	}
+
+	return syncDir(cr.journalPath)
+}
+
+func (cr *PersistentContainerRepository) entryPath(id string) string {
+	return filepath.Join(cr.journalPath, id)
+}
+
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	return dir.Sync()
+}
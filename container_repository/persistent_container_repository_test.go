@@ -0,0 +1,281 @@
+package container_repository_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden-linux/container_repository"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/lager/lagertest"
+)
+
+var _ = Describe("PersistentContainerRepository", func() {
+	var (
+		journalPath    string
+		containerRepo  *container_repository.PersistentContainerRepository
+		container      *fakes.FakeContainer
+		snapshotWrites int
+	)
+
+	BeforeEach(func() {
+		var err error
+		journalPath, err = ioutil.TempDir("", "journal")
+		Expect(err).NotTo(HaveOccurred())
+
+		containerRepo, err = container_repository.NewPersistent(journalPath, lagertest.NewTestLogger("test"))
+		Expect(err).NotTo(HaveOccurred())
+
+		snapshotWrites = 0
+
+		container = new(fakes.FakeContainer)
+		container.IDReturns("some-id")
+		container.HandleReturns("some-handle")
+		container.SnapshotStub = func(out io.Writer) error {
+			snapshotWrites++
+			_, err := out.Write([]byte("some-snapshot"))
+			return err
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(journalPath)
+	})
+
+	journaled := func() string {
+		contents, err := ioutil.ReadFile(filepath.Join(journalPath, "some-id"))
+		Expect(err).NotTo(HaveOccurred())
+		return string(contents)
+	}
+
+	Describe("Add", func() {
+		It("writes the container snapshot to the journal", func() {
+			containerRepo.Add(container)
+			Expect(journaled()).To(Equal("some-snapshot"))
+		})
+
+		It("does not leave temporary files behind", func() {
+			containerRepo.Add(container)
+
+			entries, err := ioutil.ReadDir(journalPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+		})
+
+		Context("when snapshotting fails", func() {
+			It("keeps the previous entry", func() {
+				containerRepo.Add(container)
+
+				container.SnapshotStub = func(out io.Writer) error {
+					out.Write([]byte("half-a-snap"))
+					return errors.New("banana")
+				}
+
+				found, err := containerRepo.FindByHandle("some-handle")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found.SetProperty("foo", "bar")).To(Succeed())
+
+				Expect(journaled()).To(Equal("some-snapshot"))
+			})
+		})
+	})
+
+	Describe("mutating a container", func() {
+		var found linux_backend.Container
+
+		BeforeEach(func() {
+			containerRepo.Add(container)
+
+			var err error
+			found, err = containerRepo.FindByHandle("some-handle")
+			Expect(err).NotTo(HaveOccurred())
+
+			snapshotWrites = 0
+		})
+
+		It("journals after each successful change", func() {
+			Expect(found.LimitCPU(garden.CPULimits{LimitInShares: 1})).To(Succeed())
+			Expect(found.LimitMemory(garden.MemoryLimits{LimitInBytes: 1})).To(Succeed())
+			Expect(found.LimitDisk(garden.DiskLimits{ByteHard: 1})).To(Succeed())
+			Expect(found.LimitBandwidth(garden.BandwidthLimits{RateInBytesPerSecond: 1})).To(Succeed())
+			Expect(found.NetOut(garden.NetOutRule{})).To(Succeed())
+			Expect(found.RemoveNetOut(garden.NetOutRule{})).To(Succeed())
+			Expect(found.SetProperty("foo", "bar")).To(Succeed())
+			Expect(found.RemoveProperty("foo")).To(Succeed())
+
+			_, _, err := found.NetIn(1, 2)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = found.DetailedNetIn(linux_backend.NetInSpec{HostPort: 1, Protocol: linux_backend.NetInProtocolUDP})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.RemoveNetIn(linux_backend.NetInSpec{HostPort: 1, ContainerPort: 2})).To(Succeed())
+
+			_, err = found.Run(garden.ProcessSpec{}, garden.ProcessIO{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(snapshotWrites).To(Equal(12))
+		})
+
+		It("passes the change through to the container", func() {
+			Expect(found.SetProperty("foo", "bar")).To(Succeed())
+
+			Expect(container.SetPropertyCallCount()).To(Equal(1))
+			name, value := container.SetPropertyArgsForCall(0)
+			Expect(name).To(Equal("foo"))
+			Expect(value).To(Equal("bar"))
+		})
+
+		Context("when the change fails", func() {
+			BeforeEach(func() {
+				container.LimitCPUReturns(errors.New("banana"))
+			})
+
+			It("returns the error and does not journal", func() {
+				Expect(found.LimitCPU(garden.CPULimits{})).To(MatchError("banana"))
+				Expect(snapshotWrites).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("Delete", func() {
+		It("removes the entry from the journal", func() {
+			containerRepo.Add(container)
+			containerRepo.Delete(container)
+
+			_, err := os.Stat(filepath.Join(journalPath, "some-id"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+
+			_, err = containerRepo.FindByHandle("some-handle")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("EventEmitter", func() {
+		var next *fakes.FakeEventEmitter
+		var emitter linux_backend.EventEmitter
+
+		BeforeEach(func() {
+			containerRepo.Add(container)
+
+			container.SnapshotStub = func(out io.Writer) error {
+				_, err := out.Write([]byte("stopped-snapshot"))
+				return err
+			}
+
+			next = new(fakes.FakeEventEmitter)
+			emitter = containerRepo.EventEmitter(next)
+		})
+
+		for _, eventType := range []linux_backend.EventType{linux_backend.EventOutOfMemory, linux_backend.EventStopped, linux_backend.EventProcessExited} {
+			eventType := eventType
+
+			It("journals the container on "+string(eventType)+" events before passing them on", func() {
+				next.EmitStub = func(linux_backend.Event) {
+					Expect(journaled()).To(Equal("stopped-snapshot"))
+				}
+
+				emitter.Emit(linux_backend.Event{Type: eventType, Handle: "some-handle"})
+
+				Expect(next.EmitCallCount()).To(Equal(1))
+				Expect(next.EmitArgsForCall(0)).To(Equal(linux_backend.Event{Type: eventType, Handle: "some-handle"}))
+			})
+		}
+
+		It("journals every event of a burst", func() {
+			for i := 0; i < 1000; i++ {
+				emitter.Emit(linux_backend.Event{Type: linux_backend.EventProcessExited, Handle: "some-handle"})
+			}
+
+			emitter.Emit(linux_backend.Event{Type: linux_backend.EventStopped, Handle: "some-handle"})
+			Expect(journaled()).To(Equal("stopped-snapshot"))
+			Expect(next.EmitCallCount()).To(Equal(1001))
+		})
+
+		It("replays the state of a container stopped by an OOM after a crash", func() {
+			emitter.Emit(linux_backend.Event{Type: linux_backend.EventOutOfMemory, Handle: "some-handle"})
+			Expect(journaled()).To(Equal("stopped-snapshot"))
+
+			restarted, err := container_repository.NewPersistent(journalPath, lagertest.NewTestLogger("test"))
+			Expect(err).NotTo(HaveOccurred())
+
+			replayed := map[string]string{}
+			Expect(restarted.Replay(func(id string, snapshot io.Reader) error {
+				contents, err := ioutil.ReadAll(snapshot)
+				Expect(err).NotTo(HaveOccurred())
+
+				replayed[id] = string(contents)
+				return nil
+			})).To(Succeed())
+
+			Expect(replayed).To(Equal(map[string]string{"some-id": "stopped-snapshot"}))
+		})
+
+		It("passes other events on without journaling", func() {
+			emitter.Emit(linux_backend.Event{Type: linux_backend.EventProcessSpawned, Handle: "some-handle"})
+			emitter.Emit(linux_backend.Event{Type: linux_backend.EventLimitsChanged, Handle: "some-handle"})
+
+			Expect(journaled()).To(Equal("some-snapshot"))
+			Expect(next.EmitCallCount()).To(Equal(2))
+		})
+
+		Context("when the container has been deleted", func() {
+			It("does not bring its entry back", func() {
+				containerRepo.Delete(container)
+
+				emitter.Emit(linux_backend.Event{Type: linux_backend.EventStopped, Handle: "some-handle"})
+
+				_, err := os.Stat(filepath.Join(journalPath, "some-id"))
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("Replay", func() {
+		BeforeEach(func() {
+			Expect(ioutil.WriteFile(filepath.Join(journalPath, "id-1"), []byte("snapshot-1"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(journalPath, "id-2"), []byte("snapshot-2"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(journalPath, ".id-3-12345"), []byte("partial"), 0644)).To(Succeed())
+		})
+
+		It("restores every complete entry", func() {
+			replayed := map[string]string{}
+
+			err := containerRepo.Replay(func(id string, snapshot io.Reader) error {
+				contents, err := ioutil.ReadAll(snapshot)
+				Expect(err).NotTo(HaveOccurred())
+
+				replayed[id] = string(contents)
+				return nil
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(replayed).To(Equal(map[string]string{
+				"id-1": "snapshot-1",
+				"id-2": "snapshot-2",
+			}))
+		})
+
+		Context("when restoring an entry fails", func() {
+			It("removes it from the journal", func() {
+				err := containerRepo.Replay(func(id string, snapshot io.Reader) error {
+					if id == "id-1" {
+						return errors.New("banana")
+					}
+					return nil
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = os.Stat(filepath.Join(journalPath, "id-1"))
+				Expect(os.IsNotExist(err)).To(BeTrue())
+
+				_, err = os.Stat(filepath.Join(journalPath, "id-2"))
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+	})
+})
@@ -0,0 +1,239 @@
+package cpuset_pool
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// CPUSetPool tracks which of the host's online CPUs have been handed out
+// exclusively to a container, and which containers are pinned to CPUs they
+// share, so that no two containers are pinned to the same CPU unless they
+// asked to share.
+type CPUSetPool struct {
+	cpus map[int]bool
+	mems map[int]bool
+
+	owners    map[int]string
+	pins      map[string][]int
+	poolMutex sync.Mutex
+}
+
+type PoolExhaustedError struct {
+	Requested int
+	Available int
+}
+
+func (e PoolExhaustedError) Error() string {
+	return fmt.Sprintf("cpuset pool is exhausted: requested %d cpus, %d available", e.Requested, e.Available)
+}
+
+type CPUTakenError struct {
+	CPU   int
+	Owner string
+}
+
+func (e CPUTakenError) Error() string {
+	return fmt.Sprintf("cpu %d is exclusively allocated to %s", e.CPU, e.Owner)
+}
+
+type CPUPinnedError struct {
+	CPU   int
+	Owner string
+}
+
+func (e CPUPinnedError) Error() string {
+	return fmt.Sprintf("cpu %d is pinned to %s, so cannot be allocated exclusively", e.CPU, e.Owner)
+}
+
+type OfflineError struct {
+	Kind string
+	ID   int
+}
+
+func (e OfflineError) Error() string {
+	return fmt.Sprintf("%s %d is not online", e.Kind, e.ID)
+}
+
+func New(onlineCPUs, onlineMems []int) *CPUSetPool {
+	pool := &CPUSetPool{
+		cpus:   map[int]bool{},
+		mems:   map[int]bool{},
+		owners: map[int]string{},
+		pins:   map[string][]int{},
+	}
+
+	for _, cpu := range onlineCPUs {
+		pool.cpus[cpu] = true
+	}
+
+	for _, mem := range onlineMems {
+		pool.mems[mem] = true
+	}
+
+	return pool
+}
+
+// Acquire exclusively allocates count free CPUs to owner, preferring the lowest
+// numbered, and returns them in list format. CPUs which another owner is pinned
+// to are not free. Any set previously held by owner is released first.
+func (p *CPUSetPool) Acquire(owner string, count int) (string, error) {
+	if count <= 0 {
+		return "", fmt.Errorf("cpuset_pool: Acquire: invalid cpu count: %d", count)
+	}
+
+	p.poolMutex.Lock()
+	defer p.poolMutex.Unlock()
+
+	p.release(owner)
+
+	pinned := p.pinned(owner)
+
+	free := []int{}
+	for cpu := range p.cpus {
+		if _, taken := p.owners[cpu]; !taken && pinned[cpu] == "" {
+			free = append(free, cpu)
+		}
+	}
+
+	if len(free) < count {
+		return "", PoolExhaustedError{Requested: count, Available: len(free)}
+	}
+
+	sort.Ints(free)
+
+	acquired := free[:count]
+	for _, cpu := range acquired {
+		p.owners[cpu] = owner
+	}
+
+	return Format(acquired), nil
+}
+
+// Reserve exclusively allocates the given CPUs to owner, replacing any set it
+// previously held. It fails if any of them are offline, held by another owner
+// or pinned to by another owner.
+func (p *CPUSetPool) Reserve(owner string, list string) error {
+	cpus, err := Parse(list)
+	if err != nil {
+		return err
+	}
+
+	p.poolMutex.Lock()
+	defer p.poolMutex.Unlock()
+
+	if err := p.validate(owner, cpus); err != nil {
+		return err
+	}
+
+	pinned := p.pinned(owner)
+	for _, cpu := range cpus {
+		if other := pinned[cpu]; other != "" {
+			return CPUPinnedError{CPU: cpu, Owner: other}
+		}
+	}
+
+	p.release(owner)
+
+	for _, cpu := range cpus {
+		p.owners[cpu] = owner
+	}
+
+	return nil
+}
+
+// Pin records that owner is pinned to the given CPUs without holding them
+// exclusively, replacing any set it previously held, so that they are not
+// allocated exclusively to anyone else. It fails if any of them are offline or
+// held by another owner.
+func (p *CPUSetPool) Pin(owner string, list string) error {
+	cpus, err := Parse(list)
+	if err != nil {
+		return err
+	}
+
+	p.poolMutex.Lock()
+	defer p.poolMutex.Unlock()
+
+	if err := p.validate(owner, cpus); err != nil {
+		return err
+	}
+
+	p.release(owner)
+
+	p.pins[owner] = cpus
+
+	return nil
+}
+
+// Validate checks that the CPUs and memory nodes are online and that none of
+// the CPUs are held exclusively by anyone other than owner.
+func (p *CPUSetPool) Validate(owner string, cpuList, memList string) error {
+	cpus, err := Parse(cpuList)
+	if err != nil {
+		return err
+	}
+
+	mems, err := Parse(memList)
+	if err != nil {
+		return err
+	}
+
+	p.poolMutex.Lock()
+	defer p.poolMutex.Unlock()
+
+	for _, mem := range mems {
+		if !p.mems[mem] {
+			return OfflineError{Kind: "memory node", ID: mem}
+		}
+	}
+
+	return p.validate(owner, cpus)
+}
+
+func (p *CPUSetPool) Release(owner string) {
+	p.poolMutex.Lock()
+	defer p.poolMutex.Unlock()
+
+	p.release(owner)
+}
+
+func (p *CPUSetPool) validate(owner string, cpus []int) error {
+	for _, cpu := range cpus {
+		if !p.cpus[cpu] {
+			return OfflineError{Kind: "cpu", ID: cpu}
+		}
+
+		if existing, taken := p.owners[cpu]; taken && existing != owner {
+			return CPUTakenError{CPU: cpu, Owner: existing}
+		}
+	}
+
+	return nil
+}
+
+// pinned maps each CPU pinned to by an owner other than owner to one of them.
+func (p *CPUSetPool) pinned(owner string) map[int]string {
+	pinned := map[int]string{}
+	for other, cpus := range p.pins {
+		if other == owner {
+			continue
+		}
+
+		for _, cpu := range cpus {
+			pinned[cpu] = other
+		}
+	}
+
+	return pinned
+}
+
+func (p *CPUSetPool) release(owner string) {
+	for cpu, existing := range p.owners {
+		if existing == owner {
+			delete(p.owners, cpu)
+		}
+	}
+
+	delete(p.pins, owner)
+}
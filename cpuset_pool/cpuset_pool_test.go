@@ -0,0 +1,156 @@
+package cpuset_pool_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-incubator/garden-linux/cpuset_pool"
+)
+
+var _ = Describe("CPUSet pool", func() {
+	var pool *cpuset_pool.CPUSetPool
+
+	BeforeEach(func() {
+		pool = cpuset_pool.New([]int{0, 1, 2, 3}, []int{0})
+	})
+
+	Describe("acquiring", func() {
+		It("returns the lowest numbered free cpus", func() {
+			cpus, err := pool.Acquire("container-a", 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cpus).To(Equal("0-1"))
+
+			cpus, err = pool.Acquire("container-b", 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cpus).To(Equal("2"))
+		})
+
+		It("releases the owner's previous set", func() {
+			_, err := pool.Acquire("container-a", 3)
+			Expect(err).ToNot(HaveOccurred())
+
+			cpus, err := pool.Acquire("container-a", 4)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cpus).To(Equal("0-3"))
+		})
+
+		Context("when not enough cpus are free", func() {
+			It("returns a PoolExhaustedError", func() {
+				_, err := pool.Acquire("container-a", 3)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = pool.Acquire("container-b", 2)
+				Expect(err).To(Equal(cpuset_pool.PoolExhaustedError{Requested: 2, Available: 1}))
+			})
+		})
+
+		Context("when the count is not positive", func() {
+			It("returns an error", func() {
+				_, err := pool.Acquire("container-a", 0)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("reserving", func() {
+		It("prevents others from acquiring the cpus", func() {
+			Expect(pool.Reserve("container-a", "0,2")).To(Succeed())
+
+			cpus, err := pool.Acquire("container-b", 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cpus).To(Equal("1,3"))
+		})
+
+		Context("when a cpu is held by another owner", func() {
+			It("returns a CPUTakenError", func() {
+				Expect(pool.Reserve("container-a", "1")).To(Succeed())
+				Expect(pool.Reserve("container-b", "0-1")).To(Equal(cpuset_pool.CPUTakenError{CPU: 1, Owner: "container-a"}))
+			})
+		})
+
+		Context("when a cpu is offline", func() {
+			It("returns an OfflineError", func() {
+				Expect(pool.Reserve("container-a", "3-4")).To(Equal(cpuset_pool.OfflineError{Kind: "cpu", ID: 4}))
+			})
+		})
+
+		Context("when another owner is pinned to a cpu", func() {
+			It("returns a CPUPinnedError", func() {
+				Expect(pool.Pin("container-a", "1")).To(Succeed())
+				Expect(pool.Reserve("container-b", "0-1")).To(Equal(cpuset_pool.CPUPinnedError{CPU: 1, Owner: "container-a"}))
+			})
+		})
+
+		It("allows the owner's own pins to become exclusive", func() {
+			Expect(pool.Pin("container-a", "1")).To(Succeed())
+			Expect(pool.Reserve("container-a", "0-1")).To(Succeed())
+		})
+	})
+
+	Describe("pinning", func() {
+		It("keeps others from acquiring the cpus", func() {
+			Expect(pool.Pin("container-a", "0,2")).To(Succeed())
+
+			cpus, err := pool.Acquire("container-b", 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cpus).To(Equal("1,3"))
+		})
+
+		It("lets others pin to the same cpus", func() {
+			Expect(pool.Pin("container-a", "0-1")).To(Succeed())
+			Expect(pool.Pin("container-b", "1-2")).To(Succeed())
+		})
+
+		It("replaces the owner's previous set", func() {
+			Expect(pool.Reserve("container-a", "0-1")).To(Succeed())
+			Expect(pool.Pin("container-a", "2")).To(Succeed())
+
+			Expect(pool.Reserve("container-b", "0-1")).To(Succeed())
+			Expect(pool.Reserve("container-b", "2")).To(Equal(cpuset_pool.CPUPinnedError{CPU: 2, Owner: "container-a"}))
+		})
+
+		Context("when a cpu is held by another owner", func() {
+			It("returns a CPUTakenError", func() {
+				Expect(pool.Reserve("container-a", "1")).To(Succeed())
+				Expect(pool.Pin("container-b", "0-1")).To(Equal(cpuset_pool.CPUTakenError{CPU: 1, Owner: "container-a"}))
+			})
+		})
+	})
+
+	Describe("validating", func() {
+		It("allows cpus which are held by the same owner", func() {
+			Expect(pool.Reserve("container-a", "0")).To(Succeed())
+			Expect(pool.Validate("container-a", "0-1", "0")).To(Succeed())
+		})
+
+		It("rejects cpus which are held by another owner", func() {
+			Expect(pool.Reserve("container-a", "0")).To(Succeed())
+			Expect(pool.Validate("container-b", "0-1", "0")).To(HaveOccurred())
+		})
+
+		It("rejects offline memory nodes", func() {
+			Expect(pool.Validate("container-a", "0", "1")).To(Equal(cpuset_pool.OfflineError{Kind: "memory node", ID: 1}))
+		})
+	})
+
+	Describe("releasing", func() {
+		It("returns the owner's cpus to the pool", func() {
+			_, err := pool.Acquire("container-a", 4)
+			Expect(err).ToNot(HaveOccurred())
+
+			pool.Release("container-a")
+
+			cpus, err := pool.Acquire("container-b", 4)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cpus).To(Equal("0-3"))
+		})
+
+		It("drops the owner's pins", func() {
+			Expect(pool.Pin("container-a", "0-3")).To(Succeed())
+
+			pool.Release("container-a")
+
+			Expect(pool.Reserve("container-b", "0-3")).To(Succeed())
+		})
+	})
+})
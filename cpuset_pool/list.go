@@ -0,0 +1,99 @@
+package cpuset_pool
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	OnlineCPUsPath = "/sys/devices/system/cpu/online"
+	OnlineMemsPath = "/sys/devices/system/node/online"
+
+	// MaxID bounds the IDs Parse accepts, as the largest NR_CPUS the kernel
+	// can be built with, so that a range from a client cannot make it expand
+	// billions of IDs.
+	MaxID = 8192
+)
+
+// Parse parses a list in the kernel's cpuset format (e.g. "0-3,8,10-11") into
+// a sorted set of IDs, each less than MaxID.
+func Parse(list string) ([]int, error) {
+	seen := map[int]bool{}
+
+	list = strings.TrimSpace(list)
+	if list == "" {
+		return []int{}, nil
+	}
+
+	for _, part := range strings.Split(list, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(part), "-", 2)
+
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("cpuset_pool: invalid list %q", list)
+		}
+
+		last := first
+		if len(bounds) == 2 {
+			last, err = strconv.Atoi(bounds[1])
+			if err != nil || last < first {
+				return nil, fmt.Errorf("cpuset_pool: invalid list %q", list)
+			}
+		}
+
+		if last >= MaxID {
+			return nil, fmt.Errorf("cpuset_pool: invalid list %q: IDs must be less than %d", list, MaxID)
+		}
+
+		for id := first; id <= last; id++ {
+			seen[id] = true
+		}
+	}
+
+	ids := make([]int, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+
+	return ids, nil
+}
+
+// Format renders IDs in the kernel's cpuset list format, collapsing
+// consecutive runs into ranges.
+func Format(ids []int) string {
+	sorted := append([]int{}, ids...)
+	sort.Ints(sorted)
+
+	parts := []string{}
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] <= sorted[j]+1 {
+			j++
+		}
+
+		if sorted[i] == sorted[j] {
+			parts = append(parts, strconv.Itoa(sorted[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		}
+
+		i = j + 1
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// ReadOnline reads a list of online CPUs or memory nodes from sysfs.
+func ReadOnline(path string) ([]int, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(string(contents))
+}
@@ -0,0 +1,60 @@
+package cpuset_pool_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-incubator/garden-linux/cpuset_pool"
+)
+
+var _ = Describe("CPU lists", func() {
+	Describe("Parse", func() {
+		It("parses single ids and ranges", func() {
+			Expect(cpuset_pool.Parse("0-2,5,7-8\n")).To(Equal([]int{0, 1, 2, 5, 7, 8}))
+		})
+
+		It("parses an empty list", func() {
+			Expect(cpuset_pool.Parse("")).To(BeEmpty())
+		})
+
+		It("rejects malformed lists", func() {
+			for _, list := range []string{"a", "3-1", "1-", "-1", "1,,2"} {
+				_, err := cpuset_pool.Parse(list)
+				Expect(err).To(HaveOccurred(), list)
+			}
+		})
+
+		It("rejects ids at or above the cap without expanding them", func() {
+			_, err := cpuset_pool.Parse("0-2000000000")
+			Expect(err).To(MatchError(ContainSubstring("IDs must be less than 8192")))
+
+			_, err = cpuset_pool.Parse("8192")
+			Expect(err).To(HaveOccurred())
+
+			Expect(cpuset_pool.Parse("8191")).To(Equal([]int{8191}))
+		})
+	})
+
+	Describe("Format", func() {
+		It("collapses consecutive ids into ranges", func() {
+			Expect(cpuset_pool.Format([]int{8, 0, 1, 2, 5, 7})).To(Equal("0-2,5,7-8"))
+		})
+	})
+
+	Describe("ReadOnline", func() {
+		It("reads the list from the file", func() {
+			file, err := ioutil.TempFile("", "online")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.Remove(file.Name())
+
+			_, err = file.WriteString("0-3\n")
+			Expect(err).ToNot(HaveOccurred())
+			file.Close()
+
+			Expect(cpuset_pool.ReadOnline(file.Name())).To(Equal([]int{0, 1, 2, 3}))
+		})
+	})
+})
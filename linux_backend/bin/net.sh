@@ -13,6 +13,10 @@ nat_prerouting_chain="${GARDEN_IPTABLES_NAT_PREROUTING_CHAIN}"
 nat_postrouting_chain="${GARDEN_IPTABLES_NAT_POSTROUTING_CHAIN}"
 nat_instance_prefix="${GARDEN_IPTABLES_NAT_INSTANCE_PREFIX}"
 interface_name_prefix="${GARDEN_NETWORK_INTERFACE_PREFIX}"
+firewall="${GARDEN_FIREWALL:-iptables}"
+nftables_path="${GARDEN_NFTABLES_PATH:-/usr/sbin/nft}"
+nftables_filter_table="${GARDEN_NFTABLES_FILTER_TABLE:-}"
+nftables_nat_table="${GARDEN_NFTABLES_NAT_TABLE:-}"
 
 function teardown_deprecated_rules() {
   # Remove jump to garden-dispatch from INPUT
@@ -169,17 +173,98 @@ function setup_nat() {
       --jump ${nat_postrouting_chain}
 }
 
+function teardown_nftables() {
+  # Deleting the tables deletes all their chains, including per-instance ones
+  ${nftables_path} delete table ip ${nftables_filter_table} 2> /dev/null || true
+  ${nftables_path} delete table ip ${nftables_nat_table} 2> /dev/null || true
+}
+
+# Mirrors setup_filter and setup_nat. The base chains take the place of the
+# INPUT, FORWARD, PREROUTING, OUTPUT and POSTROUTING chains of iptables.
+function setup_nftables() {
+  teardown_nftables
+
+  # Determine interface device to the outside
+  default_interface=$(ip route show | grep default | cut -d' ' -f5 | head -1)
+
+  if [ "${GARDEN_IPTABLES_ALLOW_HOST_ACCESS}" != "true" ]; then
+    input_verdict="reject with icmp type host-prohibited"
+  else
+    input_verdict="accept"
+  fi
+
+  ${nftables_path} -f - <<EOF
+table ip ${nftables_filter_table} {
+  chain ${filter_input_chain} {
+    iifname "${default_interface}" accept
+    ct state established,related accept
+    ${input_verdict}
+  }
+
+  chain ${filter_forward_chain} {
+    iifname "${default_interface}" accept
+    drop
+  }
+
+  chain ${filter_default_chain} {
+    ct state established,related accept
+  }
+
+  chain input {
+    type filter hook input priority 0; policy accept;
+    iifname "${interface_name_prefix}*" jump ${filter_input_chain}
+  }
+
+  chain forward {
+    type filter hook forward priority 0; policy accept;
+    iifname "${interface_name_prefix}*" jump ${filter_forward_chain}
+  }
+}
+
+table ip ${nftables_nat_table} {
+  chain ${nat_prerouting_chain} {
+  }
+
+  chain ${nat_postrouting_chain} {
+  }
+
+  chain prerouting {
+    type nat hook prerouting priority -100; policy accept;
+    jump ${nat_prerouting_chain}
+  }
+
+  chain output {
+    type nat hook output priority -100; policy accept;
+    oifname "lo" jump ${nat_prerouting_chain}
+  }
+
+  chain postrouting {
+    type nat hook postrouting priority 100; policy accept;
+    jump ${nat_postrouting_chain}
+  }
+}
+EOF
+}
+
 case "${1}" in
   setup)
-    setup_filter
-    setup_nat
+    if [ "${firewall}" == "nftables" ]; then
+      setup_nftables
+    else
+      setup_filter
+      setup_nat
+    fi
 
     # Enable forwarding
     echo 1 > /proc/sys/net/ipv4/ip_forward
     ;;
   teardown)
-    teardown_filter
-    teardown_nat
+    if [ "${firewall}" == "nftables" ]; then
+      teardown_nftables
+    else
+      teardown_filter
+      teardown_nat
+    fi
     ;;
   *)
     echo "Unknown command: ${1}" 1>&2
@@ -47,7 +47,29 @@ function mount_nested_cgroup() {
   done
 }
 
-if ! mountpoint -q $cgroup_path; then
+function mount_unified_cgroup() {
+  mkdir -p $1
+
+  if ! mountpoint -q $1; then
+    mount -t cgroup2 cgroup2 $1
+  fi
+
+  # containers are created under a cgroup of their own, as controllers can
+  # only be delegated to cgroups without processes
+  mkdir -p ${1}/garden
+
+  for controller in $(cat ${1}/cgroup.controllers); do
+    echo "+${controller}" > ${1}/cgroup.subtree_control || true
+  done
+
+  for controller in $(cat ${1}/garden/cgroup.controllers); do
+    echo "+${controller}" > ${1}/garden/cgroup.subtree_control || true
+  done
+}
+
+if [ -f /sys/fs/cgroup/cgroup.controllers ]; then
+  mount_unified_cgroup $cgroup_path
+elif ! mountpoint -q $cgroup_path; then
   mount_nested_cgroup $cgroup_path || \
     mount_flat_cgroup $cgroup_path
 fi
@@ -0,0 +1,98 @@
+package linux_backend
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/lager"
+)
+
+type EventType string
+
+const (
+	EventCreated        = EventType("created")
+	EventStarted        = EventType("started")
+	EventProcessSpawned = EventType("process-spawned")
+	EventProcessExited  = EventType("process-exited")
+	EventOutOfMemory    = EventType("oom")
+	EventMemoryPressure = EventType("memory-pressure")
+	EventCPUThrottled   = EventType("cpu-throttled")
+	EventLimitsChanged  = EventType("limits-changed")
+	EventStopped        = EventType("stopped")
+	EventDestroyed      = EventType("destroyed")
+	EventRestoreFailed  = EventType("restore-failed")
+)
+
+type Event struct {
+	Type      EventType
+	Handle    string
+	Timestamp time.Time
+	Data      map[string]string
+}
+
+//go:generate counterfeiter . EventEmitter
+
+type EventEmitter interface {
+	Emit(Event)
+}
+
+// EventBus fans container lifecycle events out to every subscriber. Emitting
+// never blocks: events are dropped for subscribers whose buffer is full.
+type EventBus struct {
+	logger lager.Logger
+
+	mutex       sync.RWMutex
+	subscribers map[<-chan Event]chan Event
+	bufferSize  int
+}
+
+func NewEventBus(logger lager.Logger, bufferSize int) *EventBus {
+	return &EventBus{
+		logger:      logger.Session("events"),
+		subscribers: map[<-chan Event]chan Event{},
+		bufferSize:  bufferSize,
+	}
+}
+
+func (b *EventBus) Emit(event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for _, subscriber := range b.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			b.logger.Info("dropped-event", lager.Data{
+				"type":   event.Type,
+				"handle": event.Handle,
+			})
+		}
+	}
+}
+
+func (b *EventBus) Subscribe() <-chan Event {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	subscriber := make(chan Event, b.bufferSize)
+	b.subscribers[subscriber] = subscriber
+
+	return subscriber
+}
+
+func (b *EventBus) Unsubscribe(events <-chan Event) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	subscriber, found := b.subscribers[events]
+	if !found {
+		return
+	}
+
+	delete(b.subscribers, events)
+	close(subscriber)
+}
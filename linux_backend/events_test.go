@@ -0,0 +1,84 @@
+package linux_backend_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+)
+
+var _ = Describe("EventBus", func() {
+	var bus *linux_backend.EventBus
+
+	BeforeEach(func() {
+		bus = linux_backend.NewEventBus(lagertest.NewTestLogger("test"), 2)
+	})
+
+	It("delivers emitted events to every subscriber", func() {
+		first := bus.Subscribe()
+		second := bus.Subscribe()
+
+		bus.Emit(linux_backend.Event{Type: linux_backend.EventStarted, Handle: "some-handle"})
+
+		var event linux_backend.Event
+		Expect(first).To(Receive(&event))
+		Expect(event.Type).To(Equal(linux_backend.EventStarted))
+		Expect(event.Handle).To(Equal("some-handle"))
+
+		Expect(second).To(Receive(&event))
+		Expect(event.Handle).To(Equal("some-handle"))
+	})
+
+	It("timestamps events which do not have a timestamp", func() {
+		events := bus.Subscribe()
+
+		bus.Emit(linux_backend.Event{Type: linux_backend.EventStarted})
+
+		var event linux_backend.Event
+		Expect(events).To(Receive(&event))
+		Expect(event.Timestamp).To(BeTemporally("~", time.Now(), time.Second))
+	})
+
+	It("keeps an existing timestamp", func() {
+		events := bus.Subscribe()
+		timestamp := time.Unix(123, 0)
+
+		bus.Emit(linux_backend.Event{Type: linux_backend.EventStarted, Timestamp: timestamp})
+
+		var event linux_backend.Event
+		Expect(events).To(Receive(&event))
+		Expect(event.Timestamp).To(Equal(timestamp))
+	})
+
+	Context("when a subscriber's buffer is full", func() {
+		It("drops events rather than blocking", func() {
+			events := bus.Subscribe()
+
+			for i := 0; i < 5; i++ {
+				bus.Emit(linux_backend.Event{Type: linux_backend.EventStarted})
+			}
+
+			Expect(events).To(HaveLen(2))
+		})
+	})
+
+	Describe("Unsubscribe", func() {
+		It("closes the subscription and stops delivering to it", func() {
+			events := bus.Subscribe()
+			bus.Unsubscribe(events)
+
+			bus.Emit(linux_backend.Event{Type: linux_backend.EventStarted})
+
+			Expect(events).To(BeClosed())
+		})
+
+		It("ignores unknown subscriptions", func() {
+			Expect(func() {
+				bus.Unsubscribe(make(chan linux_backend.Event))
+			}).ToNot(Panic())
+		})
+	})
+})
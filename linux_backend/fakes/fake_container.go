@@ -250,6 +250,152 @@ type FakeContainer struct {
 	removePropertyReturns struct {
 		result1 error
 	}
+	PauseStub        func() error
+	pauseMutex       sync.RWMutex
+	pauseArgsForCall []struct{}
+	pauseReturns     struct {
+		result1 error
+	}
+	ResumeStub        func() error
+	resumeMutex       sync.RWMutex
+	resumeArgsForCall []struct{}
+	resumeReturns     struct {
+		result1 error
+	}
+	LimitCPUQuotaStub        func(linux_backend.CPUQuotaLimits) error
+	limitCPUQuotaMutex       sync.RWMutex
+	limitCPUQuotaArgsForCall []struct {
+		arg1 linux_backend.CPUQuotaLimits
+	}
+	limitCPUQuotaReturns struct {
+		result1 error
+	}
+	CurrentCPUQuotaLimitsStub        func() (linux_backend.CPUQuotaLimits, error)
+	currentCPUQuotaLimitsMutex       sync.RWMutex
+	currentCPUQuotaLimitsArgsForCall []struct{}
+	currentCPUQuotaLimitsReturns     struct {
+		result1 linux_backend.CPUQuotaLimits
+		result2 error
+	}
+	LimitCPUSetStub        func(linux_backend.CPUSetLimits) error
+	limitCPUSetMutex       sync.RWMutex
+	limitCPUSetArgsForCall []struct {
+		arg1 linux_backend.CPUSetLimits
+	}
+	limitCPUSetReturns struct {
+		result1 error
+	}
+	CurrentCPUSetLimitsStub        func() (linux_backend.CPUSetLimits, error)
+	currentCPUSetLimitsMutex       sync.RWMutex
+	currentCPUSetLimitsArgsForCall []struct{}
+	currentCPUSetLimitsReturns     struct {
+		result1 linux_backend.CPUSetLimits
+		result2 error
+	}
+	LimitIOStub        func(linux_backend.IOLimits) error
+	limitIOMutex       sync.RWMutex
+	limitIOArgsForCall []struct {
+		arg1 linux_backend.IOLimits
+	}
+	limitIOReturns struct {
+		result1 error
+	}
+	CurrentIOLimitsStub        func() (linux_backend.IOLimits, error)
+	currentIOLimitsMutex       sync.RWMutex
+	currentIOLimitsArgsForCall []struct{}
+	currentIOLimitsReturns     struct {
+		result1 linux_backend.IOLimits
+		result2 error
+	}
+	DetailedMetricsStub        func() (linux_backend.ContainerMetrics, error)
+	detailedMetricsMutex       sync.RWMutex
+	detailedMetricsArgsForCall []struct{}
+	detailedMetricsReturns     struct {
+		result1 linux_backend.ContainerMetrics
+		result2 error
+	}
+	LimitPidsStub        func(linux_backend.PidLimits) error
+	limitPidsMutex       sync.RWMutex
+	limitPidsArgsForCall []struct {
+		arg1 linux_backend.PidLimits
+	}
+	limitPidsReturns struct {
+		result1 error
+	}
+	DetailedNetInStub        func(linux_backend.NetInSpec) (linux_backend.NetInSpec, error)
+	detailedNetInMutex       sync.RWMutex
+	detailedNetInArgsForCall []struct {
+		arg1 linux_backend.NetInSpec
+	}
+	detailedNetInReturns struct {
+		result1 linux_backend.NetInSpec
+		result2 error
+	}
+	RemoveNetInStub        func(linux_backend.NetInSpec) error
+	removeNetInMutex       sync.RWMutex
+	removeNetInArgsForCall []struct {
+		arg1 linux_backend.NetInSpec
+	}
+	removeNetInReturns struct {
+		result1 error
+	}
+	CurrentNetOutsStub        func() []garden.NetOutRule
+	currentNetOutsMutex       sync.RWMutex
+	currentNetOutsArgsForCall []struct{}
+	currentNetOutsReturns     struct {
+		result1 []garden.NetOutRule
+	}
+	RemoveNetOutStub        func(garden.NetOutRule) error
+	removeNetOutMutex       sync.RWMutex
+	removeNetOutArgsForCall []struct {
+		arg1 garden.NetOutRule
+	}
+	removeNetOutReturns struct {
+		result1 error
+	}
+	CurrentPidLimitsStub        func() (linux_backend.PidLimits, error)
+	currentPidLimitsMutex       sync.RWMutex
+	currentPidLimitsArgsForCall []struct{}
+	currentPidLimitsReturns     struct {
+		result1 linux_backend.PidLimits
+		result2 error
+	}
+	LimitDetailedMemoryStub        func(linux_backend.MemoryLimits) error
+	limitDetailedMemoryMutex       sync.RWMutex
+	limitDetailedMemoryArgsForCall []struct {
+		arg1 linux_backend.MemoryLimits
+	}
+	limitDetailedMemoryReturns struct {
+		result1 error
+	}
+	CurrentDetailedMemoryLimitsStub        func() (linux_backend.MemoryLimits, error)
+	currentDetailedMemoryLimitsMutex       sync.RWMutex
+	currentDetailedMemoryLimitsArgsForCall []struct{}
+	currentDetailedMemoryLimitsReturns     struct {
+		result1 linux_backend.MemoryLimits
+		result2 error
+	}
+	LimitDetailedBandwidthStub        func(arg1 linux_backend.BandwidthLimits) error
+	limitDetailedBandwidthMutex       sync.RWMutex
+	limitDetailedBandwidthArgsForCall []struct {
+		arg1 linux_backend.BandwidthLimits
+	}
+	limitDetailedBandwidthReturns struct {
+		result1 error
+	}
+	CurrentDetailedBandwidthLimitsStub        func() (linux_backend.BandwidthLimits, error)
+	currentDetailedBandwidthLimitsMutex       sync.RWMutex
+	currentDetailedBandwidthLimitsArgsForCall []struct{}
+	currentDetailedBandwidthLimitsReturns     struct {
+		result1 linux_backend.BandwidthLimits
+		result2 error
+	}
+	StateStub        func() linux_backend.State
+	stateMutex       sync.RWMutex
+	stateArgsForCall []struct{}
+	stateReturns     struct {
+		result1 linux_backend.State
+	}
 }
 
 func (fake *FakeContainer) ID() string {
@@ -1157,4 +1303,563 @@ func (fake *FakeContainer) RemovePropertyReturns(result1 error) {
 	}{result1}
 }
 
+func (fake *FakeContainer) Pause() error {
+	fake.pauseMutex.Lock()
+	fake.pauseArgsForCall = append(fake.pauseArgsForCall, struct{}{})
+	fake.pauseMutex.Unlock()
+	if fake.PauseStub != nil {
+		return fake.PauseStub()
+	} else {
+		return fake.pauseReturns.result1
+	}
+}
+
+func (fake *FakeContainer) PauseCallCount() int {
+	fake.pauseMutex.RLock()
+	defer fake.pauseMutex.RUnlock()
+	return len(fake.pauseArgsForCall)
+}
+
+func (fake *FakeContainer) PauseReturns(result1 error) {
+	fake.PauseStub = nil
+	fake.pauseReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeContainer) Resume() error {
+	fake.resumeMutex.Lock()
+	fake.resumeArgsForCall = append(fake.resumeArgsForCall, struct{}{})
+	fake.resumeMutex.Unlock()
+	if fake.ResumeStub != nil {
+		return fake.ResumeStub()
+	} else {
+		return fake.resumeReturns.result1
+	}
+}
+
+func (fake *FakeContainer) ResumeCallCount() int {
+	fake.resumeMutex.RLock()
+	defer fake.resumeMutex.RUnlock()
+	return len(fake.resumeArgsForCall)
+}
+
+func (fake *FakeContainer) ResumeReturns(result1 error) {
+	fake.ResumeStub = nil
+	fake.resumeReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeContainer) LimitCPUQuota(arg1 linux_backend.CPUQuotaLimits) error {
+	fake.limitCPUQuotaMutex.Lock()
+	fake.limitCPUQuotaArgsForCall = append(fake.limitCPUQuotaArgsForCall, struct {
+		arg1 linux_backend.CPUQuotaLimits
+	}{arg1})
+	fake.limitCPUQuotaMutex.Unlock()
+	if fake.LimitCPUQuotaStub != nil {
+		return fake.LimitCPUQuotaStub(arg1)
+	} else {
+		return fake.limitCPUQuotaReturns.result1
+	}
+}
+
+func (fake *FakeContainer) LimitCPUQuotaCallCount() int {
+	fake.limitCPUQuotaMutex.RLock()
+	defer fake.limitCPUQuotaMutex.RUnlock()
+	return len(fake.limitCPUQuotaArgsForCall)
+}
+
+func (fake *FakeContainer) LimitCPUQuotaArgsForCall(i int) linux_backend.CPUQuotaLimits {
+	fake.limitCPUQuotaMutex.RLock()
+	defer fake.limitCPUQuotaMutex.RUnlock()
+	return fake.limitCPUQuotaArgsForCall[i].arg1
+}
+
+func (fake *FakeContainer) LimitCPUQuotaReturns(result1 error) {
+	fake.LimitCPUQuotaStub = nil
+	fake.limitCPUQuotaReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeContainer) CurrentCPUQuotaLimits() (linux_backend.CPUQuotaLimits, error) {
+	fake.currentCPUQuotaLimitsMutex.Lock()
+	fake.currentCPUQuotaLimitsArgsForCall = append(fake.currentCPUQuotaLimitsArgsForCall, struct{}{})
+	fake.currentCPUQuotaLimitsMutex.Unlock()
+	if fake.CurrentCPUQuotaLimitsStub != nil {
+		return fake.CurrentCPUQuotaLimitsStub()
+	} else {
+		return fake.currentCPUQuotaLimitsReturns.result1, fake.currentCPUQuotaLimitsReturns.result2
+	}
+}
+
+func (fake *FakeContainer) CurrentCPUQuotaLimitsCallCount() int {
+	fake.currentCPUQuotaLimitsMutex.RLock()
+	defer fake.currentCPUQuotaLimitsMutex.RUnlock()
+	return len(fake.currentCPUQuotaLimitsArgsForCall)
+}
+
+func (fake *FakeContainer) CurrentCPUQuotaLimitsReturns(result1 linux_backend.CPUQuotaLimits, result2 error) {
+	fake.CurrentCPUQuotaLimitsStub = nil
+	fake.currentCPUQuotaLimitsReturns = struct {
+		result1 linux_backend.CPUQuotaLimits
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeContainer) LimitCPUSet(arg1 linux_backend.CPUSetLimits) error {
+	fake.limitCPUSetMutex.Lock()
+	fake.limitCPUSetArgsForCall = append(fake.limitCPUSetArgsForCall, struct {
+		arg1 linux_backend.CPUSetLimits
+	}{arg1})
+	fake.limitCPUSetMutex.Unlock()
+	if fake.LimitCPUSetStub != nil {
+		return fake.LimitCPUSetStub(arg1)
+	} else {
+		return fake.limitCPUSetReturns.result1
+	}
+}
+
+func (fake *FakeContainer) LimitCPUSetCallCount() int {
+	fake.limitCPUSetMutex.RLock()
+	defer fake.limitCPUSetMutex.RUnlock()
+	return len(fake.limitCPUSetArgsForCall)
+}
+
+func (fake *FakeContainer) LimitCPUSetArgsForCall(i int) linux_backend.CPUSetLimits {
+	fake.limitCPUSetMutex.RLock()
+	defer fake.limitCPUSetMutex.RUnlock()
+	return fake.limitCPUSetArgsForCall[i].arg1
+}
+
+func (fake *FakeContainer) LimitCPUSetReturns(result1 error) {
+	fake.LimitCPUSetStub = nil
+	fake.limitCPUSetReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeContainer) CurrentCPUSetLimits() (linux_backend.CPUSetLimits, error) {
+	fake.currentCPUSetLimitsMutex.Lock()
+	fake.currentCPUSetLimitsArgsForCall = append(fake.currentCPUSetLimitsArgsForCall, struct{}{})
+	fake.currentCPUSetLimitsMutex.Unlock()
+	if fake.CurrentCPUSetLimitsStub != nil {
+		return fake.CurrentCPUSetLimitsStub()
+	} else {
+		return fake.currentCPUSetLimitsReturns.result1, fake.currentCPUSetLimitsReturns.result2
+	}
+}
+
+func (fake *FakeContainer) CurrentCPUSetLimitsCallCount() int {
+	fake.currentCPUSetLimitsMutex.RLock()
+	defer fake.currentCPUSetLimitsMutex.RUnlock()
+	return len(fake.currentCPUSetLimitsArgsForCall)
+}
+
+func (fake *FakeContainer) CurrentCPUSetLimitsReturns(result1 linux_backend.CPUSetLimits, result2 error) {
+	fake.CurrentCPUSetLimitsStub = nil
+	fake.currentCPUSetLimitsReturns = struct {
+		result1 linux_backend.CPUSetLimits
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeContainer) LimitIO(arg1 linux_backend.IOLimits) error {
+	fake.limitIOMutex.Lock()
+	fake.limitIOArgsForCall = append(fake.limitIOArgsForCall, struct {
+		arg1 linux_backend.IOLimits
+	}{arg1})
+	fake.limitIOMutex.Unlock()
+	if fake.LimitIOStub != nil {
+		return fake.LimitIOStub(arg1)
+	} else {
+		return fake.limitIOReturns.result1
+	}
+}
+
+func (fake *FakeContainer) LimitIOCallCount() int {
+	fake.limitIOMutex.RLock()
+	defer fake.limitIOMutex.RUnlock()
+	return len(fake.limitIOArgsForCall)
+}
+
+func (fake *FakeContainer) LimitIOArgsForCall(i int) linux_backend.IOLimits {
+	fake.limitIOMutex.RLock()
+	defer fake.limitIOMutex.RUnlock()
+	return fake.limitIOArgsForCall[i].arg1
+}
+
+func (fake *FakeContainer) LimitIOReturns(result1 error) {
+	fake.LimitIOStub = nil
+	fake.limitIOReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeContainer) CurrentIOLimits() (linux_backend.IOLimits, error) {
+	fake.currentIOLimitsMutex.Lock()
+	fake.currentIOLimitsArgsForCall = append(fake.currentIOLimitsArgsForCall, struct{}{})
+	fake.currentIOLimitsMutex.Unlock()
+	if fake.CurrentIOLimitsStub != nil {
+		return fake.CurrentIOLimitsStub()
+	} else {
+		return fake.currentIOLimitsReturns.result1, fake.currentIOLimitsReturns.result2
+	}
+}
+
+func (fake *FakeContainer) CurrentIOLimitsCallCount() int {
+	fake.currentIOLimitsMutex.RLock()
+	defer fake.currentIOLimitsMutex.RUnlock()
+	return len(fake.currentIOLimitsArgsForCall)
+}
+
+func (fake *FakeContainer) CurrentIOLimitsReturns(result1 linux_backend.IOLimits, result2 error) {
+	fake.CurrentIOLimitsStub = nil
+	fake.currentIOLimitsReturns = struct {
+		result1 linux_backend.IOLimits
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeContainer) DetailedMetrics() (linux_backend.ContainerMetrics, error) {
+	fake.detailedMetricsMutex.Lock()
+	fake.detailedMetricsArgsForCall = append(fake.detailedMetricsArgsForCall, struct{}{})
+	fake.detailedMetricsMutex.Unlock()
+	if fake.DetailedMetricsStub != nil {
+		return fake.DetailedMetricsStub()
+	} else {
+		return fake.detailedMetricsReturns.result1, fake.detailedMetricsReturns.result2
+	}
+}
+
+func (fake *FakeContainer) DetailedMetricsCallCount() int {
+	fake.detailedMetricsMutex.RLock()
+	defer fake.detailedMetricsMutex.RUnlock()
+	return len(fake.detailedMetricsArgsForCall)
+}
+
+func (fake *FakeContainer) DetailedMetricsReturns(result1 linux_backend.ContainerMetrics, result2 error) {
+	fake.DetailedMetricsStub = nil
+	fake.detailedMetricsReturns = struct {
+		result1 linux_backend.ContainerMetrics
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeContainer) LimitPids(arg1 linux_backend.PidLimits) error {
+	fake.limitPidsMutex.Lock()
+	fake.limitPidsArgsForCall = append(fake.limitPidsArgsForCall, struct {
+		arg1 linux_backend.PidLimits
+	}{arg1})
+	fake.limitPidsMutex.Unlock()
+	if fake.LimitPidsStub != nil {
+		return fake.LimitPidsStub(arg1)
+	} else {
+		return fake.limitPidsReturns.result1
+	}
+}
+
+func (fake *FakeContainer) LimitPidsCallCount() int {
+	fake.limitPidsMutex.RLock()
+	defer fake.limitPidsMutex.RUnlock()
+	return len(fake.limitPidsArgsForCall)
+}
+
+func (fake *FakeContainer) LimitPidsArgsForCall(i int) linux_backend.PidLimits {
+	fake.limitPidsMutex.RLock()
+	defer fake.limitPidsMutex.RUnlock()
+	return fake.limitPidsArgsForCall[i].arg1
+}
+
+func (fake *FakeContainer) LimitPidsReturns(result1 error) {
+	fake.LimitPidsStub = nil
+	fake.limitPidsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeContainer) DetailedNetIn(arg1 linux_backend.NetInSpec) (linux_backend.NetInSpec, error) {
+	fake.detailedNetInMutex.Lock()
+	fake.detailedNetInArgsForCall = append(fake.detailedNetInArgsForCall, struct {
+		arg1 linux_backend.NetInSpec
+	}{arg1})
+	fake.detailedNetInMutex.Unlock()
+	if fake.DetailedNetInStub != nil {
+		return fake.DetailedNetInStub(arg1)
+	} else {
+		return fake.detailedNetInReturns.result1, fake.detailedNetInReturns.result2
+	}
+}
+
+func (fake *FakeContainer) DetailedNetInCallCount() int {
+	fake.detailedNetInMutex.RLock()
+	defer fake.detailedNetInMutex.RUnlock()
+	return len(fake.detailedNetInArgsForCall)
+}
+
+func (fake *FakeContainer) DetailedNetInArgsForCall(i int) linux_backend.NetInSpec {
+	fake.detailedNetInMutex.RLock()
+	defer fake.detailedNetInMutex.RUnlock()
+	return fake.detailedNetInArgsForCall[i].arg1
+}
+
+func (fake *FakeContainer) DetailedNetInReturns(result1 linux_backend.NetInSpec, result2 error) {
+	fake.DetailedNetInStub = nil
+	fake.detailedNetInReturns = struct {
+		result1 linux_backend.NetInSpec
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeContainer) RemoveNetIn(arg1 linux_backend.NetInSpec) error {
+	fake.removeNetInMutex.Lock()
+	fake.removeNetInArgsForCall = append(fake.removeNetInArgsForCall, struct {
+		arg1 linux_backend.NetInSpec
+	}{arg1})
+	fake.removeNetInMutex.Unlock()
+	if fake.RemoveNetInStub != nil {
+		return fake.RemoveNetInStub(arg1)
+	} else {
+		return fake.removeNetInReturns.result1
+	}
+}
+
+func (fake *FakeContainer) RemoveNetInCallCount() int {
+	fake.removeNetInMutex.RLock()
+	defer fake.removeNetInMutex.RUnlock()
+	return len(fake.removeNetInArgsForCall)
+}
+
+func (fake *FakeContainer) RemoveNetInArgsForCall(i int) linux_backend.NetInSpec {
+	fake.removeNetInMutex.RLock()
+	defer fake.removeNetInMutex.RUnlock()
+	return fake.removeNetInArgsForCall[i].arg1
+}
+
+func (fake *FakeContainer) RemoveNetInReturns(result1 error) {
+	fake.RemoveNetInStub = nil
+	fake.removeNetInReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeContainer) CurrentNetOuts() []garden.NetOutRule {
+	fake.currentNetOutsMutex.Lock()
+	fake.currentNetOutsArgsForCall = append(fake.currentNetOutsArgsForCall, struct{}{})
+	fake.currentNetOutsMutex.Unlock()
+	if fake.CurrentNetOutsStub != nil {
+		return fake.CurrentNetOutsStub()
+	} else {
+		return fake.currentNetOutsReturns.result1
+	}
+}
+
+func (fake *FakeContainer) CurrentNetOutsCallCount() int {
+	fake.currentNetOutsMutex.RLock()
+	defer fake.currentNetOutsMutex.RUnlock()
+	return len(fake.currentNetOutsArgsForCall)
+}
+
+func (fake *FakeContainer) CurrentNetOutsReturns(result1 []garden.NetOutRule) {
+	fake.CurrentNetOutsStub = nil
+	fake.currentNetOutsReturns = struct {
+		result1 []garden.NetOutRule
+	}{result1}
+}
+
+func (fake *FakeContainer) RemoveNetOut(arg1 garden.NetOutRule) error {
+	fake.removeNetOutMutex.Lock()
+	fake.removeNetOutArgsForCall = append(fake.removeNetOutArgsForCall, struct {
+		arg1 garden.NetOutRule
+	}{arg1})
+	fake.removeNetOutMutex.Unlock()
+	if fake.RemoveNetOutStub != nil {
+		return fake.RemoveNetOutStub(arg1)
+	} else {
+		return fake.removeNetOutReturns.result1
+	}
+}
+
+func (fake *FakeContainer) RemoveNetOutCallCount() int {
+	fake.removeNetOutMutex.RLock()
+	defer fake.removeNetOutMutex.RUnlock()
+	return len(fake.removeNetOutArgsForCall)
+}
+
+func (fake *FakeContainer) RemoveNetOutArgsForCall(i int) garden.NetOutRule {
+	fake.removeNetOutMutex.RLock()
+	defer fake.removeNetOutMutex.RUnlock()
+	return fake.removeNetOutArgsForCall[i].arg1
+}
+
+func (fake *FakeContainer) RemoveNetOutReturns(result1 error) {
+	fake.RemoveNetOutStub = nil
+	fake.removeNetOutReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeContainer) CurrentPidLimits() (linux_backend.PidLimits, error) {
+	fake.currentPidLimitsMutex.Lock()
+	fake.currentPidLimitsArgsForCall = append(fake.currentPidLimitsArgsForCall, struct{}{})
+	fake.currentPidLimitsMutex.Unlock()
+	if fake.CurrentPidLimitsStub != nil {
+		return fake.CurrentPidLimitsStub()
+	} else {
+		return fake.currentPidLimitsReturns.result1, fake.currentPidLimitsReturns.result2
+	}
+}
+
+func (fake *FakeContainer) CurrentPidLimitsCallCount() int {
+	fake.currentPidLimitsMutex.RLock()
+	defer fake.currentPidLimitsMutex.RUnlock()
+	return len(fake.currentPidLimitsArgsForCall)
+}
+
+func (fake *FakeContainer) CurrentPidLimitsReturns(result1 linux_backend.PidLimits, result2 error) {
+	fake.CurrentPidLimitsStub = nil
+	fake.currentPidLimitsReturns = struct {
+		result1 linux_backend.PidLimits
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeContainer) LimitDetailedMemory(arg1 linux_backend.MemoryLimits) error {
+	fake.limitDetailedMemoryMutex.Lock()
+	fake.limitDetailedMemoryArgsForCall = append(fake.limitDetailedMemoryArgsForCall, struct {
+		arg1 linux_backend.MemoryLimits
+	}{arg1})
+	fake.limitDetailedMemoryMutex.Unlock()
+	if fake.LimitDetailedMemoryStub != nil {
+		return fake.LimitDetailedMemoryStub(arg1)
+	} else {
+		return fake.limitDetailedMemoryReturns.result1
+	}
+}
+
+func (fake *FakeContainer) LimitDetailedMemoryCallCount() int {
+	fake.limitDetailedMemoryMutex.RLock()
+	defer fake.limitDetailedMemoryMutex.RUnlock()
+	return len(fake.limitDetailedMemoryArgsForCall)
+}
+
+func (fake *FakeContainer) LimitDetailedMemoryArgsForCall(i int) linux_backend.MemoryLimits {
+	fake.limitDetailedMemoryMutex.RLock()
+	defer fake.limitDetailedMemoryMutex.RUnlock()
+	return fake.limitDetailedMemoryArgsForCall[i].arg1
+}
+
+func (fake *FakeContainer) LimitDetailedMemoryReturns(result1 error) {
+	fake.LimitDetailedMemoryStub = nil
+	fake.limitDetailedMemoryReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeContainer) CurrentDetailedMemoryLimits() (linux_backend.MemoryLimits, error) {
+	fake.currentDetailedMemoryLimitsMutex.Lock()
+	fake.currentDetailedMemoryLimitsArgsForCall = append(fake.currentDetailedMemoryLimitsArgsForCall, struct{}{})
+	fake.currentDetailedMemoryLimitsMutex.Unlock()
+	if fake.CurrentDetailedMemoryLimitsStub != nil {
+		return fake.CurrentDetailedMemoryLimitsStub()
+	} else {
+		return fake.currentDetailedMemoryLimitsReturns.result1, fake.currentDetailedMemoryLimitsReturns.result2
+	}
+}
+
+func (fake *FakeContainer) CurrentDetailedMemoryLimitsCallCount() int {
+	fake.currentDetailedMemoryLimitsMutex.RLock()
+	defer fake.currentDetailedMemoryLimitsMutex.RUnlock()
+	return len(fake.currentDetailedMemoryLimitsArgsForCall)
+}
+
+func (fake *FakeContainer) CurrentDetailedMemoryLimitsReturns(result1 linux_backend.MemoryLimits, result2 error) {
+	fake.CurrentDetailedMemoryLimitsStub = nil
+	fake.currentDetailedMemoryLimitsReturns = struct {
+		result1 linux_backend.MemoryLimits
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeContainer) LimitDetailedBandwidth(arg1 linux_backend.BandwidthLimits) error {
+	fake.limitDetailedBandwidthMutex.Lock()
+	fake.limitDetailedBandwidthArgsForCall = append(fake.limitDetailedBandwidthArgsForCall, struct {
+		arg1 linux_backend.BandwidthLimits
+	}{arg1})
+	fake.limitDetailedBandwidthMutex.Unlock()
+	if fake.LimitDetailedBandwidthStub != nil {
+		return fake.LimitDetailedBandwidthStub(arg1)
+	} else {
+		return fake.limitDetailedBandwidthReturns.result1
+	}
+}
+
+func (fake *FakeContainer) LimitDetailedBandwidthCallCount() int {
+	fake.limitDetailedBandwidthMutex.RLock()
+	defer fake.limitDetailedBandwidthMutex.RUnlock()
+	return len(fake.limitDetailedBandwidthArgsForCall)
+}
+
+func (fake *FakeContainer) LimitDetailedBandwidthArgsForCall(i int) linux_backend.BandwidthLimits {
+	fake.limitDetailedBandwidthMutex.RLock()
+	defer fake.limitDetailedBandwidthMutex.RUnlock()
+	return fake.limitDetailedBandwidthArgsForCall[i].arg1
+}
+
+func (fake *FakeContainer) LimitDetailedBandwidthReturns(result1 error) {
+	fake.LimitDetailedBandwidthStub = nil
+	fake.limitDetailedBandwidthReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeContainer) CurrentDetailedBandwidthLimits() (linux_backend.BandwidthLimits, error) {
+	fake.currentDetailedBandwidthLimitsMutex.Lock()
+	fake.currentDetailedBandwidthLimitsArgsForCall = append(fake.currentDetailedBandwidthLimitsArgsForCall, struct{}{})
+	fake.currentDetailedBandwidthLimitsMutex.Unlock()
+	if fake.CurrentDetailedBandwidthLimitsStub != nil {
+		return fake.CurrentDetailedBandwidthLimitsStub()
+	} else {
+		return fake.currentDetailedBandwidthLimitsReturns.result1, fake.currentDetailedBandwidthLimitsReturns.result2
+	}
+}
+
+func (fake *FakeContainer) CurrentDetailedBandwidthLimitsCallCount() int {
+	fake.currentDetailedBandwidthLimitsMutex.RLock()
+	defer fake.currentDetailedBandwidthLimitsMutex.RUnlock()
+	return len(fake.currentDetailedBandwidthLimitsArgsForCall)
+}
+
+func (fake *FakeContainer) CurrentDetailedBandwidthLimitsReturns(result1 linux_backend.BandwidthLimits, result2 error) {
+	fake.CurrentDetailedBandwidthLimitsStub = nil
+	fake.currentDetailedBandwidthLimitsReturns = struct {
+		result1 linux_backend.BandwidthLimits
+		result2 error
+	}{result1, result2}
+}
+func (fake *FakeContainer) State() linux_backend.State {
+	fake.stateMutex.Lock()
+	fake.stateArgsForCall = append(fake.stateArgsForCall, struct{}{})
+	fake.stateMutex.Unlock()
+	if fake.StateStub != nil {
+		return fake.StateStub()
+	} else {
+		return fake.stateReturns.result1
+	}
+}
+
+func (fake *FakeContainer) StateCallCount() int {
+	fake.stateMutex.RLock()
+	defer fake.stateMutex.RUnlock()
+	return len(fake.stateArgsForCall)
+}
+
+func (fake *FakeContainer) StateReturns(result1 linux_backend.State) {
+	fake.StateStub = nil
+	fake.stateReturns = struct {
+		result1 linux_backend.State
+	}{result1}
+}
+
 var _ linux_backend.Container = new(FakeContainer)
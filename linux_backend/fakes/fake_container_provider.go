@@ -16,6 +16,14 @@ type FakeContainerProvider struct {
 	provideContainerReturns struct {
 		result1 linux_backend.Container
 	}
+	ValidateOOMPolicyStub        func(linux_backend.OOMPolicy) error
+	validateOOMPolicyMutex       sync.RWMutex
+	validateOOMPolicyArgsForCall []struct {
+		arg1 linux_backend.OOMPolicy
+	}
+	validateOOMPolicyReturns struct {
+		result1 error
+	}
 }
 
 func (fake *FakeContainerProvider) ProvideContainer(arg1 linux_backend.LinuxContainerSpec) linux_backend.Container {
@@ -50,4 +58,36 @@ func (fake *FakeContainerProvider) ProvideContainerReturns(result1 linux_backend
 	}{result1}
 }
 
+func (fake *FakeContainerProvider) ValidateOOMPolicy(arg1 linux_backend.OOMPolicy) error {
+	fake.validateOOMPolicyMutex.Lock()
+	fake.validateOOMPolicyArgsForCall = append(fake.validateOOMPolicyArgsForCall, struct {
+		arg1 linux_backend.OOMPolicy
+	}{arg1})
+	fake.validateOOMPolicyMutex.Unlock()
+	if fake.ValidateOOMPolicyStub != nil {
+		return fake.ValidateOOMPolicyStub(arg1)
+	} else {
+		return fake.validateOOMPolicyReturns.result1
+	}
+}
+
+func (fake *FakeContainerProvider) ValidateOOMPolicyCallCount() int {
+	fake.validateOOMPolicyMutex.RLock()
+	defer fake.validateOOMPolicyMutex.RUnlock()
+	return len(fake.validateOOMPolicyArgsForCall)
+}
+
+func (fake *FakeContainerProvider) ValidateOOMPolicyArgsForCall(i int) linux_backend.OOMPolicy {
+	fake.validateOOMPolicyMutex.RLock()
+	defer fake.validateOOMPolicyMutex.RUnlock()
+	return fake.validateOOMPolicyArgsForCall[i].arg1
+}
+
+func (fake *FakeContainerProvider) ValidateOOMPolicyReturns(result1 error) {
+	fake.ValidateOOMPolicyStub = nil
+	fake.validateOOMPolicyReturns = struct {
+		result1 error
+	}{result1}
+}
+
 var _ linux_backend.ContainerProvider = new(FakeContainerProvider)
@@ -0,0 +1,213 @@
+// This file was generated by counterfeiter
+package fakes
+
+import (
+	"sync"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+)
+
+type FakeCPUSetPool struct {
+	AcquireStub        func(owner string, count int) (string, error)
+	acquireMutex       sync.RWMutex
+	acquireArgsForCall []struct {
+		owner string
+		count int
+	}
+	acquireReturns struct {
+		result1 string
+		result2 error
+	}
+	ReserveStub        func(owner string, cpus string) error
+	reserveMutex       sync.RWMutex
+	reserveArgsForCall []struct {
+		owner string
+		cpus  string
+	}
+	reserveReturns struct {
+		result1 error
+	}
+	PinStub        func(owner string, cpus string) error
+	pinMutex       sync.RWMutex
+	pinArgsForCall []struct {
+		owner string
+		cpus  string
+	}
+	pinReturns struct {
+		result1 error
+	}
+	ValidateStub        func(owner string, cpus string, mems string) error
+	validateMutex       sync.RWMutex
+	validateArgsForCall []struct {
+		owner string
+		cpus  string
+		mems  string
+	}
+	validateReturns struct {
+		result1 error
+	}
+	ReleaseStub        func(owner string)
+	releaseMutex       sync.RWMutex
+	releaseArgsForCall []struct {
+		owner string
+	}
+}
+
+func (fake *FakeCPUSetPool) Acquire(owner string, count int) (string, error) {
+	fake.acquireMutex.Lock()
+	fake.acquireArgsForCall = append(fake.acquireArgsForCall, struct {
+		owner string
+		count int
+	}{owner, count})
+	fake.acquireMutex.Unlock()
+	if fake.AcquireStub != nil {
+		return fake.AcquireStub(owner, count)
+	} else {
+		return fake.acquireReturns.result1, fake.acquireReturns.result2
+	}
+}
+
+func (fake *FakeCPUSetPool) AcquireCallCount() int {
+	fake.acquireMutex.RLock()
+	defer fake.acquireMutex.RUnlock()
+	return len(fake.acquireArgsForCall)
+}
+
+func (fake *FakeCPUSetPool) AcquireArgsForCall(i int) (string, int) {
+	fake.acquireMutex.RLock()
+	defer fake.acquireMutex.RUnlock()
+	return fake.acquireArgsForCall[i].owner, fake.acquireArgsForCall[i].count
+}
+
+func (fake *FakeCPUSetPool) AcquireReturns(result1 string, result2 error) {
+	fake.AcquireStub = nil
+	fake.acquireReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCPUSetPool) Reserve(owner string, cpus string) error {
+	fake.reserveMutex.Lock()
+	fake.reserveArgsForCall = append(fake.reserveArgsForCall, struct {
+		owner string
+		cpus  string
+	}{owner, cpus})
+	fake.reserveMutex.Unlock()
+	if fake.ReserveStub != nil {
+		return fake.ReserveStub(owner, cpus)
+	} else {
+		return fake.reserveReturns.result1
+	}
+}
+
+func (fake *FakeCPUSetPool) ReserveCallCount() int {
+	fake.reserveMutex.RLock()
+	defer fake.reserveMutex.RUnlock()
+	return len(fake.reserveArgsForCall)
+}
+
+func (fake *FakeCPUSetPool) ReserveArgsForCall(i int) (string, string) {
+	fake.reserveMutex.RLock()
+	defer fake.reserveMutex.RUnlock()
+	return fake.reserveArgsForCall[i].owner, fake.reserveArgsForCall[i].cpus
+}
+
+func (fake *FakeCPUSetPool) ReserveReturns(result1 error) {
+	fake.ReserveStub = nil
+	fake.reserveReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCPUSetPool) Pin(owner string, cpus string) error {
+	fake.pinMutex.Lock()
+	fake.pinArgsForCall = append(fake.pinArgsForCall, struct {
+		owner string
+		cpus  string
+	}{owner, cpus})
+	fake.pinMutex.Unlock()
+	if fake.PinStub != nil {
+		return fake.PinStub(owner, cpus)
+	} else {
+		return fake.pinReturns.result1
+	}
+}
+
+func (fake *FakeCPUSetPool) PinCallCount() int {
+	fake.pinMutex.RLock()
+	defer fake.pinMutex.RUnlock()
+	return len(fake.pinArgsForCall)
+}
+
+func (fake *FakeCPUSetPool) PinArgsForCall(i int) (string, string) {
+	fake.pinMutex.RLock()
+	defer fake.pinMutex.RUnlock()
+	return fake.pinArgsForCall[i].owner, fake.pinArgsForCall[i].cpus
+}
+
+func (fake *FakeCPUSetPool) PinReturns(result1 error) {
+	fake.PinStub = nil
+	fake.pinReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCPUSetPool) Validate(owner string, cpus string, mems string) error {
+	fake.validateMutex.Lock()
+	fake.validateArgsForCall = append(fake.validateArgsForCall, struct {
+		owner string
+		cpus  string
+		mems  string
+	}{owner, cpus, mems})
+	fake.validateMutex.Unlock()
+	if fake.ValidateStub != nil {
+		return fake.ValidateStub(owner, cpus, mems)
+	} else {
+		return fake.validateReturns.result1
+	}
+}
+
+func (fake *FakeCPUSetPool) ValidateCallCount() int {
+	fake.validateMutex.RLock()
+	defer fake.validateMutex.RUnlock()
+	return len(fake.validateArgsForCall)
+}
+
+func (fake *FakeCPUSetPool) ValidateArgsForCall(i int) (string, string, string) {
+	fake.validateMutex.RLock()
+	defer fake.validateMutex.RUnlock()
+	return fake.validateArgsForCall[i].owner, fake.validateArgsForCall[i].cpus, fake.validateArgsForCall[i].mems
+}
+
+func (fake *FakeCPUSetPool) ValidateReturns(result1 error) {
+	fake.ValidateStub = nil
+	fake.validateReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCPUSetPool) Release(owner string) {
+	fake.releaseMutex.Lock()
+	fake.releaseArgsForCall = append(fake.releaseArgsForCall, struct {
+		owner string
+	}{owner})
+	fake.releaseMutex.Unlock()
+	if fake.ReleaseStub != nil {
+		fake.ReleaseStub(owner)
+	}
+}
+
+func (fake *FakeCPUSetPool) ReleaseCallCount() int {
+	fake.releaseMutex.RLock()
+	defer fake.releaseMutex.RUnlock()
+	return len(fake.releaseArgsForCall)
+}
+
+func (fake *FakeCPUSetPool) ReleaseArgsForCall(i int) string {
+	fake.releaseMutex.RLock()
+	defer fake.releaseMutex.RUnlock()
+	return fake.releaseArgsForCall[i].owner
+}
+
+var _ linux_backend.CPUSetPool = new(FakeCPUSetPool)
@@ -0,0 +1,41 @@
+// This file was generated by counterfeiter
+package fakes
+
+import (
+	"sync"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+)
+
+type FakeEventEmitter struct {
+	EmitStub        func(linux_backend.Event)
+	emitMutex       sync.RWMutex
+	emitArgsForCall []struct {
+		arg1 linux_backend.Event
+	}
+}
+
+func (fake *FakeEventEmitter) Emit(arg1 linux_backend.Event) {
+	fake.emitMutex.Lock()
+	fake.emitArgsForCall = append(fake.emitArgsForCall, struct {
+		arg1 linux_backend.Event
+	}{arg1})
+	fake.emitMutex.Unlock()
+	if fake.EmitStub != nil {
+		fake.EmitStub(arg1)
+	}
+}
+
+func (fake *FakeEventEmitter) EmitCallCount() int {
+	fake.emitMutex.RLock()
+	defer fake.emitMutex.RUnlock()
+	return len(fake.emitArgsForCall)
+}
+
+func (fake *FakeEventEmitter) EmitArgsForCall(i int) linux_backend.Event {
+	fake.emitMutex.RLock()
+	defer fake.emitMutex.RUnlock()
+	return fake.emitArgsForCall[i].arg1
+}
+
+var _ linux_backend.EventEmitter = new(FakeEventEmitter)
@@ -0,0 +1,88 @@
+// This file was generated by counterfeiter
+package fakes
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+)
+
+type FakeMetricsHistory struct {
+	WindowsStub        func() []time.Duration
+	windowsMutex       sync.RWMutex
+	windowsArgsForCall []struct{}
+	windowsReturns     struct {
+		result1 []time.Duration
+	}
+	RatesStub        func(handle string, window time.Duration) (linux_backend.MetricRates, error)
+	ratesMutex       sync.RWMutex
+	ratesArgsForCall []struct {
+		handle string
+		window time.Duration
+	}
+	ratesReturns struct {
+		result1 linux_backend.MetricRates
+		result2 error
+	}
+}
+
+func (fake *FakeMetricsHistory) Windows() []time.Duration {
+	fake.windowsMutex.Lock()
+	fake.windowsArgsForCall = append(fake.windowsArgsForCall, struct{}{})
+	fake.windowsMutex.Unlock()
+	if fake.WindowsStub != nil {
+		return fake.WindowsStub()
+	} else {
+		return fake.windowsReturns.result1
+	}
+}
+
+func (fake *FakeMetricsHistory) WindowsCallCount() int {
+	fake.windowsMutex.RLock()
+	defer fake.windowsMutex.RUnlock()
+	return len(fake.windowsArgsForCall)
+}
+
+func (fake *FakeMetricsHistory) WindowsReturns(result1 []time.Duration) {
+	fake.WindowsStub = nil
+	fake.windowsReturns = struct {
+		result1 []time.Duration
+	}{result1}
+}
+
+func (fake *FakeMetricsHistory) Rates(handle string, window time.Duration) (linux_backend.MetricRates, error) {
+	fake.ratesMutex.Lock()
+	fake.ratesArgsForCall = append(fake.ratesArgsForCall, struct {
+		handle string
+		window time.Duration
+	}{handle, window})
+	fake.ratesMutex.Unlock()
+	if fake.RatesStub != nil {
+		return fake.RatesStub(handle, window)
+	} else {
+		return fake.ratesReturns.result1, fake.ratesReturns.result2
+	}
+}
+
+func (fake *FakeMetricsHistory) RatesCallCount() int {
+	fake.ratesMutex.RLock()
+	defer fake.ratesMutex.RUnlock()
+	return len(fake.ratesArgsForCall)
+}
+
+func (fake *FakeMetricsHistory) RatesArgsForCall(i int) (string, time.Duration) {
+	fake.ratesMutex.RLock()
+	defer fake.ratesMutex.RUnlock()
+	return fake.ratesArgsForCall[i].handle, fake.ratesArgsForCall[i].window
+}
+
+func (fake *FakeMetricsHistory) RatesReturns(result1 linux_backend.MetricRates, result2 error) {
+	fake.RatesStub = nil
+	fake.ratesReturns = struct {
+		result1 linux_backend.MetricRates
+		result2 error
+	}{result1, result2}
+}
+
+var _ linux_backend.MetricsHistory = new(FakeMetricsHistory)
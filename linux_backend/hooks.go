@@ -8,8 +8,10 @@ import (
 
 	"fmt"
 	"os"
+	"path"
 
 	"github.com/cloudfoundry-incubator/garden-linux/hook"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/network"
 	"github.com/cloudfoundry-incubator/garden-linux/process"
 )
@@ -25,10 +27,24 @@ func RegisterHooks(hs hook.HookSet, runner Runner, config process.Env, configure
 
 	hs.Register(hook.PARENT_AFTER_CLONE, func() {
 		must(runner.Run(exec.Command("./hook-parent-after-clone.sh")))
+		must(restrictDevices(config))
 		must(configureHostNetwork(config, configurer))
 	})
 }
 
+// restrictDevices attaches the device filter to the container's cgroup on
+// the unified hierarchy, which has no devices.allow for the legacy shell
+// script to write to. The container does not run until the hook returns.
+func restrictDevices(config process.Env) error {
+	cgroupPath := os.Getenv("GARDEN_CGROUP_PATH")
+	if !cgroups_manager.IsUnified(cgroupPath) {
+		return nil
+	}
+
+	instancePath := path.Join(cgroupPath, cgroups_manager.UnifiedParent, "instance-"+config["id"])
+	return cgroups_manager.AttachDeviceFilter(instancePath, cgroups_manager.DefaultDeviceRules)
+}
+
 func configureHostNetwork(config process.Env, configurer network.Configurer) error {
 	_, ipNet, err := net.ParseCIDR(config["network_cidr"])
 	if err != nil {
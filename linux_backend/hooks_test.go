@@ -2,7 +2,9 @@ package linux_backend_test
 
 import (
 	"errors"
+	"io/ioutil"
 	"os/exec"
+	"path"
 
 	"github.com/cloudfoundry-incubator/garden-linux/hook"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
@@ -132,6 +134,32 @@ var _ = Describe("Hooks", func() {
 					})
 				})
 
+				Context("on the unified cgroup hierarchy", func() {
+					var cgroupPath string
+
+					BeforeEach(func() {
+						var err error
+						cgroupPath, err = ioutil.TempDir("", "some-unified-cgroups")
+						Expect(err).ToNot(HaveOccurred())
+
+						Expect(ioutil.WriteFile(path.Join(cgroupPath, "cgroup.controllers"), []byte("cpu memory"), 0644)).To(Succeed())
+
+						os.Setenv("GARDEN_CGROUP_PATH", cgroupPath)
+					})
+
+					AfterEach(func() {
+						os.Unsetenv("GARDEN_CGROUP_PATH")
+						os.RemoveAll(cgroupPath)
+					})
+
+					Context("when the device filter cannot be attached", func() {
+						It("panics before configuring the network", func() {
+							Expect(func() { hooks.Main(hook.PARENT_AFTER_CLONE) }).To(Panic())
+							Expect(fakeNetworkConfigurer.ConfigureHostCallCount()).To(BeZero())
+						})
+					})
+				})
+
 				It("runs the hook-parent-after-clone.sh legacy shell script", func() {
 					Expect(func() { hooks.Main(hook.PARENT_AFTER_CLONE) }).ToNot(Panic())
 					Expect(fakeRunner).To(HaveExecutedSerially(fake_command_runner.CommandSpec{
@@ -19,6 +19,7 @@ type Container interface {
 	ID() string
 	HasProperties(garden.Properties) bool
 	GraceTime() time.Duration
+	State() State
 
 	Start() error
 
@@ -28,9 +29,31 @@ type Container interface {
 	Cleanup() error
 
 	LimitCPU(garden.CPULimits) error
+	LimitCPUQuota(CPUQuotaLimits) error
+	CurrentCPUQuotaLimits() (CPUQuotaLimits, error)
+	LimitCPUSet(CPUSetLimits) error
+	CurrentCPUSetLimits() (CPUSetLimits, error)
 	LimitDisk(garden.DiskLimits) error
+	LimitIO(IOLimits) error
+	CurrentIOLimits() (IOLimits, error)
 	LimitMemory(garden.MemoryLimits) error
+	LimitDetailedMemory(MemoryLimits) error
+	CurrentDetailedMemoryLimits() (MemoryLimits, error)
+	LimitPids(PidLimits) error
+	CurrentPidLimits() (PidLimits, error)
 	LimitBandwidth(garden.BandwidthLimits) error
+	LimitDetailedBandwidth(BandwidthLimits) error
+	CurrentDetailedBandwidthLimits() (BandwidthLimits, error)
+
+	DetailedNetIn(NetInSpec) (NetInSpec, error)
+	RemoveNetIn(NetInSpec) error
+	CurrentNetOuts() []garden.NetOutRule
+	RemoveNetOut(garden.NetOutRule) error
+
+	DetailedMetrics() (ContainerMetrics, error)
+
+	Pause() error
+	Resume() error
 
 	garden.Container
 }
@@ -50,6 +73,10 @@ type ResourcePool interface {
 
 type ContainerProvider interface {
 	ProvideContainer(LinuxContainerSpec) Container
+
+	// ValidateOOMPolicy returns an error if the containers it provides cannot
+	// enforce the policy on this host.
+	ValidateOOMPolicy(OOMPolicy) error
 }
 
 type ContainerRepository interface {
@@ -60,6 +87,31 @@ type ContainerRepository interface {
 	Delete(Container)
 }
 
+// ContainerJournal is implemented by container repositories which persist
+// containers as they change, so that they can be restored after a crash.
+type ContainerJournal interface {
+	Replay(restore func(id string, snapshot io.Reader) error) error
+}
+
+//go:generate counterfeiter . CPUSetPool
+
+type CPUSetPool interface {
+	Acquire(owner string, count int) (string, error)
+	Reserve(owner string, cpus string) error
+	Pin(owner string, cpus string) error
+	Validate(owner string, cpus string, mems string) error
+	Release(owner string)
+}
+
+//go:generate counterfeiter . MetricsHistory
+
+// MetricsHistory keeps recent samples of each container's metrics, from which
+// it computes rates over windows of time.
+type MetricsHistory interface {
+	Windows() []time.Duration
+	Rates(handle string, window time.Duration) (MetricRates, error)
+}
+
 //go:generate counterfeiter . HealthChecker
 
 type HealthChecker interface {
@@ -75,9 +127,14 @@ type LinuxBackend struct {
 
 	snapshotsPath string
 	maxContainers int
+	pidLimit      uint64
 
 	containerRepo     ContainerRepository
 	containerProvider ContainerProvider
+	cpusetPool        CPUSetPool
+	metricsHistory    MetricsHistory
+
+	events *EventBus
 }
 
 type HandleExistsError struct {
@@ -104,15 +161,27 @@ func (e MaxContainersReachedError) Error() string {
 	return fmt.Sprintf("cannot create more than %d containers", e.MaxContainers)
 }
 
+type InvalidOOMPolicyError struct {
+	Policy string
+}
+
+func (e InvalidOOMPolicyError) Error() string {
+	return fmt.Sprintf("invalid oom policy: %s", e.Policy)
+}
+
 func New(
 	logger lager.Logger,
 	resourcePool ResourcePool,
 	containerRepo ContainerRepository,
 	containerProvider ContainerProvider,
+	cpusetPool CPUSetPool,
+	metricsHistory MetricsHistory,
+	events *EventBus,
 	systemInfo sysinfo.Provider,
 	healthCheck HealthChecker,
 	snapshotsPath string,
 	maxContainers int,
+	pidLimit uint64,
 ) *LinuxBackend {
 	return &LinuxBackend{
 		logger: logger.Session("backend"),
@@ -122,9 +191,14 @@ func New(
 		healthCheck:   healthCheck,
 		snapshotsPath: snapshotsPath,
 		maxContainers: maxContainers,
+		pidLimit:      pidLimit,
 
 		containerRepo:     containerRepo,
 		containerProvider: containerProvider,
+		cpusetPool:        cpusetPool,
+		metricsHistory:    metricsHistory,
+
+		events: events,
 	}
 }
 
@@ -146,6 +220,10 @@ func (b *LinuxBackend) Start() error {
 		}
 	}
 
+	if journal, ok := b.containerRepo.(ContainerJournal); ok {
+		b.replayJournal(journal)
+	}
+
 	keep := map[string]bool{}
 
 	containers := b.containerRepo.All()
@@ -197,6 +275,18 @@ func (b *LinuxBackend) Create(spec garden.ContainerSpec) (garden.Container, erro
 		return nil, HandleExistsError{Handle: spec.Handle}
 	}
 
+	oomPolicy := OOMPolicyStop
+	if policy, found := spec.Properties[OOMPolicyProperty]; found {
+		oomPolicy = OOMPolicy(policy)
+		if !oomPolicy.Valid() {
+			return nil, InvalidOOMPolicyError{Policy: policy}
+		}
+	}
+
+	if err := b.containerProvider.ValidateOOMPolicy(oomPolicy); err != nil {
+		return nil, err
+	}
+
 	if b.maxContainers > 0 {
 		containers := b.containerRepo.All()
 		if len(containers) >= b.maxContainers {
@@ -211,6 +301,8 @@ func (b *LinuxBackend) Create(spec garden.ContainerSpec) (garden.Container, erro
 		return nil, err
 	}
 
+	containerSpec.OOMPolicy = oomPolicy
+
 	container := b.containerProvider.ProvideContainer(containerSpec)
 
 	if err := container.Start(); err != nil {
@@ -225,6 +317,11 @@ func (b *LinuxBackend) Create(spec garden.ContainerSpec) (garden.Container, erro
 
 	b.containerRepo.Add(container)
 
+	b.events.Emit(Event{
+		Type:   EventCreated,
+		Handle: container.Handle(),
+	})
+
 	return container, nil
 }
 
@@ -253,6 +350,12 @@ func (b *LinuxBackend) applyLimits(container Container, limits garden.Limits) er
 		}
 	}
 
+	if b.pidLimit != 0 {
+		if err := container.LimitPids(PidLimits{Max: b.pidLimit}); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -272,11 +375,113 @@ func (b *LinuxBackend) Destroy(handle string) error {
 		return err
 	}
 
+	b.cpusetPool.Release(container.ID())
+
 	b.containerRepo.Delete(container)
 
+	b.events.Emit(Event{
+		Type:   EventDestroyed,
+		Handle: handle,
+	})
+
 	return nil
 }
 
+// LimitCPUSet pins the container to the given CPUs after checking that they
+// are online and not held exclusively by another container. Exclusive sets are
+// reserved, and shared ones recorded so that they are not reserved by anyone
+// else, until the container is destroyed or pinned elsewhere.
+func (b *LinuxBackend) LimitCPUSet(handle string, limits CPUSetLimits) error {
+	container, err := b.containerRepo.FindByHandle(handle)
+	if err != nil {
+		return err
+	}
+
+	if err := b.cpusetPool.Validate(container.ID(), limits.CPUs, limits.Mems); err != nil {
+		return err
+	}
+
+	if limits.Exclusive {
+		if err := b.cpusetPool.Reserve(container.ID(), limits.CPUs); err != nil {
+			return err
+		}
+	} else {
+		if err := b.cpusetPool.Pin(container.ID(), limits.CPUs); err != nil {
+			return err
+		}
+	}
+
+	if err := container.LimitCPUSet(limits); err != nil {
+		b.reserveCPUSet(container)
+		return err
+	}
+
+	return nil
+}
+
+// AllocateCPUSet pins the container to count CPUs which no other container is
+// using exclusively, and reserves them for it.
+func (b *LinuxBackend) AllocateCPUSet(handle string, count int, mems string) (CPUSetLimits, error) {
+	container, err := b.containerRepo.FindByHandle(handle)
+	if err != nil {
+		return CPUSetLimits{}, err
+	}
+
+	if err := b.cpusetPool.Validate(container.ID(), "", mems); err != nil {
+		return CPUSetLimits{}, err
+	}
+
+	cpus, err := b.cpusetPool.Acquire(container.ID(), count)
+	if err != nil {
+		return CPUSetLimits{}, err
+	}
+
+	limits := CPUSetLimits{
+		CPUs:      cpus,
+		Mems:      mems,
+		Exclusive: true,
+	}
+
+	if err := container.LimitCPUSet(limits); err != nil {
+		b.reserveCPUSet(container)
+		return CPUSetLimits{}, err
+	}
+
+	return limits, nil
+}
+
+// reserveCPUSet brings the pool back in line with the set recorded on the
+// container, e.g. after a failed change or when restoring it.
+func (b *LinuxBackend) reserveCPUSet(container Container) {
+	cpuset := container.ResourceSpec().Limits.CPUSet
+	if cpuset == nil {
+		b.cpusetPool.Release(container.ID())
+		return
+	}
+
+	reserve := b.cpusetPool.Reserve
+	if !cpuset.Exclusive {
+		reserve = b.cpusetPool.Pin
+	}
+
+	if err := reserve(container.ID(), cpuset.CPUs); err != nil {
+		b.logger.Error("failed-to-reserve-cpuset", err, lager.Data{
+			"handle": container.Handle(),
+			"cpus":   cpuset.CPUs,
+		})
+	}
+}
+
+// Subscribe returns a channel on which lifecycle events for every container
+// are delivered until it is passed to Unsubscribe.
+func (b *LinuxBackend) Subscribe() <-chan Event {
+	return b.events.Subscribe()
+}
+
+func (b *LinuxBackend) Unsubscribe(events <-chan Event) {
+	b.events.Unsubscribe(events)
+}
+
 func (b *LinuxBackend) Containers(props garden.Properties) ([]garden.Container, error) {
 	logger := b.logger.Session("containers")
 	logger.Debug("started")
@@ -337,6 +542,44 @@ func (b *LinuxBackend) BulkMetrics(handles []string) (map[string]garden.Containe
 	return metrics, nil
 }
 
+// BulkDetailedMetrics is BulkMetrics with the statistics garden does not
+// model, and with rates over each of the history's windows. Rates are left out
+// for windows which there are not yet enough samples to cover.
+func (b *LinuxBackend) BulkDetailedMetrics(handles []string) (map[string]ContainerMetricsEntry, error) {
+	containers := b.containerRepo.Query(withHandles(handles), nil)
+
+	metrics := make(map[string]ContainerMetricsEntry)
+	for _, container := range containers {
+		metric, err := container.DetailedMetrics()
+		if err != nil {
+			metrics[container.Handle()] = ContainerMetricsEntry{Err: err}
+			continue
+		}
+
+		rates := []MetricRates{}
+		for _, window := range b.metricsHistory.Windows() {
+			if rate, err := b.metricsHistory.Rates(container.Handle(), window); err == nil {
+				rates = append(rates, rate)
+			}
+		}
+
+		metrics[container.Handle()] = ContainerMetricsEntry{
+			Metrics: metric,
+			Rates:   rates,
+		}
+	}
+
+	return metrics, nil
+}
+
+func (b *LinuxBackend) MetricRates(handle string, window time.Duration) (MetricRates, error) {
+	if _, err := b.containerRepo.FindByHandle(handle); err != nil {
+		return MetricRates{}, err
+	}
+
+	return b.metricsHistory.Rates(handle, window)
+}
+
 func (b *LinuxBackend) GraceTime(container garden.Container) time.Duration {
 	return container.(Container).GraceTime()
 }
@@ -380,10 +623,39 @@ func (b *LinuxBackend) restoreSnapshots() {
 		_, err = b.restore(file)
 		if err != nil {
 			lLog.Error("failed-to-restore", err)
+			b.emitRestoreFailed(entry.Name(), err)
 		}
 	}
 }
 
+func (b *LinuxBackend) replayJournal(journal ContainerJournal) {
+	jLog := b.logger.Session("replay-journal")
+
+	restored := map[string]bool{}
+	for _, container := range b.containerRepo.All() {
+		restored[container.ID()] = true
+	}
+
+	err := journal.Replay(func(id string, snapshot io.Reader) error {
+		if restored[id] {
+			// already restored from a snapshot written on a clean shutdown
+			return nil
+		}
+
+		jLog.Debug("restoring", lager.Data{"id": id})
+
+		_, err := b.restore(snapshot)
+		if err != nil {
+			b.emitRestoreFailed(id, err)
+		}
+
+		return err
+	})
+	if err != nil {
+		jLog.Error("failed-to-replay", err)
+	}
+}
+
 func (b *LinuxBackend) saveSnapshot(container Container) error {
 	if b.snapshotsPath == "" {
 		return nil
@@ -414,12 +686,31 @@ func (b *LinuxBackend) restore(snapshot io.Reader) (garden.Container, error) {
 	}
 
 	container := b.containerProvider.ProvideContainer(containerSpec)
-	container.Restore(containerSpec)
+	if err := container.Restore(containerSpec); err != nil {
+		b.logger.Error("failed-to-restore-container", err, lager.Data{
+			"handle": container.Handle(),
+		})
+
+		b.events.Emit(Event{
+			Type:   EventRestoreFailed,
+			Handle: container.Handle(),
+			Data:   map[string]string{"id": container.ID(), "error": err.Error()},
+		})
+	}
+
+	b.reserveCPUSet(container)
 
 	b.containerRepo.Add(container)
 	return container, nil
 }
 
+func (b *LinuxBackend) emitRestoreFailed(id string, err error) {
+	b.events.Emit(Event{
+		Type: EventRestoreFailed,
+		Data: map[string]string{"id": id, "error": err.Error()},
+	})
+}
+
 func withHandles(handles []string) func(Container) bool {
 	return func(c Container) bool {
 		for _, e := range handles {
@@ -27,11 +27,15 @@ var _ = Describe("LinuxBackend", func() {
 	var fakeSystemInfo *fake_sysinfo.FakeProvider
 	var fakeContainerProvider *fakes.FakeContainerProvider
 	var fakeHealthCheck *fakes.FakeHealthChecker
+	var fakeCPUSetPool *fakes.FakeCPUSetPool
+	var fakeMetricsHistory *fakes.FakeMetricsHistory
 	var containerRepo linux_backend.ContainerRepository
 	var linuxBackend *linux_backend.LinuxBackend
 	var snapshotsPath string
 	var maxContainers int
+	var pidLimit uint64
 	var fakeContainers map[string]*fakes.FakeContainer
+	var events *linux_backend.EventBus
 
 	newTestContainer := func(spec linux_backend.LinuxContainerSpec) *fakes.FakeContainer {
 		container := new(fakes.FakeContainer)
@@ -68,9 +72,13 @@ var _ = Describe("LinuxBackend", func() {
 		containerRepo = container_repository.New()
 		fakeSystemInfo = new(fake_sysinfo.FakeProvider)
 		fakeHealthCheck = new(fakes.FakeHealthChecker)
+		fakeCPUSetPool = new(fakes.FakeCPUSetPool)
+		fakeMetricsHistory = new(fakes.FakeMetricsHistory)
+		events = linux_backend.NewEventBus(logger, 10)
 
 		snapshotsPath = ""
 		maxContainers = 0
+		pidLimit = 0
 
 		id := 0
 		fakeResourcePool.AcquireStub = func(spec garden.ContainerSpec) (linux_backend.LinuxContainerSpec, error) {
@@ -107,10 +115,14 @@ var _ = Describe("LinuxBackend", func() {
 			fakeResourcePool,
 			containerRepo,
 			fakeContainerProvider,
+			fakeCPUSetPool,
+			fakeMetricsHistory,
+			events,
 			fakeSystemInfo,
 			fakeHealthCheck,
 			snapshotsPath,
 			maxContainers,
+			pidLimit,
 		)
 	})
 
@@ -250,6 +262,55 @@ var _ = Describe("LinuxBackend", func() {
 				}))
 			})
 
+			Context("when a container was pinned to an exclusive cpuset", func() {
+				BeforeEach(func() {
+					container := registerTestContainer(newTestContainer(linux_backend.LinuxContainerSpec{
+						ContainerSpec: garden.ContainerSpec{Handle: "handle-a"},
+					}))
+
+					container.ResourceSpecReturns(linux_backend.LinuxContainerSpec{
+						Limits: linux_backend.Limits{
+							CPUSet: &linux_backend.CPUSetLimits{CPUs: "2-3", Exclusive: true},
+						},
+					})
+				})
+
+				It("reserves the cpus again", func() {
+					err := linuxBackend.Start()
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeCPUSetPool.ReserveCallCount()).To(Equal(1))
+					owner, cpus := fakeCPUSetPool.ReserveArgsForCall(0)
+					Expect(owner).To(Equal("handle-a"))
+					Expect(cpus).To(Equal("2-3"))
+				})
+			})
+
+			Context("when a container was pinned to a shared cpuset", func() {
+				BeforeEach(func() {
+					container := registerTestContainer(newTestContainer(linux_backend.LinuxContainerSpec{
+						ContainerSpec: garden.ContainerSpec{Handle: "handle-a"},
+					}))
+
+					container.ResourceSpecReturns(linux_backend.LinuxContainerSpec{
+						Limits: linux_backend.Limits{
+							CPUSet: &linux_backend.CPUSetLimits{CPUs: "2-3"},
+						},
+					})
+				})
+
+				It("records the pins again", func() {
+					err := linuxBackend.Start()
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeCPUSetPool.ReserveCallCount()).To(Equal(0))
+					Expect(fakeCPUSetPool.PinCallCount()).To(Equal(1))
+					owner, cpus := fakeCPUSetPool.PinArgsForCall(0)
+					Expect(owner).To(Equal("handle-a"))
+					Expect(cpus).To(Equal("2-3"))
+				})
+			})
+
 			Context("when restoring the container fails", func() {
 				disaster := errors.New("failed to restore")
 
@@ -261,6 +322,88 @@ var _ = Describe("LinuxBackend", func() {
 					err := linuxBackend.Start()
 					Expect(err).ToNot(HaveOccurred())
 				})
+
+				It("emits restore-failed events", func() {
+					subscription := linuxBackend.Subscribe()
+					defer linuxBackend.Unsubscribe(subscription)
+
+					err := linuxBackend.Start()
+					Expect(err).ToNot(HaveOccurred())
+
+					var event linux_backend.Event
+					Eventually(subscription).Should(Receive(&event))
+					Expect(event.Type).To(Equal(linux_backend.EventRestoreFailed))
+					Expect(event.Data).To(HaveKeyWithValue("error", "failed to restore"))
+				})
+			})
+		})
+
+		Describe("when the container repository is journaled", func() {
+			var journalPath string
+
+			BeforeEach(func() {
+				journalPath = path.Join(tmpdir, "journal")
+
+				var err error
+				containerRepo, err = container_repository.NewPersistent(journalPath, logger)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(ioutil.WriteFile(path.Join(journalPath, "handle-a"), []byte("handle-a"), 0644)).To(Succeed())
+				Expect(ioutil.WriteFile(path.Join(journalPath, "handle-b"), []byte("handle-b"), 0644)).To(Succeed())
+			})
+
+			It("restores the journaled containers", func() {
+				err := linuxBackend.Start()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeResourcePool.RestoreCallCount()).To(Equal(2))
+
+				containers, err := linuxBackend.Containers(nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(containers).To(HaveLen(2))
+			})
+
+			It("keeps them when pruning the container pool", func() {
+				err := linuxBackend.Start()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeResourcePool.PruneArgsForCall(0)).To(Equal(map[string]bool{
+					"handle-a": true,
+					"handle-b": true,
+				}))
+			})
+
+			Context("when a container was also restored from a snapshot", func() {
+				BeforeEach(func() {
+					Expect(os.MkdirAll(snapshotsPath, 0755)).To(Succeed())
+					Expect(ioutil.WriteFile(path.Join(snapshotsPath, "some-id"), []byte("handle-a"), 0644)).To(Succeed())
+				})
+
+				It("does not restore it twice", func() {
+					err := linuxBackend.Start()
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeResourcePool.RestoreCallCount()).To(Equal(2))
+				})
+			})
+
+			Context("when restoring a journaled container fails", func() {
+				BeforeEach(func() {
+					fakeResourcePool.RestoreReturns(linux_backend.LinuxContainerSpec{}, errors.New("failed to restore"))
+				})
+
+				It("successfully starts anyway", func() {
+					err := linuxBackend.Start()
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("removes the entries from the journal", func() {
+					err := linuxBackend.Start()
+					Expect(err).ToNot(HaveOccurred())
+
+					_, err = os.Stat(path.Join(journalPath, "handle-a"))
+					Expect(os.IsNotExist(err)).To(BeTrue())
+				})
 			})
 		})
 
@@ -435,6 +578,20 @@ var _ = Describe("LinuxBackend", func() {
 			Expect(fakeContainer.StartCallCount()).To(Equal(1))
 		})
 
+		It("emits a created event to subscribers", func() {
+			subscription := linuxBackend.Subscribe()
+			defer linuxBackend.Unsubscribe(subscription)
+
+			_, err := linuxBackend.Create(garden.ContainerSpec{Handle: "foo"})
+			Expect(err).ToNot(HaveOccurred())
+
+			var event linux_backend.Event
+			Eventually(subscription).Should(Receive(&event))
+			Expect(event.Type).To(Equal(linux_backend.EventCreated))
+			Expect(event.Handle).To(Equal("foo"))
+			Expect(event.Timestamp).ToNot(BeZero())
+		})
+
 		Context("when starting the container fails", func() {
 			It("destroys the container", func() {
 				container := registerTestContainer(newTestContainer(
@@ -462,6 +619,65 @@ var _ = Describe("LinuxBackend", func() {
 			Expect(foundContainer).To(Equal(container))
 		})
 
+		Describe("the OOM policy", func() {
+			It("defaults to stopping the container", func() {
+				_, err := linuxBackend.Create(garden.ContainerSpec{Handle: "foo"})
+				Expect(err).ToNot(HaveOccurred())
+
+				spec := fakeContainerProvider.ProvideContainerArgsForCall(0)
+				Expect(spec.OOMPolicy).To(Equal(linux_backend.OOMPolicyStop))
+			})
+
+			It("is taken from the container's properties", func() {
+				_, err := linuxBackend.Create(garden.ContainerSpec{
+					Handle: "foo",
+					Properties: garden.Properties{
+						linux_backend.OOMPolicyProperty: "kill-process",
+					},
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				spec := fakeContainerProvider.ProvideContainerArgsForCall(0)
+				Expect(spec.OOMPolicy).To(Equal(linux_backend.OOMPolicyKillProcess))
+			})
+
+			Context("when the policy is not recognised", func() {
+				It("returns an InvalidOOMPolicyError without acquiring resources", func() {
+					_, err := linuxBackend.Create(garden.ContainerSpec{
+						Handle: "foo",
+						Properties: garden.Properties{
+							linux_backend.OOMPolicyProperty: "panic",
+						},
+					})
+					Expect(err).To(Equal(linux_backend.InvalidOOMPolicyError{Policy: "panic"}))
+
+					Expect(fakeResourcePool.AcquireCallCount()).To(BeZero())
+				})
+			})
+
+			Context("when the host cannot enforce the policy", func() {
+				disaster := errors.New("oom policy freeze is not supported on this host")
+
+				BeforeEach(func() {
+					fakeContainerProvider.ValidateOOMPolicyReturns(disaster)
+				})
+
+				It("returns the error without acquiring resources", func() {
+					_, err := linuxBackend.Create(garden.ContainerSpec{
+						Handle: "foo",
+						Properties: garden.Properties{
+							linux_backend.OOMPolicyProperty: "freeze",
+						},
+					})
+					Expect(err).To(Equal(disaster))
+
+					Expect(fakeContainerProvider.ValidateOOMPolicyCallCount()).To(Equal(1))
+					Expect(fakeContainerProvider.ValidateOOMPolicyArgsForCall(0)).To(Equal(linux_backend.OOMPolicyFreeze))
+					Expect(fakeResourcePool.AcquireCallCount()).To(BeZero())
+				})
+			})
+		})
+
 		Context("when creating the container fails", func() {
 			disaster := errors.New("failed to create")
 
@@ -533,6 +749,40 @@ var _ = Describe("LinuxBackend", func() {
 			})
 		})
 
+		Context("when a pid limit is configured", func() {
+			var container *fakes.FakeContainer
+
+			BeforeEach(func() {
+				pidLimit = 512
+
+				container = new(fakes.FakeContainer)
+				fakeContainerProvider.ProvideContainerReturns(container)
+			})
+
+			It("applies it to the container", func() {
+				_, err := linuxBackend.Create(garden.ContainerSpec{})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(container.LimitPidsCallCount()).To(Equal(1))
+				Expect(container.LimitPidsArgsForCall(0)).To(Equal(linux_backend.PidLimits{Max: 512}))
+			})
+
+			Context("when applying it fails", func() {
+				disaster := errors.New("failed to limit")
+
+				BeforeEach(func() {
+					container.LimitPidsReturns(disaster)
+				})
+
+				It("returns the error and releases the container's resources", func() {
+					_, err := linuxBackend.Create(garden.ContainerSpec{})
+					Expect(err).To(Equal(disaster))
+
+					Expect(fakeResourcePool.ReleaseCallCount()).To(Equal(1))
+				})
+			})
+		})
+
 		Context("when limits are set in the container spec", func() {
 			var containerSpec garden.ContainerSpec
 			var container *fakes.FakeContainer
@@ -570,6 +820,8 @@ var _ = Describe("LinuxBackend", func() {
 
 				Expect(container.LimitMemoryCallCount()).To(Equal(1))
 				Expect(container.LimitMemoryArgsForCall(0)).To(Equal(containerSpec.Limits.Memory))
+
+				Expect(container.LimitPidsCallCount()).To(Equal(0))
 			})
 
 			Context("when applying limits fails", func() {
@@ -594,12 +846,21 @@ var _ = Describe("LinuxBackend", func() {
 
 		JustBeforeEach(func() {
 			container = new(fakes.FakeContainer)
+			container.IDReturns("something")
 			container.HandleReturns("some-handle")
 			container.ResourceSpecReturns(resources)
 
 			containerRepo.Add(container)
 		})
 
+		It("reclaims the container's exclusive cpus", func() {
+			err := linuxBackend.Destroy("some-handle")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCPUSetPool.ReleaseCallCount()).To(Equal(1))
+			Expect(fakeCPUSetPool.ReleaseArgsForCall(0)).To(Equal("something"))
+		})
+
 		It("removes the given container's resoureces from the pool", func() {
 			Expect(fakeResourcePool.ReleaseCallCount()).To(Equal(0))
 
@@ -610,6 +871,19 @@ var _ = Describe("LinuxBackend", func() {
 			Expect(fakeResourcePool.ReleaseArgsForCall(0)).To(Equal(resources))
 		})
 
+		It("emits a destroyed event to subscribers", func() {
+			subscription := linuxBackend.Subscribe()
+			defer linuxBackend.Unsubscribe(subscription)
+
+			err := linuxBackend.Destroy("some-handle")
+			Expect(err).ToNot(HaveOccurred())
+
+			var event linux_backend.Event
+			Eventually(subscription).Should(Receive(&event))
+			Expect(event.Type).To(Equal(linux_backend.EventDestroyed))
+			Expect(event.Handle).To(Equal("some-handle"))
+		})
+
 		It("unregisters the container", func() {
 			err := linuxBackend.Destroy("some-handle")
 			Expect(err).ToNot(HaveOccurred())
@@ -651,6 +925,182 @@ var _ = Describe("LinuxBackend", func() {
 		})
 	})
 
+	Describe("LimitCPUSet", func() {
+		var container *fakes.FakeContainer
+
+		JustBeforeEach(func() {
+			container = new(fakes.FakeContainer)
+			container.IDReturns("some-id")
+			container.HandleReturns("some-handle")
+
+			containerRepo.Add(container)
+		})
+
+		It("validates the cpus and memory nodes for the container", func() {
+			err := linuxBackend.LimitCPUSet("some-handle", linux_backend.CPUSetLimits{CPUs: "0-1", Mems: "0"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCPUSetPool.ValidateCallCount()).To(Equal(1))
+			owner, cpus, mems := fakeCPUSetPool.ValidateArgsForCall(0)
+			Expect(owner).To(Equal("some-id"))
+			Expect(cpus).To(Equal("0-1"))
+			Expect(mems).To(Equal("0"))
+		})
+
+		It("limits the container", func() {
+			limits := linux_backend.CPUSetLimits{CPUs: "0-1", Mems: "0"}
+
+			err := linuxBackend.LimitCPUSet("some-handle", limits)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(container.LimitCPUSetCallCount()).To(Equal(1))
+			Expect(container.LimitCPUSetArgsForCall(0)).To(Equal(limits))
+		})
+
+		Context("when the set is exclusive", func() {
+			It("reserves the cpus for the container", func() {
+				err := linuxBackend.LimitCPUSet("some-handle", linux_backend.CPUSetLimits{CPUs: "2", Exclusive: true})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeCPUSetPool.ReserveCallCount()).To(Equal(1))
+				owner, cpus := fakeCPUSetPool.ReserveArgsForCall(0)
+				Expect(owner).To(Equal("some-id"))
+				Expect(cpus).To(Equal("2"))
+			})
+		})
+
+		Context("when the set is shared", func() {
+			It("pins the container to the cpus in place of any it held exclusively", func() {
+				err := linuxBackend.LimitCPUSet("some-handle", linux_backend.CPUSetLimits{CPUs: "2"})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeCPUSetPool.ReserveCallCount()).To(Equal(0))
+				Expect(fakeCPUSetPool.PinCallCount()).To(Equal(1))
+				owner, cpus := fakeCPUSetPool.PinArgsForCall(0)
+				Expect(owner).To(Equal("some-id"))
+				Expect(cpus).To(Equal("2"))
+			})
+
+			Context("when pinning fails", func() {
+				disaster := errors.New("oh no")
+
+				BeforeEach(func() {
+					fakeCPUSetPool.PinReturns(disaster)
+				})
+
+				It("returns the error without limiting the container", func() {
+					err := linuxBackend.LimitCPUSet("some-handle", linux_backend.CPUSetLimits{CPUs: "2"})
+					Expect(err).To(Equal(disaster))
+
+					Expect(container.LimitCPUSetCallCount()).To(Equal(0))
+				})
+			})
+		})
+
+		Context("when validation fails", func() {
+			disaster := errors.New("cpu 7 is not online")
+
+			BeforeEach(func() {
+				fakeCPUSetPool.ValidateReturns(disaster)
+			})
+
+			It("returns the error without limiting the container", func() {
+				err := linuxBackend.LimitCPUSet("some-handle", linux_backend.CPUSetLimits{CPUs: "7"})
+				Expect(err).To(Equal(disaster))
+
+				Expect(container.LimitCPUSetCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when limiting the container fails", func() {
+			disaster := errors.New("oh no")
+
+			JustBeforeEach(func() {
+				container.LimitCPUSetReturns(disaster)
+				container.ResourceSpecReturns(linux_backend.LinuxContainerSpec{
+					Limits: linux_backend.Limits{
+						CPUSet: &linux_backend.CPUSetLimits{CPUs: "0", Exclusive: true},
+					},
+				})
+			})
+
+			It("returns the error and restores the previous reservation", func() {
+				err := linuxBackend.LimitCPUSet("some-handle", linux_backend.CPUSetLimits{CPUs: "1-2", Exclusive: true})
+				Expect(err).To(Equal(disaster))
+
+				Expect(fakeCPUSetPool.ReserveCallCount()).To(Equal(2))
+				_, cpus := fakeCPUSetPool.ReserveArgsForCall(1)
+				Expect(cpus).To(Equal("0"))
+			})
+		})
+
+		Context("when the container does not exist", func() {
+			It("returns ContainerNotFoundError", func() {
+				err := linuxBackend.LimitCPUSet("bogus-handle", linux_backend.CPUSetLimits{CPUs: "0"})
+				Expect(err).To(Equal(garden.ContainerNotFoundError{"bogus-handle"}))
+			})
+		})
+	})
+
+	Describe("AllocateCPUSet", func() {
+		var container *fakes.FakeContainer
+
+		BeforeEach(func() {
+			fakeCPUSetPool.AcquireReturns("4-5", nil)
+		})
+
+		JustBeforeEach(func() {
+			container = new(fakes.FakeContainer)
+			container.IDReturns("some-id")
+			container.HandleReturns("some-handle")
+
+			containerRepo.Add(container)
+		})
+
+		It("pins the container to exclusively acquired cpus", func() {
+			limits, err := linuxBackend.AllocateCPUSet("some-handle", 2, "0")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(limits).To(Equal(linux_backend.CPUSetLimits{CPUs: "4-5", Mems: "0", Exclusive: true}))
+
+			owner, count := fakeCPUSetPool.AcquireArgsForCall(0)
+			Expect(owner).To(Equal("some-id"))
+			Expect(count).To(Equal(2))
+
+			Expect(container.LimitCPUSetArgsForCall(0)).To(Equal(limits))
+		})
+
+		Context("when the pool is exhausted", func() {
+			disaster := errors.New("cpuset pool is exhausted")
+
+			BeforeEach(func() {
+				fakeCPUSetPool.AcquireReturns("", disaster)
+			})
+
+			It("returns the error without limiting the container", func() {
+				_, err := linuxBackend.AllocateCPUSet("some-handle", 2, "")
+				Expect(err).To(Equal(disaster))
+
+				Expect(container.LimitCPUSetCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when limiting the container fails", func() {
+			disaster := errors.New("oh no")
+
+			JustBeforeEach(func() {
+				container.LimitCPUSetReturns(disaster)
+			})
+
+			It("releases the acquired cpus", func() {
+				_, err := linuxBackend.AllocateCPUSet("some-handle", 2, "")
+				Expect(err).To(Equal(disaster))
+
+				Expect(fakeCPUSetPool.ReleaseCallCount()).To(Equal(1))
+				Expect(fakeCPUSetPool.ReleaseArgsForCall(0)).To(Equal("some-id"))
+			})
+		})
+	})
+
 	Describe("BulkInfo", func() {
 		newContainer := func(handle string) *fakes.FakeContainer {
 			fakeContainer := &fakes.FakeContainer{}
@@ -825,6 +1275,99 @@ var _ = Describe("LinuxBackend", func() {
 		})
 	})
 
+	Describe("BulkDetailedMetrics", func() {
+		var container1, container2 *fakes.FakeContainer
+
+		BeforeEach(func() {
+			container1 = newTestContainer(linux_backend.LinuxContainerSpec{
+				ContainerSpec: garden.ContainerSpec{Handle: "handle1"},
+			})
+			containerRepo.Add(container1)
+			container1.DetailedMetricsReturns(linux_backend.ContainerMetrics{
+				Metrics: garden.Metrics{CPUStat: garden.ContainerCPUStat{Usage: 1}},
+			}, nil)
+
+			container2 = newTestContainer(linux_backend.LinuxContainerSpec{
+				ContainerSpec: garden.ContainerSpec{Handle: "handle2"},
+			})
+			containerRepo.Add(container2)
+			container2.DetailedMetricsReturns(linux_backend.ContainerMetrics{
+				Metrics: garden.Metrics{CPUStat: garden.ContainerCPUStat{Usage: 2}},
+			}, nil)
+
+			fakeMetricsHistory.WindowsReturns([]time.Duration{time.Minute, 5 * time.Minute})
+			fakeMetricsHistory.RatesStub = func(handle string, window time.Duration) (linux_backend.MetricRates, error) {
+				if window == 5*time.Minute {
+					return linux_backend.MetricRates{}, errors.New("not enough samples")
+				}
+
+				return linux_backend.MetricRates{Window: window, CPUPercent: 12.5}, nil
+			}
+		})
+
+		It("returns the metrics and the rates over each window with enough samples", func() {
+			bulkMetrics, err := linuxBackend.BulkDetailedMetrics([]string{"handle1", "handle2"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(bulkMetrics).To(Equal(map[string]linux_backend.ContainerMetricsEntry{
+				"handle1": linux_backend.ContainerMetricsEntry{
+					Metrics: linux_backend.ContainerMetrics{
+						Metrics: garden.Metrics{CPUStat: garden.ContainerCPUStat{Usage: 1}},
+					},
+					Rates: []linux_backend.MetricRates{{Window: time.Minute, CPUPercent: 12.5}},
+				},
+				"handle2": linux_backend.ContainerMetricsEntry{
+					Metrics: linux_backend.ContainerMetrics{
+						Metrics: garden.Metrics{CPUStat: garden.ContainerCPUStat{Usage: 2}},
+					},
+					Rates: []linux_backend.MetricRates{{Window: time.Minute, CPUPercent: 12.5}},
+				},
+			}))
+		})
+
+		Context("when getting the metrics of a container fails", func() {
+			BeforeEach(func() {
+				container2.DetailedMetricsReturns(linux_backend.ContainerMetrics{}, errors.New("Oh no!"))
+			})
+
+			It("returns the err for the failed container", func() {
+				bulkMetrics, err := linuxBackend.BulkDetailedMetrics([]string{"handle2"})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(bulkMetrics).To(Equal(map[string]linux_backend.ContainerMetricsEntry{
+					"handle2": linux_backend.ContainerMetricsEntry{Err: errors.New("Oh no!")},
+				}))
+			})
+		})
+	})
+
+	Describe("MetricRates", func() {
+		BeforeEach(func() {
+			containerRepo.Add(newTestContainer(linux_backend.LinuxContainerSpec{
+				ContainerSpec: garden.ContainerSpec{Handle: "some-handle"},
+			}))
+
+			fakeMetricsHistory.RatesReturns(linux_backend.MetricRates{Window: time.Minute, RxBytesPerSecond: 100}, nil)
+		})
+
+		It("returns the rates from the metrics history", func() {
+			rates, err := linuxBackend.MetricRates("some-handle", time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rates).To(Equal(linux_backend.MetricRates{Window: time.Minute, RxBytesPerSecond: 100}))
+
+			handle, window := fakeMetricsHistory.RatesArgsForCall(0)
+			Expect(handle).To(Equal("some-handle"))
+			Expect(window).To(Equal(time.Minute))
+		})
+
+		Context("when the container does not exist", func() {
+			It("returns ContainerNotFoundError", func() {
+				_, err := linuxBackend.MetricRates("bogus-handle", time.Minute)
+				Expect(err).To(Equal(garden.ContainerNotFoundError{"bogus-handle"}))
+			})
+		})
+	})
+
 	Describe("Lookup", func() {
 		It("returns the container", func() {
 			container, err := linuxBackend.Create(garden.ContainerSpec{})
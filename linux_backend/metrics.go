@@ -0,0 +1,125 @@
+package linux_backend
+
+import (
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// ContainerMetrics extends garden.Metrics with statistics which garden does
+// not model.
+type ContainerMetrics struct {
+	garden.Metrics
+
+	CPUThrottlingStat ContainerCPUThrottlingStat
+	MemoryLimitStat   ContainerMemoryLimitStat
+	IOStat            ContainerIOStat
+	PidStat           ContainerPidStat
+	ProcessStat       ContainerProcessStat
+	NetworkDetailStat ContainerNetworkDetailStat
+	BandwidthStat     ContainerBandwidthUsageStat
+}
+
+// ContainerMetricsEntry is a container's metrics, or the error getting them,
+// as returned by BulkDetailedMetrics.
+type ContainerMetricsEntry struct {
+	Metrics ContainerMetrics
+	Rates   []MetricRates
+	Err     error
+}
+
+// MetricRates are the rates at which a container's counters changed over a
+// window of recent samples. Window is the time the samples actually cover,
+// which is shorter than requested until enough history has accumulated.
+// CPUPercent is relative to a single CPU, so may exceed 100.
+type MetricRates struct {
+	Window time.Duration
+
+	CPUPercent                 float64
+	RxBytesPerSecond           float64
+	TxBytesPerSecond           float64
+	MemoryGrowthBytesPerSecond float64
+}
+
+// ContainerCPUThrottlingStat counts the CFS enforcement periods which have
+// elapsed while the container was runnable, how many of them it was throttled
+// in for reaching its quota, and for how long in total, in nanoseconds.
+type ContainerCPUThrottlingStat struct {
+	Periods          uint64
+	ThrottledPeriods uint64
+	ThrottledTime    uint64
+}
+
+// ContainerMemoryLimitStat reports the memory limits which garden's
+// ContainerMemoryStat does not, where zero means no limit.
+type ContainerMemoryLimitStat struct {
+	SwapLimit   uint64
+	SoftLimit   uint64
+	KernelLimit uint64
+}
+
+// ContainerIOStat totals the block I/O performed by the container across all
+// devices, and breaks it down by device.
+type ContainerIOStat struct {
+	ReadBytes  uint64
+	WriteBytes uint64
+	ReadOps    uint64
+	WriteOps   uint64
+
+	Devices []ContainerDeviceIOStat
+}
+
+// ContainerDeviceIOStat is the block I/O performed by the container on the
+// device with the given "major:minor" number.
+type ContainerDeviceIOStat struct {
+	Device string
+
+	ReadBytes  uint64
+	WriteBytes uint64
+	ReadOps    uint64
+	WriteOps   uint64
+}
+
+// ContainerPidStat reports the number of tasks in the container against its
+// limit, where a zero Max means no limit.
+type ContainerPidStat struct {
+	Current uint64
+	Max     uint64
+}
+
+// ContainerProcessStat reports the number of tasks in the container's cgroup
+// and the number of file descriptors its processes hold open.
+type ContainerProcessStat struct {
+	Tasks           uint64
+	FileDescriptors uint64
+}
+
+// ContainerNetworkDetailStat reports the packet counters of the container's
+// network interface, which garden's ContainerNetworkStat does not, and the
+// number of connections tracked to or from the container.
+type ContainerNetworkDetailStat struct {
+	RxPackets   uint64
+	TxPackets   uint64
+	RxErrors    uint64
+	TxErrors    uint64
+	RxDropped   uint64
+	TxDropped   uint64
+	TxMulticast uint64
+
+	ConntrackEntries uint64
+}
+
+// ContainerBandwidthUsageStat counts the traffic subject to the container's
+// bandwidth limits, as seen by the qdiscs which shape it. In is traffic to the
+// container, Out is traffic from it.
+type ContainerBandwidthUsageStat struct {
+	InBytes      uint64
+	InPackets    uint64
+	InDropped    uint64
+	InOverlimits uint64
+
+	OutBytes      uint64
+	OutPackets    uint64
+	OutDropped    uint64
+	OutOverlimits uint64
+}
@@ -27,6 +27,8 @@ type LinuxContainerSpec struct {
 	NetIns  []NetInSpec
 	NetOuts []garden.NetOutRule
 
+	OOMPolicy OOMPolicy
+
 	Version semver.Version
 }
 
@@ -36,15 +38,180 @@ type ActiveProcess struct {
 }
 
 type Limits struct {
-	Memory    *garden.MemoryLimits
+	Memory    *MemoryLimits
 	Disk      *garden.DiskLimits
-	Bandwidth *garden.BandwidthLimits
+	Bandwidth *BandwidthLimits
 	CPU       *garden.CPULimits
+	CPUQuota  *CPUQuotaLimits
+	CPUSet    *CPUSetLimits
+	IO        *IOLimits
+	Pids      *PidLimits
+}
+
+// MemoryLimits extends garden.MemoryLimits with settings which garden does
+// not model. SwapInBytes is the swap the container may use on top of
+// LimitInBytes, SoftLimitInBytes is the usage the container is reclaimed
+// towards when the host is short of memory, and KernelLimitInBytes caps the
+// kernel memory charged to the container. Zero values mean no swap, no soft
+// limit and no separate kernel memory limit.
+type MemoryLimits struct {
+	garden.MemoryLimits
+
+	SwapInBytes        uint64
+	SoftLimitInBytes   uint64
+	KernelLimitInBytes uint64
+}
+
+// BandwidthLimits shapes the traffic to (In) and from (Out) a container
+// independently, each direction with its own burst. A zero rate leaves that
+// direction unlimited.
+type BandwidthLimits struct {
+	InRateInBytesPerSecond  uint64
+	InBurstInBytes          uint64
+	OutRateInBytesPerSecond uint64
+	OutBurstInBytes         uint64
+}
+
+// SymmetricBandwidthLimits applies garden's single rate and burst to both
+// directions.
+func SymmetricBandwidthLimits(limits garden.BandwidthLimits) BandwidthLimits {
+	return BandwidthLimits{
+		InRateInBytesPerSecond:  limits.RateInBytesPerSecond,
+		InBurstInBytes:          limits.BurstRateInBytesPerSecond,
+		OutRateInBytesPerSecond: limits.RateInBytesPerSecond,
+		OutBurstInBytes:         limits.BurstRateInBytesPerSecond,
+	}
+}
+
+// UnmarshalJSON also accepts the garden.BandwidthLimits saved by earlier
+// versions, which applied to both directions.
+func (l *BandwidthLimits) UnmarshalJSON(data []byte) error {
+	type bandwidthLimits BandwidthLimits
+
+	var limits struct {
+		bandwidthLimits
+		garden.BandwidthLimits
+	}
+
+	if err := json.Unmarshal(data, &limits); err != nil {
+		return err
+	}
+
+	*l = BandwidthLimits(limits.bandwidthLimits)
+	if *l == (BandwidthLimits{}) {
+		*l = SymmetricBandwidthLimits(limits.BandwidthLimits)
+	}
+
+	return nil
 }
 
+// CPUQuotaLimits caps the CPU time available to a container using the CFS
+// bandwidth controller: its processes may run for at most QuotaInMicroseconds
+// in every PeriodInMicroseconds. A zero quota means no cap.
+type CPUQuotaLimits struct {
+	PeriodInMicroseconds uint64
+	QuotaInMicroseconds  uint64
+}
+
+// CPUSetLimits pins a container to CPUs and NUMA memory nodes, given in the
+// kernel's list format (e.g. "0-3,8"). An empty Mems leaves the memory nodes
+// inherited from the parent cgroup. Exclusive CPUs are not handed to any other
+// container.
+type CPUSetLimits struct {
+	CPUs      string
+	Mems      string
+	Exclusive bool
+}
+
+// IOLimits sets the container's proportional share of block I/O (Weight,
+// 10-1000; zero leaves it unchanged) and caps its throughput on individual
+// devices.
+type IOLimits struct {
+	Weight  uint64
+	Devices []DeviceIOLimits
+}
+
+// DeviceIOLimits throttles I/O to the block device identified by
+// "major:minor". A zero value means no cap.
+type DeviceIOLimits struct {
+	Device string
+
+	ReadBytesPerSecond  uint64
+	WriteBytesPerSecond uint64
+	ReadIOPerSecond     uint64
+	WriteIOPerSecond    uint64
+}
+
+// PidLimits caps the number of processes and threads which may exist in the
+// container at once. A zero Max means no cap.
+type PidLimits struct {
+	Max uint64
+}
+
+// NetInSpec maps a host port to a container port for Protocol. Specs saved
+// before the protocol was recorded have an empty Protocol, which means TCP.
+// HostIP is the host address the port is bound to: nil means the external IP
+// of the container, and the unspecified address (0.0.0.0) every address of
+// the host.
 type NetInSpec struct {
+	HostIP        net.IP
 	HostPort      uint32
 	ContainerPort uint32
+	Protocol      NetInProtocol
+}
+
+// NetInProtocol is the protocol, or protocols, a NetInSpec forwards.
+type NetInProtocol string
+
+const (
+	NetInProtocolTCP = NetInProtocol("tcp")
+	NetInProtocolUDP = NetInProtocol("udp")
+	// NetInProtocolAll forwards both TCP and UDP.
+	NetInProtocolAll = NetInProtocol("all")
+)
+
+func (p NetInProtocol) Valid() bool {
+	switch p {
+	case NetInProtocolTCP, NetInProtocolUDP, NetInProtocolAll:
+		return true
+	}
+
+	return false
+}
+
+// Includes reports whether forwarding p also forwards other, e.g. all
+// includes udp.
+func (p NetInProtocol) Includes(other NetInProtocol) bool {
+	return p == other || p == NetInProtocolAll
+}
+
+// OOMPolicy decides what happens when a process in the container exceeds its
+// memory limit.
+type OOMPolicy string
+
+const (
+	// OOMPolicyStop kills every process in the container and stops it.
+	OOMPolicyStop = OOMPolicy("stop")
+	// OOMPolicyKillProcess kills only the process the kernel would have chosen
+	// and leaves the rest of the container running.
+	OOMPolicyKillProcess = OOMPolicy("kill-process")
+	// OOMPolicyFreeze leaves the processes as they were and pauses the
+	// container for inspection. If the freezer times out, the container is
+	// stopped as with OOMPolicyStop.
+	OOMPolicyFreeze = OOMPolicy("freeze")
+
+	// OOMPolicyProperty is the container property used to choose a policy on
+	// create. Containers without it use OOMPolicyStop.
+	OOMPolicyProperty = "garden.oom-policy"
+)
+
+func (p OOMPolicy) Valid() bool {
+	switch p {
+	case OOMPolicyStop, OOMPolicyKillProcess, OOMPolicyFreeze:
+		return true
+	}
+
+	return false
 }
 
 type State string
@@ -52,6 +219,7 @@ type State string
 const (
 	StateBorn    = State("born")
 	StateActive  = State("active")
+	StatePaused  = State("paused")
 	StateStopped = State("stopped")
 )
 
@@ -88,6 +256,7 @@ type Resources struct {
 	Network    *Network
 	Bridge     string
 	Ports      []uint32
+	UDPPorts   []uint32
 	ExternalIP net.IP
 
 	portsLock *sync.Mutex
@@ -117,3 +286,39 @@ func (r *Resources) AddPort(port uint32) {
 
 	r.Ports = append(r.Ports, port)
 }
+
+// RemovePort removes a port added by AddPort, reporting whether it was there.
+func (r *Resources) RemovePort(port uint32) bool {
+	r.portsLock.Lock()
+	defer r.portsLock.Unlock()
+
+	return removePort(&r.Ports, port)
+}
+
+// AddUDPPort is AddPort for ports acquired from the UDP port pool.
+func (r *Resources) AddUDPPort(port uint32) {
+	r.portsLock.Lock()
+	defer r.portsLock.Unlock()
+
+	r.UDPPorts = append(r.UDPPorts, port)
+}
+
+// RemoveUDPPort removes a port added by AddUDPPort, reporting whether it was
+// there.
+func (r *Resources) RemoveUDPPort(port uint32) bool {
+	r.portsLock.Lock()
+	defer r.portsLock.Unlock()
+
+	return removePort(&r.UDPPorts, port)
+}
+
+func removePort(ports *[]uint32, port uint32) bool {
+	for i, p := range *ports {
+		if p == port {
+			*ports = append((*ports)[:i:i], (*ports)[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
@@ -11,14 +11,29 @@ source ./etc/config
 
 cgroup_path="${GARDEN_CGROUP_PATH}"
 
+# Unlike the veth pair, the IFB device shaping outbound traffic does not go
+# away with the container's network namespace. It is named after the host
+# side of the veth pair, so is found the same way for containers created
+# before it existed.
+if [ -n "${network_host_iface:-}" ]
+then
+  ip link del ${network_host_iface%-0}-i 2> /dev/null || true
+fi
+
 if [ -f ./run/wshd.pid ]
 then
   pid=$(cat ./run/wshd.pid)
 
-  # Arbitrarily pick the cpu substem to check for live tasks.
-  cgroup_path_segment=$(cat /proc/self/cgroup | grep cpu: | cut -d ':' -f 3)
-  path=${cgroup_path}/cpu${cgroup_path_segment}/instance-$id
-  tasks=$path/tasks
+  if [ -f $cgroup_path/cgroup.controllers ]
+  then
+    path=${cgroup_path}/garden/instance-$id
+    tasks=$path/cgroup.procs
+  else
+    # Arbitrarily pick the cpu substem to check for live tasks.
+    cgroup_path_segment=$(cat /proc/self/cgroup | grep cpu: | cut -d ':' -f 3)
+    path=${cgroup_path}/cpu${cgroup_path_segment}/instance-$id
+    tasks=$path/tasks
+  fi
 
   if [ -d $path ]
   then
@@ -38,11 +53,20 @@ then
   rm -f ./run/wshd.pid
 
   # Remove cgroups
-  for subsystem in {cpuset,cpu,cpuacct,devices,memory}
-  do
-    cgroup_path_segment=$(cat /proc/self/cgroup | grep ${subsystem}: | cut -d ':' -f 3)
-    path=${cgroup_path}/${subsystem}${cgroup_path_segment}/instance-$id
+  if [ -f $cgroup_path/cgroup.controllers ]
+  then
+    instance_paths=${cgroup_path}/garden/instance-$id
+  else
+    instance_paths=""
+    for subsystem in {cpuset,cpu,cpuacct,devices,memory,freezer,blkio,pids}
+    do
+      cgroup_path_segment=$(cat /proc/self/cgroup | grep ${subsystem}: | cut -d ':' -f 3)
+      instance_paths="$instance_paths ${cgroup_path}/${subsystem}${cgroup_path_segment}/instance-$id"
+    done
+  fi
 
+  for path in $instance_paths
+  do
     if [ -d $path ]
     then
       # Recursively remove all cgroup trees under (and including) the instance.
@@ -9,13 +9,35 @@ cd $(dirname $0)/../
 
 source etc/config
 
+# On the unified hierarchy there is a single cgroup for every controller.
+# Device access is controlled by eBPF programs rather than devices.allow; the
+# hook attaches one allowing the devices below once this script has finished.
+if [ -f $GARDEN_CGROUP_PATH/cgroup.controllers ]
+then
+  instance_path=$GARDEN_CGROUP_PATH/garden/instance-$id
+
+  mkdir -p $instance_path
+  echo $PID > $instance_path/cgroup.procs
+
+  echo $PID > ./run/wshd.pid
+
+  exit 0
+fi
+
 # Add new group for every subsystem
 #
 # cpuset must be set up first, so that cpuset.cpus and cpuset.mems is assigned
 # otherwise adding the process to the subsystem's tasks will fail with ENOSPC
-for subsystem in {cpuset,cpu,cpuacct,devices,memory}
+for subsystem in {cpuset,cpu,cpuacct,devices,memory,freezer,blkio,pids}
 do
   system_path=$GARDEN_CGROUP_PATH/$subsystem
+
+  # the pids controller is only available from Linux 4.3
+  if [ ! -d $system_path ]
+  then
+    continue
+  fi
+
   cgroup_path_segment=$(cat /proc/self/cgroup | grep ${subsystem}: | cut -d ':' -f 3)
   instance_path=${system_path}${cgroup_path_segment}/instance-$id
 
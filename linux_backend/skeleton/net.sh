@@ -11,6 +11,38 @@ source ./etc/config
 
 filter_instance_prefix="${GARDEN_IPTABLES_FILTER_INSTANCE_PREFIX}"
 nat_instance_chain="${filter_instance_prefix}${id}"
+nft="${GARDEN_NFTABLES_PATH:-/usr/sbin/nft}"
+
+# PROTOCOL is tcp, udp or all, which forwards both
+net_in_protocols() {
+  case "${PROTOCOL:-tcp}" in
+    tcp|udp)
+      echo "${PROTOCOL:-tcp}"
+      ;;
+    all)
+      echo "tcp udp"
+      ;;
+    *)
+      echo "Unknown PROTOCOL: ${PROTOCOL}" 1>&2
+      exit 1
+      ;;
+  esac
+}
+
+# HOST_IP is the host address a port is mapped on, defaulting to the external
+# IP. 0.0.0.0 maps it on every address of the host but loopback, which cannot
+# be routed to containers.
+net_in_destination() {
+  host_ip="${HOST_IP:-${external_ip}}"
+
+  if [ "${host_ip}" == "0.0.0.0" ]; then
+    iptables_destination="-m addrtype --dst-type LOCAL ! --destination 127.0.0.0/8"
+    nft_destination="fib daddr type local ip daddr != 127.0.0.0/8"
+  else
+    iptables_destination="--destination ${host_ip}"
+    nft_destination="ip daddr ${host_ip}"
+  fi
+}
 
 case "${1}" in
    "in")
@@ -24,31 +56,77 @@ case "${1}" in
       exit 1
     fi
 
-    iptables --wait --table nat -A ${nat_instance_chain} \
-      --protocol tcp \
-      --destination "${external_ip}" \
-      --destination-port "${HOST_PORT}" \
-      --jump DNAT \
-      --to-destination "${network_container_ip}:${CONTAINER_PORT}"
+    protocols=$(net_in_protocols)
+    net_in_destination
+
+    for protocol in ${protocols}; do
+      if [ "${GARDEN_FIREWALL:-iptables}" == "nftables" ]; then
+        ${nft} add rule ip ${GARDEN_NFTABLES_NAT_TABLE} ${nat_instance_chain} \
+          ${nft_destination} \
+          ${protocol} dport "${HOST_PORT}" \
+          dnat to "${network_container_ip}:${CONTAINER_PORT}"
+      else
+        iptables --wait --table nat -A ${nat_instance_chain} \
+          --protocol ${protocol} \
+          --destination-port "${HOST_PORT}" \
+          ${iptables_destination} \
+          --jump DNAT \
+          --to-destination "${network_container_ip}:${CONTAINER_PORT}"
+      fi
+    done
 
     ;;
 
-  "get_ingress_info")
-    if [ -z "${ID:-}" ]; then
-      echo "Please specify container ID..." 1>&2
+  "in_remove")
+    if [ -z "${HOST_PORT:-}" ]; then
+      echo "Please specify HOST_PORT..." 1>&2
       exit 1
     fi
-    tc filter show dev ${network_host_iface} parent ffff:
 
-    ;;
-  "get_egress_info")
-    if [ -z "${ID:-}" ]; then
-      echo "Please specify container ID..." 1>&2
+    if [ -z "${CONTAINER_PORT:-}" ]; then
+      echo "Please specify CONTAINER_PORT..." 1>&2
       exit 1
     fi
-    tc qdisc show dev ${network_host_iface}
+
+    protocols=$(net_in_protocols)
+    net_in_destination
+
+    if [ "${GARDEN_FIREWALL:-iptables}" == "nftables" ]; then
+      # nft deletes rules by handle, so look up the first matching rule of
+      # every protocol before deleting any of them
+      handles=""
+      for protocol in ${protocols}; do
+        handle=$(
+          ${nft} --handle list chain ip ${GARDEN_NFTABLES_NAT_TABLE} ${nat_instance_chain} |
+            grep -F "${nft_destination} ${protocol} dport ${HOST_PORT} dnat to ${network_container_ip}:${CONTAINER_PORT} # handle" |
+            sed -e "s/.* # handle //" |
+            head -1
+        )
+
+        if [ -z "${handle}" ]; then
+          echo "No ${protocol} rule for HOST_PORT ${HOST_PORT} and CONTAINER_PORT ${CONTAINER_PORT}" 1>&2
+          exit 1
+        fi
+
+        handles="${handles} ${handle}"
+      done
+
+      for handle in ${handles}; do
+        ${nft} delete rule ip ${GARDEN_NFTABLES_NAT_TABLE} ${nat_instance_chain} handle ${handle}
+      done
+    else
+      for protocol in ${protocols}; do
+        iptables --wait --table nat -D ${nat_instance_chain} \
+          --protocol ${protocol} \
+          --destination-port "${HOST_PORT}" \
+          ${iptables_destination} \
+          --jump DNAT \
+          --to-destination "${network_container_ip}:${CONTAINER_PORT}"
+      done
+    fi
 
     ;;
+
   *)
     echo "Unknown command: ${1}" 1>&2
     exit 1
@@ -47,8 +47,13 @@ ms_start=$(ms)
 ms_end=$(($ms_start + ($WAIT * 1000)))
 
 pid=$(cat ./run/wshd.pid)
-cgroup_path_segment=$(cat /proc/self/cgroup | grep cpu: | cut -d ':' -f 3)
-path=${GARDEN_CGROUP_PATH}/cpu${cgroup_path_segment}/instance-$id
+if [ -f ${GARDEN_CGROUP_PATH}/cgroup.controllers ]
+then
+  path=${GARDEN_CGROUP_PATH}/garden/instance-$id
+else
+  cgroup_path_segment=$(cat /proc/self/cgroup | grep cpu: | cut -d ':' -f 3)
+  path=${GARDEN_CGROUP_PATH}/cpu${cgroup_path_segment}/instance-$id
+fi
 tasks=$path/cgroup.procs
 
 while true
@@ -2,22 +2,26 @@ package fake_bandwidth_manager
 
 import (
 	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
 	"github.com/pivotal-golang/lager"
 )
 
 type FakeBandwidthManager struct {
 	SetLimitsError error
-	EnforcedLimits []garden.BandwidthLimits
+	EnforcedLimits []linux_backend.BandwidthLimits
 
 	GetLimitsError  error
 	GetLimitsResult garden.ContainerBandwidthStat
+
+	GetUsageError  error
+	GetUsageResult linux_backend.ContainerBandwidthUsageStat
 }
 
 func New() *FakeBandwidthManager {
 	return &FakeBandwidthManager{}
 }
 
-func (m *FakeBandwidthManager) SetLimits(logger lager.Logger, limits garden.BandwidthLimits) error {
+func (m *FakeBandwidthManager) SetLimits(logger lager.Logger, limits linux_backend.BandwidthLimits) error {
 	if m.SetLimitsError != nil {
 		return m.SetLimitsError
 	}
@@ -34,3 +38,11 @@ func (m *FakeBandwidthManager) GetLimits(logger lager.Logger) (garden.ContainerB
 
 	return m.GetLimitsResult, nil
 }
+
+func (m *FakeBandwidthManager) GetUsage(logger lager.Logger) (linux_backend.ContainerBandwidthUsageStat, error) {
+	if m.GetUsageError != nil {
+		return linux_backend.ContainerBandwidthUsageStat{}, m.GetUsageError
+	}
+
+	return m.GetUsageResult, nil
+}
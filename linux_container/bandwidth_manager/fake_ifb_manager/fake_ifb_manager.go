@@ -0,0 +1,138 @@
+// This file was generated by counterfeiter
+package fake_ifb_manager
+
+import (
+	"net"
+	"sync"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/bandwidth_manager"
+)
+
+type FakeIFBManager struct {
+	CreateStub        func(name string) (*net.Interface, error)
+	createMutex       sync.RWMutex
+	createArgsForCall []struct {
+		name string
+	}
+	createReturns struct {
+		result1 *net.Interface
+		result2 error
+	}
+	ExistsStub        func(name string) (bool, error)
+	existsMutex       sync.RWMutex
+	existsArgsForCall []struct {
+		name string
+	}
+	existsReturns struct {
+		result1 bool
+		result2 error
+	}
+	DestroyStub        func(name string) error
+	destroyMutex       sync.RWMutex
+	destroyArgsForCall []struct {
+		name string
+	}
+	destroyReturns struct {
+		result1 error
+	}
+}
+
+func (fake *FakeIFBManager) Create(name string) (*net.Interface, error) {
+	fake.createMutex.Lock()
+	fake.createArgsForCall = append(fake.createArgsForCall, struct {
+		name string
+	}{name})
+	fake.createMutex.Unlock()
+	if fake.CreateStub != nil {
+		return fake.CreateStub(name)
+	} else {
+		return fake.createReturns.result1, fake.createReturns.result2
+	}
+}
+
+func (fake *FakeIFBManager) CreateCallCount() int {
+	fake.createMutex.RLock()
+	defer fake.createMutex.RUnlock()
+	return len(fake.createArgsForCall)
+}
+
+func (fake *FakeIFBManager) CreateArgsForCall(i int) string {
+	fake.createMutex.RLock()
+	defer fake.createMutex.RUnlock()
+	return fake.createArgsForCall[i].name
+}
+
+func (fake *FakeIFBManager) CreateReturns(result1 *net.Interface, result2 error) {
+	fake.CreateStub = nil
+	fake.createReturns = struct {
+		result1 *net.Interface
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeIFBManager) Exists(name string) (bool, error) {
+	fake.existsMutex.Lock()
+	fake.existsArgsForCall = append(fake.existsArgsForCall, struct {
+		name string
+	}{name})
+	fake.existsMutex.Unlock()
+	if fake.ExistsStub != nil {
+		return fake.ExistsStub(name)
+	} else {
+		return fake.existsReturns.result1, fake.existsReturns.result2
+	}
+}
+
+func (fake *FakeIFBManager) ExistsCallCount() int {
+	fake.existsMutex.RLock()
+	defer fake.existsMutex.RUnlock()
+	return len(fake.existsArgsForCall)
+}
+
+func (fake *FakeIFBManager) ExistsArgsForCall(i int) string {
+	fake.existsMutex.RLock()
+	defer fake.existsMutex.RUnlock()
+	return fake.existsArgsForCall[i].name
+}
+
+func (fake *FakeIFBManager) ExistsReturns(result1 bool, result2 error) {
+	fake.ExistsStub = nil
+	fake.existsReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeIFBManager) Destroy(name string) error {
+	fake.destroyMutex.Lock()
+	fake.destroyArgsForCall = append(fake.destroyArgsForCall, struct {
+		name string
+	}{name})
+	fake.destroyMutex.Unlock()
+	if fake.DestroyStub != nil {
+		return fake.DestroyStub(name)
+	} else {
+		return fake.destroyReturns.result1
+	}
+}
+
+func (fake *FakeIFBManager) DestroyCallCount() int {
+	fake.destroyMutex.RLock()
+	defer fake.destroyMutex.RUnlock()
+	return len(fake.destroyArgsForCall)
+}
+
+func (fake *FakeIFBManager) DestroyArgsForCall(i int) string {
+	fake.destroyMutex.RLock()
+	defer fake.destroyMutex.RUnlock()
+	return fake.destroyArgsForCall[i].name
+}
+
+func (fake *FakeIFBManager) DestroyReturns(result1 error) {
+	fake.DestroyStub = nil
+	fake.destroyReturns = struct {
+		result1 error
+	}{result1}
+}
+
+var _ bandwidth_manager.IFBManager = new(FakeIFBManager)
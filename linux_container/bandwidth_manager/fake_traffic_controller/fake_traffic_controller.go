@@ -0,0 +1,190 @@
+// This file was generated by counterfeiter
+package fake_traffic_controller
+
+import (
+	"sync"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/bandwidth_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/network/devices"
+)
+
+type FakeTrafficController struct {
+	ClearTrafficControlStub        func() error
+	clearTrafficControlMutex       sync.RWMutex
+	clearTrafficControlArgsForCall []struct{}
+	clearTrafficControlReturns     struct {
+		result1 error
+	}
+	AddTBFStub        func(arg1 devices.TokenBucket) error
+	addTBFMutex       sync.RWMutex
+	addTBFArgsForCall []struct {
+		arg1 devices.TokenBucket
+	}
+	addTBFReturns struct {
+		result1 error
+	}
+	RedirectIngressStub        func(to string) error
+	redirectIngressMutex       sync.RWMutex
+	redirectIngressArgsForCall []struct {
+		to string
+	}
+	redirectIngressReturns struct {
+		result1 error
+	}
+	TBFStub        func() (devices.TokenBucket, bool, error)
+	tBFMutex       sync.RWMutex
+	tBFArgsForCall []struct{}
+	tBFReturns     struct {
+		result1 devices.TokenBucket
+		result2 bool
+		result3 error
+	}
+	TrafficControlStatisticsStub        func() (devices.TrafficControlStatistics, error)
+	trafficControlStatisticsMutex       sync.RWMutex
+	trafficControlStatisticsArgsForCall []struct{}
+	trafficControlStatisticsReturns     struct {
+		result1 devices.TrafficControlStatistics
+		result2 error
+	}
+}
+
+func (fake *FakeTrafficController) ClearTrafficControl() error {
+	fake.clearTrafficControlMutex.Lock()
+	fake.clearTrafficControlArgsForCall = append(fake.clearTrafficControlArgsForCall, struct{}{})
+	fake.clearTrafficControlMutex.Unlock()
+	if fake.ClearTrafficControlStub != nil {
+		return fake.ClearTrafficControlStub()
+	} else {
+		return fake.clearTrafficControlReturns.result1
+	}
+}
+
+func (fake *FakeTrafficController) ClearTrafficControlCallCount() int {
+	fake.clearTrafficControlMutex.RLock()
+	defer fake.clearTrafficControlMutex.RUnlock()
+	return len(fake.clearTrafficControlArgsForCall)
+}
+
+func (fake *FakeTrafficController) ClearTrafficControlReturns(result1 error) {
+	fake.ClearTrafficControlStub = nil
+	fake.clearTrafficControlReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeTrafficController) AddTBF(arg1 devices.TokenBucket) error {
+	fake.addTBFMutex.Lock()
+	fake.addTBFArgsForCall = append(fake.addTBFArgsForCall, struct {
+		arg1 devices.TokenBucket
+	}{arg1})
+	fake.addTBFMutex.Unlock()
+	if fake.AddTBFStub != nil {
+		return fake.AddTBFStub(arg1)
+	} else {
+		return fake.addTBFReturns.result1
+	}
+}
+
+func (fake *FakeTrafficController) AddTBFCallCount() int {
+	fake.addTBFMutex.RLock()
+	defer fake.addTBFMutex.RUnlock()
+	return len(fake.addTBFArgsForCall)
+}
+
+func (fake *FakeTrafficController) AddTBFArgsForCall(i int) devices.TokenBucket {
+	fake.addTBFMutex.RLock()
+	defer fake.addTBFMutex.RUnlock()
+	return fake.addTBFArgsForCall[i].arg1
+}
+
+func (fake *FakeTrafficController) AddTBFReturns(result1 error) {
+	fake.AddTBFStub = nil
+	fake.addTBFReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeTrafficController) RedirectIngress(to string) error {
+	fake.redirectIngressMutex.Lock()
+	fake.redirectIngressArgsForCall = append(fake.redirectIngressArgsForCall, struct {
+		to string
+	}{to})
+	fake.redirectIngressMutex.Unlock()
+	if fake.RedirectIngressStub != nil {
+		return fake.RedirectIngressStub(to)
+	} else {
+		return fake.redirectIngressReturns.result1
+	}
+}
+
+func (fake *FakeTrafficController) RedirectIngressCallCount() int {
+	fake.redirectIngressMutex.RLock()
+	defer fake.redirectIngressMutex.RUnlock()
+	return len(fake.redirectIngressArgsForCall)
+}
+
+func (fake *FakeTrafficController) RedirectIngressArgsForCall(i int) string {
+	fake.redirectIngressMutex.RLock()
+	defer fake.redirectIngressMutex.RUnlock()
+	return fake.redirectIngressArgsForCall[i].to
+}
+
+func (fake *FakeTrafficController) RedirectIngressReturns(result1 error) {
+	fake.RedirectIngressStub = nil
+	fake.redirectIngressReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeTrafficController) TBF() (devices.TokenBucket, bool, error) {
+	fake.tBFMutex.Lock()
+	fake.tBFArgsForCall = append(fake.tBFArgsForCall, struct{}{})
+	fake.tBFMutex.Unlock()
+	if fake.TBFStub != nil {
+		return fake.TBFStub()
+	} else {
+		return fake.tBFReturns.result1, fake.tBFReturns.result2, fake.tBFReturns.result3
+	}
+}
+
+func (fake *FakeTrafficController) TBFCallCount() int {
+	fake.tBFMutex.RLock()
+	defer fake.tBFMutex.RUnlock()
+	return len(fake.tBFArgsForCall)
+}
+
+func (fake *FakeTrafficController) TBFReturns(result1 devices.TokenBucket, result2 bool, result3 error) {
+	fake.TBFStub = nil
+	fake.tBFReturns = struct {
+		result1 devices.TokenBucket
+		result2 bool
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeTrafficController) TrafficControlStatistics() (devices.TrafficControlStatistics, error) {
+	fake.trafficControlStatisticsMutex.Lock()
+	fake.trafficControlStatisticsArgsForCall = append(fake.trafficControlStatisticsArgsForCall, struct{}{})
+	fake.trafficControlStatisticsMutex.Unlock()
+	if fake.TrafficControlStatisticsStub != nil {
+		return fake.TrafficControlStatisticsStub()
+	} else {
+		return fake.trafficControlStatisticsReturns.result1, fake.trafficControlStatisticsReturns.result2
+	}
+}
+
+func (fake *FakeTrafficController) TrafficControlStatisticsCallCount() int {
+	fake.trafficControlStatisticsMutex.RLock()
+	defer fake.trafficControlStatisticsMutex.RUnlock()
+	return len(fake.trafficControlStatisticsArgsForCall)
+}
+
+func (fake *FakeTrafficController) TrafficControlStatisticsReturns(result1 devices.TrafficControlStatistics, result2 error) {
+	fake.TrafficControlStatisticsStub = nil
+	fake.trafficControlStatisticsReturns = struct {
+		result1 devices.TrafficControlStatistics
+		result2 error
+	}{result1, result2}
+}
+
+var _ bandwidth_manager.TrafficController = new(FakeTrafficController)
@@ -0,0 +1,171 @@
+package bandwidth_manager
+
+import (
+	"net"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	"github.com/cloudfoundry-incubator/garden-linux/network/devices"
+	"github.com/pivotal-golang/lager"
+)
+
+//go:generate counterfeiter -o fake_traffic_controller/fake_traffic_controller.go . TrafficController
+type TrafficController interface {
+	ClearTrafficControl() error
+	AddTBF(devices.TokenBucket) error
+	RedirectIngress(to string) error
+	TBF() (devices.TokenBucket, bool, error)
+	TrafficControlStatistics() (devices.TrafficControlStatistics, error)
+}
+
+//go:generate counterfeiter -o fake_ifb_manager/fake_ifb_manager.go . IFBManager
+type IFBManager interface {
+	Create(name string) (*net.Interface, error)
+	Exists(name string) (bool, error)
+	Destroy(name string) error
+}
+
+// NetlinkBandwidthManager limits a container's bandwidth on the host side
+// of its veth pair. A tbf qdisc shapes traffic to the container. Traffic from
+// the container arrives on the ingress of the host interface, where it can
+// only be policed, so it is redirected to an IFB device and shaped by a tbf
+// qdisc there instead.
+type NetlinkBandwidthManager struct {
+	hostIfc TrafficController
+
+	ifbName string
+	ifbIfc  TrafficController
+	ifbs    IFBManager
+}
+
+func NewNetlink(hostIfc TrafficController, ifbName string, ifbIfc TrafficController, ifbs IFBManager) *NetlinkBandwidthManager {
+	return &NetlinkBandwidthManager{
+		hostIfc: hostIfc,
+
+		ifbName: ifbName,
+		ifbIfc:  ifbIfc,
+		ifbs:    ifbs,
+	}
+}
+
+func (m *NetlinkBandwidthManager) SetLimits(
+	logger lager.Logger,
+	limits linux_backend.BandwidthLimits,
+) error {
+	logger.Debug("set-limits", lager.Data{"limits": limits})
+
+	if err := m.hostIfc.ClearTrafficControl(); err != nil {
+		logger.Error("clear-traffic-control-failed", err)
+		return err
+	}
+
+	if limits.InRateInBytesPerSecond != 0 {
+		if err := m.hostIfc.AddTBF(devices.TokenBucket{
+			RateInBytesPerSecond: limits.InRateInBytesPerSecond,
+			BurstInBytes:         limits.InBurstInBytes,
+		}); err != nil {
+			logger.Error("add-tbf-failed", err)
+			return err
+		}
+	}
+
+	if limits.OutRateInBytesPerSecond == 0 {
+		if err := m.ifbs.Destroy(m.ifbName); err != nil {
+			logger.Error("destroy-ifb-failed", err)
+			return err
+		}
+
+		return nil
+	}
+
+	if _, err := m.ifbs.Create(m.ifbName); err != nil {
+		logger.Error("create-ifb-failed", err)
+		return err
+	}
+
+	if err := m.ifbIfc.ClearTrafficControl(); err != nil {
+		logger.Error("clear-ifb-traffic-control-failed", err)
+		return err
+	}
+
+	if err := m.ifbIfc.AddTBF(devices.TokenBucket{
+		RateInBytesPerSecond: limits.OutRateInBytesPerSecond,
+		BurstInBytes:         limits.OutBurstInBytes,
+	}); err != nil {
+		logger.Error("add-ifb-tbf-failed", err)
+		return err
+	}
+
+	if err := m.hostIfc.RedirectIngress(m.ifbName); err != nil {
+		logger.Error("redirect-ingress-failed", err)
+		return err
+	}
+
+	return nil
+}
+
+func (m *NetlinkBandwidthManager) GetLimits(logger lager.Logger) (garden.ContainerBandwidthStat, error) {
+	limits := garden.ContainerBandwidthStat{}
+
+	in, found, err := m.hostIfc.TBF()
+	if err != nil {
+		logger.Error("get-tbf-failed", err)
+		return limits, err
+	}
+
+	if found {
+		limits.InRate = in.RateInBytesPerSecond
+		limits.InBurst = in.BurstInBytes
+	}
+
+	exists, err := m.ifbs.Exists(m.ifbName)
+	if err != nil || !exists {
+		return limits, err
+	}
+
+	out, found, err := m.ifbIfc.TBF()
+	if err != nil {
+		logger.Error("get-ifb-tbf-failed", err)
+		return limits, err
+	}
+
+	if found {
+		limits.OutRate = out.RateInBytesPerSecond
+		limits.OutBurst = out.BurstInBytes
+	}
+
+	return limits, nil
+}
+
+func (m *NetlinkBandwidthManager) GetUsage(logger lager.Logger) (linux_backend.ContainerBandwidthUsageStat, error) {
+	hostStats, err := m.hostIfc.TrafficControlStatistics()
+	if err != nil {
+		logger.Error("get-traffic-control-statistics-failed", err)
+		return linux_backend.ContainerBandwidthUsageStat{}, err
+	}
+
+	usage := linux_backend.ContainerBandwidthUsageStat{
+		InBytes:      hostStats.Root.Bytes,
+		InPackets:    hostStats.Root.Packets,
+		InDropped:    hostStats.Root.Drops,
+		InOverlimits: hostStats.Root.Overlimits,
+	}
+
+	exists, err := m.ifbs.Exists(m.ifbName)
+	if err != nil || !exists {
+		return usage, err
+	}
+
+	ifbStats, err := m.ifbIfc.TrafficControlStatistics()
+	if err != nil {
+		logger.Error("get-ifb-traffic-control-statistics-failed", err)
+		return linux_backend.ContainerBandwidthUsageStat{}, err
+	}
+
+	usage.OutBytes = ifbStats.Root.Bytes
+	usage.OutPackets = ifbStats.Root.Packets
+	usage.OutDropped = ifbStats.Root.Drops
+	usage.OutOverlimits = ifbStats.Root.Overlimits
+
+	return usage, nil
+}
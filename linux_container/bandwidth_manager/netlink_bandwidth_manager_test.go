@@ -0,0 +1,248 @@
+package bandwidth_manager_test
+
+import (
+	"errors"
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/bandwidth_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/bandwidth_manager/fake_ifb_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/bandwidth_manager/fake_traffic_controller"
+	"github.com/cloudfoundry-incubator/garden-linux/network/devices"
+)
+
+var _ = Describe("NetlinkBandwidthManager", func() {
+	var (
+		hostIfc *fake_traffic_controller.FakeTrafficController
+		ifbIfc  *fake_traffic_controller.FakeTrafficController
+		ifbs    *fake_ifb_manager.FakeIFBManager
+		manager *bandwidth_manager.NetlinkBandwidthManager
+		logger  *lagertest.TestLogger
+	)
+
+	BeforeEach(func() {
+		hostIfc = new(fake_traffic_controller.FakeTrafficController)
+		ifbIfc = new(fake_traffic_controller.FakeTrafficController)
+		ifbs = new(fake_ifb_manager.FakeIFBManager)
+		manager = bandwidth_manager.NewNetlink(hostIfc, "some-ifb", ifbIfc, ifbs)
+		logger = lagertest.NewTestLogger("test")
+	})
+
+	Describe("SetLimits", func() {
+		limits := linux_backend.BandwidthLimits{
+			InRateInBytesPerSecond:  128,
+			InBurstInBytes:          256,
+			OutRateInBytesPerSecond: 512,
+			OutBurstInBytes:         1024,
+		}
+
+		It("shapes inbound traffic with a tbf qdisc on the host interface", func() {
+			Expect(manager.SetLimits(logger, limits)).To(Succeed())
+
+			Expect(hostIfc.ClearTrafficControlCallCount()).To(Equal(1))
+			Expect(hostIfc.AddTBFCallCount()).To(Equal(1))
+			Expect(hostIfc.AddTBFArgsForCall(0)).To(Equal(devices.TokenBucket{RateInBytesPerSecond: 128, BurstInBytes: 256}))
+		})
+
+		It("shapes outbound traffic by redirecting it to a tbf qdisc on an ifb device", func() {
+			var calls []string
+			ifbs.CreateStub = func(name string) (*net.Interface, error) {
+				calls = append(calls, "create "+name)
+				return nil, nil
+			}
+			ifbIfc.AddTBFStub = func(devices.TokenBucket) error {
+				calls = append(calls, "ifb tbf")
+				return nil
+			}
+			hostIfc.RedirectIngressStub = func(to string) error {
+				calls = append(calls, "redirect to "+to)
+				return nil
+			}
+
+			Expect(manager.SetLimits(logger, limits)).To(Succeed())
+			Expect(calls).To(Equal([]string{"create some-ifb", "ifb tbf", "redirect to some-ifb"}))
+
+			Expect(ifbIfc.ClearTrafficControlCallCount()).To(Equal(1))
+			Expect(ifbIfc.AddTBFArgsForCall(0)).To(Equal(devices.TokenBucket{RateInBytesPerSecond: 512, BurstInBytes: 1024}))
+		})
+
+		Context("when the inbound rate is zero", func() {
+			It("does not shape inbound traffic", func() {
+				Expect(manager.SetLimits(logger, linux_backend.BandwidthLimits{OutRateInBytesPerSecond: 512})).To(Succeed())
+
+				Expect(hostIfc.ClearTrafficControlCallCount()).To(Equal(1))
+				Expect(hostIfc.AddTBFCallCount()).To(Equal(0))
+				Expect(hostIfc.RedirectIngressCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when the outbound rate is zero", func() {
+			It("removes the ifb device instead of redirecting to it", func() {
+				Expect(manager.SetLimits(logger, linux_backend.BandwidthLimits{InRateInBytesPerSecond: 128})).To(Succeed())
+
+				Expect(ifbs.DestroyCallCount()).To(Equal(1))
+				Expect(ifbs.DestroyArgsForCall(0)).To(Equal("some-ifb"))
+				Expect(ifbs.CreateCallCount()).To(Equal(0))
+				Expect(hostIfc.RedirectIngressCallCount()).To(Equal(0))
+			})
+
+			Context("and removing the ifb device fails", func() {
+				It("returns the error", func() {
+					ifbs.DestroyReturns(errors.New("oh no!"))
+
+					Expect(manager.SetLimits(logger, linux_backend.BandwidthLimits{})).To(MatchError("oh no!"))
+				})
+			})
+		})
+
+		Context("when clearing the existing limits fails", func() {
+			It("returns the error without adding limits", func() {
+				hostIfc.ClearTrafficControlReturns(errors.New("oh no!"))
+
+				Expect(manager.SetLimits(logger, limits)).To(MatchError("oh no!"))
+				Expect(hostIfc.AddTBFCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when adding the tbf qdisc fails", func() {
+			It("returns the error", func() {
+				hostIfc.AddTBFReturns(errors.New("oh no!"))
+
+				Expect(manager.SetLimits(logger, limits)).To(MatchError("oh no!"))
+				Expect(ifbs.CreateCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when creating the ifb device fails", func() {
+			It("returns the error", func() {
+				ifbs.CreateReturns(nil, errors.New("oh no!"))
+
+				Expect(manager.SetLimits(logger, limits)).To(MatchError("oh no!"))
+				Expect(hostIfc.RedirectIngressCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when adding the ifb tbf qdisc fails", func() {
+			It("returns the error", func() {
+				ifbIfc.AddTBFReturns(errors.New("oh no!"))
+
+				Expect(manager.SetLimits(logger, limits)).To(MatchError("oh no!"))
+				Expect(hostIfc.RedirectIngressCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when redirecting ingress fails", func() {
+			It("returns the error", func() {
+				hostIfc.RedirectIngressReturns(errors.New("oh no!"))
+
+				Expect(manager.SetLimits(logger, limits)).To(MatchError("oh no!"))
+			})
+		})
+	})
+
+	Describe("GetLimits", func() {
+		BeforeEach(func() {
+			hostIfc.TBFReturns(devices.TokenBucket{RateInBytesPerSecond: 128, BurstInBytes: 256}, true, nil)
+			ifbIfc.TBFReturns(devices.TokenBucket{RateInBytesPerSecond: 512, BurstInBytes: 1024}, true, nil)
+		})
+
+		It("reports the host tbf as inbound and the ifb tbf as outbound limits", func() {
+			ifbs.ExistsReturns(true, nil)
+
+			Expect(manager.GetLimits(logger)).To(Equal(garden.ContainerBandwidthStat{
+				InRate:   128,
+				InBurst:  256,
+				OutRate:  512,
+				OutBurst: 1024,
+			}))
+
+			Expect(ifbs.ExistsArgsForCall(0)).To(Equal("some-ifb"))
+		})
+
+		Context("when there is no ifb device", func() {
+			It("reports no outbound limits", func() {
+				Expect(manager.GetLimits(logger)).To(Equal(garden.ContainerBandwidthStat{
+					InRate:  128,
+					InBurst: 256,
+				}))
+
+				Expect(ifbIfc.TBFCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when no limits are set", func() {
+			It("reports zero limits", func() {
+				hostIfc.TBFReturns(devices.TokenBucket{}, false, nil)
+				Expect(manager.GetLimits(logger)).To(BeZero())
+			})
+		})
+
+		Context("when reading the tbf fails", func() {
+			It("returns the error", func() {
+				hostIfc.TBFReturns(devices.TokenBucket{}, false, errors.New("oh no!"))
+
+				_, err := manager.GetLimits(logger)
+				Expect(err).To(MatchError("oh no!"))
+			})
+		})
+
+		Context("when reading the ifb tbf fails", func() {
+			It("returns the error", func() {
+				ifbs.ExistsReturns(true, nil)
+				ifbIfc.TBFReturns(devices.TokenBucket{}, false, errors.New("oh no!"))
+
+				_, err := manager.GetLimits(logger)
+				Expect(err).To(MatchError("oh no!"))
+			})
+		})
+	})
+
+	Describe("GetUsage", func() {
+		BeforeEach(func() {
+			hostIfc.TrafficControlStatisticsReturns(devices.TrafficControlStatistics{
+				Root: devices.QdiscStatistics{Bytes: 1, Packets: 2, Drops: 3, Overlimits: 4},
+			}, nil)
+			ifbIfc.TrafficControlStatisticsReturns(devices.TrafficControlStatistics{
+				Root: devices.QdiscStatistics{Bytes: 5, Packets: 6, Drops: 7, Overlimits: 8},
+			}, nil)
+		})
+
+		It("reports the host root qdisc as inbound and the ifb root qdisc as outbound traffic", func() {
+			ifbs.ExistsReturns(true, nil)
+
+			Expect(manager.GetUsage(logger)).To(Equal(linux_backend.ContainerBandwidthUsageStat{
+				InBytes:       1,
+				InPackets:     2,
+				InDropped:     3,
+				InOverlimits:  4,
+				OutBytes:      5,
+				OutPackets:    6,
+				OutDropped:    7,
+				OutOverlimits: 8,
+			}))
+		})
+
+		Context("when there is no ifb device", func() {
+			It("reports no outbound traffic", func() {
+				usage, err := manager.GetUsage(logger)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(usage.OutBytes).To(BeZero())
+				Expect(usage.InBytes).To(Equal(uint64(1)))
+			})
+		})
+
+		Context("when reading the statistics fails", func() {
+			It("returns the error", func() {
+				hostIfc.TrafficControlStatisticsReturns(devices.TrafficControlStatistics{}, errors.New("oh no!"))
+
+				_, err := manager.GetUsage(logger)
+				Expect(err).To(MatchError("oh no!"))
+			})
+		})
+	})
+})
@@ -0,0 +1,37 @@
+package cgroups_manager
+
+// DeviceRule allows access to the devices of a type, with a major and minor
+// number, as a devices.allow entry would on the legacy hierarchy. A negative
+// Major or Minor matches any number.
+type DeviceRule struct {
+	// Type is 'c' for character devices or 'b' for block devices.
+	Type rune
+	// Major and Minor identify the device, or are negative to match any.
+	Major int64
+	Minor int64
+	// Access is any of 'r', 'w' and 'm', for read, write and mknod.
+	Access string
+}
+
+// DefaultDeviceRules are the devices containers may use. They match the
+// devices.allow entries written for containers on the legacy hierarchy; every
+// other device is denied.
+var DefaultDeviceRules = []DeviceRule{
+	// mknod for everything
+	{Type: 'c', Major: -1, Minor: -1, Access: "m"},
+	{Type: 'b', Major: -1, Minor: -1, Access: "m"},
+
+	{Type: 'c', Major: 1, Minor: 3, Access: "rwm"},    // /dev/null
+	{Type: 'c', Major: 1, Minor: 5, Access: "rwm"},    // /dev/zero
+	{Type: 'c', Major: 1, Minor: 7, Access: "rwm"},    // /dev/full
+	{Type: 'c', Major: 1, Minor: 8, Access: "rwm"},    // /dev/random
+	{Type: 'c', Major: 1, Minor: 9, Access: "rwm"},    // /dev/urandom
+	{Type: 'c', Major: 4, Minor: 0, Access: "rwm"},    // /dev/tty0
+	{Type: 'c', Major: 4, Minor: 1, Access: "rwm"},    // /dev/tty1
+	{Type: 'c', Major: 5, Minor: 0, Access: "rwm"},    // /dev/tty
+	{Type: 'c', Major: 5, Minor: 1, Access: "rwm"},    // /dev/console
+	{Type: 'c', Major: 5, Minor: 2, Access: "rwm"},    // /dev/ptmx
+	{Type: 'c', Major: 136, Minor: -1, Access: "rwm"}, // /dev/pts/*
+	{Type: 'c', Major: 10, Minor: 200, Access: "rwm"}, // tuntap
+	{Type: 'c', Major: 10, Minor: 229, Access: "rwm"}, // /dev/fuse
+}
@@ -0,0 +1,213 @@
+package cgroups_manager
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// the syscall package does not define SYS_BPF on amd64
+	sysBPF = 321
+
+	bpfProgLoad   = 5
+	bpfProgAttach = 8
+
+	bpfProgTypeCgroupDevice = 15
+	bpfCgroupDevice         = 6
+
+	// bpf_cgroup_dev_ctx.access_type holds the device type in its low 16 bits
+	// and the requested access in its high 16 bits
+	bpfDevcgDevBlock  = 1
+	bpfDevcgDevChar   = 2
+	bpfDevcgAccMknod  = 1
+	bpfDevcgAccRead   = 2
+	bpfDevcgAccWrite  = 4
+	bpfDevcgAccessAll = bpfDevcgAccMknod | bpfDevcgAccRead | bpfDevcgAccWrite
+
+	// instruction opcodes
+	bpfLdxMemW   = 0x61 // dst = *(u32 *)(src + off)
+	bpfAlu32AndK = 0x54 // dst &= imm
+	bpfAlu32RshK = 0x74 // dst >>= imm
+	bpfAlu32MovX = 0xbc // dst = src
+	bpfJneK      = 0x55 // if dst != imm goto pc + off
+	bpfJneX      = 0x5d // if dst != src goto pc + off
+	bpfMov64K    = 0xb7 // dst = imm
+	bpfExit      = 0x95
+)
+
+type bpfInsn struct {
+	code uint8
+	regs uint8 // dst in the low nibble, src in the high nibble
+	off  int16
+	imm  int32
+}
+
+func insn(code, dst, src uint8, off int16, imm int32) bpfInsn {
+	return bpfInsn{code: code, regs: dst | src<<4, off: off, imm: imm}
+}
+
+// AttachDeviceFilter attaches a BPF_CGROUP_DEVICE program to the cgroup v2
+// directory at cgroupPath which allows access only to the devices matched by
+// rules. The unified hierarchy has no devices.allow, so without it processes
+// in the cgroup could open any device node they can create.
+//
+// The program stays attached once its descriptor is closed, and attaching
+// again replaces it.
+func AttachDeviceFilter(cgroupPath string, rules []DeviceRule) error {
+	insns, err := deviceFilter(rules)
+	if err != nil {
+		return err
+	}
+
+	prog, err := loadDeviceFilter(insns)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(prog)
+
+	cgroup, err := os.Open(cgroupPath)
+	if err != nil {
+		return err
+	}
+	defer cgroup.Close()
+
+	attr := struct {
+		targetFd    uint32
+		attachBpfFd uint32
+		attachType  uint32
+		attachFlags uint32
+	}{
+		targetFd:    uint32(cgroup.Fd()),
+		attachBpfFd: uint32(prog),
+		attachType:  bpfCgroupDevice,
+	}
+
+	_, _, errno := syscall.Syscall(sysBPF, bpfProgAttach, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr))
+	if errno != 0 {
+		return os.NewSyscallError("bpf: attach device filter", errno)
+	}
+
+	return nil
+}
+
+// deviceFilter compiles the rules to a program which returns 1 to allow the
+// access described by its bpf_cgroup_dev_ctx and 0 to deny it.
+func deviceFilter(rules []DeviceRule) ([]bpfInsn, error) {
+	insns := []bpfInsn{
+		insn(bpfLdxMemW, 2, 1, 0, 0), // r2 = type
+		insn(bpfAlu32AndK, 2, 0, 0, 0xffff),
+		insn(bpfLdxMemW, 3, 1, 0, 0), // r3 = access
+		insn(bpfAlu32RshK, 3, 0, 0, 16),
+		insn(bpfLdxMemW, 4, 1, 4, 0), // r4 = major
+		insn(bpfLdxMemW, 5, 1, 8, 0), // r5 = minor
+	}
+
+	for _, rule := range rules {
+		var devType int32
+		switch rule.Type {
+		case 'c':
+			devType = bpfDevcgDevChar
+		case 'b':
+			devType = bpfDevcgDevBlock
+		default:
+			return nil, fmt.Errorf("cgroups_manager: invalid device type %q", rule.Type)
+		}
+
+		var access int32
+		for _, a := range rule.Access {
+			switch a {
+			case 'r':
+				access |= bpfDevcgAccRead
+			case 'w':
+				access |= bpfDevcgAccWrite
+			case 'm':
+				access |= bpfDevcgAccMknod
+			default:
+				return nil, fmt.Errorf("cgroups_manager: invalid device access %q", a)
+			}
+		}
+
+		// each check skips to the next rule when it does not match
+		checks := []bpfInsn{insn(bpfJneK, 2, 0, 0, devType)}
+
+		if access != bpfDevcgAccessAll {
+			// only allow the access if every requested kind is in the rule
+			checks = append(checks,
+				insn(bpfAlu32MovX, 1, 3, 0, 0),
+				insn(bpfAlu32AndK, 1, 0, 0, access),
+				insn(bpfJneX, 1, 3, 0, 0),
+			)
+		}
+
+		if rule.Major >= 0 {
+			checks = append(checks, insn(bpfJneK, 4, 0, 0, int32(rule.Major)))
+		}
+
+		if rule.Minor >= 0 {
+			checks = append(checks, insn(bpfJneK, 5, 0, 0, int32(rule.Minor)))
+		}
+
+		checks = append(checks,
+			insn(bpfMov64K, 0, 0, 0, 1),
+			insn(bpfExit, 0, 0, 0, 0),
+		)
+
+		for i := range checks {
+			if checks[i].code == bpfJneK || checks[i].code == bpfJneX {
+				checks[i].off = int16(len(checks) - i - 1)
+			}
+		}
+
+		insns = append(insns, checks...)
+	}
+
+	return append(insns,
+		insn(bpfMov64K, 0, 0, 0, 0),
+		insn(bpfExit, 0, 0, 0, 0),
+	), nil
+}
+
+func loadDeviceFilter(insns []bpfInsn) (int, error) {
+	license := []byte("GPL\x00")
+	log := make([]byte, 64*1024)
+
+	attr := struct {
+		progType uint32
+		insnCnt  uint32
+		insns    uint64
+		license  uint64
+		logLevel uint32
+		logSize  uint32
+		logBuf   uint64
+	}{
+		progType: bpfProgTypeCgroupDevice,
+		insnCnt:  uint32(len(insns)),
+		insns:    uint64(uintptr(unsafe.Pointer(&insns[0]))),
+		license:  uint64(uintptr(unsafe.Pointer(&license[0]))),
+		logLevel: 1,
+		logSize:  uint32(len(log)),
+		logBuf:   uint64(uintptr(unsafe.Pointer(&log[0]))),
+	}
+
+	fd, _, errno := syscall.Syscall(sysBPF, bpfProgLoad, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr))
+	runtime.KeepAlive(insns)
+	runtime.KeepAlive(license)
+	if errno != 0 {
+		return -1, fmt.Errorf("bpf: load device filter: %s: %s", errno, cString(log))
+	}
+
+	return int(fd), nil
+}
+
+func cString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+
+	return string(b)
+}
@@ -0,0 +1,104 @@
+package cgroups_manager_test
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"syscall"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager"
+)
+
+var _ = Describe("AttachDeviceFilter", func() {
+	var (
+		cgroupPath string
+		devPath    string
+	)
+
+	// open opens the device node from a process in the cgroup, returning the
+	// shell's output if it fails
+	open := func(name, mode string) (string, error) {
+		script := fmt.Sprintf("echo $$ > %s/cgroup.procs && exec 3%s %s", cgroupPath, mode, path.Join(devPath, name))
+		output, err := exec.Command("sh", "-c", script).CombinedOutput()
+		return string(output), err
+	}
+
+	BeforeEach(func() {
+		if os.Getuid() != 0 {
+			Skip("must be run as root")
+		}
+
+		unifiedPath := unifiedMount()
+		if unifiedPath == "" {
+			Skip("no cgroup2 hierarchy is mounted")
+		}
+
+		var err error
+		cgroupPath, err = ioutil.TempDir(unifiedPath, "device-filter")
+		Expect(err).ToNot(HaveOccurred())
+
+		devPath, err = ioutil.TempDir("", "device-filter-dev")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(syscall.Mknod(path.Join(devPath, "null"), syscall.S_IFCHR|0666, 1<<8|3)).To(Succeed())
+		Expect(syscall.Mknod(path.Join(devPath, "kmsg"), syscall.S_IFCHR|0666, 1<<8|11)).To(Succeed())
+
+		Expect(cgroups_manager.AttachDeviceFilter(cgroupPath, cgroups_manager.DefaultDeviceRules)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		// the processes exited, so the cgroup is empty and can be removed
+		os.Remove(cgroupPath)
+		os.RemoveAll(devPath)
+	})
+
+	It("allows the whitelisted devices to be opened", func() {
+		output, err := open("null", "<>")
+		Expect(err).ToNot(HaveOccurred(), output)
+	})
+
+	It("denies opening any other device", func() {
+		output, err := open("kmsg", "<")
+		Expect(err).To(HaveOccurred())
+		Expect(output).To(ContainSubstring("Operation not permitted"))
+	})
+
+	It("allows creating device nodes", func() {
+		script := fmt.Sprintf("echo $$ > %s/cgroup.procs && mknod %s c 1 11", cgroupPath, path.Join(devPath, "created"))
+		output, err := exec.Command("sh", "-c", script).CombinedOutput()
+		Expect(err).ToNot(HaveOccurred(), string(output))
+	})
+
+	It("rejects rules with an unknown device type", func() {
+		err := cgroups_manager.AttachDeviceFilter(cgroupPath, []cgroups_manager.DeviceRule{
+			{Type: 'x', Major: 1, Minor: 3, Access: "rwm"},
+		})
+		Expect(err).To(MatchError(`cgroups_manager: invalid device type 'x'`))
+	})
+})
+
+// unifiedMount returns where a cgroup2 hierarchy is mounted, or "" if none is.
+func unifiedMount() string {
+	mounts, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return ""
+	}
+	defer mounts.Close()
+
+	scanner := bufio.NewScanner(mounts)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 2 && fields[2] == "cgroup2" {
+			return fields[1]
+		}
+	}
+
+	return ""
+}
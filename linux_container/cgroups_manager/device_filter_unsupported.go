@@ -0,0 +1,9 @@
+// +build !linux !amd64
+
+package cgroups_manager
+
+import "errors"
+
+func AttachDeviceFilter(cgroupPath string, rules []DeviceRule) error {
+	return errors.New("cgroups_manager: device filters are not supported on this platform")
+}
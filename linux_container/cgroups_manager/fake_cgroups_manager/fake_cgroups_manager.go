@@ -80,7 +80,8 @@ func (m *FakeCgroupsManager) Get(subsytem, name string) (string, error) {
 		}
 	}
 
-	for _, val := range m.setValues {
+	for i := len(m.setValues) - 1; i >= 0; i-- {
+		val := m.setValues[i]
 		if val.Subsystem == subsytem && val.Name == name {
 			return val.Value, nil
 		}
@@ -0,0 +1,642 @@
+package cgroups_manager
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	// HostCgroupsPath is where the host mounts its cgroup hierarchy.
+	HostCgroupsPath = "/sys/fs/cgroup"
+
+	// UnifiedParent is the cgroup, relative to the root of the unified
+	// hierarchy, under which container cgroups are created. Controllers can
+	// only be delegated to a cgroup without processes of its own, so containers
+	// are not created alongside the garden process.
+	UnifiedParent = "garden"
+
+	// v1 reports an unlimited memory limit as the largest page-aligned value
+	unlimitedMemory = "9223372036854771712"
+
+	// cpuacct.stat is reported in USER_HZ, which is always 100 on Linux
+	microsecondsPerUserHZ = 10000
+)
+
+// IsUnified reports whether the cgroup hierarchy mounted at cgroupsPath is
+// the cgroup v2 unified hierarchy.
+func IsUnified(cgroupsPath string) bool {
+	_, err := os.Stat(path.Join(cgroupsPath, "cgroup.controllers"))
+	return err == nil
+}
+
+type UnsupportedError struct {
+	Name string
+}
+
+func (e UnsupportedError) Error() string {
+	return fmt.Sprintf("%s is not supported on the unified cgroup hierarchy", e.Name)
+}
+
+// UnifiedCgroupsManager manages a container's cgroup on the cgroup v2 unified
+// hierarchy. It accepts the same v1 subsystem and file names as
+// ContainerCgroupsManager and translates them, and their values, to the
+// unified hierarchy's interface files, so that callers need not know which
+// hierarchy the host uses.
+//
+// Some translations are lossy: cpu.shares and blkio.weight are scaled to the
+// range of cpu.weight and io.weight, so reading them back may not return
+// exactly the value written.
+type UnifiedCgroupsManager struct {
+	cgroupsPath string
+	containerID string
+}
+
+func NewUnified(cgroupsPath, containerID string) *UnifiedCgroupsManager {
+	return &UnifiedCgroupsManager{cgroupsPath, containerID}
+}
+
+var unifiedControllers = map[string]string{
+	"cpu":     "cpu",
+	"cpuacct": "cpu",
+	"cpuset":  "cpuset",
+	"memory":  "memory",
+	"blkio":   "io",
+	"pids":    "pids",
+}
+
+var ioThrottleKeys = map[string]string{
+	"blkio.throttle.read_bps_device":   "rbps",
+	"blkio.throttle.write_bps_device":  "wbps",
+	"blkio.throttle.read_iops_device":  "riops",
+	"blkio.throttle.write_iops_device": "wiops",
+}
+
+func (m *UnifiedCgroupsManager) Set(subsystem, name, value string) error {
+	if err := m.set(name, value); err != nil {
+		// left as is so that callers can tell an unsupported file from a
+		// failed write
+		if _, unsupported := err.(UnsupportedError); unsupported {
+			return err
+		}
+
+		return fmt.Errorf("cgroups_manager: set: %s", err)
+	}
+
+	return nil
+}
+
+func (m *UnifiedCgroupsManager) set(name, value string) error {
+	switch name {
+	case "memory.limit_in_bytes":
+		return m.write("memory.max", toMax(value))
+
+	case "memory.memsw.limit_in_bytes":
+		return m.setMemsw(value)
+
+	case "memory.soft_limit_in_bytes":
+		// memory.low protects usage below it from reclaim, which is the
+		// closest the unified hierarchy has to a soft limit
+		if value == "-1" {
+			value = "0"
+		}
+
+		return m.write("memory.low", value)
+
+	case "memory.kmem.limit_in_bytes":
+		// kernel memory is charged to memory.max and cannot be limited apart
+		if value == "-1" {
+			return nil
+		}
+
+		return UnsupportedError{Name: name}
+
+	case "memory.oom_control", "cgroup.event_control":
+		return UnsupportedError{Name: name}
+
+	case "cpu.shares":
+		shares, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		return m.write("cpu.weight", strconv.FormatUint(scale(shares, 2, 262144, 1, 10000), 10))
+
+	case "cpu.cfs_quota_us":
+		_, period, err := m.cpuMax()
+		if err != nil {
+			return err
+		}
+
+		return m.write("cpu.max", toMax(value)+" "+period)
+
+	case "cpu.cfs_period_us":
+		quota, _, err := m.cpuMax()
+		if err != nil {
+			return err
+		}
+
+		return m.write("cpu.max", quota+" "+value)
+
+	case "blkio.weight":
+		weight, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		return m.write("io.weight", "default "+strconv.FormatUint(scale(weight, 10, 1000, 1, 10000), 10))
+
+	case "blkio.throttle.read_bps_device", "blkio.throttle.write_bps_device",
+		"blkio.throttle.read_iops_device", "blkio.throttle.write_iops_device":
+		fields := strings.Fields(value)
+		if len(fields) != 2 {
+			return fmt.Errorf("invalid throttle %q", value)
+		}
+
+		limit := fields[1]
+		if limit == "0" {
+			limit = "max"
+		}
+
+		return m.write("io.max", fmt.Sprintf("%s %s=%s", fields[0], ioThrottleKeys[name], limit))
+
+	case "freezer.state":
+		frozen := "0"
+		if value == "FROZEN" {
+			frozen = "1"
+		}
+
+		return m.write("cgroup.freeze", frozen)
+
+	case "tasks":
+		return m.write("cgroup.procs", value)
+	}
+
+	return m.write(name, value)
+}
+
+func (m *UnifiedCgroupsManager) Get(subsystem, name string) (string, error) {
+	value, err := m.get(name)
+	if err != nil {
+		return "", fmt.Errorf("cgroups_manager: get: %s", err)
+	}
+
+	return value, nil
+}
+
+func (m *UnifiedCgroupsManager) get(name string) (string, error) {
+	switch name {
+	case "memory.limit_in_bytes":
+		limit, err := m.read("memory.max")
+		if err != nil {
+			return "", err
+		}
+
+		return fromMemoryMax(limit), nil
+
+	case "memory.memsw.limit_in_bytes":
+		return m.memsw()
+
+	case "memory.soft_limit_in_bytes":
+		low, err := m.read("memory.low")
+		if err != nil {
+			return "", err
+		}
+
+		if low == "0" {
+			return unlimitedMemory, nil
+		}
+
+		return fromMemoryMax(low), nil
+
+	case "memory.kmem.limit_in_bytes":
+		return unlimitedMemory, nil
+
+	case "memory.stat":
+		return m.memoryStat()
+
+	case "memory.oom_control", "cgroup.event_control":
+		return "", UnsupportedError{Name: name}
+
+	case "cpu.shares":
+		weight, err := m.readUint("cpu.weight")
+		if err != nil {
+			return "", err
+		}
+
+		return strconv.FormatUint(scale(weight, 1, 10000, 2, 262144), 10), nil
+
+	case "cpu.cfs_quota_us":
+		quota, _, err := m.cpuMax()
+		if err != nil {
+			return "", err
+		}
+
+		if quota == "max" {
+			return "-1", nil
+		}
+
+		return quota, nil
+
+	case "cpu.cfs_period_us":
+		_, period, err := m.cpuMax()
+		return period, err
+
+	case "cpuacct.usage":
+		stat, err := m.readKeyed("cpu.stat")
+		if err != nil {
+			return "", err
+		}
+
+		return strconv.FormatUint(stat["usage_usec"]*1000, 10), nil
+
+	case "cpuacct.stat":
+		stat, err := m.readKeyed("cpu.stat")
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf(
+			"user %d\nsystem %d",
+			stat["user_usec"]/microsecondsPerUserHZ,
+			stat["system_usec"]/microsecondsPerUserHZ,
+		), nil
+
+	case "blkio.weight":
+		weights, err := m.read("io.weight")
+		if err != nil {
+			return "", err
+		}
+
+		for _, line := range strings.Split(weights, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 2 && fields[0] == "default" {
+				weight, err := strconv.ParseUint(fields[1], 10, 64)
+				if err != nil {
+					return "", err
+				}
+
+				return strconv.FormatUint(scale(weight, 1, 10000, 10, 1000), 10), nil
+			}
+		}
+
+		return "", fmt.Errorf("no default weight in io.weight")
+
+	case "blkio.throttle.read_bps_device", "blkio.throttle.write_bps_device",
+		"blkio.throttle.read_iops_device", "blkio.throttle.write_iops_device":
+		return m.ioThrottle(ioThrottleKeys[name])
+
+	case "blkio.throttle.io_service_bytes":
+		return m.ioStat("rbytes", "wbytes")
+
+	case "blkio.throttle.io_serviced":
+		return m.ioStat("rios", "wios")
+
+	case "freezer.state":
+		events, err := m.readKeyed("cgroup.events")
+		if err != nil {
+			return "", err
+		}
+
+		if events["frozen"] == 1 {
+			return "FROZEN", nil
+		}
+
+		return "THAWED", nil
+
+	case "tasks":
+		return m.read("cgroup.procs")
+	}
+
+	return m.read(name)
+}
+
+// Setup creates the container's cgroup and enables the controllers backing
+// the given v1 subsystems for it.
+func (m *UnifiedCgroupsManager) Setup(subsystems ...string) error {
+	controllers := []string{}
+	enabled := map[string]bool{}
+
+	for _, subsystem := range subsystems {
+		controller, found := unifiedControllers[subsystem]
+		if !found || enabled[controller] {
+			continue
+		}
+
+		enabled[controller] = true
+		controllers = append(controllers, "+"+controller)
+	}
+
+	if len(controllers) > 0 {
+		subtreeControl := path.Join(m.cgroupsPath, "cgroup.subtree_control")
+		if err := ioutil.WriteFile(subtreeControl, []byte(strings.Join(controllers, " ")), 0644); err != nil {
+			return fmt.Errorf("cgroups_manager: enable controllers: %s", err)
+		}
+	}
+
+	return os.MkdirAll(m.instancePath(), 0755)
+}
+
+// Add moves the process into the container's cgroup. As there is a single
+// hierarchy, the subsystems are irrelevant.
+func (m *UnifiedCgroupsManager) Add(pid int, subsystems ...string) error {
+	procs, err := os.OpenFile(path.Join(m.instancePath(), "cgroup.procs"), os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer procs.Close()
+
+	_, err = fmt.Fprintf(procs, "%d\n", pid)
+	return err
+}
+
+// SubsystemPath returns the container's cgroup, which is the same for every
+// subsystem.
+func (m *UnifiedCgroupsManager) SubsystemPath(subsystem string) (string, error) {
+	return m.instancePath(), nil
+}
+
+func (m *UnifiedCgroupsManager) instancePath() string {
+	return path.Join(m.cgroupsPath, "instance-"+m.containerID)
+}
+
+func (m *UnifiedCgroupsManager) read(name string) (string, error) {
+	body, err := ioutil.ReadFile(path.Join(m.instancePath(), name))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Trim(string(body), "\n"), nil
+}
+
+func (m *UnifiedCgroupsManager) readUint(name string) (uint64, error) {
+	value, err := m.read(name)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// readKeyed reads a flat keyed file, e.g. cpu.stat, of "key value" lines.
+func (m *UnifiedCgroupsManager) readKeyed(name string) (map[string]uint64, error) {
+	contents, err := m.read(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseKeyed(contents), nil
+}
+
+func (m *UnifiedCgroupsManager) write(name, value string) error {
+	return ioutil.WriteFile(path.Join(m.instancePath(), name), []byte(value), 0644)
+}
+
+// setMemsw translates v1's combined memory and swap limit to the unified
+// hierarchy's separate swap limit, relative to the current memory limit.
+func (m *UnifiedCgroupsManager) setMemsw(value string) error {
+	if value == "-1" {
+		return m.write("memory.swap.max", "max")
+	}
+
+	total, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	memoryMax, err := m.read("memory.max")
+	if err != nil {
+		return err
+	}
+
+	if memoryMax == "max" {
+		return m.write("memory.swap.max", "max")
+	}
+
+	memory, err := strconv.ParseUint(memoryMax, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	if total < memory {
+		return fmt.Errorf("memory and swap limit %d is below the memory limit %d", total, memory)
+	}
+
+	return m.write("memory.swap.max", strconv.FormatUint(total-memory, 10))
+}
+
+func (m *UnifiedCgroupsManager) memsw() (string, error) {
+	memoryMax, err := m.read("memory.max")
+	if err != nil {
+		return "", err
+	}
+
+	swapMax, err := m.read("memory.swap.max")
+	if err != nil {
+		return "", err
+	}
+
+	if memoryMax == "max" || swapMax == "max" {
+		return unlimitedMemory, nil
+	}
+
+	memory, err := strconv.ParseUint(memoryMax, 10, 64)
+	if err != nil {
+		return "", err
+	}
+
+	swap, err := strconv.ParseUint(swapMax, 10, 64)
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.FormatUint(memory+swap, 10), nil
+}
+
+// unified memory.stat keys and their v1 equivalents; the unified hierarchy's
+// statistics are always hierarchical, so they also stand in for the total_
+// variants
+var memoryStatKeys = []struct{ unified, v1 string }{
+	{"file", "cache"},
+	{"anon", "rss"},
+	{"file_mapped", "mapped_file"},
+	{"pgfault", "pgfault"},
+	{"pgmajfault", "pgmajfault"},
+	{"inactive_anon", "inactive_anon"},
+	{"active_anon", "active_anon"},
+	{"inactive_file", "inactive_file"},
+	{"active_file", "active_file"},
+	{"unevictable", "unevictable"},
+}
+
+func (m *UnifiedCgroupsManager) memoryStat() (string, error) {
+	stat, err := m.readKeyed("memory.stat")
+	if err != nil {
+		return "", err
+	}
+
+	lines := []string{}
+	for _, key := range memoryStatKeys {
+		lines = append(lines, fmt.Sprintf("%s %d", key.v1, stat[key.unified]))
+	}
+
+	if swap, err := m.read("memory.swap.current"); err == nil {
+		lines = append(lines, "swap "+swap)
+	}
+
+	if limit, err := m.read("memory.max"); err == nil {
+		lines = append(lines, "hierarchical_memory_limit "+fromMemoryMax(limit))
+	}
+
+	for _, key := range memoryStatKeys {
+		lines = append(lines, fmt.Sprintf("total_%s %d", key.v1, stat[key.unified]))
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func (m *UnifiedCgroupsManager) cpuMax() (quota, period string, err error) {
+	cpuMax, err := m.read("cpu.max")
+	if err != nil {
+		return "", "", err
+	}
+
+	fields := strings.Fields(cpuMax)
+	if len(fields) != 2 {
+		return "", "", fmt.Errorf("invalid cpu.max %q", cpuMax)
+	}
+
+	return fields[0], fields[1], nil
+}
+
+// ioThrottle renders one io.max key as a v1 throttle file's "major:minor
+// value" lines, omitting unthrottled devices.
+func (m *UnifiedCgroupsManager) ioThrottle(key string) (string, error) {
+	contents, err := m.read("io.max")
+	if err != nil {
+		return "", err
+	}
+
+	lines := []string{}
+	for _, device := range parseNestedKeyed(contents) {
+		if limit, found := device.values[key]; found && limit != "max" {
+			lines = append(lines, device.id+" "+limit)
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// ioStat renders two io.stat keys as v1's "major:minor Read value" and
+// "major:minor Write value" lines.
+func (m *UnifiedCgroupsManager) ioStat(readKey, writeKey string) (string, error) {
+	contents, err := m.read("io.stat")
+	if err != nil {
+		return "", err
+	}
+
+	lines := []string{}
+	var total uint64
+	for _, device := range parseNestedKeyed(contents) {
+		read, _ := strconv.ParseUint(device.values[readKey], 10, 64)
+		write, _ := strconv.ParseUint(device.values[writeKey], 10, 64)
+
+		lines = append(lines,
+			fmt.Sprintf("%s Read %d", device.id, read),
+			fmt.Sprintf("%s Write %d", device.id, write),
+			fmt.Sprintf("%s Total %d", device.id, read+write),
+		)
+
+		total += read + write
+	}
+
+	lines = append(lines, fmt.Sprintf("Total %d", total))
+
+	return strings.Join(lines, "\n"), nil
+}
+
+type nestedKeyedEntry struct {
+	id     string
+	values map[string]string
+}
+
+// parseNestedKeyed parses a nested keyed file, e.g. io.stat, of
+// "id key=value ..." lines.
+func parseNestedKeyed(contents string) []nestedKeyedEntry {
+	entries := []nestedKeyedEntry{}
+
+	for _, line := range strings.Split(contents, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		entry := nestedKeyedEntry{id: fields[0], values: map[string]string{}}
+		for _, field := range fields[1:] {
+			keyValue := strings.SplitN(field, "=", 2)
+			if len(keyValue) == 2 {
+				entry.values[keyValue[0]] = keyValue[1]
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func parseKeyed(contents string) map[string]uint64 {
+	values := map[string]uint64{}
+
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		values[fields[0]] = value
+	}
+
+	return values
+}
+
+func toMax(value string) string {
+	if value == "-1" {
+		return "max"
+	}
+
+	return value
+}
+
+func fromMemoryMax(value string) string {
+	if value == "max" {
+		return unlimitedMemory
+	}
+
+	return value
+}
+
+// scale maps value linearly from one range onto another, rounding to the
+// nearest integer and clamping to the target range.
+func scale(value, fromMin, fromMax, toMin, toMax uint64) uint64 {
+	if value <= fromMin {
+		return toMin
+	}
+
+	if value >= fromMax {
+		return toMax
+	}
+
+	fromRange := fromMax - fromMin
+	return toMin + ((value-fromMin)*(toMax-toMin)+fromRange/2)/fromRange
+}
@@ -0,0 +1,253 @@
+package cgroups_manager_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager"
+)
+
+var _ = Describe("Unified cgroups", func() {
+	var (
+		cgroupsPath    string
+		instancePath   string
+		cgroupsManager *cgroups_manager.UnifiedCgroupsManager
+	)
+
+	writeFile := func(name, contents string) {
+		Expect(ioutil.WriteFile(path.Join(instancePath, name), []byte(contents), 0644)).To(Succeed())
+	}
+
+	readFile := func(name string) string {
+		contents, err := ioutil.ReadFile(path.Join(instancePath, name))
+		Expect(err).ToNot(HaveOccurred())
+		return string(contents)
+	}
+
+	BeforeEach(func() {
+		var err error
+		cgroupsPath, err = ioutil.TempDir("", "some-unified-cgroups")
+		Expect(err).ToNot(HaveOccurred())
+
+		instancePath = path.Join(cgroupsPath, "instance-some-container-id")
+		Expect(os.MkdirAll(instancePath, 0755)).To(Succeed())
+
+		cgroupsManager = cgroups_manager.NewUnified(cgroupsPath, "some-container-id")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(cgroupsPath)
+	})
+
+	Describe("detecting the unified hierarchy", func() {
+		It("is unified when the hierarchy has cgroup.controllers", func() {
+			Expect(cgroups_manager.IsUnified(cgroupsPath)).To(BeFalse())
+
+			Expect(ioutil.WriteFile(path.Join(cgroupsPath, "cgroup.controllers"), []byte("cpu io memory pids"), 0644)).To(Succeed())
+			Expect(cgroups_manager.IsUnified(cgroupsPath)).To(BeTrue())
+		})
+	})
+
+	Describe("setup cgroups", func() {
+		It("enables the controllers for the subsystems and creates the container's cgroup", func() {
+			Expect(os.RemoveAll(instancePath)).To(Succeed())
+
+			Expect(cgroupsManager.Setup("cpu", "cpuacct", "memory", "blkio", "devices")).To(Succeed())
+
+			Expect(instancePath).To(BeADirectory())
+			Expect(ioutil.ReadFile(path.Join(cgroupsPath, "cgroup.subtree_control"))).To(Equal([]byte("+cpu +memory +io")))
+		})
+	})
+
+	Describe("adding a process", func() {
+		It("writes the process to cgroup.procs", func() {
+			writeFile("cgroup.procs", "")
+
+			Expect(cgroupsManager.Add(1235, "memory", "cpu")).To(Succeed())
+			Expect(readFile("cgroup.procs")).To(Equal("1235\n"))
+		})
+	})
+
+	Describe("retrieving a subsystem path", func() {
+		It("returns the container's cgroup for every subsystem", func() {
+			Expect(cgroupsManager.SubsystemPath("memory")).To(Equal(instancePath))
+			Expect(cgroupsManager.SubsystemPath("cpu")).To(Equal(instancePath))
+		})
+	})
+
+	Describe("memory", func() {
+		It("translates the memory limit to memory.max", func() {
+			Expect(cgroupsManager.Set("memory", "memory.limit_in_bytes", "102400")).To(Succeed())
+			Expect(readFile("memory.max")).To(Equal("102400"))
+
+			Expect(cgroupsManager.Get("memory", "memory.limit_in_bytes")).To(Equal("102400"))
+		})
+
+		It("reports an unlimited memory.max as v1 does", func() {
+			writeFile("memory.max", "max\n")
+
+			Expect(cgroupsManager.Get("memory", "memory.limit_in_bytes")).To(Equal("9223372036854771712"))
+		})
+
+		It("translates the memory and swap limit to a swap limit relative to memory.max", func() {
+			writeFile("memory.max", "102400\n")
+
+			Expect(cgroupsManager.Set("memory", "memory.memsw.limit_in_bytes", "153600")).To(Succeed())
+			Expect(readFile("memory.swap.max")).To(Equal("51200"))
+
+			Expect(cgroupsManager.Get("memory", "memory.memsw.limit_in_bytes")).To(Equal("153600"))
+		})
+
+		Context("when the memory and swap limit is below memory.max", func() {
+			It("returns an error", func() {
+				writeFile("memory.max", "102400\n")
+
+				Expect(cgroupsManager.Set("memory", "memory.memsw.limit_in_bytes", "1024")).ToNot(Succeed())
+			})
+		})
+
+		It("translates memory.stat to the v1 fields", func() {
+			writeFile("memory.stat", "anon 1\nfile 2\nfile_mapped 3\npgfault 4\npgmajfault 5\ninactive_anon 6\nactive_anon 7\ninactive_file 8\nactive_file 9\nunevictable 10\n")
+			writeFile("memory.swap.current", "11\n")
+			writeFile("memory.max", "12\n")
+
+			stat, err := cgroupsManager.Get("memory", "memory.stat")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(stat).To(ContainSubstring("rss 1\n"))
+			Expect(stat).To(ContainSubstring("cache 2\n"))
+			Expect(stat).To(ContainSubstring("mapped_file 3\n"))
+			Expect(stat).To(ContainSubstring("unevictable 10\n"))
+			Expect(stat).To(ContainSubstring("swap 11\n"))
+			Expect(stat).To(ContainSubstring("hierarchical_memory_limit 12\n"))
+			Expect(stat).To(ContainSubstring("total_rss 1\n"))
+			Expect(stat).To(ContainSubstring("total_inactive_file 8\n"))
+		})
+
+		It("translates the soft limit to memory.low", func() {
+			Expect(cgroupsManager.Set("memory", "memory.soft_limit_in_bytes", "81920")).To(Succeed())
+			Expect(readFile("memory.low")).To(Equal("81920"))
+			Expect(cgroupsManager.Get("memory", "memory.soft_limit_in_bytes")).To(Equal("81920"))
+
+			Expect(cgroupsManager.Set("memory", "memory.soft_limit_in_bytes", "-1")).To(Succeed())
+			Expect(readFile("memory.low")).To(Equal("0"))
+			Expect(cgroupsManager.Get("memory", "memory.soft_limit_in_bytes")).To(Equal("9223372036854771712"))
+		})
+
+		It("does not support a separate kernel memory limit", func() {
+			Expect(cgroupsManager.Set("memory", "memory.kmem.limit_in_bytes", "-1")).To(Succeed())
+			Expect(cgroupsManager.Set("memory", "memory.kmem.limit_in_bytes", "20480")).To(MatchError(ContainSubstring("not supported")))
+
+			Expect(cgroupsManager.Get("memory", "memory.kmem.limit_in_bytes")).To(Equal("9223372036854771712"))
+		})
+
+		It("does not support disabling the OOM killer", func() {
+			err := cgroupsManager.Set("memory", "memory.oom_control", "1")
+			Expect(err).To(MatchError(cgroups_manager.UnsupportedError{Name: "memory.oom_control"}))
+		})
+	})
+
+	Describe("cpu", func() {
+		It("scales cpu.shares to cpu.weight", func() {
+			Expect(cgroupsManager.Set("cpu", "cpu.shares", "262144")).To(Succeed())
+			Expect(readFile("cpu.weight")).To(Equal("10000"))
+
+			Expect(cgroupsManager.Set("cpu", "cpu.shares", "2")).To(Succeed())
+			Expect(readFile("cpu.weight")).To(Equal("1"))
+
+			Expect(cgroupsManager.Get("cpu", "cpu.shares")).To(Equal("2"))
+		})
+
+		It("translates the CFS quota and period to cpu.max", func() {
+			writeFile("cpu.max", "max 100000\n")
+
+			Expect(cgroupsManager.Set("cpu", "cpu.cfs_period_us", "50000")).To(Succeed())
+			Expect(readFile("cpu.max")).To(Equal("max 50000"))
+
+			Expect(cgroupsManager.Set("cpu", "cpu.cfs_quota_us", "25000")).To(Succeed())
+			Expect(readFile("cpu.max")).To(Equal("25000 50000"))
+
+			Expect(cgroupsManager.Get("cpu", "cpu.cfs_quota_us")).To(Equal("25000"))
+			Expect(cgroupsManager.Get("cpu", "cpu.cfs_period_us")).To(Equal("50000"))
+
+			Expect(cgroupsManager.Set("cpu", "cpu.cfs_quota_us", "-1")).To(Succeed())
+			Expect(cgroupsManager.Get("cpu", "cpu.cfs_quota_us")).To(Equal("-1"))
+		})
+
+		It("translates cpu.stat to cpuacct usage and stat", func() {
+			writeFile("cpu.stat", "usage_usec 123456\nuser_usec 100000\nsystem_usec 20000\n")
+
+			Expect(cgroupsManager.Get("cpuacct", "cpuacct.usage")).To(Equal("123456000"))
+			Expect(cgroupsManager.Get("cpuacct", "cpuacct.stat")).To(Equal("user 10\nsystem 2"))
+		})
+	})
+
+	Describe("block I/O", func() {
+		It("scales blkio.weight to the default io.weight", func() {
+			Expect(cgroupsManager.Set("blkio", "blkio.weight", "500")).To(Succeed())
+			Expect(readFile("io.weight")).To(Equal("default 4950"))
+
+			writeFile("io.weight", "default 4950\n")
+			Expect(cgroupsManager.Get("blkio", "blkio.weight")).To(Equal("500"))
+		})
+
+		It("translates throttles to io.max", func() {
+			Expect(cgroupsManager.Set("blkio", "blkio.throttle.read_bps_device", "8:0 1048576")).To(Succeed())
+			Expect(readFile("io.max")).To(Equal("8:0 rbps=1048576"))
+
+			Expect(cgroupsManager.Set("blkio", "blkio.throttle.write_iops_device", "8:0 0")).To(Succeed())
+			Expect(readFile("io.max")).To(Equal("8:0 wiops=max"))
+		})
+
+		It("reads throttles back from io.max, omitting unthrottled devices", func() {
+			writeFile("io.max", "8:0 rbps=1048576 wbps=max riops=max wiops=max\n8:16 rbps=max wbps=2048 riops=max wiops=max\n")
+
+			Expect(cgroupsManager.Get("blkio", "blkio.throttle.read_bps_device")).To(Equal("8:0 1048576"))
+			Expect(cgroupsManager.Get("blkio", "blkio.throttle.write_bps_device")).To(Equal("8:16 2048"))
+			Expect(cgroupsManager.Get("blkio", "blkio.throttle.read_iops_device")).To(Equal(""))
+		})
+
+		It("translates io.stat to the v1 service bytes and serviced formats", func() {
+			writeFile("io.stat", "8:0 rbytes=100 wbytes=200 rios=1 wios=2 dbytes=0 dios=0\n8:16 rbytes=10 wbytes=20 rios=3 wios=4 dbytes=0 dios=0\n")
+
+			Expect(cgroupsManager.Get("blkio", "blkio.throttle.io_service_bytes")).To(Equal(
+				"8:0 Read 100\n8:0 Write 200\n8:0 Total 300\n8:16 Read 10\n8:16 Write 20\n8:16 Total 30\nTotal 330",
+			))
+
+			Expect(cgroupsManager.Get("blkio", "blkio.throttle.io_serviced")).To(Equal(
+				"8:0 Read 1\n8:0 Write 2\n8:0 Total 3\n8:16 Read 3\n8:16 Write 4\n8:16 Total 7\nTotal 10",
+			))
+		})
+	})
+
+	Describe("freezer", func() {
+		It("translates freezer.state to cgroup.freeze", func() {
+			Expect(cgroupsManager.Set("freezer", "freezer.state", "FROZEN")).To(Succeed())
+			Expect(readFile("cgroup.freeze")).To(Equal("1"))
+
+			Expect(cgroupsManager.Set("freezer", "freezer.state", "THAWED")).To(Succeed())
+			Expect(readFile("cgroup.freeze")).To(Equal("0"))
+		})
+
+		It("reads the state from cgroup.events", func() {
+			writeFile("cgroup.events", "populated 1\nfrozen 1\n")
+			Expect(cgroupsManager.Get("freezer", "freezer.state")).To(Equal("FROZEN"))
+
+			writeFile("cgroup.events", "populated 1\nfrozen 0\n")
+			Expect(cgroupsManager.Get("freezer", "freezer.state")).To(Equal("THAWED"))
+		})
+	})
+
+	Describe("files common to both hierarchies", func() {
+		It("reads and writes them unchanged", func() {
+			Expect(cgroupsManager.Set("pids", "pids.max", "100")).To(Succeed())
+			Expect(readFile("pids.max")).To(Equal("100"))
+
+			Expect(cgroupsManager.Get("pids", "pids.max")).To(Equal("100"))
+		})
+	})
+})
@@ -0,0 +1,83 @@
+package linux_container
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProcConntrackCounter counts the connections tracked in the nf_conntrack
+// table at Path which have the IP as a source or destination in either
+// direction, i.e. those made by or to a container.
+//
+// The table can hold hundreds of thousands of entries, so it is read at most
+// once every MaxAge, counting the connections of every IP in it, and the
+// counts are shared by all of the containers; one counter serves a whole
+// metrics sampling pass.
+type ProcConntrackCounter struct {
+	Path   string
+	MaxAge time.Duration
+
+	mutex  sync.Mutex
+	readAt time.Time
+	counts map[string]uint64
+}
+
+func (c *ProcConntrackCounter) Count(ip net.IP) (uint64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.counts == nil || time.Since(c.readAt) >= c.MaxAge {
+		counts, err := c.read()
+		if err != nil {
+			return 0, err
+		}
+
+		c.counts = counts
+		c.readAt = time.Now()
+	}
+
+	return c.counts[ip.String()], nil
+}
+
+func (c *ProcConntrackCounter) read() (map[string]uint64, error) {
+	counts := map[string]uint64{}
+
+	table, err := os.Open(c.Path)
+	if os.IsNotExist(err) {
+		// connection tracking is not loaded
+		return counts, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("linux_container: count conntrack entries: %s", err)
+	}
+
+	defer table.Close()
+
+	scanner := bufio.NewScanner(table)
+	for scanner.Scan() {
+		// an entry names each of its IPs up to four times, but is one
+		// connection of each
+		ips := map[string]bool{}
+		for _, field := range strings.Fields(scanner.Text()) {
+			if strings.HasPrefix(field, "src=") || strings.HasPrefix(field, "dst=") {
+				ips[field[len("src="):]] = true
+			}
+		}
+
+		for ip := range ips {
+			counts[ip]++
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("linux_container: count conntrack entries: %s", err)
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,82 @@
+package linux_container_test
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ProcConntrackCounter", func() {
+	var counter *linux_container.ProcConntrackCounter
+
+	BeforeEach(func() {
+		counter = &linux_container.ProcConntrackCounter{Path: filepath.Join("test_assets", "nf_conntrack")}
+	})
+
+	It("counts the connections made by or to the IP, in either direction", func() {
+		Expect(counter.Count(net.ParseIP("10.254.0.2"))).To(Equal(uint64(4)))
+		Expect(counter.Count(net.ParseIP("10.254.0.6"))).To(Equal(uint64(1)))
+	})
+
+	It("does not count connections of IPs which the IP is a prefix of", func() {
+		Expect(counter.Count(net.ParseIP("10.254.0.22"))).To(Equal(uint64(1)))
+	})
+
+	It("counts no connections for an IP without any", func() {
+		Expect(counter.Count(net.ParseIP("10.254.0.99"))).To(BeZero())
+	})
+
+	Context("when the counts are younger than MaxAge", func() {
+		var tablePath string
+
+		BeforeEach(func() {
+			contents, err := ioutil.ReadFile(counter.Path)
+			Expect(err).ToNot(HaveOccurred())
+
+			table, err := ioutil.TempFile("", "nf_conntrack")
+			Expect(err).ToNot(HaveOccurred())
+			defer table.Close()
+
+			_, err = table.Write(contents)
+			Expect(err).ToNot(HaveOccurred())
+
+			tablePath = table.Name()
+			counter = &linux_container.ProcConntrackCounter{Path: tablePath, MaxAge: time.Hour}
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(tablePath)
+		})
+
+		It("counts every IP from a single read of the table", func() {
+			Expect(counter.Count(net.ParseIP("10.254.0.2"))).To(Equal(uint64(4)))
+
+			Expect(ioutil.WriteFile(tablePath, nil, 0644)).To(Succeed())
+
+			Expect(counter.Count(net.ParseIP("10.254.0.2"))).To(Equal(uint64(4)))
+			Expect(counter.Count(net.ParseIP("10.254.0.6"))).To(Equal(uint64(1)))
+		})
+
+		It("reads the table again once they are older", func() {
+			Expect(counter.Count(net.ParseIP("10.254.0.2"))).To(Equal(uint64(4)))
+
+			Expect(ioutil.WriteFile(tablePath, nil, 0644)).To(Succeed())
+			counter.MaxAge = 0
+
+			Expect(counter.Count(net.ParseIP("10.254.0.2"))).To(BeZero())
+		})
+	})
+
+	Context("when connection tracking is not loaded", func() {
+		It("counts no connections", func() {
+			counter.Path = filepath.Join("test_assets", "does-not-exist")
+			Expect(counter.Count(net.ParseIP("10.254.0.2"))).To(BeZero())
+		})
+	})
+})
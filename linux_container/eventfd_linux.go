@@ -0,0 +1,19 @@
+package linux_container
+
+import (
+	"os"
+	"syscall"
+)
+
+// newEventfd returns a non-blocking eventfd, so that reading it parks the
+// goroutine in the runtime's poller rather than holding an OS thread, along
+// with its descriptor for registering it. Calling Fd on the file would put it
+// back in blocking mode.
+func newEventfd() (*os.File, uintptr, error) {
+	fd, _, errno := syscall.RawSyscall(syscall.SYS_EVENTFD2, 0, syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if errno != 0 {
+		return nil, 0, os.NewSyscallError("eventfd2", errno)
+	}
+
+	return os.NewFile(fd, "eventfd"), fd, nil
+}
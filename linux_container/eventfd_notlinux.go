@@ -0,0 +1,12 @@
+// +build !linux
+
+package linux_container
+
+import (
+	"errors"
+	"os"
+)
+
+func newEventfd() (*os.File, uintptr, error) {
+	return nil, 0, errors.New("eventfd: not supported on this OS")
+}
@@ -0,0 +1,56 @@
+// This file was generated by counterfeiter
+package fake_conntrack_counter
+
+import (
+	"net"
+	"sync"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
+)
+
+type FakeConntrackCounter struct {
+	CountStub        func(ip net.IP) (uint64, error)
+	countMutex       sync.RWMutex
+	countArgsForCall []struct {
+		ip net.IP
+	}
+	countReturns struct {
+		result1 uint64
+		result2 error
+	}
+}
+
+func (fake *FakeConntrackCounter) Count(ip net.IP) (uint64, error) {
+	fake.countMutex.Lock()
+	fake.countArgsForCall = append(fake.countArgsForCall, struct {
+		ip net.IP
+	}{ip})
+	fake.countMutex.Unlock()
+	if fake.CountStub != nil {
+		return fake.CountStub(ip)
+	} else {
+		return fake.countReturns.result1, fake.countReturns.result2
+	}
+}
+
+func (fake *FakeConntrackCounter) CountCallCount() int {
+	fake.countMutex.RLock()
+	defer fake.countMutex.RUnlock()
+	return len(fake.countArgsForCall)
+}
+
+func (fake *FakeConntrackCounter) CountArgsForCall(i int) net.IP {
+	fake.countMutex.RLock()
+	defer fake.countMutex.RUnlock()
+	return fake.countArgsForCall[i].ip
+}
+
+func (fake *FakeConntrackCounter) CountReturns(result1 uint64, result2 error) {
+	fake.CountStub = nil
+	fake.countReturns = struct {
+		result1 uint64
+		result2 error
+	}{result1, result2}
+}
+
+var _ linux_container.ConntrackCounter = new(FakeConntrackCounter)
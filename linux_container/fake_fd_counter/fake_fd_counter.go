@@ -0,0 +1,55 @@
+// This file was generated by counterfeiter
+package fake_fd_counter
+
+import (
+	"sync"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
+)
+
+type FakeFileDescriptorCounter struct {
+	CountStub        func(pids []int) (uint64, error)
+	countMutex       sync.RWMutex
+	countArgsForCall []struct {
+		pids []int
+	}
+	countReturns struct {
+		result1 uint64
+		result2 error
+	}
+}
+
+func (fake *FakeFileDescriptorCounter) Count(pids []int) (uint64, error) {
+	fake.countMutex.Lock()
+	fake.countArgsForCall = append(fake.countArgsForCall, struct {
+		pids []int
+	}{pids})
+	fake.countMutex.Unlock()
+	if fake.CountStub != nil {
+		return fake.CountStub(pids)
+	} else {
+		return fake.countReturns.result1, fake.countReturns.result2
+	}
+}
+
+func (fake *FakeFileDescriptorCounter) CountCallCount() int {
+	fake.countMutex.RLock()
+	defer fake.countMutex.RUnlock()
+	return len(fake.countArgsForCall)
+}
+
+func (fake *FakeFileDescriptorCounter) CountArgsForCall(i int) []int {
+	fake.countMutex.RLock()
+	defer fake.countMutex.RUnlock()
+	return fake.countArgsForCall[i].pids
+}
+
+func (fake *FakeFileDescriptorCounter) CountReturns(result1 uint64, result2 error) {
+	fake.CountStub = nil
+	fake.countReturns = struct {
+		result1 uint64
+		result2 error
+	}{result1, result2}
+}
+
+var _ linux_container.FileDescriptorCounter = new(FakeFileDescriptorCounter)
@@ -4,21 +4,21 @@ package fake_network_statisticser
 import (
 	"sync"
 
-	"github.com/cloudfoundry-incubator/garden"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
+	"github.com/cloudfoundry-incubator/garden-linux/network/devices"
 )
 
 type FakeNetworkStatisticser struct {
-	StatisticsStub        func() (stats garden.ContainerNetworkStat, err error)
+	StatisticsStub        func() (stats devices.LinkStatistics, err error)
 	statisticsMutex       sync.RWMutex
 	statisticsArgsForCall []struct{}
 	statisticsReturns     struct {
-		result1 garden.ContainerNetworkStat
+		result1 devices.LinkStatistics
 		result2 error
 	}
 }
 
-func (fake *FakeNetworkStatisticser) Statistics() (stats garden.ContainerNetworkStat, err error) {
+func (fake *FakeNetworkStatisticser) Statistics() (stats devices.LinkStatistics, err error) {
 	fake.statisticsMutex.Lock()
 	fake.statisticsArgsForCall = append(fake.statisticsArgsForCall, struct{}{})
 	fake.statisticsMutex.Unlock()
@@ -35,10 +35,10 @@ func (fake *FakeNetworkStatisticser) StatisticsCallCount() int {
 	return len(fake.statisticsArgsForCall)
 }
 
-func (fake *FakeNetworkStatisticser) StatisticsReturns(result1 garden.ContainerNetworkStat, result2 error) {
+func (fake *FakeNetworkStatisticser) StatisticsReturns(result1 devices.LinkStatistics, result2 error) {
 	fake.StatisticsStub = nil
 	fake.statisticsReturns = struct {
-		result1 garden.ContainerNetworkStat
+		result1 devices.LinkStatistics
 		result2 error
 	}{result1, result2}
 }
@@ -0,0 +1,55 @@
+// This file was generated by counterfeiter
+package fake_oom_killer
+
+import (
+	"sync"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
+)
+
+type FakeOOMKiller struct {
+	KillStub        func(pids []int) (linux_container.OOMVictim, error)
+	killMutex       sync.RWMutex
+	killArgsForCall []struct {
+		pids []int
+	}
+	killReturns struct {
+		result1 linux_container.OOMVictim
+		result2 error
+	}
+}
+
+func (fake *FakeOOMKiller) Kill(pids []int) (linux_container.OOMVictim, error) {
+	fake.killMutex.Lock()
+	fake.killArgsForCall = append(fake.killArgsForCall, struct {
+		pids []int
+	}{pids})
+	fake.killMutex.Unlock()
+	if fake.KillStub != nil {
+		return fake.KillStub(pids)
+	} else {
+		return fake.killReturns.result1, fake.killReturns.result2
+	}
+}
+
+func (fake *FakeOOMKiller) KillCallCount() int {
+	fake.killMutex.RLock()
+	defer fake.killMutex.RUnlock()
+	return len(fake.killArgsForCall)
+}
+
+func (fake *FakeOOMKiller) KillArgsForCall(i int) []int {
+	fake.killMutex.RLock()
+	defer fake.killMutex.RUnlock()
+	return fake.killArgsForCall[i].pids
+}
+
+func (fake *FakeOOMKiller) KillReturns(result1 linux_container.OOMVictim, result2 error) {
+	fake.KillStub = nil
+	fake.killReturns = struct {
+		result1 linux_container.OOMVictim
+		result2 error
+	}{result1, result2}
+}
+
+var _ linux_container.OOMKiller = new(FakeOOMKiller)
@@ -0,0 +1,111 @@
+// This file was generated by counterfeiter
+package fake_watcher
+
+import (
+	"sync"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
+)
+
+type FakeMemoryWatcher struct {
+	WatchStub        func(func()) error
+	watchMutex       sync.RWMutex
+	watchArgsForCall []struct {
+		arg1 func()
+	}
+	watchReturns struct {
+		result1 error
+	}
+	UnwatchStub              func()
+	unwatchMutex             sync.RWMutex
+	unwatchArgsForCall       []struct{}
+	WatchPressureStub        func(func(level string)) error
+	watchPressureMutex       sync.RWMutex
+	watchPressureArgsForCall []struct {
+		arg1 func(level string)
+	}
+	watchPressureReturns struct {
+		result1 error
+	}
+}
+
+func (fake *FakeMemoryWatcher) Watch(arg1 func()) error {
+	fake.watchMutex.Lock()
+	fake.watchArgsForCall = append(fake.watchArgsForCall, struct {
+		arg1 func()
+	}{arg1})
+	fake.watchMutex.Unlock()
+	if fake.WatchStub != nil {
+		return fake.WatchStub(arg1)
+	} else {
+		return fake.watchReturns.result1
+	}
+}
+
+func (fake *FakeMemoryWatcher) WatchCallCount() int {
+	fake.watchMutex.RLock()
+	defer fake.watchMutex.RUnlock()
+	return len(fake.watchArgsForCall)
+}
+
+func (fake *FakeMemoryWatcher) WatchArgsForCall(i int) func() {
+	fake.watchMutex.RLock()
+	defer fake.watchMutex.RUnlock()
+	return fake.watchArgsForCall[i].arg1
+}
+
+func (fake *FakeMemoryWatcher) WatchReturns(result1 error) {
+	fake.WatchStub = nil
+	fake.watchReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeMemoryWatcher) Unwatch() {
+	fake.unwatchMutex.Lock()
+	fake.unwatchArgsForCall = append(fake.unwatchArgsForCall, struct{}{})
+	fake.unwatchMutex.Unlock()
+	if fake.UnwatchStub != nil {
+		fake.UnwatchStub()
+	}
+}
+
+func (fake *FakeMemoryWatcher) UnwatchCallCount() int {
+	fake.unwatchMutex.RLock()
+	defer fake.unwatchMutex.RUnlock()
+	return len(fake.unwatchArgsForCall)
+}
+
+func (fake *FakeMemoryWatcher) WatchPressure(arg1 func(level string)) error {
+	fake.watchPressureMutex.Lock()
+	fake.watchPressureArgsForCall = append(fake.watchPressureArgsForCall, struct {
+		arg1 func(level string)
+	}{arg1})
+	fake.watchPressureMutex.Unlock()
+	if fake.WatchPressureStub != nil {
+		return fake.WatchPressureStub(arg1)
+	} else {
+		return fake.watchPressureReturns.result1
+	}
+}
+
+func (fake *FakeMemoryWatcher) WatchPressureCallCount() int {
+	fake.watchPressureMutex.RLock()
+	defer fake.watchPressureMutex.RUnlock()
+	return len(fake.watchPressureArgsForCall)
+}
+
+func (fake *FakeMemoryWatcher) WatchPressureArgsForCall(i int) func(level string) {
+	fake.watchPressureMutex.RLock()
+	defer fake.watchPressureMutex.RUnlock()
+	return fake.watchPressureArgsForCall[i].arg1
+}
+
+func (fake *FakeMemoryWatcher) WatchPressureReturns(result1 error) {
+	fake.WatchPressureStub = nil
+	fake.watchPressureReturns = struct {
+		result1 error
+	}{result1}
+}
+
+var _ linux_container.MemoryWatcher = new(FakeMemoryWatcher)
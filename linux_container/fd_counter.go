@@ -0,0 +1,34 @@
+package linux_container
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+)
+
+// ProcFileDescriptorCounter counts the entries in each process's fd directory
+// under ProcPath.
+type ProcFileDescriptorCounter struct {
+	ProcPath string
+}
+
+func (c *ProcFileDescriptorCounter) Count(pids []int) (uint64, error) {
+	var count uint64
+	for _, pid := range pids {
+		fds, err := ioutil.ReadDir(path.Join(c.ProcPath, strconv.Itoa(pid), "fd"))
+		if os.IsNotExist(err) {
+			// the process has already exited
+			continue
+		}
+
+		if err != nil {
+			return 0, fmt.Errorf("linux_container: count fds: %s", err)
+		}
+
+		count += uint64(len(fds))
+	}
+
+	return count, nil
+}
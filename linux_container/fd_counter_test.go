@@ -0,0 +1,62 @@
+package linux_container_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ProcFileDescriptorCounter", func() {
+	var (
+		procPath string
+		counter  *linux_container.ProcFileDescriptorCounter
+	)
+
+	writeFDs := func(pid, fds int) {
+		fdPath := path.Join(procPath, strconv.Itoa(pid), "fd")
+		Expect(os.MkdirAll(fdPath, 0755)).To(Succeed())
+
+		for fd := 0; fd < fds; fd++ {
+			Expect(os.Symlink("/dev/null", path.Join(fdPath, strconv.Itoa(fd)))).To(Succeed())
+		}
+	}
+
+	BeforeEach(func() {
+		var err error
+		procPath, err = ioutil.TempDir("", "fake-proc")
+		Expect(err).ToNot(HaveOccurred())
+
+		counter = &linux_container.ProcFileDescriptorCounter{ProcPath: procPath}
+
+		writeFDs(1235, 3)
+		writeFDs(1302, 5)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(procPath)
+	})
+
+	It("totals the open file descriptors of the processes", func() {
+		Expect(counter.Count([]int{1235, 1302})).To(Equal(uint64(8)))
+	})
+
+	Context("when a process has exited", func() {
+		It("skips it", func() {
+			Expect(counter.Count([]int{1235, 9999})).To(Equal(uint64(3)))
+		})
+	})
+
+	Context("when a process's file descriptors cannot be read", func() {
+		It("returns an error", func() {
+			Expect(ioutil.WriteFile(path.Join(procPath, "1400"), []byte{}, 0644)).To(Succeed())
+
+			_, err := counter.Count([]int{1400})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -10,6 +10,7 @@ import (
 	"bytes"
 	"io/ioutil"
 
+	"github.com/cloudfoundry-incubator/garden-linux/network/iptables"
 	"github.com/cloudfoundry-incubator/garden-linux/sysconfig"
 	"github.com/cloudfoundry/gunk/command_runner"
 	"github.com/pivotal-golang/lager"
@@ -32,29 +33,23 @@ func NewFilterChain(cfg *sysconfig.IPTablesFilterConfig, runner command_runner.C
 func (mgr *filterChain) Setup(containerID, bridgeName string, ip net.IP, network *net.IPNet) error {
 	instanceChain := mgr.cfg.InstancePrefix + containerID
 
-	commands := []*exec.Cmd{
-		// Create filter instance chain
-		exec.Command("iptables", "--wait", "-N", instanceChain),
-		// Allow intra-subnet traffic (Linux ethernet bridging goes through ip stack)
-		exec.Command("iptables", "--wait", "-A", instanceChain, "-s", network.String(), "-d", network.String(), "-j", "ACCEPT"),
-		// Otherwise, use the default filter chain
-		exec.Command("iptables", "--wait", "-A", instanceChain, "--goto", mgr.cfg.DefaultChain),
-		// Bind filter instance chain to filter forward chain
-		exec.Command("iptables", "--wait", "-I", mgr.cfg.ForwardChain, "2", "--in-interface", bridgeName, "--source", ip.String(), "--goto", instanceChain),
-	}
+	txn := iptables.NewRestore()
+	// Create filter instance chain
+	txn.Add("", "-N", instanceChain)
+	// Allow intra-subnet traffic (Linux ethernet bridging goes through ip stack)
+	txn.Add("", "-A", instanceChain, "-s", network.String(), "-d", network.String(), "-j", "ACCEPT")
+	// Otherwise, use the default filter chain
+	txn.Add("", "-A", instanceChain, "--goto", mgr.cfg.DefaultChain)
+	// Bind filter instance chain to filter forward chain
+	txn.Add("", "-I", mgr.cfg.ForwardChain, "2", "--in-interface", bridgeName, "--source", ip.String(), "--goto", instanceChain)
 
-	for _, cmd := range commands {
-		buffer := &bytes.Buffer{}
-		cmd.Stderr = buffer
-		logger := mgr.logger.Session("setup", lager.Data{"cmd": cmd})
-		logger.Debug("starting")
-		if err := mgr.runner.Run(cmd); err != nil {
-			stderr, _ := ioutil.ReadAll(buffer)
-			logger.Error("failed", err, lager.Data{"stderr": string(stderr)})
-			return fmt.Errorf("iptables_manager: filter: %s", err)
-		}
-		logger.Debug("ended")
+	logger := mgr.logger.Session("setup", lager.Data{"restore": txn.String()})
+	logger.Debug("starting")
+	if err := txn.Commit(mgr.runner); err != nil {
+		logger.Error("failed", err)
+		return fmt.Errorf("iptables_manager: filter: %s", err)
 	}
+	logger.Debug("ended")
 
 	return nil
 }
@@ -3,6 +3,7 @@ package iptables_manager_test
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os/exec"
 
 	"github.com/cloudfoundry/gunk/command_runner/fake_command_runner"
@@ -49,52 +50,50 @@ var _ = Describe("filterChain", func() {
 	})
 
 	Describe("Setup", func() {
-		var specs []fake_command_runner.CommandSpec
+		var restored []string
+		var restoreErr error
+
+		restoreSpec := fake_command_runner.CommandSpec{
+			Path: "/sbin/iptables-restore",
+			Args: []string{"--noflush"},
+		}
 
 		BeforeEach(func() {
-			expectedFilterInstanceChain := testCfg.InstancePrefix + containerID
-			specs = []fake_command_runner.CommandSpec{
-				fake_command_runner.CommandSpec{
-					Path: "iptables",
-					Args: []string{"--wait", "-N", expectedFilterInstanceChain},
-				},
-				fake_command_runner.CommandSpec{
-					Path: "iptables",
-					Args: []string{"--wait", "-A", expectedFilterInstanceChain,
-						"-s", network.String(), "-d", network.String(), "-j", "ACCEPT"},
-				},
-				fake_command_runner.CommandSpec{
-					Path: "iptables",
-					Args: []string{"--wait", "-A", expectedFilterInstanceChain,
-						"--goto", testCfg.DefaultChain},
-				},
-				fake_command_runner.CommandSpec{
-					Path: "iptables",
-					Args: []string{"--wait", "-I", testCfg.ForwardChain, "2", "--in-interface", bridgeName,
-						"--source", ip.String(), "--goto", expectedFilterInstanceChain},
-				},
-			}
+			restored = nil
+			restoreErr = nil
+
+			fakeRunner.WhenRunning(restoreSpec, func(cmd *exec.Cmd) error {
+				input, err := ioutil.ReadAll(cmd.Stdin)
+				Expect(err).ToNot(HaveOccurred())
+				restored = append(restored, string(input))
+
+				return restoreErr
+			})
 		})
 
-		It("should set up the chain", func() {
+		It("should set up the chain with a single iptables-restore", func() {
 			Expect(chain.Setup(containerID, bridgeName, ip, network)).To(Succeed())
 
-			Expect(fakeRunner).To(HaveExecutedSerially(specs...))
+			expectedFilterInstanceChain := testCfg.InstancePrefix + containerID
+			Expect(restored).To(Equal([]string{
+				"*filter\n" +
+					"-N " + expectedFilterInstanceChain + "\n" +
+					"-A " + expectedFilterInstanceChain + " -s 1.2.3.0/28 -d 1.2.3.0/28 -j ACCEPT\n" +
+					"-A " + expectedFilterInstanceChain + " --goto " + testCfg.DefaultChain + "\n" +
+					"-I " + testCfg.ForwardChain + " 2 --in-interface " + bridgeName + " --source 1.2.3.4 --goto " + expectedFilterInstanceChain + "\n" +
+					"COMMIT\n",
+			}))
 		})
 
-		DescribeTable("iptables failures",
-			func(specIndex int, errorString string) {
-				fakeRunner.WhenRunning(specs[specIndex], func(*exec.Cmd) error {
-					return errors.New("iptables failed")
-				})
-				Expect(chain.Setup(containerID, bridgeName, ip, network)).To(MatchError(errorString))
+		Context("when iptables-restore fails", func() {
+			BeforeEach(func() {
+				restoreErr = errors.New("iptables failed")
+			})
 
-			},
-			Entry("create filter instance chain", 0, "iptables_manager: filter: iptables failed"),
-			Entry("allow intra-subnet traffic", 1, "iptables_manager: filter: iptables failed"),
-			Entry("use the default filter chain otherwise", 2, "iptables_manager: filter: iptables failed"),
-			Entry("bind filter instance chain to filter forward chain", 3, "iptables_manager: filter: iptables failed"),
-		)
+			It("returns the error", func() {
+				Expect(chain.Setup(containerID, bridgeName, ip, network)).To(MatchError(ContainSubstring("iptables_manager: filter: iptables-restore: iptables failed")))
+			})
+		})
 	})
 
 	Describe("Teardown", func() {
@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"io/ioutil"
 	"net"
+	"strings"
 
 	"os/exec"
 
 	"fmt"
 
+	"github.com/cloudfoundry-incubator/garden-linux/network/iptables"
 	"github.com/cloudfoundry-incubator/garden-linux/sysconfig"
 	"github.com/cloudfoundry/gunk/command_runner"
 	"github.com/pivotal-golang/lager"
@@ -31,37 +33,63 @@ func NewNATChain(cfg *sysconfig.IPTablesNATConfig, runner command_runner.Command
 func (mgr *natChain) Setup(containerID, bridgeName string, ip net.IP, network *net.IPNet) error {
 	instanceChain := mgr.cfg.InstancePrefix + containerID
 
-	commands := []*exec.Cmd{
-		// Create nat instance chain
-		exec.Command("iptables", "--wait", "--table", "nat", "-N", instanceChain),
-		// Bind nat instance chain to nat prerouting chain
-		exec.Command("iptables", "--wait", "--table", "nat", "-A", mgr.cfg.PreroutingChain, "--jump", instanceChain),
-		// Enable NAT for traffic coming from containers
-		exec.Command("sh", "-c", fmt.Sprintf(
-			`(iptables --wait --table nat -S %s | grep "\-j MASQUERADE\b" | grep -q -F -- "-s %s") || iptables --wait --table nat -A %s --source %s ! --destination %s --jump MASQUERADE`,
-			mgr.cfg.PostroutingChain, network.String(), mgr.cfg.PostroutingChain,
-			network.String(), network.String(),
-		)),
+	logger := mgr.logger.Session("setup")
+	logger.Debug("starting")
+
+	// the postrouting rules are for the container's whole network, which it
+	// may share, so are only added if they are missing
+	postrouting, err := mgr.rules(mgr.cfg.PostroutingChain)
+	if err != nil {
+		logger.Error("failed", err)
+		return fmt.Errorf("iptables_manager: nat: %s", err)
 	}
 
-	for _, cmd := range commands {
-		if err := mgr.runner.Run(cmd); err != nil {
-			buffer := &bytes.Buffer{}
-			cmd.Stderr = buffer
-			logger := mgr.logger.Session("setup", lager.Data{"cmd": cmd})
-			logger.Debug("starting")
-			if err := mgr.runner.Run(cmd); err != nil {
-				stderr, _ := ioutil.ReadAll(buffer)
-				logger.Error("failed", err, lager.Data{"stderr": string(stderr)})
-				return fmt.Errorf("iptables_manager: nat: %s", err)
-			}
-			logger.Debug("ended")
-		}
+	txn := iptables.NewRestore()
+	// Create nat instance chain
+	txn.Add(iptables.Nat, "-N", instanceChain)
+	// Bind nat instance chain to nat prerouting chain
+	txn.Add(iptables.Nat, "-A", mgr.cfg.PreroutingChain, "--jump", instanceChain)
+
+	// Enable NAT for traffic coming from containers
+	if !postrouting[fmt.Sprintf("-A %s -s %s ! -d %s -j MASQUERADE", mgr.cfg.PostroutingChain, network, network)] {
+		txn.Add(iptables.Nat, "-A", mgr.cfg.PostroutingChain, "--source", network.String(), "!", "--destination", network.String(), "--jump", "MASQUERADE")
+	}
+
+	// Enable hairpin NAT, so that the container reaching ports of its subnet
+	// mapped by NetIn gets the replies back through the host. The rule is the
+	// container's own, commented with its instance chain so that Teardown can
+	// find it, as the network may be shared
+	txn.Add(iptables.Nat, "-A", mgr.cfg.PostroutingChain, "--source", ip.String(), "--destination", network.String(), "-m", "conntrack", "--ctstate", "DNAT", "-m", "comment", "--comment", instanceChain, "--jump", "MASQUERADE")
+
+	if err := txn.Commit(mgr.runner); err != nil {
+		logger.Error("failed", err, lager.Data{"restore": txn.String()})
+		return fmt.Errorf("iptables_manager: nat: %s", err)
 	}
 
+	logger.Debug("ended")
 	return nil
 }
 
+// rules lists the rules of the nat table's chain as iptables -S does.
+func (mgr *natChain) rules(chain string) (map[string]bool, error) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.Command("iptables", "--wait", "--table", "nat", "-S", chain)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := mgr.runner.Run(cmd); err != nil {
+		return nil, fmt.Errorf("%s, %s", err, stderr.String())
+	}
+
+	rules := map[string]bool{}
+	for _, rule := range strings.Split(stdout.String(), "\n") {
+		rules[strings.TrimSpace(rule)] = true
+	}
+
+	return rules, nil
+}
+
 func (mgr *natChain) Teardown(containerID string) error {
 	instanceChain := mgr.cfg.InstancePrefix + containerID
 
@@ -71,6 +99,11 @@ func (mgr *natChain) Teardown(containerID string) error {
 			`iptables --wait --table nat -S %s 2> /dev/null | grep "\-j %s\b" | sed -e "s/-A/-D/" | xargs --no-run-if-empty --max-lines=1 iptables --wait --table nat`,
 			mgr.cfg.PreroutingChain, instanceChain,
 		)),
+		// Prune hairpin NAT rule of the container
+		exec.Command("sh", "-c", fmt.Sprintf(
+			`iptables --wait --table nat -S %s 2> /dev/null | grep -F -e "--comment %s -j MASQUERADE" | sed -e "s/-A/-D/" | xargs --no-run-if-empty --max-lines=1 iptables --wait --table nat`,
+			mgr.cfg.PostroutingChain, instanceChain,
+		)),
 		// Flush nat instance chain
 		exec.Command("sh", "-c", fmt.Sprintf(`iptables --wait --table nat -F %s 2> /dev/null || true`, instanceChain)),
 		// Delete nat instance chain
@@ -3,6 +3,7 @@ package iptables_manager_test
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os/exec"
 
 	"github.com/cloudfoundry/gunk/command_runner/fake_command_runner"
@@ -48,48 +49,92 @@ var _ = Describe("natChain", func() {
 	})
 
 	Describe("ContainerSetup", func() {
-		var specs []fake_command_runner.CommandSpec
+		var postrouting string
+		var listErr error
+		var restored []string
+		var restoreErr error
+
+		listSpec := fake_command_runner.CommandSpec{
+			Path: "iptables",
+			Args: []string{"--wait", "--table", "nat", "-S", "nat-postrouting-chain"},
+		}
+
+		restoreSpec := fake_command_runner.CommandSpec{
+			Path: "/sbin/iptables-restore",
+			Args: []string{"--noflush"},
+		}
+
 		BeforeEach(func() {
-			expectedNatInstanceChain := testCfg.InstancePrefix + containerID
-			specs = []fake_command_runner.CommandSpec{
-				fake_command_runner.CommandSpec{
-					Path: "iptables",
-					Args: []string{"--wait", "--table", "nat", "-N", expectedNatInstanceChain},
-				},
-				fake_command_runner.CommandSpec{
-					Path: "iptables",
-					Args: []string{"--wait", "--table", "nat", "-A", testCfg.PreroutingChain,
-						"--jump", expectedNatInstanceChain},
-				},
-				fake_command_runner.CommandSpec{
-					Path: "sh",
-					Args: []string{"-c", fmt.Sprintf(
-						`(iptables --wait --table nat -S %s | grep "\-j MASQUERADE\b" | grep -q -F -- "-s %s") || iptables --wait --table nat -A %s --source %s ! --destination %s --jump MASQUERADE`,
-						testCfg.PostroutingChain, network.String(), testCfg.PostroutingChain,
-						network.String(), network.String(),
-					)},
-				},
-			}
+			postrouting = "-N nat-postrouting-chain\n"
+			listErr = nil
+			restored = nil
+			restoreErr = nil
+
+			fakeRunner.WhenRunning(listSpec, func(cmd *exec.Cmd) error {
+				cmd.Stdout.Write([]byte(postrouting))
+				return listErr
+			})
+
+			fakeRunner.WhenRunning(restoreSpec, func(cmd *exec.Cmd) error {
+				input, err := ioutil.ReadAll(cmd.Stdin)
+				Expect(err).ToNot(HaveOccurred())
+				restored = append(restored, string(input))
+
+				return restoreErr
+			})
 		})
 
-		It("should set up the chain", func() {
+		It("should set up the chain with a single iptables-restore", func() {
 			Expect(chain.Setup(containerID, bridgeName, ip, network)).To(Succeed())
 
-			Expect(fakeRunner).To(HaveExecutedSerially(specs...))
+			expectedNatInstanceChain := testCfg.InstancePrefix + containerID
+			Expect(restored).To(Equal([]string{
+				"*nat\n" +
+					"-N " + expectedNatInstanceChain + "\n" +
+					"-A " + testCfg.PreroutingChain + " --jump " + expectedNatInstanceChain + "\n" +
+					"-A " + testCfg.PostroutingChain + " --source 1.2.3.0/28 ! --destination 1.2.3.0/28 --jump MASQUERADE\n" +
+					"-A " + testCfg.PostroutingChain + " --source 1.2.3.4 --destination 1.2.3.0/28 -m conntrack --ctstate DNAT -m comment --comment " + expectedNatInstanceChain + " --jump MASQUERADE\n" +
+					"COMMIT\n",
+			}))
 		})
 
-		DescribeTable("iptables failures",
-			func(specIndex int, errorString string) {
-				fakeRunner.WhenRunning(specs[specIndex], func(*exec.Cmd) error {
-					return errors.New("iptables failed")
-				})
-
-				Expect(chain.Setup(containerID, bridgeName, ip, network)).To(MatchError(errorString))
-			},
-			Entry("create nat instance chain", 0, "iptables_manager: nat: iptables failed"),
-			Entry("bind nat instance chain to nat prerouting chain", 1, "iptables_manager: nat: iptables failed"),
-			Entry("enable NAT for traffic coming from containers", 2, "iptables_manager: nat: iptables failed"),
-		)
+		Context("when the network's postrouting rule already exists", func() {
+			BeforeEach(func() {
+				postrouting = fmt.Sprintf(
+					"-N %s\n-A %s -s 1.2.3.0/28 ! -d 1.2.3.0/28 -j MASQUERADE\n",
+					testCfg.PostroutingChain, testCfg.PostroutingChain,
+				)
+			})
+
+			It("does not add it again", func() {
+				Expect(chain.Setup(containerID, bridgeName, ip, network)).To(Succeed())
+
+				Expect(restored).To(HaveLen(1))
+				Expect(restored[0]).ToNot(ContainSubstring("! --destination"))
+				Expect(restored[0]).To(ContainSubstring("--ctstate DNAT"))
+			})
+		})
+
+		Context("when listing the postrouting rules fails", func() {
+			BeforeEach(func() {
+				listErr = errors.New("iptables failed")
+			})
+
+			It("returns the error without changing any rules", func() {
+				Expect(chain.Setup(containerID, bridgeName, ip, network)).To(MatchError(ContainSubstring("iptables_manager: nat: iptables failed")))
+				Expect(restored).To(BeEmpty())
+			})
+		})
+
+		Context("when iptables-restore fails", func() {
+			BeforeEach(func() {
+				restoreErr = errors.New("iptables failed")
+			})
+
+			It("returns the error", func() {
+				Expect(chain.Setup(containerID, bridgeName, ip, network)).To(MatchError(ContainSubstring("iptables_manager: nat: iptables-restore: iptables failed")))
+			})
+		})
 	})
 
 	Describe("ContainerTeardown", func() {
@@ -106,6 +151,13 @@ var _ = Describe("natChain", func() {
 							testCfg.PreroutingChain, expectedFilterInstanceChain,
 						)},
 					},
+					fake_command_runner.CommandSpec{
+						Path: "sh",
+						Args: []string{"-c", fmt.Sprintf(
+							`iptables --wait --table nat -S %s 2> /dev/null | grep -F -e "--comment %s -j MASQUERADE" | sed -e "s/-A/-D/" | xargs --no-run-if-empty --max-lines=1 iptables --wait --table nat`,
+							testCfg.PostroutingChain, expectedFilterInstanceChain,
+						)},
+					},
 					fake_command_runner.CommandSpec{
 						Path: "sh",
 						Args: []string{"-c", fmt.Sprintf(
@@ -138,8 +190,9 @@ var _ = Describe("natChain", func() {
 					Expect(chain.Teardown(containerID)).To(MatchError(errorString))
 				},
 				Entry("prune prerouting chain", 0, "iptables_manager: nat: iptables failed"),
-				Entry("flush instance chain", 1, "iptables_manager: nat: iptables failed"),
-				Entry("delete instance chain", 2, "iptables_manager: nat: iptables failed"),
+				Entry("prune hairpin NAT rule", 1, "iptables_manager: nat: iptables failed"),
+				Entry("flush instance chain", 2, "iptables_manager: nat: iptables failed"),
+				Entry("delete instance chain", 3, "iptables_manager: nat: iptables failed"),
 			)
 		})
 	})
@@ -0,0 +1,90 @@
+package iptables_manager
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os/exec"
+
+	"github.com/cloudfoundry-incubator/garden-linux/network/iptables"
+	"github.com/cloudfoundry-incubator/garden-linux/sysconfig"
+	"github.com/cloudfoundry/gunk/command_runner"
+	"github.com/pivotal-golang/lager"
+)
+
+type nftFilterChain struct {
+	nft    string
+	table  string
+	cfg    *sysconfig.IPTablesFilterConfig
+	runner command_runner.CommandRunner
+	logger lager.Logger
+}
+
+// NewNFTablesFilterChain is like NewFilterChain, but programs the chains of
+// cfg in the given nftables table, which is created by net.sh, with the nft
+// binary at nft.
+func NewNFTablesFilterChain(nft, table string, cfg *sysconfig.IPTablesFilterConfig, runner command_runner.CommandRunner, logger lager.Logger) *nftFilterChain {
+	return &nftFilterChain{
+		nft:    nft,
+		table:  table,
+		cfg:    cfg,
+		runner: runner,
+		logger: logger,
+	}
+}
+
+func (mgr *nftFilterChain) Setup(containerID, bridgeName string, ip net.IP, network *net.IPNet) error {
+	instanceChain := mgr.cfg.InstancePrefix + containerID
+
+	txn := iptables.NewNFTBatch(mgr.nft)
+	// Create filter instance chain
+	txn.Add("add chain ip %s %s", mgr.table, instanceChain)
+	// Allow intra-subnet traffic (Linux ethernet bridging goes through ip stack)
+	txn.Add("add rule ip %s %s ip saddr %s ip daddr %s accept", mgr.table, instanceChain, network, network)
+	// Otherwise, use the default filter chain
+	txn.Add("add rule ip %s %s goto %s", mgr.table, instanceChain, mgr.cfg.DefaultChain)
+	// Bind filter instance chain to filter forward chain, ahead of its final drop
+	txn.Add("insert rule ip %s %s index 1 iifname %q ip saddr %s goto %s", mgr.table, mgr.cfg.ForwardChain, bridgeName, ip, instanceChain)
+
+	logger := mgr.logger.Session("setup", lager.Data{"batch": txn.String()})
+	logger.Debug("starting")
+	if err := txn.Commit(mgr.runner); err != nil {
+		logger.Error("failed", err)
+		return fmt.Errorf("iptables_manager: nftables filter: %s", err)
+	}
+	logger.Debug("ended")
+
+	return nil
+}
+
+func (mgr *nftFilterChain) Teardown(containerID string) error {
+	instanceChain := mgr.cfg.InstancePrefix + containerID
+
+	commands := []*exec.Cmd{
+		// Prune forward chain
+		exec.Command("sh", "-c", fmt.Sprintf(
+			`%s --handle list chain ip %s %s 2> /dev/null | grep "goto %s # handle" | sed -e "s/.* # handle //" | xargs --no-run-if-empty --max-lines=1 %s delete rule ip %s %s handle`,
+			mgr.nft, mgr.table, mgr.cfg.ForwardChain, instanceChain, mgr.nft, mgr.table, mgr.cfg.ForwardChain,
+		)),
+		// Flush instance chain
+		exec.Command("sh", "-c", fmt.Sprintf("%s flush chain ip %s %s 2> /dev/null || true", mgr.nft, mgr.table, instanceChain)),
+		// Delete instance chain
+		exec.Command("sh", "-c", fmt.Sprintf("%s delete chain ip %s %s 2> /dev/null || true", mgr.nft, mgr.table, instanceChain)),
+	}
+
+	for _, cmd := range commands {
+		buffer := &bytes.Buffer{}
+		cmd.Stderr = buffer
+		logger := mgr.logger.Session("teardown", lager.Data{"cmd": cmd})
+		logger.Debug("starting")
+		if err := mgr.runner.Run(cmd); err != nil {
+			stderr, _ := ioutil.ReadAll(buffer)
+			logger.Error("failed", err, lager.Data{"stderr": string(stderr)})
+			return fmt.Errorf("iptables_manager: nftables filter: %s", err)
+		}
+		logger.Debug("ended")
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package iptables_manager_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os/exec"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/iptables_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/sysconfig"
+	"github.com/cloudfoundry/gunk/command_runner/fake_command_runner"
+	. "github.com/cloudfoundry/gunk/command_runner/fake_command_runner/matchers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/lager/lagertest"
+)
+
+var _ = Describe("nftFilterChain", func() {
+	var (
+		fakeRunner  *fake_command_runner.FakeCommandRunner
+		testCfg     *sysconfig.IPTablesFilterConfig
+		chain       iptables_manager.Chain
+		containerID string
+		bridgeName  string
+		ip          net.IP
+		network     *net.IPNet
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		fakeRunner = fake_command_runner.New()
+		testCfg = &sysconfig.IPTablesFilterConfig{
+			InputChain:     "filter-input-chain",
+			ForwardChain:   "filter-forward-chain",
+			DefaultChain:   "filter-default-chain",
+			InstancePrefix: "filter-instance-prefix",
+		}
+
+		containerID = "some-ctr-id"
+		bridgeName = "some-bridge"
+		ip, network, err = net.ParseCIDR("1.2.3.4/28")
+		Expect(err).NotTo(HaveOccurred())
+
+		chain = iptables_manager.NewNFTablesFilterChain("/path/to/nft", "some-table", testCfg, fakeRunner, lagertest.NewTestLogger("test"))
+	})
+
+	Describe("Setup", func() {
+		var batches []string
+		var batchErr error
+
+		batchSpec := fake_command_runner.CommandSpec{
+			Path: "/path/to/nft",
+			Args: []string{"-f", "-"},
+		}
+
+		BeforeEach(func() {
+			batches = nil
+			batchErr = nil
+
+			fakeRunner.WhenRunning(batchSpec, func(cmd *exec.Cmd) error {
+				input, err := ioutil.ReadAll(cmd.Stdin)
+				Expect(err).ToNot(HaveOccurred())
+				batches = append(batches, string(input))
+
+				return batchErr
+			})
+		})
+
+		It("should set up the chain with a single nft transaction", func() {
+			Expect(chain.Setup(containerID, bridgeName, ip, network)).To(Succeed())
+
+			expectedFilterInstanceChain := testCfg.InstancePrefix + containerID
+			Expect(batches).To(Equal([]string{
+				"add chain ip some-table " + expectedFilterInstanceChain + "\n" +
+					"add rule ip some-table " + expectedFilterInstanceChain + " ip saddr 1.2.3.0/28 ip daddr 1.2.3.0/28 accept\n" +
+					"add rule ip some-table " + expectedFilterInstanceChain + " goto " + testCfg.DefaultChain + "\n" +
+					"insert rule ip some-table " + testCfg.ForwardChain + ` index 1 iifname "some-bridge" ip saddr 1.2.3.4 goto ` + expectedFilterInstanceChain + "\n",
+			}))
+		})
+
+		Context("when nft fails", func() {
+			BeforeEach(func() {
+				batchErr = errors.New("nft failed")
+			})
+
+			It("returns the error", func() {
+				Expect(chain.Setup(containerID, bridgeName, ip, network)).To(MatchError(ContainSubstring("iptables_manager: nftables filter: nft: nft failed")))
+			})
+		})
+	})
+
+	Describe("Teardown", func() {
+		var specs []fake_command_runner.CommandSpec
+
+		BeforeEach(func() {
+			expectedFilterInstanceChain := testCfg.InstancePrefix + containerID
+			specs = []fake_command_runner.CommandSpec{
+				fake_command_runner.CommandSpec{
+					Path: "sh",
+					Args: []string{"-c", fmt.Sprintf(
+						`/path/to/nft --handle list chain ip some-table %s 2> /dev/null | grep "goto %s # handle" | sed -e "s/.* # handle //" | xargs --no-run-if-empty --max-lines=1 /path/to/nft delete rule ip some-table %s handle`,
+						testCfg.ForwardChain, expectedFilterInstanceChain, testCfg.ForwardChain,
+					)},
+				},
+				fake_command_runner.CommandSpec{
+					Path: "sh",
+					Args: []string{"-c", fmt.Sprintf("/path/to/nft flush chain ip some-table %s 2> /dev/null || true", expectedFilterInstanceChain)},
+				},
+				fake_command_runner.CommandSpec{
+					Path: "sh",
+					Args: []string{"-c", fmt.Sprintf("/path/to/nft delete chain ip some-table %s 2> /dev/null || true", expectedFilterInstanceChain)},
+				},
+			}
+		})
+
+		It("should tear down the chain", func() {
+			Expect(chain.Teardown(containerID)).To(Succeed())
+
+			Expect(fakeRunner).To(HaveExecutedSerially(specs...))
+		})
+
+		DescribeTable("nft failures",
+			func(specIndex int, errorString string) {
+				fakeRunner.WhenRunning(specs[specIndex], func(*exec.Cmd) error {
+					return errors.New("nft failed")
+				})
+
+				Expect(chain.Teardown(containerID)).To(MatchError(errorString))
+			},
+			Entry("prune forward chain", 0, "iptables_manager: nftables filter: nft failed"),
+			Entry("flush instance chain", 1, "iptables_manager: nftables filter: nft failed"),
+			Entry("delete instance chain", 2, "iptables_manager: nftables filter: nft failed"),
+		)
+	})
+})
@@ -0,0 +1,134 @@
+package iptables_manager
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os/exec"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/garden-linux/network/iptables"
+	"github.com/cloudfoundry-incubator/garden-linux/sysconfig"
+	"github.com/cloudfoundry/gunk/command_runner"
+	"github.com/pivotal-golang/lager"
+)
+
+type nftNATChain struct {
+	nft    string
+	table  string
+	cfg    *sysconfig.IPTablesNATConfig
+	runner command_runner.CommandRunner
+	logger lager.Logger
+}
+
+// NewNFTablesNATChain is like NewNATChain, but programs the chains of cfg in
+// the given nftables table, which is created by net.sh, with the nft binary at
+// nft. The DNAT rules of NetIn are added to the instance chain by the
+// container's net.sh.
+func NewNFTablesNATChain(nft, table string, cfg *sysconfig.IPTablesNATConfig, runner command_runner.CommandRunner, logger lager.Logger) *nftNATChain {
+	return &nftNATChain{
+		nft:    nft,
+		table:  table,
+		cfg:    cfg,
+		runner: runner,
+		logger: logger,
+	}
+}
+
+func (mgr *nftNATChain) Setup(containerID, bridgeName string, ip net.IP, network *net.IPNet) error {
+	instanceChain := mgr.cfg.InstancePrefix + containerID
+
+	logger := mgr.logger.Session("setup")
+	logger.Debug("starting")
+
+	// the postrouting rules are for the container's whole network, which it
+	// may share, so are only added if they are missing
+	postrouting, err := mgr.rules(mgr.cfg.PostroutingChain)
+	if err != nil {
+		logger.Error("failed", err)
+		return fmt.Errorf("iptables_manager: nftables nat: %s", err)
+	}
+
+	txn := iptables.NewNFTBatch(mgr.nft)
+	// Create nat instance chain
+	txn.Add("add chain ip %s %s", mgr.table, instanceChain)
+	// Bind nat instance chain to nat prerouting chain
+	txn.Add("add rule ip %s %s jump %s", mgr.table, mgr.cfg.PreroutingChain, instanceChain)
+
+	// Enable NAT for traffic coming from containers
+	masquerade := fmt.Sprintf("ip saddr %s ip daddr != %s masquerade", network, network)
+	if !postrouting[masquerade] {
+		txn.Add("add rule ip %s %s %s", mgr.table, mgr.cfg.PostroutingChain, masquerade)
+	}
+
+	// Enable hairpin NAT, so that the container reaching ports of its subnet
+	// mapped by NetIn gets the replies back through the host. The rule is the
+	// container's own, commented with its instance chain so that Teardown can
+	// find it, as the network may be shared
+	txn.Add("add rule ip %s %s ip saddr %s ip daddr %s ct status dnat masquerade comment %q", mgr.table, mgr.cfg.PostroutingChain, ip, network, instanceChain)
+
+	if err := txn.Commit(mgr.runner); err != nil {
+		logger.Error("failed", err, lager.Data{"batch": txn.String()})
+		return fmt.Errorf("iptables_manager: nftables nat: %s", err)
+	}
+
+	logger.Debug("ended")
+	return nil
+}
+
+// rules lists the rules of the chain as nft prints them, without handles.
+func (mgr *nftNATChain) rules(chain string) (map[string]bool, error) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.Command(mgr.nft, "list", "chain", "ip", mgr.table, chain)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := mgr.runner.Run(cmd); err != nil {
+		return nil, fmt.Errorf("%s, %s", err, stderr.String())
+	}
+
+	rules := map[string]bool{}
+	for _, rule := range strings.Split(stdout.String(), "\n") {
+		rules[strings.TrimSpace(rule)] = true
+	}
+
+	return rules, nil
+}
+
+func (mgr *nftNATChain) Teardown(containerID string) error {
+	instanceChain := mgr.cfg.InstancePrefix + containerID
+
+	commands := []*exec.Cmd{
+		// Prune nat prerouting chain
+		exec.Command("sh", "-c", fmt.Sprintf(
+			`%s --handle list chain ip %s %s 2> /dev/null | grep "jump %s # handle" | sed -e "s/.* # handle //" | xargs --no-run-if-empty --max-lines=1 %s delete rule ip %s %s handle`,
+			mgr.nft, mgr.table, mgr.cfg.PreroutingChain, instanceChain, mgr.nft, mgr.table, mgr.cfg.PreroutingChain,
+		)),
+		// Prune hairpin NAT rule of the container
+		exec.Command("sh", "-c", fmt.Sprintf(
+			`%s --handle list chain ip %s %s 2> /dev/null | grep -F "comment \"%s\" # handle" | sed -e "s/.* # handle //" | xargs --no-run-if-empty --max-lines=1 %s delete rule ip %s %s handle`,
+			mgr.nft, mgr.table, mgr.cfg.PostroutingChain, instanceChain, mgr.nft, mgr.table, mgr.cfg.PostroutingChain,
+		)),
+		// Flush nat instance chain
+		exec.Command("sh", "-c", fmt.Sprintf("%s flush chain ip %s %s 2> /dev/null || true", mgr.nft, mgr.table, instanceChain)),
+		// Delete nat instance chain
+		exec.Command("sh", "-c", fmt.Sprintf("%s delete chain ip %s %s 2> /dev/null || true", mgr.nft, mgr.table, instanceChain)),
+	}
+
+	for _, cmd := range commands {
+		buffer := &bytes.Buffer{}
+		cmd.Stderr = buffer
+		logger := mgr.logger.Session("teardown", lager.Data{"cmd": cmd})
+		logger.Debug("starting")
+		if err := mgr.runner.Run(cmd); err != nil {
+			stderr, _ := ioutil.ReadAll(buffer)
+			logger.Error("failed", err, lager.Data{"stderr": string(stderr)})
+			return fmt.Errorf("iptables_manager: nftables nat: %s", err)
+		}
+		logger.Debug("ended")
+	}
+
+	return nil
+}
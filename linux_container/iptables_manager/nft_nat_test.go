@@ -0,0 +1,187 @@
+package iptables_manager_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os/exec"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/iptables_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/sysconfig"
+	"github.com/cloudfoundry/gunk/command_runner/fake_command_runner"
+	. "github.com/cloudfoundry/gunk/command_runner/fake_command_runner/matchers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/lager/lagertest"
+)
+
+var _ = Describe("nftNATChain", func() {
+	var (
+		fakeRunner  *fake_command_runner.FakeCommandRunner
+		testCfg     *sysconfig.IPTablesNATConfig
+		chain       iptables_manager.Chain
+		containerID string
+		bridgeName  string
+		ip          net.IP
+		network     *net.IPNet
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		fakeRunner = fake_command_runner.New()
+		testCfg = &sysconfig.IPTablesNATConfig{
+			PreroutingChain:  "nat-prerouting-chain",
+			PostroutingChain: "nat-postrouting-chain",
+			InstancePrefix:   "nat-instance-prefix",
+		}
+
+		containerID = "some-ctr-id"
+		bridgeName = "some-bridge"
+		ip, network, err = net.ParseCIDR("1.2.3.4/28")
+		Expect(err).NotTo(HaveOccurred())
+
+		chain = iptables_manager.NewNFTablesNATChain("/path/to/nft", "some-table", testCfg, fakeRunner, lagertest.NewTestLogger("test"))
+	})
+
+	Describe("Setup", func() {
+		var postrouting string
+		var listErr error
+		var batches []string
+		var batchErr error
+
+		listSpec := fake_command_runner.CommandSpec{
+			Path: "/path/to/nft",
+			Args: []string{"list", "chain", "ip", "some-table", "nat-postrouting-chain"},
+		}
+
+		batchSpec := fake_command_runner.CommandSpec{
+			Path: "/path/to/nft",
+			Args: []string{"-f", "-"},
+		}
+
+		BeforeEach(func() {
+			postrouting = "table ip some-table {\n\tchain nat-postrouting-chain {\n\t}\n}\n"
+			listErr = nil
+			batches = nil
+			batchErr = nil
+
+			fakeRunner.WhenRunning(listSpec, func(cmd *exec.Cmd) error {
+				cmd.Stdout.Write([]byte(postrouting))
+				return listErr
+			})
+
+			fakeRunner.WhenRunning(batchSpec, func(cmd *exec.Cmd) error {
+				input, err := ioutil.ReadAll(cmd.Stdin)
+				Expect(err).ToNot(HaveOccurred())
+				batches = append(batches, string(input))
+
+				return batchErr
+			})
+		})
+
+		It("should set up the chain with a single nft transaction", func() {
+			Expect(chain.Setup(containerID, bridgeName, ip, network)).To(Succeed())
+
+			expectedNatInstanceChain := testCfg.InstancePrefix + containerID
+			Expect(batches).To(Equal([]string{
+				"add chain ip some-table " + expectedNatInstanceChain + "\n" +
+					"add rule ip some-table " + testCfg.PreroutingChain + " jump " + expectedNatInstanceChain + "\n" +
+					"add rule ip some-table " + testCfg.PostroutingChain + " ip saddr 1.2.3.0/28 ip daddr != 1.2.3.0/28 masquerade\n" +
+					"add rule ip some-table " + testCfg.PostroutingChain + ` ip saddr 1.2.3.4 ip daddr 1.2.3.0/28 ct status dnat masquerade comment "` + expectedNatInstanceChain + `"` + "\n",
+			}))
+		})
+
+		Context("when the network's postrouting rule already exists", func() {
+			BeforeEach(func() {
+				postrouting = fmt.Sprintf(
+					"table ip some-table {\n\tchain %s {\n\t\tip saddr 1.2.3.0/28 ip daddr != 1.2.3.0/28 masquerade\n\t}\n}\n",
+					testCfg.PostroutingChain,
+				)
+			})
+
+			It("does not add it again", func() {
+				Expect(chain.Setup(containerID, bridgeName, ip, network)).To(Succeed())
+
+				Expect(batches).To(HaveLen(1))
+				Expect(batches[0]).ToNot(ContainSubstring("daddr != "))
+				Expect(batches[0]).To(ContainSubstring("ct status dnat masquerade"))
+			})
+		})
+
+		Context("when listing the postrouting rules fails", func() {
+			BeforeEach(func() {
+				listErr = errors.New("nft failed")
+			})
+
+			It("returns the error without changing any rules", func() {
+				Expect(chain.Setup(containerID, bridgeName, ip, network)).To(MatchError(ContainSubstring("iptables_manager: nftables nat: nft failed")))
+				Expect(batches).To(BeEmpty())
+			})
+		})
+
+		Context("when nft fails", func() {
+			BeforeEach(func() {
+				batchErr = errors.New("nft failed")
+			})
+
+			It("returns the error", func() {
+				Expect(chain.Setup(containerID, bridgeName, ip, network)).To(MatchError(ContainSubstring("iptables_manager: nftables nat: nft: nft failed")))
+			})
+		})
+	})
+
+	Describe("Teardown", func() {
+		var specs []fake_command_runner.CommandSpec
+
+		BeforeEach(func() {
+			expectedNatInstanceChain := testCfg.InstancePrefix + containerID
+			specs = []fake_command_runner.CommandSpec{
+				fake_command_runner.CommandSpec{
+					Path: "sh",
+					Args: []string{"-c", fmt.Sprintf(
+						`/path/to/nft --handle list chain ip some-table %s 2> /dev/null | grep "jump %s # handle" | sed -e "s/.* # handle //" | xargs --no-run-if-empty --max-lines=1 /path/to/nft delete rule ip some-table %s handle`,
+						testCfg.PreroutingChain, expectedNatInstanceChain, testCfg.PreroutingChain,
+					)},
+				},
+				fake_command_runner.CommandSpec{
+					Path: "sh",
+					Args: []string{"-c", fmt.Sprintf(
+						`/path/to/nft --handle list chain ip some-table %s 2> /dev/null | grep -F "comment \"%s\" # handle" | sed -e "s/.* # handle //" | xargs --no-run-if-empty --max-lines=1 /path/to/nft delete rule ip some-table %s handle`,
+						testCfg.PostroutingChain, expectedNatInstanceChain, testCfg.PostroutingChain,
+					)},
+				},
+				fake_command_runner.CommandSpec{
+					Path: "sh",
+					Args: []string{"-c", fmt.Sprintf("/path/to/nft flush chain ip some-table %s 2> /dev/null || true", expectedNatInstanceChain)},
+				},
+				fake_command_runner.CommandSpec{
+					Path: "sh",
+					Args: []string{"-c", fmt.Sprintf("/path/to/nft delete chain ip some-table %s 2> /dev/null || true", expectedNatInstanceChain)},
+				},
+			}
+		})
+
+		It("should tear down the chain", func() {
+			Expect(chain.Teardown(containerID)).To(Succeed())
+
+			Expect(fakeRunner).To(HaveExecutedSerially(specs...))
+		})
+
+		DescribeTable("nft failures",
+			func(specIndex int, errorString string) {
+				fakeRunner.WhenRunning(specs[specIndex], func(*exec.Cmd) error {
+					return errors.New("nft failed")
+				})
+
+				Expect(chain.Teardown(containerID)).To(MatchError(errorString))
+			},
+			Entry("prune prerouting chain", 0, "iptables_manager: nftables nat: nft failed"),
+			Entry("prune hairpin NAT rule", 1, "iptables_manager: nftables nat: nft failed"),
+			Entry("flush instance chain", 2, "iptables_manager: nftables nat: nft failed"),
+			Entry("delete instance chain", 3, "iptables_manager: nftables nat: nft failed"),
+		)
+	})
+})
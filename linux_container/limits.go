@@ -1,13 +1,39 @@
 package linux_container
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 
 	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden-linux/cpuset_pool"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager"
+	"github.com/pivotal-golang/lager"
 )
 
+const (
+	DefaultCPUPeriodInMicroseconds = 100000
+
+	// bounds enforced by the kernel on cpu.cfs_period_us and cpu.cfs_quota_us
+	MinCPUPeriodInMicroseconds = 1000
+	MaxCPUPeriodInMicroseconds = 1000000
+	MinCPUQuotaInMicroseconds  = 1000
+
+	// bounds enforced by the kernel on blkio.weight
+	MinIOWeight = 10
+	MaxIOWeight = 1000
+)
+
+// LimitBandwidth applies the same rate and burst to traffic to and from the
+// container.
 func (c *LinuxContainer) LimitBandwidth(limits garden.BandwidthLimits) error {
+	return c.LimitDetailedBandwidth(linux_backend.SymmetricBandwidthLimits(limits))
+}
+
+func (c *LinuxContainer) LimitDetailedBandwidth(limits linux_backend.BandwidthLimits) error {
 	cLog := c.logger.Session("limit-bandwidth")
 
 	err := c.bandwidthManager.SetLimits(cLog, limits)
@@ -20,15 +46,31 @@ func (c *LinuxContainer) LimitBandwidth(limits garden.BandwidthLimits) error {
 
 	c.LinuxContainerSpec.Limits.Bandwidth = &limits
 
+	c.emitEvent(linux_backend.EventLimitsChanged, map[string]string{"limit": "bandwidth"})
+
 	return nil
 }
 
+// CurrentBandwidthLimits reports the limits on traffic to the container, as
+// garden has no notion of separate inbound and outbound limits.
 func (c *LinuxContainer) CurrentBandwidthLimits() (garden.BandwidthLimits, error) {
+	limits, err := c.CurrentDetailedBandwidthLimits()
+	if err != nil {
+		return garden.BandwidthLimits{}, err
+	}
+
+	return garden.BandwidthLimits{
+		RateInBytesPerSecond:      limits.InRateInBytesPerSecond,
+		BurstRateInBytesPerSecond: limits.InBurstInBytes,
+	}, nil
+}
+
+func (c *LinuxContainer) CurrentDetailedBandwidthLimits() (linux_backend.BandwidthLimits, error) {
 	c.bandwidthMutex.RLock()
 	defer c.bandwidthMutex.RUnlock()
 
 	if c.LinuxContainerSpec.Limits.Bandwidth == nil {
-		return garden.BandwidthLimits{}, nil
+		return linux_backend.BandwidthLimits{}, nil
 	}
 
 	return *c.LinuxContainerSpec.Limits.Bandwidth, nil
@@ -47,6 +89,8 @@ func (c *LinuxContainer) LimitDisk(limits garden.DiskLimits) error {
 
 	c.LinuxContainerSpec.Limits.Disk = &limits
 
+	c.emitEvent(linux_backend.EventLimitsChanged, map[string]string{"limit": "disk"})
+
 	return nil
 }
 
@@ -55,15 +99,190 @@ func (c *LinuxContainer) CurrentDiskLimits() (garden.DiskLimits, error) {
 	return c.quotaManager.GetLimits(cLog, c.RootFSPath())
 }
 
+func (c *LinuxContainer) LimitIO(limits linux_backend.IOLimits) error {
+	if limits.Weight != 0 && (limits.Weight < MinIOWeight || limits.Weight > MaxIOWeight) {
+		return fmt.Errorf("linux_container: io weight must be between %d and %d", MinIOWeight, MaxIOWeight)
+	}
+
+	for _, device := range limits.Devices {
+		var major, minor uint
+		if _, err := fmt.Sscanf(device.Device, "%d:%d", &major, &minor); err != nil {
+			return fmt.Errorf("linux_container: invalid block device %q, expected major:minor", device.Device)
+		}
+	}
+
+	if limits.Weight != 0 {
+		if err := c.cgroupsManager.Set("blkio", "blkio.weight", fmt.Sprintf("%d", limits.Weight)); err != nil {
+			return err
+		}
+	}
+
+	c.ioMutex.Lock()
+	defer c.ioMutex.Unlock()
+
+	previous := c.LinuxContainerSpec.Limits.IO
+
+	// a zero weight leaves the weight unchanged, so keep recording it
+	if limits.Weight == 0 && previous != nil {
+		limits.Weight = previous.Weight
+	}
+
+	// writing a zero throttle removes it, so clear devices which are no longer
+	// limited before applying the new ones
+	if previous != nil {
+		for _, device := range previous.Devices {
+			if !hasDevice(limits.Devices, device.Device) {
+				if err := c.setIOThrottles(linux_backend.DeviceIOLimits{Device: device.Device}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for _, device := range limits.Devices {
+		if err := c.setIOThrottles(device); err != nil {
+			return err
+		}
+	}
+
+	c.LinuxContainerSpec.Limits.IO = &limits
+
+	c.emitEvent(linux_backend.EventLimitsChanged, map[string]string{"limit": "io"})
+
+	return nil
+}
+
+func (c *LinuxContainer) CurrentIOLimits() (linux_backend.IOLimits, error) {
+	weight, err := c.cgroupsManager.Get("blkio", "blkio.weight")
+	if err != nil {
+		return linux_backend.IOLimits{}, err
+	}
+
+	numericWeight, err := strconv.ParseUint(weight, 10, 0)
+	if err != nil {
+		return linux_backend.IOLimits{}, err
+	}
+
+	devices := map[string]*linux_backend.DeviceIOLimits{}
+	order := []string{}
+
+	for _, throttle := range ioThrottles {
+		contents, err := c.cgroupsManager.Get("blkio", throttle.file)
+		if err != nil {
+			return linux_backend.IOLimits{}, err
+		}
+
+		for _, line := range strings.Split(contents, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				continue
+			}
+
+			value, err := strconv.ParseUint(fields[1], 10, 0)
+			if err != nil {
+				return linux_backend.IOLimits{}, err
+			}
+
+			device, found := devices[fields[0]]
+			if !found {
+				device = &linux_backend.DeviceIOLimits{Device: fields[0]}
+				devices[fields[0]] = device
+				order = append(order, fields[0])
+			}
+
+			*throttle.field(device) = value
+		}
+	}
+
+	limits := linux_backend.IOLimits{Weight: numericWeight}
+	for _, name := range order {
+		limits.Devices = append(limits.Devices, *devices[name])
+	}
+
+	return limits, nil
+}
+
+var ioThrottles = []struct {
+	file  string
+	field func(*linux_backend.DeviceIOLimits) *uint64
+}{
+	{"blkio.throttle.read_bps_device", func(d *linux_backend.DeviceIOLimits) *uint64 { return &d.ReadBytesPerSecond }},
+	{"blkio.throttle.write_bps_device", func(d *linux_backend.DeviceIOLimits) *uint64 { return &d.WriteBytesPerSecond }},
+	{"blkio.throttle.read_iops_device", func(d *linux_backend.DeviceIOLimits) *uint64 { return &d.ReadIOPerSecond }},
+	{"blkio.throttle.write_iops_device", func(d *linux_backend.DeviceIOLimits) *uint64 { return &d.WriteIOPerSecond }},
+}
+
+func (c *LinuxContainer) setIOThrottles(device linux_backend.DeviceIOLimits) error {
+	for _, throttle := range ioThrottles {
+		value := fmt.Sprintf("%s %d", device.Device, *throttle.field(&device))
+		if err := c.cgroupsManager.Set("blkio", throttle.file, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func hasDevice(devices []linux_backend.DeviceIOLimits, name string) bool {
+	for _, device := range devices {
+		if device.Device == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LimitMemory sets the memory limit with no swap, soft limit or separate
+// kernel memory limit.
 func (c *LinuxContainer) LimitMemory(limits garden.MemoryLimits) error {
-	if err := c.oomWatcher.Watch(func() {
-		c.registerEvent("out of memory")
-		c.Stop(true) // ignore any error
-	}); err != nil {
+	return c.LimitDetailedMemory(linux_backend.MemoryLimits{MemoryLimits: limits})
+}
+
+// ValidateOOMPolicy refuses the policies which rely on disabling the kernel's
+// OOM killer when the host uses the unified cgroup hierarchy, so that they can
+// be rejected before a container is created rather than on its first limit.
+func ValidateOOMPolicy(policy linux_backend.OOMPolicy, unifiedCgroups bool) error {
+	if unifiedCgroups && policy != linux_backend.OOMPolicyStop {
+		return UnsupportedOOMPolicyError{
+			Policy: policy,
+			Cause:  cgroups_manager.UnsupportedError{Name: "memory.oom_control"},
+		}
+	}
+
+	return nil
+}
+
+func (c *LinuxContainer) LimitDetailedMemory(limits linux_backend.MemoryLimits) error {
+	// with the kernel's OOM killer disabled, processes which hit the limit wait
+	// for memory to be freed, leaving the choice of what to do to handleOOM
+	//
+	// the unified hierarchy cannot disable the OOM killer, so the policies
+	// which rely on it are refused before any limit is set
+	if c.oomPolicy() != linux_backend.OOMPolicyStop {
+		if err := c.cgroupsManager.Set("memory", "memory.oom_control", "1"); err != nil {
+			if _, unsupported := err.(cgroups_manager.UnsupportedError); unsupported {
+				return UnsupportedOOMPolicyError{Policy: c.oomPolicy(), Cause: err}
+			}
+
+			return err
+		}
+	}
+
+	if err := c.oomWatcher.Watch(c.handleOOM); err != nil {
 		return err
 	}
 
+	if memoryWatcher, ok := c.oomWatcher.(MemoryWatcher); ok {
+		if err := memoryWatcher.WatchPressure(func(level string) {
+			c.emitEvent(linux_backend.EventMemoryPressure, map[string]string{"level": level})
+		}); err != nil {
+			return err
+		}
+	}
+
 	limit := fmt.Sprintf("%d", limits.LimitInBytes)
+	memswLimit := fmt.Sprintf("%d", limits.LimitInBytes+limits.SwapInBytes)
 
 	// memory.memsw.limit_in_bytes must be >= memory.limit_in_bytes
 	//
@@ -72,21 +291,139 @@ func (c *LinuxContainer) LimitMemory(limits garden.MemoryLimits) error {
 	//
 	// so, write memory.limit_in_bytes before and after
 	c.cgroupsManager.Set("memory", "memory.limit_in_bytes", limit)
-	c.cgroupsManager.Set("memory", "memory.memsw.limit_in_bytes", limit)
+	c.cgroupsManager.Set("memory", "memory.memsw.limit_in_bytes", memswLimit)
 
 	err := c.cgroupsManager.Set("memory", "memory.limit_in_bytes", limit)
 	if err != nil {
 		return err
 	}
 
+	// without swap accounting there is no memory.memsw.limit_in_bytes, which
+	// only matters if the container is meant to swap
+	if limits.SwapInBytes != 0 {
+		if err := c.cgroupsManager.Set("memory", "memory.memsw.limit_in_bytes", memswLimit); err != nil {
+			return err
+		}
+	}
+
+	softLimit := "-1"
+	if limits.SoftLimitInBytes != 0 {
+		softLimit = fmt.Sprintf("%d", limits.SoftLimitInBytes)
+	}
+
+	if err := c.cgroupsManager.Set("memory", "memory.soft_limit_in_bytes", softLimit); err != nil {
+		return err
+	}
+
+	// kernel memory accounting is only enabled once a limit is first set, so
+	// leave it alone unless a limit is wanted or one is being removed
+	if limits.KernelLimitInBytes != 0 || c.kernelMemoryLimited() {
+		kernelLimit := "-1"
+		if limits.KernelLimitInBytes != 0 {
+			kernelLimit = fmt.Sprintf("%d", limits.KernelLimitInBytes)
+		}
+
+		if err := c.cgroupsManager.Set("memory", "memory.kmem.limit_in_bytes", kernelLimit); err != nil {
+			return err
+		}
+	}
+
 	c.memoryMutex.Lock()
 	defer c.memoryMutex.Unlock()
 
 	c.LinuxContainerSpec.Limits.Memory = &limits
 
+	c.emitEvent(linux_backend.EventLimitsChanged, map[string]string{"limit": "memory"})
+
 	return nil
 }
 
+func (c *LinuxContainer) oomPolicy() linux_backend.OOMPolicy {
+	// containers restored from snapshots which predate OOM policies
+	if c.OOMPolicy == "" {
+		return linux_backend.OOMPolicyStop
+	}
+
+	return c.OOMPolicy
+}
+
+func (c *LinuxContainer) handleOOM() {
+	cLog := c.logger.Session("handle-oom", lager.Data{"policy": c.oomPolicy()})
+
+	c.registerEvent("out of memory")
+
+	data := map[string]string{"policy": string(c.oomPolicy())}
+	stop := c.oomPolicy() == linux_backend.OOMPolicyStop
+
+	switch c.oomPolicy() {
+	case linux_backend.OOMPolicyKillProcess:
+		victim, err := c.killOOMVictim()
+		if err != nil {
+			cLog.Error("failed-to-kill-process", err)
+			data["error"] = err.Error()
+			break
+		}
+
+		cLog.Info("killed-process", lager.Data{"pid": victim.Pid, "command": victim.Command})
+		c.registerEvent(fmt.Sprintf("out of memory: killed process %d (%s)", victim.Pid, victim.Command))
+
+		data["pid"] = strconv.Itoa(victim.Pid)
+		data["command"] = victim.Command
+
+	case linux_backend.OOMPolicyFreeze:
+		// tasks waiting in the OOM handler may not reach the freezer before it
+		// times out; left thawed they would wait for memory forever, so the
+		// container is stopped instead
+		if err := c.Pause(); err != nil {
+			cLog.Error("failed-to-freeze", err)
+			data["error"] = err.Error()
+			stop = true
+		}
+	}
+
+	c.emitEvent(linux_backend.EventOutOfMemory, data)
+
+	if stop {
+		c.Stop(true) // ignore any error
+	}
+}
+
+func (c *LinuxContainer) killOOMVictim() (OOMVictim, error) {
+	pids, err := c.pids()
+	if err != nil {
+		return OOMVictim{}, err
+	}
+
+	return c.oomKiller.Kill(pids)
+}
+
+// pids lists the processes in the container's memory cgroup.
+func (c *LinuxContainer) pids() ([]int, error) {
+	procs, err := c.cgroupsManager.Get("memory", "cgroup.procs")
+	if err != nil {
+		return nil, err
+	}
+
+	var pids []int
+	for _, line := range strings.Fields(procs) {
+		pid, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, err
+		}
+
+		pids = append(pids, pid)
+	}
+
+	return pids, nil
+}
+
+func (c *LinuxContainer) kernelMemoryLimited() bool {
+	c.memoryMutex.RLock()
+	defer c.memoryMutex.RUnlock()
+
+	return c.LinuxContainerSpec.Limits.Memory != nil && c.LinuxContainerSpec.Limits.Memory.KernelLimitInBytes != 0
+}
+
 func (c *LinuxContainer) CurrentMemoryLimits() (garden.MemoryLimits, error) {
 	limitInBytes, err := c.cgroupsManager.Get("memory", "memory.limit_in_bytes")
 	if err != nil {
@@ -101,6 +438,104 @@ func (c *LinuxContainer) CurrentMemoryLimits() (garden.MemoryLimits, error) {
 	return garden.MemoryLimits{uint64(numericLimit)}, nil
 }
 
+func (c *LinuxContainer) CurrentDetailedMemoryLimits() (linux_backend.MemoryLimits, error) {
+	memoryLimits, err := c.CurrentMemoryLimits()
+	if err != nil {
+		return linux_backend.MemoryLimits{}, err
+	}
+
+	limits := linux_backend.MemoryLimits{MemoryLimits: memoryLimits}
+
+	softLimit, err := c.memoryLimit("memory.soft_limit_in_bytes")
+	if err != nil {
+		return linux_backend.MemoryLimits{}, err
+	}
+
+	limits.SoftLimitInBytes = softLimit
+
+	// memory.memsw.limit_in_bytes and memory.kmem.limit_in_bytes are missing
+	// when the kernel does not account swap or kernel memory, in which case
+	// there is no limit on either
+	if memswLimit, err := c.memoryLimit("memory.memsw.limit_in_bytes"); err == nil && memswLimit > limits.LimitInBytes {
+		limits.SwapInBytes = memswLimit - limits.LimitInBytes
+	}
+
+	if kernelLimit, err := c.memoryLimit("memory.kmem.limit_in_bytes"); err == nil {
+		limits.KernelLimitInBytes = kernelLimit
+	}
+
+	return limits, nil
+}
+
+// memoryLimit reads a memory cgroup limit, where zero means no limit.
+func (c *LinuxContainer) memoryLimit(file string) (uint64, error) {
+	value, err := c.cgroupsManager.Get("memory", file)
+	if err != nil {
+		return 0, err
+	}
+
+	limit, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return unlimitedToZero(limit), nil
+}
+
+// the kernel reports the absence of a memory limit as the largest page-aligned
+// value it can hold, which depends on the page size
+func unlimitedToZero(limit uint64) uint64 {
+	if limit >= math.MaxInt64-(1<<16) {
+		return 0
+	}
+
+	return limit
+}
+
+func (c *LinuxContainer) LimitPids(limits linux_backend.PidLimits) error {
+	max := "max"
+	if limits.Max != 0 {
+		max = fmt.Sprintf("%d", limits.Max)
+	}
+
+	if err := c.cgroupsManager.Set("pids", "pids.max", max); err != nil {
+		return err
+	}
+
+	c.pidsMutex.Lock()
+	defer c.pidsMutex.Unlock()
+
+	c.LinuxContainerSpec.Limits.Pids = &limits
+
+	c.emitEvent(linux_backend.EventLimitsChanged, map[string]string{"limit": "pids"})
+
+	return nil
+}
+
+func (c *LinuxContainer) CurrentPidLimits() (linux_backend.PidLimits, error) {
+	max, err := c.cgroupsManager.Get("pids", "pids.max")
+	if err != nil {
+		return linux_backend.PidLimits{}, err
+	}
+
+	numericMax, err := parsePidsMax(max)
+	if err != nil {
+		return linux_backend.PidLimits{}, err
+	}
+
+	return linux_backend.PidLimits{Max: numericMax}, nil
+}
+
+// parsePidsMax parses pids.max, which is "max" when the container is not
+// capped.
+func parsePidsMax(max string) (uint64, error) {
+	if max == "max" {
+		return 0, nil
+	}
+
+	return strconv.ParseUint(max, 10, 0)
+}
+
 func (c *LinuxContainer) LimitCPU(limits garden.CPULimits) error {
 	limit := fmt.Sprintf("%d", limits.LimitInShares)
 
@@ -114,6 +549,8 @@ func (c *LinuxContainer) LimitCPU(limits garden.CPULimits) error {
 
 	c.LinuxContainerSpec.Limits.CPU = &limits
 
+	c.emitEvent(linux_backend.EventLimitsChanged, map[string]string{"limit": "cpu"})
+
 	return nil
 }
 
@@ -130,3 +567,170 @@ func (c *LinuxContainer) CurrentCPULimits() (garden.CPULimits, error) {
 
 	return garden.CPULimits{uint64(numericLimit)}, nil
 }
+
+func (c *LinuxContainer) LimitCPUQuota(limits linux_backend.CPUQuotaLimits) error {
+	if limits.PeriodInMicroseconds == 0 {
+		limits.PeriodInMicroseconds = DefaultCPUPeriodInMicroseconds
+	}
+
+	if limits.PeriodInMicroseconds < MinCPUPeriodInMicroseconds || limits.PeriodInMicroseconds > MaxCPUPeriodInMicroseconds {
+		return fmt.Errorf("linux_container: cpu period must be between %dus and %dus", MinCPUPeriodInMicroseconds, MaxCPUPeriodInMicroseconds)
+	}
+
+	if limits.QuotaInMicroseconds != 0 && limits.QuotaInMicroseconds < MinCPUQuotaInMicroseconds {
+		return fmt.Errorf("linux_container: cpu quota must be at least %dus", MinCPUQuotaInMicroseconds)
+	}
+
+	quota := "-1"
+	if limits.QuotaInMicroseconds != 0 {
+		quota = fmt.Sprintf("%d", limits.QuotaInMicroseconds)
+	}
+
+	currentPeriod, err := c.cgroupsManager.Get("cpu", "cpu.cfs_period_us")
+	if err != nil {
+		return err
+	}
+
+	numericPeriod, err := strconv.ParseUint(currentPeriod, 10, 0)
+	if err != nil {
+		return err
+	}
+
+	previousQuota, err := c.cgroupsManager.Get("cpu", "cpu.cfs_quota_us")
+	if err != nil {
+		return err
+	}
+
+	// the kernel rejects a period or quota which would give the container more
+	// bandwidth than its parent, so write them in the order in which the
+	// bandwidth in between is no more than the new one, never lifting the cap:
+	// the quota first when the period shrinks, and the period first otherwise.
+	// Should the second write fail, the first is undone
+	if limits.PeriodInMicroseconds < numericPeriod {
+		if err := c.cgroupsManager.Set("cpu", "cpu.cfs_quota_us", quota); err != nil {
+			return err
+		}
+
+		if err := c.cgroupsManager.Set("cpu", "cpu.cfs_period_us", fmt.Sprintf("%d", limits.PeriodInMicroseconds)); err != nil {
+			if restoreErr := c.cgroupsManager.Set("cpu", "cpu.cfs_quota_us", previousQuota); restoreErr != nil {
+				c.logger.Error("failed-to-restore-cpu-quota", restoreErr, lager.Data{"quota": previousQuota})
+			}
+
+			return err
+		}
+	} else {
+		if err := c.cgroupsManager.Set("cpu", "cpu.cfs_period_us", fmt.Sprintf("%d", limits.PeriodInMicroseconds)); err != nil {
+			return err
+		}
+
+		if err := c.cgroupsManager.Set("cpu", "cpu.cfs_quota_us", quota); err != nil {
+			if restoreErr := c.cgroupsManager.Set("cpu", "cpu.cfs_period_us", currentPeriod); restoreErr != nil {
+				c.logger.Error("failed-to-restore-cpu-period", restoreErr, lager.Data{"period": currentPeriod})
+			}
+
+			return err
+		}
+	}
+
+	c.cpuMutex.Lock()
+	defer c.cpuMutex.Unlock()
+
+	c.LinuxContainerSpec.Limits.CPUQuota = &limits
+
+	c.emitEvent(linux_backend.EventLimitsChanged, map[string]string{"limit": "cpu-quota"})
+
+	return nil
+}
+
+func (c *LinuxContainer) CurrentCPUQuotaLimits() (linux_backend.CPUQuotaLimits, error) {
+	period, err := c.cgroupsManager.Get("cpu", "cpu.cfs_period_us")
+	if err != nil {
+		return linux_backend.CPUQuotaLimits{}, err
+	}
+
+	numericPeriod, err := strconv.ParseUint(period, 10, 0)
+	if err != nil {
+		return linux_backend.CPUQuotaLimits{}, err
+	}
+
+	quota, err := c.cgroupsManager.Get("cpu", "cpu.cfs_quota_us")
+	if err != nil {
+		return linux_backend.CPUQuotaLimits{}, err
+	}
+
+	numericQuota, err := strconv.ParseInt(quota, 10, 0)
+	if err != nil {
+		return linux_backend.CPUQuotaLimits{}, err
+	}
+
+	// -1 means the container is not capped
+	if numericQuota < 0 {
+		numericQuota = 0
+	}
+
+	return linux_backend.CPUQuotaLimits{
+		PeriodInMicroseconds: numericPeriod,
+		QuotaInMicroseconds:  uint64(numericQuota),
+	}, nil
+}
+
+func (c *LinuxContainer) LimitCPUSet(limits linux_backend.CPUSetLimits) error {
+	if limits.CPUs == "" {
+		return errors.New("linux_container: cpuset must include at least one cpu")
+	}
+
+	if _, err := cpuset_pool.Parse(limits.CPUs); err != nil {
+		return err
+	}
+
+	if _, err := cpuset_pool.Parse(limits.Mems); err != nil {
+		return err
+	}
+
+	// tasks may only be attached to a cpuset with both cpus and mems, so write
+	// mems first to avoid ever leaving the cgroup without a memory node
+	if limits.Mems != "" {
+		if err := c.cgroupsManager.Set("cpuset", "cpuset.mems", limits.Mems); err != nil {
+			return err
+		}
+	}
+
+	if err := c.cgroupsManager.Set("cpuset", "cpuset.cpus", limits.CPUs); err != nil {
+		return err
+	}
+
+	c.cpuMutex.Lock()
+	defer c.cpuMutex.Unlock()
+
+	c.LinuxContainerSpec.Limits.CPUSet = &limits
+
+	c.emitEvent(linux_backend.EventLimitsChanged, map[string]string{"limit": "cpuset"})
+
+	return nil
+}
+
+func (c *LinuxContainer) CurrentCPUSetLimits() (linux_backend.CPUSetLimits, error) {
+	cpus, err := c.cgroupsManager.Get("cpuset", "cpuset.cpus")
+	if err != nil {
+		return linux_backend.CPUSetLimits{}, err
+	}
+
+	mems, err := c.cgroupsManager.Get("cpuset", "cpuset.mems")
+	if err != nil {
+		return linux_backend.CPUSetLimits{}, err
+	}
+
+	c.cpuMutex.RLock()
+	defer c.cpuMutex.RUnlock()
+
+	exclusive := false
+	if c.LinuxContainerSpec.Limits.CPUSet != nil {
+		exclusive = c.LinuxContainerSpec.Limits.CPUSet.Exclusive
+	}
+
+	return linux_backend.CPUSetLimits{
+		CPUs:      cpus,
+		Mems:      mems,
+		Exclusive: exclusive,
+	}, nil
+}
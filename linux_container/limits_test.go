@@ -5,6 +5,8 @@ import (
 	"io/ioutil"
 	"math"
 	"net"
+	"os"
+	"path"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -13,11 +15,16 @@ import (
 
 	"github.com/cloudfoundry-incubator/garden"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend/fakes"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/bandwidth_manager/fake_bandwidth_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager/fake_cgroups_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_conntrack_counter"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_fd_counter"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_iptables_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_network_statisticser"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_oom_killer"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_quota_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_watcher"
 	networkFakes "github.com/cloudfoundry-incubator/garden-linux/network/fakes"
@@ -29,22 +36,30 @@ import (
 
 var _ = Describe("Linux containers", func() {
 	var fakeCgroups *fake_cgroups_manager.FakeCgroupsManager
+	var cgroupsManager linux_container.CgroupsManager
 	var fakeQuotaManager *fake_quota_manager.FakeQuotaManager
 	var fakeBandwidthManager *fake_bandwidth_manager.FakeBandwidthManager
 	var fakeRunner *fake_command_runner.FakeCommandRunner
-	var fakeOomWatcher *fake_watcher.FakeWatcher
+	var fakeOomWatcher *fake_watcher.FakeMemoryWatcher
+	var fakeOOMKiller *fake_oom_killer.FakeOOMKiller
+	var fakeEvents *fakes.FakeEventEmitter
 	var containerResources *linux_backend.Resources
 	var container *linux_container.LinuxContainer
 	var containerDir string
+	var oomPolicy linux_backend.OOMPolicy
 
 	BeforeEach(func() {
 		fakeRunner = fake_command_runner.New()
+		oomPolicy = ""
 
 		fakeCgroups = fake_cgroups_manager.New("/cgroups", "some-id")
+		cgroupsManager = fakeCgroups
 
 		fakeQuotaManager = new(fake_quota_manager.FakeQuotaManager)
 		fakeBandwidthManager = fake_bandwidth_manager.New()
-		fakeOomWatcher = new(fake_watcher.FakeWatcher)
+		fakeOomWatcher = new(fake_watcher.FakeMemoryWatcher)
+		fakeOOMKiller = new(fake_oom_killer.FakeOOMKiller)
+		fakeEvents = new(fakes.FakeEventEmitter)
 
 		var err error
 		containerDir, err = ioutil.TempDir("", "depot")
@@ -74,10 +89,11 @@ var _ = Describe("Linux containers", func() {
 					Handle:    "some-handle",
 					GraceTime: time.Second * 1,
 				},
+				OOMPolicy: oomPolicy,
 			},
 			fake_port_pool.New(1000),
 			fakeRunner,
-			fakeCgroups,
+			cgroupsManager,
 			fakeQuotaManager,
 			fakeBandwidthManager,
 			new(fake_process_tracker.FakeProcessTracker),
@@ -85,6 +101,10 @@ var _ = Describe("Linux containers", func() {
 			new(fake_iptables_manager.FakeIPTablesManager),
 			new(fake_network_statisticser.FakeNetworkStatisticser),
 			fakeOomWatcher,
+			fakeOOMKiller,
+			new(fake_fd_counter.FakeFileDescriptorCounter),
+			new(fake_conntrack_counter.FakeConntrackCounter),
+			fakeEvents,
 			lagertest.NewTestLogger("linux-container-limits-test"),
 		)
 	})
@@ -95,11 +115,16 @@ var _ = Describe("Linux containers", func() {
 			BurstRateInBytesPerSecond: 256,
 		}
 
-		It("sets the limit via the bandwidth manager with the new limits", func() {
+		It("sets the same limit in both directions via the bandwidth manager", func() {
 			err := container.LimitBandwidth(limits)
 			Expect(err).ToNot(HaveOccurred())
 
-			Expect(fakeBandwidthManager.EnforcedLimits).To(ContainElement(limits))
+			Expect(fakeBandwidthManager.EnforcedLimits).To(ContainElement(linux_backend.BandwidthLimits{
+				InRateInBytesPerSecond:  128,
+				InBurstInBytes:          256,
+				OutRateInBytesPerSecond: 128,
+				OutBurstInBytes:         256,
+			}))
 		})
 
 		Context("when setting the limit fails", func() {
@@ -116,6 +141,39 @@ var _ = Describe("Linux containers", func() {
 		})
 	})
 
+	Describe("Limiting bandwidth in each direction", func() {
+		limits := linux_backend.BandwidthLimits{
+			InRateInBytesPerSecond:  128,
+			InBurstInBytes:          256,
+			OutRateInBytesPerSecond: 512,
+			OutBurstInBytes:         1024,
+		}
+
+		It("sets the limits via the bandwidth manager", func() {
+			Expect(container.LimitDetailedBandwidth(limits)).To(Succeed())
+			Expect(fakeBandwidthManager.EnforcedLimits).To(ContainElement(limits))
+		})
+
+		It("emits a limits changed event", func() {
+			Expect(container.LimitDetailedBandwidth(limits)).To(Succeed())
+
+			Expect(fakeEvents.EmitCallCount()).To(Equal(1))
+			Expect(fakeEvents.EmitArgsForCall(0).Data).To(Equal(map[string]string{"limit": "bandwidth"}))
+		})
+
+		Context("when setting the limits fails", func() {
+			disaster := errors.New("oh no!")
+
+			BeforeEach(func() {
+				fakeBandwidthManager.SetLimitsError = disaster
+			})
+
+			It("returns the error", func() {
+				Expect(container.LimitDetailedBandwidth(limits)).To(Equal(disaster))
+			})
+		})
+	})
+
 	Describe("Getting the current bandwidth limit", func() {
 		limits := garden.BandwidthLimits{
 			RateInBytesPerSecond:      128,
@@ -155,6 +213,27 @@ var _ = Describe("Linux containers", func() {
 				})
 			})
 		})
+
+		Context("when different limits are set in each direction", func() {
+			detailedLimits := linux_backend.BandwidthLimits{
+				InRateInBytesPerSecond:  128,
+				InBurstInBytes:          256,
+				OutRateInBytesPerSecond: 512,
+				OutBurstInBytes:         1024,
+			}
+
+			JustBeforeEach(func() {
+				Expect(container.LimitDetailedBandwidth(detailedLimits)).To(Succeed())
+			})
+
+			It("returns the inbound limits", func() {
+				Expect(container.CurrentBandwidthLimits()).To(Equal(limits))
+			})
+
+			It("returns both directions in the detailed limits", func() {
+				Expect(container.CurrentDetailedBandwidthLimits()).To(Equal(detailedLimits))
+			})
+		})
 	})
 
 	Describe("Limiting memory", func() {
@@ -169,7 +248,7 @@ var _ = Describe("Linux containers", func() {
 			Expect(fakeOomWatcher.WatchCallCount()).To(Equal(1))
 		})
 
-		It("sets memory.limit_in_bytes and then memory.memsw.limit_in_bytes", func() {
+		It("sets memory.limit_in_bytes and then memory.memsw.limit_in_bytes, with no soft limit", func() {
 			limits := garden.MemoryLimits{
 				LimitInBytes: 102400,
 			}
@@ -194,11 +273,92 @@ var _ = Describe("Linux containers", func() {
 						Name:      "memory.limit_in_bytes",
 						Value:     "102400",
 					},
+					{
+						Subsystem: "memory",
+						Name:      "memory.soft_limit_in_bytes",
+						Value:     "-1",
+					},
 				},
 			))
 
 		})
 
+		Describe("with swap, a soft limit and a kernel memory limit", func() {
+			limits := linux_backend.MemoryLimits{
+				MemoryLimits:       garden.MemoryLimits{LimitInBytes: 102400},
+				SwapInBytes:        51200,
+				SoftLimitInBytes:   81920,
+				KernelLimitInBytes: 20480,
+			}
+
+			It("allows the swap on top of the memory limit", func() {
+				Expect(container.LimitDetailedMemory(limits)).To(Succeed())
+
+				Expect(fakeCgroups.SetValues()).To(ContainElement(fake_cgroups_manager.SetValue{
+					Subsystem: "memory",
+					Name:      "memory.memsw.limit_in_bytes",
+					Value:     "153600",
+				}))
+			})
+
+			It("sets the soft and kernel memory limits", func() {
+				Expect(container.LimitDetailedMemory(limits)).To(Succeed())
+
+				Expect(fakeCgroups.SetValues()).To(ContainElement(fake_cgroups_manager.SetValue{
+					Subsystem: "memory",
+					Name:      "memory.soft_limit_in_bytes",
+					Value:     "81920",
+				}))
+
+				Expect(fakeCgroups.SetValues()).To(ContainElement(fake_cgroups_manager.SetValue{
+					Subsystem: "memory",
+					Name:      "memory.kmem.limit_in_bytes",
+					Value:     "20480",
+				}))
+			})
+
+			It("persists the limits", func() {
+				Expect(container.LimitDetailedMemory(limits)).To(Succeed())
+
+				Expect(container.ResourceSpec().Limits.Memory).To(Equal(&limits))
+			})
+
+			It("removes the kernel memory limit when it is later unset", func() {
+				Expect(container.LimitDetailedMemory(limits)).To(Succeed())
+				Expect(container.LimitMemory(garden.MemoryLimits{LimitInBytes: 102400})).To(Succeed())
+
+				Expect(fakeCgroups.SetValues()).To(ContainElement(fake_cgroups_manager.SetValue{
+					Subsystem: "memory",
+					Name:      "memory.kmem.limit_in_bytes",
+					Value:     "-1",
+				}))
+			})
+
+			Context("when setting memory.memsw.limit_in_bytes fails", func() {
+				JustBeforeEach(func() {
+					fakeCgroups.WhenSetting("memory", "memory.memsw.limit_in_bytes", func() error {
+						return errors.New("no swap accounting")
+					})
+				})
+
+				It("returns the error", func() {
+					Expect(container.LimitDetailedMemory(limits)).To(MatchError("no swap accounting"))
+				})
+			})
+
+			Context("when setting the kernel memory limit fails", func() {
+				JustBeforeEach(func() {
+					fakeCgroups.WhenSetting("memory", "memory.kmem.limit_in_bytes", func() error {
+						return errors.New("no kernel memory accounting")
+					})
+				})
+
+				It("returns the error", func() {
+					Expect(container.LimitDetailedMemory(limits)).To(MatchError("no kernel memory accounting"))
+				})
+			})
+		})
+
 		Context("when the OOM watcher calls back", func() {
 			BeforeEach(func() {
 				fakeOomWatcher.WatchStub = func(onOom func()) error {
@@ -234,6 +394,298 @@ var _ = Describe("Linux containers", func() {
 					return container.Events()
 				}).Should(ContainElement("out of memory"))
 			})
+
+			It("emits an oom event", func() {
+				err := container.LimitMemory(garden.MemoryLimits{
+					LimitInBytes: 102400,
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(fakeEvents.EmitCallCount).ShouldNot(BeZero())
+
+				event := fakeEvents.EmitArgsForCall(0)
+				Expect(event.Type).To(Equal(linux_backend.EventOutOfMemory))
+				Expect(event.Handle).To(Equal("some-handle"))
+				Expect(event.Data).To(HaveKeyWithValue("policy", "stop"))
+			})
+
+			It("leaves the kernel's OOM killer enabled", func() {
+				err := container.LimitMemory(garden.MemoryLimits{
+					LimitInBytes: 102400,
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				for _, value := range fakeCgroups.SetValues() {
+					Expect(value.Name).ToNot(Equal("memory.oom_control"))
+				}
+			})
+
+			Context("and the policy is to kill the offending process", func() {
+				BeforeEach(func() {
+					oomPolicy = linux_backend.OOMPolicyKillProcess
+
+					fakeCgroups.WhenGetting("memory", "cgroup.procs", func() (string, error) {
+						return "123\n456\n", nil
+					})
+
+					fakeOOMKiller.KillReturns(linux_container.OOMVictim{Pid: 456, Command: "java"}, nil)
+				})
+
+				It("disables the kernel's OOM killer", func() {
+					err := container.LimitMemory(garden.MemoryLimits{
+						LimitInBytes: 102400,
+					})
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeCgroups.SetValues()).To(ContainElement(fake_cgroups_manager.SetValue{
+						Subsystem: "memory",
+						Name:      "memory.oom_control",
+						Value:     "1",
+					}))
+				})
+
+				It("kills one of the container's processes", func() {
+					err := container.LimitMemory(garden.MemoryLimits{
+						LimitInBytes: 102400,
+					})
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeOOMKiller.KillCallCount()).To(Equal(1))
+					Expect(fakeOOMKiller.KillArgsForCall(0)).To(Equal([]int{123, 456}))
+				})
+
+				It("does not stop the container", func() {
+					err := container.LimitMemory(garden.MemoryLimits{
+						LimitInBytes: 102400,
+					})
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeRunner).ToNot(HaveExecutedSerially(
+						fake_command_runner.CommandSpec{
+							Path: containerDir + "/stop.sh",
+						},
+					))
+				})
+
+				It("reports the killed process", func() {
+					err := container.LimitMemory(garden.MemoryLimits{
+						LimitInBytes: 102400,
+					})
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(container.Events()).To(ContainElement("out of memory: killed process 456 (java)"))
+
+					event := fakeEvents.EmitArgsForCall(0)
+					Expect(event.Type).To(Equal(linux_backend.EventOutOfMemory))
+					Expect(event.Data).To(Equal(map[string]string{
+						"policy":  "kill-process",
+						"pid":     "456",
+						"command": "java",
+					}))
+				})
+
+				Context("when killing the process fails", func() {
+					BeforeEach(func() {
+						fakeOOMKiller.KillReturns(linux_container.OOMVictim{}, errors.New("banana"))
+					})
+
+					It("reports the error", func() {
+						err := container.LimitMemory(garden.MemoryLimits{
+							LimitInBytes: 102400,
+						})
+						Expect(err).ToNot(HaveOccurred())
+
+						event := fakeEvents.EmitArgsForCall(0)
+						Expect(event.Data).To(HaveKeyWithValue("error", "banana"))
+					})
+				})
+			})
+
+			Context("and the policy is to freeze the container", func() {
+				BeforeEach(func() {
+					oomPolicy = linux_backend.OOMPolicyFreeze
+				})
+
+				It("pauses the container without stopping it", func() {
+					Expect(container.Start()).To(Succeed())
+
+					err := container.LimitMemory(garden.MemoryLimits{
+						LimitInBytes: 102400,
+					})
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(container.State()).To(Equal(linux_backend.StatePaused))
+					Expect(fakeRunner).ToNot(HaveExecutedSerially(
+						fake_command_runner.CommandSpec{
+							Path: containerDir + "/stop.sh",
+						},
+					))
+					Expect(fakeOOMKiller.KillCallCount()).To(BeZero())
+				})
+
+				Context("when the container cannot be frozen", func() {
+					BeforeEach(func() {
+						fakeCgroups.WhenSetting("freezer", "freezer.state", func() error {
+							return errors.New("banana")
+						})
+					})
+
+					It("reports the error and stops the container", func() {
+						Expect(container.Start()).To(Succeed())
+
+						err := container.LimitMemory(garden.MemoryLimits{
+							LimitInBytes: 102400,
+						})
+						Expect(err).ToNot(HaveOccurred())
+
+						var event linux_backend.Event
+						for i := 0; i < fakeEvents.EmitCallCount(); i++ {
+							if fakeEvents.EmitArgsForCall(i).Type == linux_backend.EventOutOfMemory {
+								event = fakeEvents.EmitArgsForCall(i)
+							}
+						}
+						Expect(event.Data).To(HaveKeyWithValue("error", "container: pause: banana"))
+
+						Expect(fakeRunner).To(HaveExecutedSerially(
+							fake_command_runner.CommandSpec{
+								Path: containerDir + "/stop.sh",
+							},
+						))
+					})
+				})
+			})
+		})
+
+		Context("on the unified cgroup hierarchy", func() {
+			var cgroupsPath string
+
+			BeforeEach(func() {
+				var err error
+				cgroupsPath, err = ioutil.TempDir("", "some-unified-cgroups")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(os.MkdirAll(path.Join(cgroupsPath, "instance-some-id"), 0755)).To(Succeed())
+
+				cgroupsManager = cgroups_manager.NewUnified(cgroupsPath, "some-id")
+			})
+
+			AfterEach(func() {
+				os.RemoveAll(cgroupsPath)
+			})
+
+			Context("and the policy is to stop the container", func() {
+				BeforeEach(func() {
+					oomPolicy = linux_backend.OOMPolicyStop
+				})
+
+				It("sets the limit", func() {
+					err := container.LimitMemory(garden.MemoryLimits{
+						LimitInBytes: 102400,
+					})
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(ioutil.ReadFile(path.Join(cgroupsPath, "instance-some-id", "memory.max"))).To(Equal([]byte("102400")))
+				})
+			})
+
+			for _, policy := range []linux_backend.OOMPolicy{linux_backend.OOMPolicyKillProcess, linux_backend.OOMPolicyFreeze} {
+				policy := policy
+
+				Context("and the policy is "+string(policy), func() {
+					BeforeEach(func() {
+						oomPolicy = policy
+					})
+
+					It("refuses the policy before setting any limit", func() {
+						err := container.LimitMemory(garden.MemoryLimits{
+							LimitInBytes: 102400,
+						})
+						Expect(err).To(MatchError(linux_container.UnsupportedOOMPolicyError{
+							Policy: policy,
+							Cause:  cgroups_manager.UnsupportedError{Name: "memory.oom_control"},
+						}))
+						Expect(err.Error()).To(ContainSubstring("oom policy " + string(policy) + " is not supported"))
+
+						Expect(fakeOomWatcher.WatchCallCount()).To(BeZero())
+						Expect(path.Join(cgroupsPath, "instance-some-id", "memory.max")).ToNot(BeAnExistingFile())
+					})
+				})
+			}
+		})
+
+		Describe("validating the OOM policy", func() {
+			It("accepts every policy on the legacy hierarchy", func() {
+				for _, policy := range []linux_backend.OOMPolicy{linux_backend.OOMPolicyStop, linux_backend.OOMPolicyKillProcess, linux_backend.OOMPolicyFreeze} {
+					Expect(linux_container.ValidateOOMPolicy(policy, false)).To(Succeed())
+				}
+			})
+
+			It("accepts only the stop policy on the unified hierarchy", func() {
+				Expect(linux_container.ValidateOOMPolicy(linux_backend.OOMPolicyStop, true)).To(Succeed())
+
+				Expect(linux_container.ValidateOOMPolicy(linux_backend.OOMPolicyFreeze, true)).To(MatchError(linux_container.UnsupportedOOMPolicyError{
+					Policy: linux_backend.OOMPolicyFreeze,
+					Cause:  cgroups_manager.UnsupportedError{Name: "memory.oom_control"},
+				}))
+			})
+		})
+
+		It("starts watching for memory pressure", func() {
+			err := container.LimitMemory(garden.MemoryLimits{
+				LimitInBytes: 102400,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeOomWatcher.WatchPressureCallCount()).To(Equal(1))
+		})
+
+		Context("when the OOM watcher reports memory pressure", func() {
+			BeforeEach(func() {
+				fakeOomWatcher.WatchPressureStub = func(onPressure func(string)) error {
+					onPressure("critical")
+					return nil
+				}
+			})
+
+			It("emits a memory pressure event with the level", func() {
+				err := container.LimitMemory(garden.MemoryLimits{
+					LimitInBytes: 102400,
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeEvents.EmitCallCount()).ToNot(BeZero())
+
+				event := fakeEvents.EmitArgsForCall(0)
+				Expect(event.Type).To(Equal(linux_backend.EventMemoryPressure))
+				Expect(event.Data).To(Equal(map[string]string{"level": "critical"}))
+			})
+
+			It("does not stop the container", func() {
+				err := container.LimitMemory(garden.MemoryLimits{
+					LimitInBytes: 102400,
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeRunner).ToNot(HaveExecutedSerially(
+					fake_command_runner.CommandSpec{
+						Path: containerDir + "/stop.sh",
+					},
+				))
+			})
+		})
+
+		Context("when watching for memory pressure fails", func() {
+			BeforeEach(func() {
+				fakeOomWatcher.WatchPressureReturns(errors.New("banana"))
+			})
+
+			It("returns the error", func() {
+				err := container.LimitMemory(garden.MemoryLimits{
+					LimitInBytes: 102400,
+				})
+
+				Expect(err).To(MatchError("banana"))
+			})
 		})
 
 		Context("when setting memory.memsw.limit_in_bytes fails", func() {
@@ -361,6 +813,66 @@ var _ = Describe("Linux containers", func() {
 
 	})
 
+	Describe("Getting the current detailed memory limits", func() {
+		BeforeEach(func() {
+			fakeCgroups.WhenGetting("memory", "memory.limit_in_bytes", func() (string, error) {
+				return "102400", nil
+			})
+		})
+
+		It("reads back the swap, soft and kernel memory limits", func() {
+			fakeCgroups.WhenGetting("memory", "memory.memsw.limit_in_bytes", func() (string, error) {
+				return "153600", nil
+			})
+
+			fakeCgroups.WhenGetting("memory", "memory.soft_limit_in_bytes", func() (string, error) {
+				return "81920", nil
+			})
+
+			fakeCgroups.WhenGetting("memory", "memory.kmem.limit_in_bytes", func() (string, error) {
+				return "20480", nil
+			})
+
+			Expect(container.CurrentDetailedMemoryLimits()).To(Equal(linux_backend.MemoryLimits{
+				MemoryLimits:       garden.MemoryLimits{LimitInBytes: 102400},
+				SwapInBytes:        51200,
+				SoftLimitInBytes:   81920,
+				KernelLimitInBytes: 20480,
+			}))
+		})
+
+		It("reports unlimited settings as zero", func() {
+			for _, file := range []string{"memory.memsw.limit_in_bytes", "memory.soft_limit_in_bytes", "memory.kmem.limit_in_bytes"} {
+				fakeCgroups.WhenGetting("memory", file, func() (string, error) {
+					return "9223372036854771712", nil
+				})
+			}
+
+			Expect(container.CurrentDetailedMemoryLimits()).To(Equal(linux_backend.MemoryLimits{
+				MemoryLimits: garden.MemoryLimits{LimitInBytes: 102400},
+			}))
+		})
+
+		Context("when the kernel does not account swap or kernel memory", func() {
+			It("reports no swap or kernel memory limit", func() {
+				fakeCgroups.WhenGetting("memory", "memory.soft_limit_in_bytes", func() (string, error) {
+					return "81920", nil
+				})
+
+				for _, file := range []string{"memory.memsw.limit_in_bytes", "memory.kmem.limit_in_bytes"} {
+					fakeCgroups.WhenGetting("memory", file, func() (string, error) {
+						return "", errors.New("no such file or directory")
+					})
+				}
+
+				Expect(container.CurrentDetailedMemoryLimits()).To(Equal(linux_backend.MemoryLimits{
+					MemoryLimits:     garden.MemoryLimits{LimitInBytes: 102400},
+					SoftLimitInBytes: 81920,
+				}))
+			})
+		})
+	})
+
 	Describe("Limiting CPU", func() {
 		It("sets cpu.shares", func() {
 			limits := garden.CPULimits{
@@ -382,6 +894,20 @@ var _ = Describe("Linux containers", func() {
 
 		})
 
+		It("emits a limits-changed event", func() {
+			err := container.LimitCPU(garden.CPULimits{
+				LimitInShares: 512,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeEvents.EmitCallCount()).To(Equal(1))
+
+			event := fakeEvents.EmitArgsForCall(0)
+			Expect(event.Type).To(Equal(linux_backend.EventLimitsChanged))
+			Expect(event.Handle).To(Equal("some-handle"))
+			Expect(event.Data).To(Equal(map[string]string{"limit": "cpu"}))
+		})
+
 		Context("when setting cpu.shares fails", func() {
 			disaster := errors.New("oh no!")
 
@@ -436,6 +962,558 @@ var _ = Describe("Linux containers", func() {
 		})
 	})
 
+	Describe("Limiting CPU quota", func() {
+		var periodErr error
+
+		BeforeEach(func() {
+			periodErr = nil
+
+			fakeCgroups.WhenGetting("cpu", "cpu.cfs_period_us", func() (string, error) {
+				return "100000", periodErr
+			})
+			fakeCgroups.WhenGetting("cpu", "cpu.cfs_quota_us", func() (string, error) {
+				return "80000", nil
+			})
+		})
+
+		Context("when the period shrinks", func() {
+			It("sets the quota, then the period, never lifting the cap", func() {
+				err := container.LimitCPUQuota(linux_backend.CPUQuotaLimits{
+					PeriodInMicroseconds: 50000,
+					QuotaInMicroseconds:  25000,
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeCgroups.SetValues()).To(Equal(
+					[]fake_cgroups_manager.SetValue{
+						{
+							Subsystem: "cpu",
+							Name:      "cpu.cfs_quota_us",
+							Value:     "25000",
+						},
+						{
+							Subsystem: "cpu",
+							Name:      "cpu.cfs_period_us",
+							Value:     "50000",
+						},
+					},
+				))
+			})
+
+			Context("when setting the period fails", func() {
+				disaster := errors.New("oh no!")
+
+				BeforeEach(func() {
+					fakeCgroups.WhenSetting("cpu", "cpu.cfs_period_us", func() error {
+						return disaster
+					})
+				})
+
+				It("restores the previous quota and keeps the stored limit", func() {
+					err := container.LimitCPUQuota(linux_backend.CPUQuotaLimits{
+						PeriodInMicroseconds: 50000,
+						QuotaInMicroseconds:  25000,
+					})
+					Expect(err).To(Equal(disaster))
+
+					values := fakeCgroups.SetValues()
+					Expect(values[len(values)-1]).To(Equal(fake_cgroups_manager.SetValue{
+						Subsystem: "cpu",
+						Name:      "cpu.cfs_quota_us",
+						Value:     "80000",
+					}))
+					Expect(values).ToNot(ContainElement(fake_cgroups_manager.SetValue{
+						Subsystem: "cpu",
+						Name:      "cpu.cfs_quota_us",
+						Value:     "-1",
+					}))
+					Expect(container.LinuxContainerSpec.Limits.CPUQuota).To(BeNil())
+				})
+			})
+		})
+
+		Context("when the period grows", func() {
+			It("sets the period, then the quota, never lifting the cap", func() {
+				err := container.LimitCPUQuota(linux_backend.CPUQuotaLimits{
+					PeriodInMicroseconds: 200000,
+					QuotaInMicroseconds:  25000,
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeCgroups.SetValues()).To(Equal(
+					[]fake_cgroups_manager.SetValue{
+						{
+							Subsystem: "cpu",
+							Name:      "cpu.cfs_period_us",
+							Value:     "200000",
+						},
+						{
+							Subsystem: "cpu",
+							Name:      "cpu.cfs_quota_us",
+							Value:     "25000",
+						},
+					},
+				))
+			})
+
+			Context("when setting the quota fails", func() {
+				disaster := errors.New("oh no!")
+
+				BeforeEach(func() {
+					fakeCgroups.WhenSetting("cpu", "cpu.cfs_quota_us", func() error {
+						return disaster
+					})
+				})
+
+				It("restores the previous period and keeps the stored limit", func() {
+					err := container.LimitCPUQuota(linux_backend.CPUQuotaLimits{
+						PeriodInMicroseconds: 200000,
+						QuotaInMicroseconds:  25000,
+					})
+					Expect(err).To(Equal(disaster))
+
+					Expect(fakeCgroups.SetValues()).To(Equal(
+						[]fake_cgroups_manager.SetValue{
+							{
+								Subsystem: "cpu",
+								Name:      "cpu.cfs_period_us",
+								Value:     "200000",
+							},
+							{
+								Subsystem: "cpu",
+								Name:      "cpu.cfs_period_us",
+								Value:     "100000",
+							},
+						},
+					))
+					Expect(container.LinuxContainerSpec.Limits.CPUQuota).To(BeNil())
+				})
+			})
+		})
+
+		Context("when reading the current period fails", func() {
+			BeforeEach(func() {
+				periodErr = errors.New("oh no!")
+			})
+
+			It("returns the error without touching the cgroup", func() {
+				err := container.LimitCPUQuota(linux_backend.CPUQuotaLimits{
+					QuotaInMicroseconds: 25000,
+				})
+				Expect(err).To(MatchError("oh no!"))
+				Expect(fakeCgroups.SetValues()).To(BeEmpty())
+			})
+		})
+
+		Context("when no period is given", func() {
+			It("uses the default period", func() {
+				err := container.LimitCPUQuota(linux_backend.CPUQuotaLimits{
+					QuotaInMicroseconds: 25000,
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeCgroups.SetValues()).To(ContainElement(fake_cgroups_manager.SetValue{
+					Subsystem: "cpu",
+					Name:      "cpu.cfs_period_us",
+					Value:     "100000",
+				}))
+			})
+		})
+
+		Context("when the quota is zero", func() {
+			It("removes the cap", func() {
+				err := container.LimitCPUQuota(linux_backend.CPUQuotaLimits{
+					PeriodInMicroseconds: 50000,
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeCgroups.SetValues()).To(ContainElement(fake_cgroups_manager.SetValue{
+					Subsystem: "cpu",
+					Name:      "cpu.cfs_quota_us",
+					Value:     "-1",
+				}))
+			})
+		})
+
+		Context("when the period is out of range", func() {
+			It("returns an error without touching the cgroup", func() {
+				err := container.LimitCPUQuota(linux_backend.CPUQuotaLimits{
+					PeriodInMicroseconds: 10,
+					QuotaInMicroseconds:  25000,
+				})
+				Expect(err).To(MatchError(ContainSubstring("cpu period must be between")))
+				Expect(fakeCgroups.SetValues()).To(BeEmpty())
+			})
+		})
+
+		Context("when the quota is too small", func() {
+			It("returns an error", func() {
+				err := container.LimitCPUQuota(linux_backend.CPUQuotaLimits{
+					QuotaInMicroseconds: 10,
+				})
+				Expect(err).To(MatchError(ContainSubstring("cpu quota must be at least")))
+			})
+		})
+
+		Context("when setting the quota fails", func() {
+			disaster := errors.New("oh no!")
+
+			BeforeEach(func() {
+				fakeCgroups.WhenSetting("cpu", "cpu.cfs_quota_us", func() error {
+					return disaster
+				})
+			})
+
+			It("returns the error", func() {
+				err := container.LimitCPUQuota(linux_backend.CPUQuotaLimits{
+					QuotaInMicroseconds: 25000,
+				})
+				Expect(err).To(Equal(disaster))
+			})
+		})
+	})
+
+	Describe("Getting the current CPU quota limits", func() {
+		It("returns the period and quota", func() {
+			fakeCgroups.WhenGetting("cpu", "cpu.cfs_period_us", func() (string, error) {
+				return "100000", nil
+			})
+			fakeCgroups.WhenGetting("cpu", "cpu.cfs_quota_us", func() (string, error) {
+				return "50000", nil
+			})
+
+			limits, err := container.CurrentCPUQuotaLimits()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(limits).To(Equal(linux_backend.CPUQuotaLimits{
+				PeriodInMicroseconds: 100000,
+				QuotaInMicroseconds:  50000,
+			}))
+		})
+
+		Context("when the container is not capped", func() {
+			It("returns a zero quota", func() {
+				fakeCgroups.WhenGetting("cpu", "cpu.cfs_period_us", func() (string, error) {
+					return "100000", nil
+				})
+				fakeCgroups.WhenGetting("cpu", "cpu.cfs_quota_us", func() (string, error) {
+					return "-1", nil
+				})
+
+				limits, err := container.CurrentCPUQuotaLimits()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(limits.QuotaInMicroseconds).To(BeZero())
+			})
+		})
+	})
+
+	Describe("Limiting the cpuset", func() {
+		It("sets the memory nodes, then the cpus", func() {
+			err := container.LimitCPUSet(linux_backend.CPUSetLimits{
+				CPUs: "0-1,4",
+				Mems: "0",
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCgroups.SetValues()).To(Equal(
+				[]fake_cgroups_manager.SetValue{
+					{
+						Subsystem: "cpuset",
+						Name:      "cpuset.mems",
+						Value:     "0",
+					},
+					{
+						Subsystem: "cpuset",
+						Name:      "cpuset.cpus",
+						Value:     "0-1,4",
+					},
+				},
+			))
+		})
+
+		Context("when no memory nodes are given", func() {
+			It("leaves the inherited memory nodes alone", func() {
+				err := container.LimitCPUSet(linux_backend.CPUSetLimits{CPUs: "2"})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeCgroups.SetValues()).To(Equal(
+					[]fake_cgroups_manager.SetValue{
+						{
+							Subsystem: "cpuset",
+							Name:      "cpuset.cpus",
+							Value:     "2",
+						},
+					},
+				))
+			})
+		})
+
+		Context("when the cpus are malformed", func() {
+			It("returns an error without touching the cgroup", func() {
+				err := container.LimitCPUSet(linux_backend.CPUSetLimits{CPUs: "3-1"})
+				Expect(err).To(HaveOccurred())
+				Expect(fakeCgroups.SetValues()).To(BeEmpty())
+			})
+		})
+
+		Context("when no cpus are given", func() {
+			It("returns an error", func() {
+				err := container.LimitCPUSet(linux_backend.CPUSetLimits{Mems: "0"})
+				Expect(err).To(MatchError(ContainSubstring("at least one cpu")))
+			})
+		})
+
+		Context("when setting the cpus fails", func() {
+			disaster := errors.New("oh no!")
+
+			BeforeEach(func() {
+				fakeCgroups.WhenSetting("cpuset", "cpuset.cpus", func() error {
+					return disaster
+				})
+			})
+
+			It("returns the error", func() {
+				err := container.LimitCPUSet(linux_backend.CPUSetLimits{CPUs: "0"})
+				Expect(err).To(Equal(disaster))
+			})
+		})
+	})
+
+	Describe("Getting the current cpuset limits", func() {
+		It("returns the cpus and memory nodes, and whether they are exclusive", func() {
+			Expect(container.LimitCPUSet(linux_backend.CPUSetLimits{
+				CPUs:      "0-1",
+				Mems:      "0",
+				Exclusive: true,
+			})).To(Succeed())
+
+			limits, err := container.CurrentCPUSetLimits()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(limits).To(Equal(linux_backend.CPUSetLimits{
+				CPUs:      "0-1",
+				Mems:      "0",
+				Exclusive: true,
+			}))
+		})
+
+		Context("when getting the cpus fails", func() {
+			It("returns the error", func() {
+				disaster := errors.New("oh no!")
+				fakeCgroups.WhenGetting("cpuset", "cpuset.cpus", func() (string, error) {
+					return "", disaster
+				})
+
+				_, err := container.CurrentCPUSetLimits()
+				Expect(err).To(Equal(disaster))
+			})
+		})
+	})
+
+	Describe("Limiting block io", func() {
+		It("sets the weight and the throttles for each device", func() {
+			err := container.LimitIO(linux_backend.IOLimits{
+				Weight: 500,
+				Devices: []linux_backend.DeviceIOLimits{
+					{
+						Device:              "8:0",
+						ReadBytesPerSecond:  1048576,
+						WriteBytesPerSecond: 2097152,
+						ReadIOPerSecond:     100,
+						WriteIOPerSecond:    200,
+					},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCgroups.SetValues()).To(Equal(
+				[]fake_cgroups_manager.SetValue{
+					{Subsystem: "blkio", Name: "blkio.weight", Value: "500"},
+					{Subsystem: "blkio", Name: "blkio.throttle.read_bps_device", Value: "8:0 1048576"},
+					{Subsystem: "blkio", Name: "blkio.throttle.write_bps_device", Value: "8:0 2097152"},
+					{Subsystem: "blkio", Name: "blkio.throttle.read_iops_device", Value: "8:0 100"},
+					{Subsystem: "blkio", Name: "blkio.throttle.write_iops_device", Value: "8:0 200"},
+				},
+			))
+		})
+
+		Context("when a previously throttled device is no longer limited", func() {
+			It("clears its throttles", func() {
+				err := container.LimitIO(linux_backend.IOLimits{
+					Devices: []linux_backend.DeviceIOLimits{
+						{Device: "8:16", ReadBytesPerSecond: 1024},
+					},
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				err = container.LimitIO(linux_backend.IOLimits{})
+				Expect(err).ToNot(HaveOccurred())
+
+				values := fakeCgroups.SetValues()
+				Expect(values[len(values)-4:]).To(Equal(
+					[]fake_cgroups_manager.SetValue{
+						{Subsystem: "blkio", Name: "blkio.throttle.read_bps_device", Value: "8:16 0"},
+						{Subsystem: "blkio", Name: "blkio.throttle.write_bps_device", Value: "8:16 0"},
+						{Subsystem: "blkio", Name: "blkio.throttle.read_iops_device", Value: "8:16 0"},
+						{Subsystem: "blkio", Name: "blkio.throttle.write_iops_device", Value: "8:16 0"},
+					},
+				))
+			})
+		})
+
+		Context("when the weight is zero", func() {
+			It("leaves the weight unchanged and keeps it in the snapshot", func() {
+				err := container.LimitIO(linux_backend.IOLimits{Weight: 500})
+				Expect(err).ToNot(HaveOccurred())
+
+				err = container.LimitIO(linux_backend.IOLimits{
+					Devices: []linux_backend.DeviceIOLimits{{Device: "8:0", ReadBytesPerSecond: 1024}},
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeCgroups.SetValues()).To(HaveLen(5))
+				Expect(container.LinuxContainerSpec.Limits.IO.Weight).To(Equal(uint64(500)))
+			})
+		})
+
+		Context("when the weight is out of range", func() {
+			It("returns an error without touching the cgroup", func() {
+				err := container.LimitIO(linux_backend.IOLimits{Weight: 5})
+				Expect(err).To(MatchError(ContainSubstring("io weight must be between")))
+				Expect(fakeCgroups.SetValues()).To(BeEmpty())
+			})
+		})
+
+		Context("when a device is malformed", func() {
+			It("returns an error without touching the cgroup", func() {
+				err := container.LimitIO(linux_backend.IOLimits{
+					Devices: []linux_backend.DeviceIOLimits{{Device: "/dev/sda"}},
+				})
+				Expect(err).To(MatchError(ContainSubstring("invalid block device")))
+				Expect(fakeCgroups.SetValues()).To(BeEmpty())
+			})
+		})
+
+		Context("when setting a throttle fails", func() {
+			disaster := errors.New("oh no!")
+
+			BeforeEach(func() {
+				fakeCgroups.WhenSetting("blkio", "blkio.throttle.write_bps_device", func() error {
+					return disaster
+				})
+			})
+
+			It("returns the error", func() {
+				err := container.LimitIO(linux_backend.IOLimits{
+					Devices: []linux_backend.DeviceIOLimits{{Device: "8:0", WriteBytesPerSecond: 1}},
+				})
+				Expect(err).To(Equal(disaster))
+			})
+		})
+	})
+
+	Describe("Getting the current block io limits", func() {
+		It("returns the weight and the throttles for each device", func() {
+			fakeCgroups.WhenGetting("blkio", "blkio.weight", func() (string, error) {
+				return "500", nil
+			})
+			fakeCgroups.WhenGetting("blkio", "blkio.throttle.read_bps_device", func() (string, error) {
+				return "8:0 1048576\n8:16 1024", nil
+			})
+			fakeCgroups.WhenGetting("blkio", "blkio.throttle.write_iops_device", func() (string, error) {
+				return "8:0 200", nil
+			})
+
+			limits, err := container.CurrentIOLimits()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(limits).To(Equal(linux_backend.IOLimits{
+				Weight: 500,
+				Devices: []linux_backend.DeviceIOLimits{
+					{Device: "8:0", ReadBytesPerSecond: 1048576, WriteIOPerSecond: 200},
+					{Device: "8:16", ReadBytesPerSecond: 1024},
+				},
+			}))
+		})
+
+		Context("when the weight is malformed", func() {
+			It("returns the error", func() {
+				fakeCgroups.WhenGetting("blkio", "blkio.weight", func() (string, error) {
+					return "fifty", nil
+				})
+
+				_, err := container.CurrentIOLimits()
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Limiting pids", func() {
+		It("sets pids.max", func() {
+			err := container.LimitPids(linux_backend.PidLimits{Max: 1024})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCgroups.SetValues()).To(Equal(
+				[]fake_cgroups_manager.SetValue{
+					{
+						Subsystem: "pids",
+						Name:      "pids.max",
+						Value:     "1024",
+					},
+				},
+			))
+		})
+
+		Context("when the max is zero", func() {
+			It("removes the cap", func() {
+				err := container.LimitPids(linux_backend.PidLimits{})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeCgroups.SetValues()).To(ContainElement(fake_cgroups_manager.SetValue{
+					Subsystem: "pids",
+					Name:      "pids.max",
+					Value:     "max",
+				}))
+			})
+		})
+
+		Context("when setting pids.max fails", func() {
+			disaster := errors.New("oh no!")
+
+			BeforeEach(func() {
+				fakeCgroups.WhenSetting("pids", "pids.max", func() error {
+					return disaster
+				})
+			})
+
+			It("returns the error", func() {
+				err := container.LimitPids(linux_backend.PidLimits{Max: 1024})
+				Expect(err).To(Equal(disaster))
+			})
+		})
+	})
+
+	Describe("Getting the current pid limits", func() {
+		It("returns the max", func() {
+			fakeCgroups.WhenGetting("pids", "pids.max", func() (string, error) {
+				return "1024", nil
+			})
+
+			limits, err := container.CurrentPidLimits()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(limits).To(Equal(linux_backend.PidLimits{Max: 1024}))
+		})
+
+		Context("when the container is not capped", func() {
+			It("returns a zero max", func() {
+				fakeCgroups.WhenGetting("pids", "pids.max", func() (string, error) {
+					return "max", nil
+				})
+
+				limits, err := container.CurrentPidLimits()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(limits.Max).To(BeZero())
+			})
+		})
+	})
+
 	Describe("Limiting disk", func() {
 		limits := garden.DiskLimits{
 			InodeSoft: 13,
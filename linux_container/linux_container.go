@@ -20,7 +20,9 @@ import (
 	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
 	"github.com/cloudfoundry-incubator/garden-linux/logging"
 	"github.com/cloudfoundry-incubator/garden-linux/network"
+	"github.com/cloudfoundry-incubator/garden-linux/network/devices"
 	"github.com/cloudfoundry-incubator/garden-linux/network/subnets"
+	"github.com/cloudfoundry-incubator/garden-linux/port_pool"
 	"github.com/cloudfoundry-incubator/garden-linux/process_tracker"
 	"github.com/cloudfoundry/gunk/command_runner"
 	"github.com/pivotal-golang/lager"
@@ -36,6 +38,54 @@ func (err UndefinedPropertyError) Error() string {
 	return fmt.Sprintf("property does not exist: %s", err.Key)
 }
 
+type UndefinedNetInError struct {
+	HostIP        net.IP
+	HostPort      uint32
+	ContainerPort uint32
+	Protocol      linux_backend.NetInProtocol
+}
+
+func (err UndefinedNetInError) Error() string {
+	if err.HostIP != nil {
+		return fmt.Sprintf("net in does not exist: %s:%d:%d/%s", err.HostIP, err.HostPort, err.ContainerPort, err.Protocol)
+	}
+
+	return fmt.Sprintf("net in does not exist: %d:%d/%s", err.HostPort, err.ContainerPort, err.Protocol)
+}
+
+type InvalidNetInHostIPError struct {
+	HostIP net.IP
+}
+
+func (err InvalidNetInHostIPError) Error() string {
+	return fmt.Sprintf("invalid net in host ip: %s", err.HostIP)
+}
+
+type InvalidNetInProtocolError struct {
+	Protocol linux_backend.NetInProtocol
+}
+
+func (err InvalidNetInProtocolError) Error() string {
+	return fmt.Sprintf("invalid net in protocol: %s", err.Protocol)
+}
+
+type UnsupportedOOMPolicyError struct {
+	Policy linux_backend.OOMPolicy
+	Cause  error
+}
+
+func (err UnsupportedOOMPolicyError) Error() string {
+	return fmt.Sprintf("oom policy %s is not supported on this host: %s", err.Policy, err.Cause)
+}
+
+type UndefinedNetOutRuleError struct {
+	Rule garden.NetOutRule
+}
+
+func (err UndefinedNetOutRuleError) Error() string {
+	return fmt.Sprintf("net out rule does not exist: %+v", err.Rule)
+}
+
 //go:generate counterfeiter -o fake_iptables_manager/fake_iptables_manager.go . IPTablesManager
 type IPTablesManager interface {
 	ContainerSetup(containerID, bridgeName string, ip net.IP, network *net.IPNet) error
@@ -53,7 +103,7 @@ type QuotaManager interface {
 
 //go:generate counterfeiter -o fake_network_statisticser/fake_network_statisticser.go . NetworkStatisticser
 type NetworkStatisticser interface {
-	Statistics() (stats garden.ContainerNetworkStat, err error)
+	Statistics() (stats devices.LinkStatistics, err error)
 }
 
 //go:generate counterfeiter -o fake_watcher/fake_watcher.go . Watcher
@@ -62,9 +112,31 @@ type Watcher interface {
 	Unwatch()
 }
 
+//go:generate counterfeiter -o fake_oom_killer/fake_oom_killer.go . OOMKiller
+type OOMKiller interface {
+	Kill(pids []int) (OOMVictim, error)
+}
+
+//go:generate counterfeiter -o fake_fd_counter/fake_fd_counter.go . FileDescriptorCounter
+type FileDescriptorCounter interface {
+	Count(pids []int) (uint64, error)
+}
+
+//go:generate counterfeiter -o fake_conntrack_counter/fake_conntrack_counter.go . ConntrackCounter
+type ConntrackCounter interface {
+	Count(ip net.IP) (uint64, error)
+}
+
+//go:generate counterfeiter -o fake_watcher/fake_memory_watcher.go . MemoryWatcher
+type MemoryWatcher interface {
+	Watcher
+	WatchPressure(func(level string)) error
+}
+
 type BandwidthManager interface {
-	SetLimits(lager.Logger, garden.BandwidthLimits) error
+	SetLimits(lager.Logger, linux_backend.BandwidthLimits) error
 	GetLimits(lager.Logger) (garden.ContainerBandwidthStat, error)
+	GetUsage(lager.Logger) (linux_backend.ContainerBandwidthUsageStat, error)
 }
 
 type CgroupsManager interface {
@@ -81,9 +153,12 @@ type LinuxContainer struct {
 	diskMutex       sync.RWMutex
 	memoryMutex     sync.RWMutex
 	cpuMutex        sync.RWMutex
+	ioMutex         sync.RWMutex
+	pidsMutex       sync.RWMutex
 	netInsMutex     sync.RWMutex
 	netOutsMutex    sync.RWMutex
 	graceTimeMutex  sync.RWMutex
+	freezerMutex    sync.Mutex
 	linux_backend.LinuxContainerSpec
 
 	portPool         PortPool
@@ -99,10 +174,15 @@ type LinuxContainer struct {
 	graceTime time.Duration
 
 	oomWatcher Watcher
+	oomKiller  OOMKiller
+	fdCounter  FileDescriptorCounter
 
 	mtu uint32
 
-	netStats NetworkStatisticser
+	netStats         NetworkStatisticser
+	conntrackCounter ConntrackCounter
+
+	events linux_backend.EventEmitter
 
 	logger lager.Logger
 }
@@ -130,9 +210,9 @@ func (p *ProcessIDPool) Restore(id uint32) {
 }
 
 type PortPool interface {
-	Acquire() (uint32, error)
-	Remove(uint32) error
-	Release(uint32)
+	Acquire(...port_pool.Protocol) (uint32, error)
+	Remove(uint32, ...port_pool.Protocol) error
+	Release(uint32, ...port_pool.Protocol)
 }
 
 func NewLinuxContainer(
@@ -147,6 +227,10 @@ func NewLinuxContainer(
 	ipTablesManager IPTablesManager,
 	netStats NetworkStatisticser,
 	oomWatcher Watcher,
+	oomKiller OOMKiller,
+	fdCounter FileDescriptorCounter,
+	conntrackCounter ConntrackCounter,
+	events linux_backend.EventEmitter,
 	logger lager.Logger,
 ) *LinuxContainer {
 	return &LinuxContainer{
@@ -162,9 +246,13 @@ func NewLinuxContainer(
 		ipTablesManager:  ipTablesManager,
 		processIDPool:    &ProcessIDPool{},
 		netStats:         netStats,
+		conntrackCounter: conntrackCounter,
 		graceTime:        spec.GraceTime,
 
 		oomWatcher: oomWatcher,
+		oomKiller:  oomKiller,
+		fdCounter:  fdCounter,
+		events:     events,
 		logger:     logger,
 	}
 }
@@ -228,9 +316,15 @@ func (c *LinuxContainer) Snapshot(out io.Writer) error {
 	c.diskMutex.RLock()
 	defer c.diskMutex.RUnlock()
 
+	c.ioMutex.RLock()
+	defer c.ioMutex.RUnlock()
+
 	c.memoryMutex.RLock()
 	defer c.memoryMutex.RUnlock()
 
+	c.pidsMutex.RLock()
+	defer c.pidsMutex.RUnlock()
+
 	c.netInsMutex.RLock()
 	defer c.netInsMutex.RUnlock()
 
@@ -255,7 +349,7 @@ func (c *LinuxContainer) Snapshot(out io.Writer) error {
 		Handle:     c.Handle(),
 		RootFSPath: c.RootFSPath(),
 
-		GraceTime: c.LinuxContainerSpec.GraceTime,
+		GraceTime: c.GraceTime(),
 
 		State:  string(c.State()),
 		Events: c.Events(),
@@ -263,15 +357,20 @@ func (c *LinuxContainer) Snapshot(out io.Writer) error {
 		Limits: linux_backend.Limits{
 			Bandwidth: c.LinuxContainerSpec.Limits.Bandwidth,
 			CPU:       c.LinuxContainerSpec.Limits.CPU,
+			CPUQuota:  c.LinuxContainerSpec.Limits.CPUQuota,
+			CPUSet:    c.LinuxContainerSpec.Limits.CPUSet,
 			Disk:      c.LinuxContainerSpec.Limits.Disk,
+			IO:        c.LinuxContainerSpec.Limits.IO,
 			Memory:    c.LinuxContainerSpec.Limits.Memory,
+			Pids:      c.LinuxContainerSpec.Limits.Pids,
 		},
 
 		Resources: ResourcesSnapshot{
-			RootUID: c.Resources.RootUID,
-			Network: c.Resources.Network,
-			Bridge:  c.Resources.Bridge,
-			Ports:   c.Resources.Ports,
+			RootUID:  c.Resources.RootUID,
+			Network:  c.Resources.Network,
+			Bridge:   c.Resources.Bridge,
+			Ports:    c.Resources.Ports,
+			UDPPorts: c.Resources.UDPPorts,
 		},
 
 		NetIns:  c.NetIns,
@@ -282,6 +381,8 @@ func (c *LinuxContainer) Snapshot(out io.Writer) error {
 
 		Properties: properties,
 
+		OOMPolicy: c.OOMPolicy,
+
 		EnvVars: c.Env,
 	}
 
@@ -316,13 +417,48 @@ func (c *LinuxContainer) Restore(snapshot linux_backend.LinuxContainerSpec) erro
 	}
 
 	if snapshot.Limits.Memory != nil {
-		err := c.LimitMemory(*snapshot.Limits.Memory)
+		err := c.LimitDetailedMemory(*snapshot.Limits.Memory)
 		if err != nil {
 			cLog.Error("failed-to-limit-memory", err)
 			return err
 		}
 	}
 
+	if snapshot.Limits.CPU != nil {
+		if err := c.LimitCPU(*snapshot.Limits.CPU); err != nil {
+			cLog.Error("failed-to-limit-cpu", err)
+			return err
+		}
+	}
+
+	if snapshot.Limits.CPUQuota != nil {
+		if err := c.LimitCPUQuota(*snapshot.Limits.CPUQuota); err != nil {
+			cLog.Error("failed-to-limit-cpu-quota", err)
+			return err
+		}
+	}
+
+	if snapshot.Limits.CPUSet != nil {
+		if err := c.LimitCPUSet(*snapshot.Limits.CPUSet); err != nil {
+			cLog.Error("failed-to-limit-cpuset", err)
+			return err
+		}
+	}
+
+	if snapshot.Limits.IO != nil {
+		if err := c.LimitIO(*snapshot.Limits.IO); err != nil {
+			cLog.Error("failed-to-limit-io", err)
+			return err
+		}
+	}
+
+	if snapshot.Limits.Pids != nil {
+		if err := c.LimitPids(*snapshot.Limits.Pids); err != nil {
+			cLog.Error("failed-to-limit-pids", err)
+			return err
+		}
+	}
+
 	signaller := c.processSignaller()
 
 	for _, process := range snapshot.Processes {
@@ -334,18 +470,37 @@ func (c *LinuxContainer) Restore(snapshot linux_backend.LinuxContainerSpec) erro
 		c.processTracker.Restore(fmt.Sprintf("%d", process.ID), signaller)
 	}
 
+	if snapshot.State == linux_backend.StatePaused {
+		if err := c.setFreezerState(freezerFrozen); err != nil {
+			cLog.Error("failed-to-refreeze", err)
+			return err
+		}
+	}
+
 	if err := c.ipTablesManager.ContainerSetup(snapshot.ID, snapshot.Resources.Bridge, snapshot.Resources.Network.IP, snapshot.Resources.Network.Subnet); err != nil {
 		cLog.Error("failed-to-reenforce-network-rules", err)
 		return err
 	}
 
+	// the container is created with the mappings of the snapshot, which
+	// DetailedNetIn appends again as it re-applies them
+	c.netInsMutex.Lock()
+	c.NetIns = nil
+	c.netInsMutex.Unlock()
+
 	for _, in := range snapshot.NetIns {
-		if _, _, err := c.NetIn(in.HostPort, in.ContainerPort); err != nil {
+		if _, err := c.DetailedNetIn(in); err != nil {
 			cLog.Error("failed-to-reenforce-port-mapping", err)
 			return err
 		}
 	}
 
+	// the container is created with the rules of the snapshot, which NetOut
+	// appends again as it re-applies them
+	c.netOutsMutex.Lock()
+	c.NetOuts = nil
+	c.netOutsMutex.Unlock()
+
 	for _, out := range snapshot.NetOuts {
 		if err := c.NetOut(out); err != nil {
 			cLog.Error("failed-to-reenforce-net-out", err)
@@ -410,6 +565,7 @@ func (c *LinuxContainer) Start() error {
 	cLog.Debug("wshd-start-ended")
 
 	c.setState(linux_backend.StateActive)
+	c.emitEvent(linux_backend.EventStarted, nil)
 
 	cLog.Debug("ended")
 	return nil
@@ -426,6 +582,17 @@ func (c *LinuxContainer) Cleanup() error {
 }
 
 func (c *LinuxContainer) Stop(kill bool) error {
+	// keep the container from being paused again until it is stopped
+	c.freezerMutex.Lock()
+	defer c.freezerMutex.Unlock()
+
+	if c.State() == linux_backend.StatePaused {
+		// frozen processes cannot act on signals, so thaw them first
+		if err := c.resume(); err != nil {
+			return err
+		}
+	}
+
 	stop := exec.Command(path.Join(c.ContainerPath, "stop.sh"))
 	if kill {
 		stop.Args = append(stop.Args, "-w", "0")
@@ -440,6 +607,7 @@ func (c *LinuxContainer) Stop(kill bool) error {
 	}
 
 	c.setState(linux_backend.StateStopped)
+	c.emitEvent(linux_backend.EventStopped, nil)
 
 	return nil
 }
@@ -651,39 +819,156 @@ func (c *LinuxContainer) StreamOut(spec garden.StreamOutSpec) (io.ReadCloser, er
 }
 
 func (c *LinuxContainer) NetIn(hostPort uint32, containerPort uint32) (uint32, uint32, error) {
-	if hostPort == 0 {
-		randomPort, err := c.portPool.Acquire()
+	spec, err := c.DetailedNetIn(linux_backend.NetInSpec{
+		HostPort:      hostPort,
+		ContainerPort: containerPort,
+		Protocol:      linux_backend.NetInProtocolTCP,
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return spec.HostPort, spec.ContainerPort, nil
+}
+
+// DetailedNetIn is NetIn for any protocol and host address. An empty protocol
+// means TCP. A zero host port is acquired from the port pool for each of the
+// protocols, and it returns the spec as it was applied.
+func (c *LinuxContainer) DetailedNetIn(spec linux_backend.NetInSpec) (linux_backend.NetInSpec, error) {
+	if spec.Protocol == "" {
+		spec.Protocol = linux_backend.NetInProtocolTCP
+	}
+
+	if !spec.Protocol.Valid() {
+		return linux_backend.NetInSpec{}, InvalidNetInProtocolError{spec.Protocol}
+	}
+
+	// the nat table only handles IPv4
+	if spec.HostIP != nil && spec.HostIP.To4() == nil {
+		return linux_backend.NetInSpec{}, InvalidNetInHostIPError{spec.HostIP}
+	}
+
+	if spec.HostPort == 0 {
+		randomPort, err := c.portPool.Acquire(poolProtocols(spec.Protocol)...)
 		if err != nil {
-			return 0, 0, err
+			return linux_backend.NetInSpec{}, err
 		}
 
-		c.Resources.AddPort(randomPort)
+		if spec.Protocol.Includes(linux_backend.NetInProtocolTCP) {
+			c.Resources.AddPort(randomPort)
+		}
+
+		if spec.Protocol.Includes(linux_backend.NetInProtocolUDP) {
+			c.Resources.AddUDPPort(randomPort)
+		}
 
-		hostPort = randomPort
+		spec.HostPort = randomPort
 	}
 
-	if containerPort == 0 {
-		containerPort = hostPort
+	if spec.ContainerPort == 0 {
+		spec.ContainerPort = spec.HostPort
 	}
 
 	net := exec.Command(path.Join(c.ContainerPath, "net.sh"), "in")
-	net.Env = []string{
-		fmt.Sprintf("HOST_PORT=%d", hostPort),
-		fmt.Sprintf("CONTAINER_PORT=%d", containerPort),
-		"PATH=" + os.Getenv("PATH"),
-	}
+	net.Env = netInEnv(spec)
 
 	err := c.runner.Run(net)
 	if err != nil {
-		return 0, 0, err
+		return linux_backend.NetInSpec{}, err
 	}
 
 	c.netInsMutex.Lock()
 	defer c.netInsMutex.Unlock()
 
-	c.NetIns = append(c.NetIns, linux_backend.NetInSpec{hostPort, containerPort})
+	c.NetIns = append(c.NetIns, spec)
+
+	return spec, nil
+}
+
+// RemoveNetIn removes a mapping added by NetIn or DetailedNetIn. A zero
+// container port stands for the host port and an empty protocol for TCP, as
+// in DetailedNetIn. A host port acquired from the port pool goes back to the
+// pool of each protocol once no mapping uses it for that protocol.
+func (c *LinuxContainer) RemoveNetIn(spec linux_backend.NetInSpec) error {
+	if spec.ContainerPort == 0 {
+		spec.ContainerPort = spec.HostPort
+	}
+
+	if spec.Protocol == "" {
+		spec.Protocol = linux_backend.NetInProtocolTCP
+	}
+
+	c.netInsMutex.Lock()
+	defer c.netInsMutex.Unlock()
+
+	index := -1
+	tcpInUse := false
+	udpInUse := false
+	for i, in := range c.NetIns {
+		protocol := in.Protocol
+		if protocol == "" {
+			protocol = linux_backend.NetInProtocolTCP
+		}
+
+		if index < 0 && in.HostPort == spec.HostPort && in.ContainerPort == spec.ContainerPort && protocol == spec.Protocol && sameIP(in.HostIP, spec.HostIP) {
+			index = i
+		} else if in.HostPort == spec.HostPort {
+			tcpInUse = tcpInUse || protocol.Includes(linux_backend.NetInProtocolTCP)
+			udpInUse = udpInUse || protocol.Includes(linux_backend.NetInProtocolUDP)
+		}
+	}
+
+	if index < 0 {
+		return UndefinedNetInError{spec.HostIP, spec.HostPort, spec.ContainerPort, spec.Protocol}
+	}
+
+	net := exec.Command(path.Join(c.ContainerPath, "net.sh"), "in_remove")
+	net.Env = netInEnv(spec)
+
+	if err := c.runner.Run(net); err != nil {
+		return err
+	}
+
+	netIns := make([]linux_backend.NetInSpec, 0, len(c.NetIns)-1)
+	netIns = append(netIns, c.NetIns[:index]...)
+	c.NetIns = append(netIns, c.NetIns[index+1:]...)
 
-	return hostPort, containerPort, nil
+	if spec.Protocol.Includes(linux_backend.NetInProtocolTCP) && !tcpInUse && c.Resources.RemovePort(spec.HostPort) {
+		c.portPool.Release(spec.HostPort, port_pool.TCP)
+	}
+
+	if spec.Protocol.Includes(linux_backend.NetInProtocolUDP) && !udpInUse && c.Resources.RemoveUDPPort(spec.HostPort) {
+		c.portPool.Release(spec.HostPort, port_pool.UDP)
+	}
+
+	return nil
+}
+
+// netInEnv leaves HOST_IP out for mappings on the external IP, which net.sh
+// defaults to.
+func netInEnv(spec linux_backend.NetInSpec) []string {
+	env := []string{
+		fmt.Sprintf("HOST_PORT=%d", spec.HostPort),
+		fmt.Sprintf("CONTAINER_PORT=%d", spec.ContainerPort),
+		fmt.Sprintf("PROTOCOL=%s", spec.Protocol),
+	}
+
+	if spec.HostIP != nil {
+		env = append(env, "HOST_IP="+spec.HostIP.String())
+	}
+
+	return append(env, "PATH="+os.Getenv("PATH"))
+}
+
+func poolProtocols(protocol linux_backend.NetInProtocol) []port_pool.Protocol {
+	switch protocol {
+	case linux_backend.NetInProtocolUDP:
+		return []port_pool.Protocol{port_pool.UDP}
+	case linux_backend.NetInProtocolAll:
+		return []port_pool.Protocol{port_pool.TCP, port_pool.UDP}
+	default:
+		return []port_pool.Protocol{port_pool.TCP}
+	}
 }
 
 func (c *LinuxContainer) NetOut(r garden.NetOutRule) error {
@@ -700,6 +985,86 @@ func (c *LinuxContainer) NetOut(r garden.NetOutRule) error {
 	return nil
 }
 
+// CurrentNetOuts returns the rules added by NetOut which are in effect, oldest
+// first.
+func (c *LinuxContainer) CurrentNetOuts() []garden.NetOutRule {
+	c.netOutsMutex.RLock()
+	defer c.netOutsMutex.RUnlock()
+
+	return append([]garden.NetOutRule{}, c.NetOuts...)
+}
+
+// RemoveNetOut revokes a rule added by NetOut. If the rule was added more than
+// once, only the oldest is removed.
+func (c *LinuxContainer) RemoveNetOut(r garden.NetOutRule) error {
+	c.netOutsMutex.Lock()
+	defer c.netOutsMutex.Unlock()
+
+	for i, out := range c.NetOuts {
+		if !sameNetOutRule(out, r) {
+			continue
+		}
+
+		if err := c.filter.RemoveNetOut(out); err != nil {
+			return err
+		}
+
+		netOuts := make([]garden.NetOutRule, 0, len(c.NetOuts)-1)
+		netOuts = append(netOuts, c.NetOuts[:i]...)
+		c.NetOuts = append(netOuts, c.NetOuts[i+1:]...)
+
+		return nil
+	}
+
+	return UndefinedNetOutRuleError{r}
+}
+
+// sameNetOutRule compares rules by value, treating IPs of either length as
+// equal, as rules restored from a snapshot may differ in that.
+func sameNetOutRule(a, b garden.NetOutRule) bool {
+	if a.Protocol != b.Protocol || a.Log != b.Log {
+		return false
+	}
+
+	if len(a.Networks) != len(b.Networks) || len(a.Ports) != len(b.Ports) {
+		return false
+	}
+
+	for i := range a.Networks {
+		if !sameIP(a.Networks[i].Start, b.Networks[i].Start) || !sameIP(a.Networks[i].End, b.Networks[i].End) {
+			return false
+		}
+	}
+
+	for i := range a.Ports {
+		if a.Ports[i] != b.Ports[i] {
+			return false
+		}
+	}
+
+	if a.ICMPs == nil || b.ICMPs == nil {
+		return a.ICMPs == b.ICMPs
+	}
+
+	if a.ICMPs.Type != b.ICMPs.Type {
+		return false
+	}
+
+	if a.ICMPs.Code == nil || b.ICMPs.Code == nil {
+		return a.ICMPs.Code == b.ICMPs.Code
+	}
+
+	return *a.ICMPs.Code == *b.ICMPs.Code
+}
+
+func sameIP(a, b net.IP) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	return a.Equal(b)
+}
+
 func (c *LinuxContainer) setState(state linux_backend.State) {
 	c.stateMutex.Lock()
 	defer c.stateMutex.Unlock()
@@ -713,3 +1078,11 @@ func (c *LinuxContainer) registerEvent(event string) {
 
 	c.LinuxContainerSpec.Events = append(c.LinuxContainerSpec.Events, event)
 }
+
+func (c *LinuxContainer) emitEvent(eventType linux_backend.EventType, data map[string]string) {
+	c.events.Emit(linux_backend.Event{
+		Type:   eventType,
+		Handle: c.Handle(),
+		Data:   data,
+	})
+}
@@ -2,6 +2,7 @@ package linux_container_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"io"
 	"io/ioutil"
@@ -20,14 +21,19 @@ import (
 
 	"github.com/cloudfoundry-incubator/garden"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend/fakes"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/bandwidth_manager/fake_bandwidth_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager/fake_cgroups_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_conntrack_counter"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_fd_counter"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_iptables_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_network_statisticser"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_oom_killer"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_quota_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_watcher"
 	networkFakes "github.com/cloudfoundry-incubator/garden-linux/network/fakes"
+	"github.com/cloudfoundry-incubator/garden-linux/port_pool"
 	"github.com/cloudfoundry-incubator/garden-linux/port_pool/fake_port_pool"
 	"github.com/cloudfoundry-incubator/garden-linux/process_tracker/fake_process_tracker"
 	wfakes "github.com/cloudfoundry-incubator/garden/fakes"
@@ -49,6 +55,7 @@ var _ = Describe("Linux containers", func() {
 	var fakeFilter *networkFakes.FakeFilter
 	var fakeIPTablesManager *fake_iptables_manager.FakeIPTablesManager
 	var fakeOomWatcher *fake_watcher.FakeWatcher
+	var fakeEvents *fakes.FakeEventEmitter
 	var containerDir string
 	var containerProps map[string]string
 	var logger *lagertest.TestLogger
@@ -64,6 +71,7 @@ var _ = Describe("Linux containers", func() {
 		fakeFilter = new(networkFakes.FakeFilter)
 		fakeIPTablesManager = new(fake_iptables_manager.FakeIPTablesManager)
 		fakeOomWatcher = new(fake_watcher.FakeWatcher)
+		fakeEvents = new(fakes.FakeEventEmitter)
 
 		fakePortPool = fake_port_pool.New(1000)
 
@@ -119,6 +127,10 @@ var _ = Describe("Linux containers", func() {
 			fakeIPTablesManager,
 			new(fake_network_statisticser.FakeNetworkStatisticser),
 			fakeOomWatcher,
+			new(fake_oom_killer.FakeOOMKiller),
+			new(fake_fd_counter.FakeFileDescriptorCounter),
+			new(fake_conntrack_counter.FakeConntrackCounter),
+			fakeEvents,
 			logger,
 		)
 	})
@@ -188,6 +200,16 @@ var _ = Describe("Linux containers", func() {
 			))
 		})
 
+		It("emits a started event", func() {
+			Expect(container.Start()).To(Succeed())
+
+			Expect(fakeEvents.EmitCallCount()).To(Equal(1))
+
+			event := fakeEvents.EmitArgsForCall(0)
+			Expect(event.Type).To(Equal(linux_backend.EventStarted))
+			Expect(event.Handle).To(Equal("some-handle"))
+		})
+
 		It("changes the container's state to active", func() {
 			Expect(container.State()).To(Equal(linux_backend.StateBorn))
 
@@ -259,6 +281,13 @@ var _ = Describe("Linux containers", func() {
 
 		})
 
+		It("emits a stopped event", func() {
+			Expect(container.Stop(false)).To(Succeed())
+
+			Expect(fakeEvents.EmitCallCount()).To(Equal(1))
+			Expect(fakeEvents.EmitArgsForCall(0).Type).To(Equal(linux_backend.EventStopped))
+		})
+
 		Context("when kill is true", func() {
 			It("executes stop.sh with -w 0", func() {
 				err := container.Stop(true)
@@ -573,7 +602,7 @@ var _ = Describe("Linux containers", func() {
 	})
 
 	Describe("Net in", func() {
-		It("executes net.sh in with HOST_PORT and CONTAINER_PORT", func() {
+		It("executes net.sh in with HOST_PORT, CONTAINER_PORT and PROTOCOL", func() {
 			hostPort, containerPort, err := container.NetIn(123, 456)
 			Expect(err).ToNot(HaveOccurred())
 
@@ -584,6 +613,7 @@ var _ = Describe("Linux containers", func() {
 					Env: []string{
 						"HOST_PORT=123",
 						"CONTAINER_PORT=456",
+						"PROTOCOL=tcp",
 						"PATH=" + os.Getenv("PATH"),
 					},
 				},
@@ -635,6 +665,7 @@ var _ = Describe("Linux containers", func() {
 						Env: []string{
 							"HOST_PORT=123",
 							"CONTAINER_PORT=123",
+							"PROTOCOL=tcp",
 							"PATH=" + os.Getenv("PATH"),
 						},
 					},
@@ -656,6 +687,7 @@ var _ = Describe("Linux containers", func() {
 							Env: []string{
 								"HOST_PORT=1000",
 								"CONTAINER_PORT=1000",
+								"PROTOCOL=tcp",
 								"PATH=" + os.Getenv("PATH"),
 							},
 						},
@@ -687,6 +719,315 @@ var _ = Describe("Linux containers", func() {
 		})
 	})
 
+	Describe("Detailed net in", func() {
+		It("executes net.sh in with the protocol", func() {
+			spec, err := container.DetailedNetIn(linux_backend.NetInSpec{
+				HostPort:      123,
+				ContainerPort: 456,
+				Protocol:      linux_backend.NetInProtocolUDP,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeRunner).To(HaveExecutedSerially(
+				fake_command_runner.CommandSpec{
+					Path: containerDir + "/net.sh",
+					Args: []string{"in"},
+					Env: []string{
+						"HOST_PORT=123",
+						"CONTAINER_PORT=456",
+						"PROTOCOL=udp",
+						"PATH=" + os.Getenv("PATH"),
+					},
+				},
+			))
+
+			Expect(spec).To(Equal(linux_backend.NetInSpec{
+				HostPort:      123,
+				ContainerPort: 456,
+				Protocol:      linux_backend.NetInProtocolUDP,
+			}))
+			Expect(container.NetIns).To(Equal([]linux_backend.NetInSpec{spec}))
+		})
+
+		Context("when the protocol is empty", func() {
+			It("defaults it to tcp", func() {
+				spec, err := container.DetailedNetIn(linux_backend.NetInSpec{HostPort: 123, ContainerPort: 456})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(spec.Protocol).To(Equal(linux_backend.NetInProtocolTCP))
+			})
+		})
+
+		Context("when a host ip is given", func() {
+			It("executes net.sh in with HOST_IP", func() {
+				_, err := container.DetailedNetIn(linux_backend.NetInSpec{
+					HostIP:        net.ParseIP("0.0.0.0"),
+					HostPort:      123,
+					ContainerPort: 456,
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeRunner).To(HaveExecutedSerially(
+					fake_command_runner.CommandSpec{
+						Path: containerDir + "/net.sh",
+						Args: []string{"in"},
+						Env: []string{
+							"HOST_PORT=123",
+							"CONTAINER_PORT=456",
+							"PROTOCOL=tcp",
+							"HOST_IP=0.0.0.0",
+							"PATH=" + os.Getenv("PATH"),
+						},
+					},
+				))
+			})
+
+			Context("and it is not an IPv4 address", func() {
+				It("returns an error without running net.sh", func() {
+					_, err := container.DetailedNetIn(linux_backend.NetInSpec{HostIP: net.ParseIP("::1"), HostPort: 123})
+					Expect(err).To(MatchError(linux_container.InvalidNetInHostIPError{net.ParseIP("::1")}))
+					Expect(fakeRunner.ExecutedCommands()).To(BeEmpty())
+				})
+			})
+		})
+
+		Context("when the protocol is invalid", func() {
+			It("returns an error without running net.sh", func() {
+				_, err := container.DetailedNetIn(linux_backend.NetInSpec{HostPort: 123, Protocol: "sctp"})
+				Expect(err).To(MatchError(linux_container.InvalidNetInProtocolError{"sctp"}))
+				Expect(fakeRunner.ExecutedCommands()).To(BeEmpty())
+			})
+		})
+
+		Context("when a host port is not provided", func() {
+			It("acquires a udp port for udp", func() {
+				spec, err := container.DetailedNetIn(linux_backend.NetInSpec{ContainerPort: 456, Protocol: linux_backend.NetInProtocolUDP})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakePortPool.AcquiredProtocols).To(Equal([][]port_pool.Protocol{{port_pool.UDP}}))
+				Expect(container.Resources.UDPPorts).To(ConsistOf(spec.HostPort))
+				Expect(container.Resources.Ports).To(BeEmpty())
+			})
+
+			It("acquires a port free for both protocols for all", func() {
+				spec, err := container.DetailedNetIn(linux_backend.NetInSpec{ContainerPort: 456, Protocol: linux_backend.NetInProtocolAll})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakePortPool.AcquiredProtocols).To(Equal([][]port_pool.Protocol{{port_pool.TCP, port_pool.UDP}}))
+				Expect(container.Resources.Ports).To(ConsistOf(spec.HostPort))
+				Expect(container.Resources.UDPPorts).To(ConsistOf(spec.HostPort))
+			})
+		})
+	})
+
+	Describe("Removing a net in", func() {
+		It("executes net.sh in_remove with HOST_PORT, CONTAINER_PORT and PROTOCOL", func() {
+			_, _, err := container.NetIn(123, 456)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(container.RemoveNetIn(linux_backend.NetInSpec{HostPort: 123, ContainerPort: 456})).To(Succeed())
+
+			Expect(fakeRunner).To(HaveExecutedSerially(
+				fake_command_runner.CommandSpec{
+					Path: containerDir + "/net.sh",
+					Args: []string{"in_remove"},
+					Env: []string{
+						"HOST_PORT=123",
+						"CONTAINER_PORT=456",
+						"PROTOCOL=tcp",
+						"PATH=" + os.Getenv("PATH"),
+					},
+				},
+			))
+
+			Expect(container.NetIns).To(BeEmpty())
+		})
+
+		It("defaults the container port to the host port, as NetIn does", func() {
+			_, _, err := container.NetIn(123, 0)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(container.RemoveNetIn(linux_backend.NetInSpec{HostPort: 123, ContainerPort: 0})).To(Succeed())
+			Expect(container.NetIns).To(BeEmpty())
+		})
+
+		It("keeps the other mappings", func() {
+			_, _, err := container.NetIn(123, 456)
+			Expect(err).ToNot(HaveOccurred())
+			_, _, err = container.NetIn(789, 456)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(container.RemoveNetIn(linux_backend.NetInSpec{HostPort: 123, ContainerPort: 456})).To(Succeed())
+			Expect(container.NetIns).To(Equal([]linux_backend.NetInSpec{{HostPort: 789, ContainerPort: 456, Protocol: linux_backend.NetInProtocolTCP}}))
+		})
+
+		Context("when the host port was acquired from the port pool", func() {
+			var hostPort uint32
+
+			JustBeforeEach(func() {
+				var err error
+				hostPort, _, err = container.NetIn(0, 456)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("releases it back to the pool", func() {
+				Expect(container.RemoveNetIn(linux_backend.NetInSpec{HostPort: hostPort, ContainerPort: 456})).To(Succeed())
+
+				Expect(fakePortPool.Released).To(Equal([]uint32{hostPort}))
+				Expect(container.Resources.Ports).ToNot(ContainElement(hostPort))
+			})
+
+			Context("and another mapping still uses it", func() {
+				JustBeforeEach(func() {
+					_, _, err := container.NetIn(hostPort, 789)
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("keeps it until that mapping is removed too", func() {
+					Expect(container.RemoveNetIn(linux_backend.NetInSpec{HostPort: hostPort, ContainerPort: 456})).To(Succeed())
+					Expect(fakePortPool.Released).To(BeEmpty())
+					Expect(container.Resources.Ports).To(ContainElement(hostPort))
+
+					Expect(container.RemoveNetIn(linux_backend.NetInSpec{HostPort: hostPort, ContainerPort: 789})).To(Succeed())
+					Expect(fakePortPool.Released).To(Equal([]uint32{hostPort}))
+					Expect(container.Resources.Ports).ToNot(ContainElement(hostPort))
+				})
+			})
+		})
+
+		Context("when the host port was not acquired from the port pool", func() {
+			It("does not release it", func() {
+				_, _, err := container.NetIn(123, 456)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(container.RemoveNetIn(linux_backend.NetInSpec{HostPort: 123, ContainerPort: 456})).To(Succeed())
+				Expect(fakePortPool.Released).To(BeEmpty())
+			})
+		})
+
+		Context("when the protocol does not match", func() {
+			It("returns an error without running net.sh", func() {
+				_, _, err := container.NetIn(123, 456)
+				Expect(err).ToNot(HaveOccurred())
+
+				spec := linux_backend.NetInSpec{HostPort: 123, ContainerPort: 456, Protocol: linux_backend.NetInProtocolUDP}
+				Expect(container.RemoveNetIn(spec)).To(MatchError(linux_container.UndefinedNetInError{HostPort: 123, ContainerPort: 456, Protocol: linux_backend.NetInProtocolUDP}))
+				Expect(fakeRunner).ToNot(HaveExecutedSerially(
+					fake_command_runner.CommandSpec{
+						Path: containerDir + "/net.sh",
+						Args: []string{"in_remove"},
+					},
+				))
+			})
+		})
+
+		Context("when the mapping is bound to a host ip", func() {
+			var spec linux_backend.NetInSpec
+
+			JustBeforeEach(func() {
+				var err error
+				spec, err = container.DetailedNetIn(linux_backend.NetInSpec{
+					HostIP:        net.ParseIP("10.0.0.1"),
+					HostPort:      123,
+					ContainerPort: 456,
+				})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("executes net.sh in_remove with HOST_IP", func() {
+				Expect(container.RemoveNetIn(spec)).To(Succeed())
+
+				Expect(fakeRunner).To(HaveExecutedSerially(
+					fake_command_runner.CommandSpec{
+						Path: containerDir + "/net.sh",
+						Args: []string{"in_remove"},
+						Env: []string{
+							"HOST_PORT=123",
+							"CONTAINER_PORT=456",
+							"PROTOCOL=tcp",
+							"HOST_IP=10.0.0.1",
+							"PATH=" + os.Getenv("PATH"),
+						},
+					},
+				))
+				Expect(container.NetIns).To(BeEmpty())
+			})
+
+			It("does not remove it without the host ip", func() {
+				Expect(container.RemoveNetIn(linux_backend.NetInSpec{HostPort: 123, ContainerPort: 456})).To(MatchError(
+					linux_container.UndefinedNetInError{HostPort: 123, ContainerPort: 456, Protocol: linux_backend.NetInProtocolTCP},
+				))
+				Expect(container.NetIns).To(HaveLen(1))
+			})
+		})
+
+		Context("when the udp host port was acquired from the port pool", func() {
+			var spec linux_backend.NetInSpec
+
+			JustBeforeEach(func() {
+				var err error
+				spec, err = container.DetailedNetIn(linux_backend.NetInSpec{ContainerPort: 456, Protocol: linux_backend.NetInProtocolAll})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("releases it back to the pool of each protocol", func() {
+				Expect(container.RemoveNetIn(spec)).To(Succeed())
+
+				Expect(fakePortPool.Released).To(Equal([]uint32{spec.HostPort, spec.HostPort}))
+				Expect(fakePortPool.ReleasedProtocols).To(Equal([][]port_pool.Protocol{{port_pool.TCP}, {port_pool.UDP}}))
+				Expect(container.Resources.Ports).To(BeEmpty())
+				Expect(container.Resources.UDPPorts).To(BeEmpty())
+			})
+
+			Context("and a tcp mapping still uses it", func() {
+				JustBeforeEach(func() {
+					_, _, err := container.NetIn(spec.HostPort, 789)
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("releases it only for udp", func() {
+					Expect(container.RemoveNetIn(spec)).To(Succeed())
+
+					Expect(fakePortPool.ReleasedProtocols).To(Equal([][]port_pool.Protocol{{port_pool.UDP}}))
+					Expect(container.Resources.Ports).To(ConsistOf(spec.HostPort))
+					Expect(container.Resources.UDPPorts).To(BeEmpty())
+				})
+			})
+		})
+
+		Context("when the mapping does not exist", func() {
+			It("returns an error without running net.sh", func() {
+				Expect(container.RemoveNetIn(linux_backend.NetInSpec{HostPort: 123, ContainerPort: 456})).To(MatchError(linux_container.UndefinedNetInError{HostPort: 123, ContainerPort: 456, Protocol: linux_backend.NetInProtocolTCP}))
+				Expect(fakeRunner.ExecutedCommands()).To(BeEmpty())
+			})
+		})
+
+		Context("when net.sh fails", func() {
+			disaster := errors.New("oh no!")
+
+			JustBeforeEach(func() {
+				_, _, err := container.NetIn(0, 456)
+				Expect(err).ToNot(HaveOccurred())
+
+				fakeRunner.WhenRunning(
+					fake_command_runner.CommandSpec{
+						Path: containerDir + "/net.sh",
+						Args: []string{"in_remove"},
+					}, func(*exec.Cmd) error {
+						return disaster
+					},
+				)
+			})
+
+			It("returns the error and keeps the mapping and its port", func() {
+				Expect(container.RemoveNetIn(linux_backend.NetInSpec{HostPort: 1000, ContainerPort: 456})).To(Equal(disaster))
+				Expect(container.NetIns).To(HaveLen(1))
+				Expect(container.Resources.Ports).To(ContainElement(uint32(1000)))
+				Expect(fakePortPool.Released).To(BeEmpty())
+			})
+		})
+	})
+
 	Describe("Net out", func() {
 		It("delegates to the filter", func() {
 			rule := garden.NetOutRule{}
@@ -709,6 +1050,82 @@ var _ = Describe("Linux containers", func() {
 				err := container.NetOut(garden.NetOutRule{})
 				Expect(err).To(Equal(disaster))
 			})
+
+			It("does not record the rule", func() {
+				container.NetOut(garden.NetOutRule{})
+				Expect(container.CurrentNetOuts()).To(BeEmpty())
+			})
+		})
+
+		It("lists the rules in the order they were added", func() {
+			tcpRule := garden.NetOutRule{Protocol: garden.ProtocolTCP}
+			udpRule := garden.NetOutRule{Protocol: garden.ProtocolUDP}
+
+			Expect(container.NetOut(tcpRule)).To(Succeed())
+			Expect(container.NetOut(udpRule)).To(Succeed())
+
+			Expect(container.CurrentNetOuts()).To(Equal([]garden.NetOutRule{tcpRule, udpRule}))
+		})
+	})
+
+	Describe("Removing a net out rule", func() {
+		var tcpRule, udpRule garden.NetOutRule
+
+		JustBeforeEach(func() {
+			tcpRule = garden.NetOutRule{
+				Protocol: garden.ProtocolTCP,
+				Networks: []garden.IPRange{{Start: net.ParseIP("1.2.3.4").To4()}},
+				Ports:    []garden.PortRange{{Start: 80, End: 80}},
+			}
+			udpRule = garden.NetOutRule{Protocol: garden.ProtocolUDP}
+
+			Expect(container.NetOut(tcpRule)).To(Succeed())
+			Expect(container.NetOut(udpRule)).To(Succeed())
+		})
+
+		It("deletes the rule it was added as from the filter", func() {
+			Expect(container.RemoveNetOut(garden.NetOutRule{
+				Protocol: garden.ProtocolTCP,
+				Networks: []garden.IPRange{{Start: net.ParseIP("1.2.3.4")}},
+				Ports:    []garden.PortRange{{Start: 80, End: 80}},
+			})).To(Succeed())
+
+			Expect(fakeFilter.RemoveNetOutCallCount()).To(Equal(1))
+			Expect(fakeFilter.RemoveNetOutArgsForCall(0)).To(Equal(tcpRule))
+		})
+
+		It("no longer lists or snapshots the rule", func() {
+			Expect(container.RemoveNetOut(tcpRule)).To(Succeed())
+			Expect(container.CurrentNetOuts()).To(Equal([]garden.NetOutRule{udpRule}))
+
+			out := new(bytes.Buffer)
+			Expect(container.Snapshot(out)).To(Succeed())
+
+			var snapshot linux_container.ContainerSnapshot
+			Expect(json.NewDecoder(out).Decode(&snapshot)).To(Succeed())
+			Expect(snapshot.NetOuts).To(HaveLen(1))
+			Expect(snapshot.NetOuts[0].Protocol).To(Equal(garden.ProtocolUDP))
+		})
+
+		Context("when the rule was never added", func() {
+			It("returns an error without touching the filter", func() {
+				missing := garden.NetOutRule{Protocol: garden.ProtocolICMP}
+				Expect(container.RemoveNetOut(missing)).To(MatchError(linux_container.UndefinedNetOutRuleError{missing}))
+				Expect(fakeFilter.RemoveNetOutCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the filter fails", func() {
+			disaster := errors.New("oh no!")
+
+			JustBeforeEach(func() {
+				fakeFilter.RemoveNetOutReturns(disaster)
+			})
+
+			It("returns the error and keeps the rule", func() {
+				Expect(container.RemoveNetOut(tcpRule)).To(Equal(disaster))
+				Expect(container.CurrentNetOuts()).To(Equal([]garden.NetOutRule{tcpRule, udpRule}))
+			})
 		})
 	})
 
@@ -0,0 +1,105 @@
+package linux_container
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/lager"
+)
+
+// MemoryEventsWatcher watches for OOM kills on the unified cgroup hierarchy,
+// which has no cgroup.event_control, by polling the counters in the
+// container's memory.events.
+type MemoryEventsWatcher struct {
+	cgroupsManager CgroupsManager
+	interval       time.Duration
+	logger         lager.Logger
+
+	mutex sync.Mutex
+	done  chan struct{}
+}
+
+func NewMemoryEventsWatcher(cgroupsManager CgroupsManager, interval time.Duration, logger lager.Logger) *MemoryEventsWatcher {
+	return &MemoryEventsWatcher{
+		cgroupsManager: cgroupsManager,
+		interval:       interval,
+		logger:         logger.Session("memory-events-watcher"),
+	}
+}
+
+func (w *MemoryEventsWatcher) Watch(onOom func()) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.done != nil {
+		return nil
+	}
+
+	// only report OOM kills which happen from now on
+	kills, err := w.oomKills()
+	if err != nil {
+		return err
+	}
+
+	w.done = make(chan struct{})
+
+	go w.poll(kills, w.done, onOom)
+
+	return nil
+}
+
+func (w *MemoryEventsWatcher) Unwatch() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.done == nil {
+		return
+	}
+
+	close(w.done)
+	w.done = nil
+}
+
+func (w *MemoryEventsWatcher) poll(kills uint64, done <-chan struct{}, onOom func()) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		current, err := w.oomKills()
+		if err != nil {
+			// the cgroup has been removed
+			w.logger.Info("stopped-watching", lager.Data{"reason": err.Error()})
+			return
+		}
+
+		if current > kills {
+			onOom()
+		}
+
+		kills = current
+	}
+}
+
+func (w *MemoryEventsWatcher) oomKills() (uint64, error) {
+	events, err := w.cgroupsManager.Get("memory", "memory.events")
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range strings.Split(events, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,84 @@
+package linux_container_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager/fake_cgroups_manager"
+)
+
+var _ = Describe("MemoryEventsWatcher", func() {
+	var (
+		cgroupsManager *fake_cgroups_manager.FakeCgroupsManager
+		watcher        *linux_container.MemoryEventsWatcher
+
+		eventsMutex sync.Mutex
+		oomKills    int
+		eventsErr   error
+	)
+
+	setOOMKills := func(kills int) {
+		eventsMutex.Lock()
+		defer eventsMutex.Unlock()
+		oomKills = kills
+	}
+
+	BeforeEach(func() {
+		oomKills = 0
+		eventsErr = nil
+
+		cgroupsManager = fake_cgroups_manager.New("/cgroups", "some-id")
+		cgroupsManager.WhenGetting("memory", "memory.events", func() (string, error) {
+			eventsMutex.Lock()
+			defer eventsMutex.Unlock()
+			return fmt.Sprintf("low 0\nhigh 0\nmax 3\noom 2\noom_kill %d\n", oomKills), eventsErr
+		})
+
+		watcher = linux_container.NewMemoryEventsWatcher(cgroupsManager, 10*time.Millisecond, lagertest.NewTestLogger("test"))
+	})
+
+	AfterEach(func() {
+		watcher.Unwatch()
+	})
+
+	It("calls back when a process in the container is OOM killed", func() {
+		setOOMKills(1)
+
+		ooms := make(chan struct{}, 10)
+		Expect(watcher.Watch(func() { ooms <- struct{}{} })).To(Succeed())
+
+		Consistently(ooms).ShouldNot(Receive())
+
+		setOOMKills(2)
+		Eventually(ooms).Should(Receive())
+		Consistently(ooms).ShouldNot(Receive())
+
+		setOOMKills(3)
+		Eventually(ooms).Should(Receive())
+	})
+
+	It("stops calling back once unwatched", func() {
+		ooms := make(chan struct{}, 10)
+		Expect(watcher.Watch(func() { ooms <- struct{}{} })).To(Succeed())
+
+		watcher.Unwatch()
+
+		setOOMKills(1)
+		Consistently(ooms).ShouldNot(Receive())
+	})
+
+	Context("when memory.events cannot be read", func() {
+		It("returns an error", func() {
+			eventsErr = errors.New("banana")
+
+			Expect(watcher.Watch(func() {})).To(MatchError("banana"))
+		})
+	})
+})
@@ -0,0 +1,170 @@
+package linux_container
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/pivotal-golang/lager"
+)
+
+const (
+	PressureLow      = "low"
+	PressureMedium   = "medium"
+	PressureCritical = "critical"
+)
+
+// MemoryNotifier watches a container's memory cgroup for OOM and memory
+// pressure notifications by registering eventfds through
+// cgroup.event_control. It keeps notifying until Unwatch is called or the
+// cgroup is removed.
+type MemoryNotifier struct {
+	cgroupsManager CgroupsManager
+	logger         lager.Logger
+
+	mutex            sync.Mutex
+	done             chan struct{}
+	eventfds         []*os.File
+	watchingOom      bool
+	watchingPressure bool
+}
+
+func NewMemoryNotifier(cgroupsManager CgroupsManager, logger lager.Logger) *MemoryNotifier {
+	return &MemoryNotifier{
+		cgroupsManager: cgroupsManager,
+		logger:         logger.Session("memory-notifier"),
+	}
+}
+
+func (n *MemoryNotifier) Watch(onOom func()) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if n.watchingOom {
+		return nil
+	}
+
+	if err := n.register("memory.oom_control", "", onOom); err != nil {
+		return fmt.Errorf("linux_container: watch oom: %s", err)
+	}
+
+	n.watchingOom = true
+	return nil
+}
+
+// WatchPressure notifies of memory pressure at each level. As the kernel
+// signals every level at or below the current pressure, a critical event is
+// accompanied by low and medium events.
+func (n *MemoryNotifier) WatchPressure(onPressure func(level string)) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if n.watchingPressure {
+		return nil
+	}
+
+	for _, level := range []string{PressureLow, PressureMedium, PressureCritical} {
+		level := level
+
+		if err := n.register("memory.pressure_level", level, func() { onPressure(level) }); err != nil {
+			return fmt.Errorf("linux_container: watch memory pressure: %s", err)
+		}
+	}
+
+	n.watchingPressure = true
+	return nil
+}
+
+func (n *MemoryNotifier) Unwatch() {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if n.done == nil {
+		return
+	}
+
+	close(n.done)
+
+	// wake the readers so that they see they are done
+	for _, eventfd := range n.eventfds {
+		eventfd.Write(eventfdIncrement())
+	}
+
+	n.done = nil
+	n.eventfds = nil
+	n.watchingOom = false
+	n.watchingPressure = false
+}
+
+func (n *MemoryNotifier) register(controlFile, args string, callback func()) error {
+	memoryPath, err := n.cgroupsManager.SubsystemPath("memory")
+	if err != nil {
+		return err
+	}
+
+	control, err := os.Open(path.Join(memoryPath, controlFile))
+	if err != nil {
+		return err
+	}
+
+	// the kernel holds its own reference once the event is registered
+	defer control.Close()
+
+	eventfd, eventfdNumber, err := newEventfd()
+	if err != nil {
+		return err
+	}
+
+	registration := fmt.Sprintf("%d %d", eventfdNumber, control.Fd())
+	if args != "" {
+		registration += " " + args
+	}
+
+	if err := n.cgroupsManager.Set("memory", "cgroup.event_control", registration); err != nil {
+		eventfd.Close()
+		return err
+	}
+
+	if n.done == nil {
+		n.done = make(chan struct{})
+	}
+
+	n.eventfds = append(n.eventfds, eventfd)
+
+	go n.notify(eventfd, n.done, path.Join(memoryPath, "cgroup.event_control"), callback)
+
+	return nil
+}
+
+func (n *MemoryNotifier) notify(eventfd *os.File, done <-chan struct{}, eventControlPath string, callback func()) {
+	defer eventfd.Close()
+
+	counter := make([]byte, 8)
+	for {
+		if _, err := eventfd.Read(counter); err != nil {
+			n.logger.Error("failed-to-read-eventfd", err)
+			return
+		}
+
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		// the eventfd is also signalled when the cgroup is removed
+		if _, err := os.Stat(eventControlPath); os.IsNotExist(err) {
+			return
+		}
+
+		callback()
+	}
+}
+
+func eventfdIncrement() []byte {
+	increment := make([]byte, 8)
+	binary.LittleEndian.PutUint64(increment, 1)
+	return increment
+}
@@ -0,0 +1,193 @@
+package linux_container_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager/fake_cgroups_manager"
+)
+
+var _ = Describe("MemoryNotifier", func() {
+	var (
+		cgroupsPath    string
+		memoryPath     string
+		cgroupsManager *fake_cgroups_manager.FakeCgroupsManager
+		notifier       *linux_container.MemoryNotifier
+	)
+
+	// registrations returns the registered eventfds, keyed by the arguments
+	// they were registered with
+	registrations := func() map[string]string {
+		eventfds := map[string]string{}
+		for _, value := range cgroupsManager.SetValues() {
+			if value.Subsystem != "memory" || value.Name != "cgroup.event_control" {
+				continue
+			}
+
+			fields := strings.Fields(value.Value)
+			args := ""
+			if len(fields) > 2 {
+				args = fields[2]
+			}
+
+			eventfds[args] = fields[0]
+		}
+
+		return eventfds
+	}
+
+	signal := func(eventfd string) {
+		fd, err := strconv.Atoi(eventfd)
+		Expect(err).ToNot(HaveOccurred())
+
+		increment := make([]byte, 8)
+		binary.LittleEndian.PutUint64(increment, 1)
+
+		_, err = syscall.Write(fd, increment)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		var err error
+		cgroupsPath, err = ioutil.TempDir("", "cgroups")
+		Expect(err).ToNot(HaveOccurred())
+
+		cgroupsManager = fake_cgroups_manager.New(cgroupsPath, "some-id")
+
+		memoryPath, err = cgroupsManager.SubsystemPath("memory")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.MkdirAll(memoryPath, 0755)).To(Succeed())
+
+		for _, file := range []string{"memory.oom_control", "memory.pressure_level", "cgroup.event_control"} {
+			Expect(ioutil.WriteFile(path.Join(memoryPath, file), []byte{}, 0644)).To(Succeed())
+		}
+
+		notifier = linux_container.NewMemoryNotifier(cgroupsManager, lagertest.NewTestLogger("test"))
+	})
+
+	AfterEach(func() {
+		notifier.Unwatch()
+		os.RemoveAll(cgroupsPath)
+	})
+
+	Describe("Watch", func() {
+		var ooms chan struct{}
+
+		BeforeEach(func() {
+			ooms = make(chan struct{}, 10)
+		})
+
+		JustBeforeEach(func() {
+			Expect(notifier.Watch(func() { ooms <- struct{}{} })).To(Succeed())
+		})
+
+		It("registers an eventfd against memory.oom_control", func() {
+			Expect(registrations()).To(HaveKey(""))
+		})
+
+		It("reads the eventfd without blocking an OS thread", func() {
+			fd, err := strconv.Atoi(registrations()[""])
+			Expect(err).ToNot(HaveOccurred())
+
+			flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, uintptr(fd), syscall.F_GETFL, 0)
+			Expect(errno).To(BeZero())
+			Expect(flags & syscall.O_NONBLOCK).ToNot(BeZero())
+		})
+
+		It("notifies on every oom", func() {
+			eventfd := registrations()[""]
+
+			signal(eventfd)
+			Eventually(ooms).Should(Receive())
+
+			signal(eventfd)
+			Eventually(ooms).Should(Receive())
+		})
+
+		It("only registers once", func() {
+			Expect(notifier.Watch(func() {})).To(Succeed())
+			Expect(cgroupsManager.SetValues()).To(HaveLen(1))
+		})
+
+		Context("when the cgroup has been removed", func() {
+			It("stops notifying", func() {
+				eventfd := registrations()[""]
+
+				Expect(os.Remove(path.Join(memoryPath, "cgroup.event_control"))).To(Succeed())
+
+				signal(eventfd)
+				Consistently(ooms).ShouldNot(Receive())
+			})
+		})
+	})
+
+	Context("when the registration fails", func() {
+		BeforeEach(func() {
+			cgroupsManager.WhenSetting("memory", "cgroup.event_control", func() error {
+				return errors.New("banana")
+			})
+		})
+
+		It("returns the error", func() {
+			Expect(notifier.Watch(func() {})).To(MatchError(ContainSubstring("banana")))
+			Expect(notifier.WatchPressure(func(string) {})).To(MatchError(ContainSubstring("banana")))
+		})
+	})
+
+	Describe("WatchPressure", func() {
+		var levels chan string
+
+		BeforeEach(func() {
+			levels = make(chan string, 10)
+		})
+
+		JustBeforeEach(func() {
+			Expect(notifier.WatchPressure(func(level string) { levels <- level })).To(Succeed())
+		})
+
+		It("registers an eventfd for each pressure level", func() {
+			Expect(registrations()).To(HaveLen(3))
+			Expect(registrations()).To(HaveKey("low"))
+			Expect(registrations()).To(HaveKey("medium"))
+			Expect(registrations()).To(HaveKey("critical"))
+		})
+
+		It("notifies with the level which was signalled", func() {
+			signal(registrations()["critical"])
+			Eventually(levels).Should(Receive(Equal("critical")))
+
+			signal(registrations()["low"])
+			Eventually(levels).Should(Receive(Equal("low")))
+		})
+	})
+
+	Describe("Unwatch", func() {
+		It("allows watching again", func() {
+			Expect(notifier.Watch(func() {})).To(Succeed())
+			notifier.Unwatch()
+
+			Expect(notifier.Watch(func() {})).To(Succeed())
+			Expect(cgroupsManager.SetValues()).To(HaveLen(2))
+		})
+
+		It("stops notifying", func() {
+			ooms := make(chan struct{}, 10)
+			Expect(notifier.Watch(func() { ooms <- struct{}{} })).To(Succeed())
+
+			notifier.Unwatch()
+
+			Consistently(ooms).ShouldNot(Receive())
+		})
+	})
+})
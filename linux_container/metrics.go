@@ -6,29 +6,62 @@ import (
 	"strings"
 
 	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
 )
 
 func (c *LinuxContainer) Metrics() (garden.Metrics, error) {
+	metrics, err := c.DetailedMetrics()
+	if err != nil {
+		return garden.Metrics{}, err
+	}
+
+	return metrics.Metrics, nil
+}
+
+func (c *LinuxContainer) DetailedMetrics() (linux_backend.ContainerMetrics, error) {
 	cLog := c.logger.Session("metrics")
 
 	diskStat, err := c.quotaManager.GetUsage(cLog, c.RootFSPath())
 	if err != nil {
-		return garden.Metrics{}, err
+		return linux_backend.ContainerMetrics{}, err
 	}
 
 	cpuStat, err := c.cgroupsManager.Get("cpuacct", "cpuacct.stat")
 	if err != nil {
-		return garden.Metrics{}, err
+		return linux_backend.ContainerMetrics{}, err
 	}
 
 	cpuUsage, err := c.cgroupsManager.Get("cpuacct", "cpuacct.usage")
 	if err != nil {
-		return garden.Metrics{}, err
+		return linux_backend.ContainerMetrics{}, err
+	}
+
+	// cpu.stat is missing on kernels without CFS bandwidth control
+	cpuThrottlingStat, err := c.cgroupsManager.Get("cpu", "cpu.stat")
+	if err != nil {
+		c.logger.Error("linux_container: metrics: getting cpu throttling stats", err)
 	}
 
 	memoryStat, err := c.cgroupsManager.Get("memory", "memory.stat")
 	if err != nil {
-		return garden.Metrics{}, err
+		return linux_backend.ContainerMetrics{}, err
+	}
+
+	// the blkio controller (io on the unified hierarchy) may not be mounted
+	ioStat, err := c.ioStat()
+	if err != nil {
+		c.logger.Error("linux_container: metrics: getting io stats", err)
+	}
+
+	// the pids controller is missing on older kernels
+	pidStat, err := c.pidStat()
+	if err != nil {
+		c.logger.Error("linux_container: metrics: getting pid stats", err)
+	}
+
+	processStat, err := c.processStat()
+	if err != nil {
+		c.logger.Error("linux_container: metrics: getting process stats", err)
 	}
 
 	hostNetworkStat, err := c.netStats.Statistics()
@@ -36,20 +69,125 @@ func (c *LinuxContainer) Metrics() (garden.Metrics, error) {
 		c.logger.Error("linux_container: metrics: getting network stats", err)
 	}
 
-	// tx for host_intf is rx for cont_intf and vice-versa
+	// tx for host_intf is rx for cont_intf and vice-versa, so multicast
+	// received by host_intf was sent by the container
 	var contNetworkStat garden.ContainerNetworkStat
 	contNetworkStat.RxBytes = hostNetworkStat.TxBytes
 	contNetworkStat.TxBytes = hostNetworkStat.RxBytes
 
-	return garden.Metrics{
-		MemoryStat:  parseMemoryStat(memoryStat),
-		CPUStat:     parseCPUStat(cpuUsage, cpuStat),
-		DiskStat:    diskStat,
-		NetworkStat: contNetworkStat,
+	networkDetailStat := linux_backend.ContainerNetworkDetailStat{
+		RxPackets:   hostNetworkStat.TxPackets,
+		TxPackets:   hostNetworkStat.RxPackets,
+		RxErrors:    hostNetworkStat.TxErrors,
+		TxErrors:    hostNetworkStat.RxErrors,
+		RxDropped:   hostNetworkStat.TxDropped,
+		TxDropped:   hostNetworkStat.RxDropped,
+		TxMulticast: hostNetworkStat.Multicast,
+	}
+
+	networkDetailStat.ConntrackEntries, err = c.conntrackCounter.Count(c.Resources.Network.IP)
+	if err != nil {
+		c.logger.Error("linux_container: metrics: counting conntrack entries", err)
+	}
+
+	bandwidthStat, err := c.bandwidthManager.GetUsage(cLog)
+	if err != nil {
+		c.logger.Error("linux_container: metrics: getting bandwidth usage", err)
+	}
+
+	parsedMemoryStat, memoryLimitStat := parseMemoryStat(memoryStat)
+
+	// memory.stat has no soft or kernel memory limits, so report what was set
+	c.memoryMutex.RLock()
+	if c.LinuxContainerSpec.Limits.Memory != nil {
+		memoryLimitStat.SoftLimit = c.LinuxContainerSpec.Limits.Memory.SoftLimitInBytes
+		memoryLimitStat.KernelLimit = c.LinuxContainerSpec.Limits.Memory.KernelLimitInBytes
+	}
+	c.memoryMutex.RUnlock()
+
+	return linux_backend.ContainerMetrics{
+		Metrics: garden.Metrics{
+			MemoryStat:  parsedMemoryStat,
+			CPUStat:     parseCPUStat(cpuUsage, cpuStat),
+			DiskStat:    diskStat,
+			NetworkStat: contNetworkStat,
+		},
+		CPUThrottlingStat: parseCPUThrottlingStat(cpuThrottlingStat),
+		MemoryLimitStat:   memoryLimitStat,
+		IOStat:            ioStat,
+		PidStat:           pidStat,
+		ProcessStat:       processStat,
+		NetworkDetailStat: networkDetailStat,
+		BandwidthStat:     bandwidthStat,
+	}, nil
+}
+
+func (c *LinuxContainer) processStat() (linux_backend.ContainerProcessStat, error) {
+	tasks, err := c.cgroupsManager.Get("memory", "tasks")
+	if err != nil {
+		return linux_backend.ContainerProcessStat{}, err
+	}
+
+	pids, err := c.pids()
+	if err != nil {
+		return linux_backend.ContainerProcessStat{}, err
+	}
+
+	fds, err := c.fdCounter.Count(pids)
+	if err != nil {
+		return linux_backend.ContainerProcessStat{}, err
+	}
+
+	return linux_backend.ContainerProcessStat{
+		Tasks:           uint64(len(strings.Fields(tasks))),
+		FileDescriptors: fds,
+	}, nil
+}
+
+func (c *LinuxContainer) ioStat() (linux_backend.ContainerIOStat, error) {
+	serviceBytes, err := c.cgroupsManager.Get("blkio", "blkio.throttle.io_service_bytes")
+	if err != nil {
+		return linux_backend.ContainerIOStat{}, err
+	}
+
+	serviced, err := c.cgroupsManager.Get("blkio", "blkio.throttle.io_serviced")
+	if err != nil {
+		return linux_backend.ContainerIOStat{}, err
+	}
+
+	return parseIOStat(serviceBytes, serviced), nil
+}
+
+func (c *LinuxContainer) pidStat() (linux_backend.ContainerPidStat, error) {
+	current, err := c.cgroupsManager.Get("pids", "pids.current")
+	if err != nil {
+		return linux_backend.ContainerPidStat{}, err
+	}
+
+	max, err := c.cgroupsManager.Get("pids", "pids.max")
+	if err != nil {
+		return linux_backend.ContainerPidStat{}, err
+	}
+
+	numericCurrent, err := strconv.ParseUint(current, 10, 0)
+	if err != nil {
+		return linux_backend.ContainerPidStat{}, err
+	}
+
+	numericMax, err := parsePidsMax(max)
+	if err != nil {
+		return linux_backend.ContainerPidStat{}, err
+	}
+
+	return linux_backend.ContainerPidStat{
+		Current: numericCurrent,
+		Max:     numericMax,
 	}, nil
 }
 
-func parseMemoryStat(contents string) (stat garden.ContainerMemoryStat) {
+// parseMemoryStat parses memory.stat, deriving the swap limit from the
+// difference between the memory and swap limit and the memory limit.
+func parseMemoryStat(contents string) (stat garden.ContainerMemoryStat, limitStat linux_backend.ContainerMemoryLimitStat) {
 	scanner := bufio.NewScanner(strings.NewReader(contents))
 
 	scanner.Split(bufio.ScanWords)
@@ -128,6 +266,12 @@ func parseMemoryStat(contents string) (stat garden.ContainerMemoryStat) {
 
 	stat.TotalUsageTowardLimit = stat.TotalRss + (stat.TotalCache - stat.TotalInactiveFile)
 
+	memoryLimit := unlimitedToZero(stat.HierarchicalMemoryLimit)
+	memswLimit := unlimitedToZero(stat.HierarchicalMemswLimit)
+	if memoryLimit != 0 && memswLimit > memoryLimit {
+		limitStat.SwapLimit = memswLimit - memoryLimit
+	}
+
 	return
 }
 
@@ -165,3 +309,95 @@ func parseCPUStat(usage, statContents string) (stat garden.ContainerCPUStat) {
 
 	return
 }
+
+// parseCPUThrottlingStat parses cpu.stat, which reports the throttled time in
+// nanoseconds on the v1 hierarchy and in microseconds on the unified one.
+func parseCPUThrottlingStat(contents string) (stat linux_backend.ContainerCPUThrottlingStat) {
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+
+	scanner.Split(bufio.ScanWords)
+
+	for scanner.Scan() {
+		field := scanner.Text()
+
+		if !scanner.Scan() {
+			break
+		}
+
+		value, err := strconv.ParseUint(scanner.Text(), 10, 0)
+		if err != nil {
+			continue
+		}
+
+		switch field {
+		case "nr_periods":
+			stat.Periods = value
+		case "nr_throttled":
+			stat.ThrottledPeriods = value
+		case "throttled_time":
+			stat.ThrottledTime = value
+		case "throttled_usec":
+			stat.ThrottledTime = value * 1000
+		}
+	}
+
+	return
+}
+
+// parseIOStat parses the per-device "major:minor Operation value" lines of
+// blkio.throttle.io_service_bytes and blkio.throttle.io_serviced, totalling
+// them across devices. Devices are listed in the order they first appear.
+func parseIOStat(serviceBytes, serviced string) (stat linux_backend.ContainerIOStat) {
+	devices := map[string]*linux_backend.ContainerDeviceIOStat{}
+	order := []string{}
+
+	device := func(name string) *linux_backend.ContainerDeviceIOStat {
+		if _, found := devices[name]; !found {
+			devices[name] = &linux_backend.ContainerDeviceIOStat{Device: name}
+			order = append(order, name)
+		}
+
+		return devices[name]
+	}
+
+	eachIOStat(serviceBytes, func(name string, read, write uint64) {
+		device(name).ReadBytes += read
+		device(name).WriteBytes += write
+		stat.ReadBytes += read
+		stat.WriteBytes += write
+	})
+
+	eachIOStat(serviced, func(name string, read, write uint64) {
+		device(name).ReadOps += read
+		device(name).WriteOps += write
+		stat.ReadOps += read
+		stat.WriteOps += write
+	})
+
+	for _, name := range order {
+		stat.Devices = append(stat.Devices, *devices[name])
+	}
+
+	return
+}
+
+func eachIOStat(contents string, fn func(device string, read, write uint64)) {
+	for _, line := range strings.Split(contents, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+
+		value, err := strconv.ParseUint(fields[2], 10, 0)
+		if err != nil {
+			continue
+		}
+
+		switch fields[1] {
+		case "Read":
+			fn(fields[0], value, 0)
+		case "Write":
+			fn(fields[0], 0, value)
+		}
+	}
+}
@@ -2,18 +2,25 @@ package linux_container_test
 
 import (
 	"errors"
+	"io/ioutil"
 	"net"
+	"path/filepath"
 	"time"
 
 	"github.com/cloudfoundry-incubator/garden"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend/fakes"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/bandwidth_manager/fake_bandwidth_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager/fake_cgroups_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_conntrack_counter"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_fd_counter"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_iptables_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_network_statisticser"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_oom_killer"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_quota_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_watcher"
+	"github.com/cloudfoundry-incubator/garden-linux/network/devices"
 	networkFakes "github.com/cloudfoundry-incubator/garden-linux/network/fakes"
 	"github.com/cloudfoundry-incubator/garden-linux/port_pool/fake_port_pool"
 	"github.com/cloudfoundry-incubator/garden-linux/process_tracker/fake_process_tracker"
@@ -27,13 +34,26 @@ var _ = Describe("Linux containers", func() {
 	var fakeCgroups *fake_cgroups_manager.FakeCgroupsManager
 	var fakeQuotaManager *fake_quota_manager.FakeQuotaManager
 	var fakeNetStats *fake_network_statisticser.FakeNetworkStatisticser
+	var fakeFDCounter *fake_fd_counter.FakeFileDescriptorCounter
+	var fakeConntrackCounter *fake_conntrack_counter.FakeConntrackCounter
+	var fakeBandwidthManager *fake_bandwidth_manager.FakeBandwidthManager
 	var container *linux_container.LinuxContainer
 	var containerDir string
 
+	testAsset := func(name string) func() (string, error) {
+		return func() (string, error) {
+			contents, err := ioutil.ReadFile(filepath.Join("test_assets", name))
+			return string(contents), err
+		}
+	}
+
 	BeforeEach(func() {
 		fakeCgroups = fake_cgroups_manager.New("/cgroups", "some-id")
 		fakeQuotaManager = new(fake_quota_manager.FakeQuotaManager)
 		fakeNetStats = new(fake_network_statisticser.FakeNetworkStatisticser)
+		fakeFDCounter = new(fake_fd_counter.FakeFileDescriptorCounter)
+		fakeConntrackCounter = new(fake_conntrack_counter.FakeConntrackCounter)
+		fakeBandwidthManager = fake_bandwidth_manager.New()
 	})
 
 	JustBeforeEach(func() {
@@ -63,12 +83,16 @@ var _ = Describe("Linux containers", func() {
 			fake_command_runner.New(),
 			fakeCgroups,
 			fakeQuotaManager,
-			fake_bandwidth_manager.New(),
+			fakeBandwidthManager,
 			new(fake_process_tracker.FakeProcessTracker),
 			new(networkFakes.FakeFilter),
 			new(fake_iptables_manager.FakeIPTablesManager),
 			fakeNetStats,
 			new(fake_watcher.FakeWatcher),
+			new(fake_oom_killer.FakeOOMKiller),
+			fakeFDCounter,
+			fakeConntrackCounter,
+			new(fakes.FakeEventEmitter),
 			lagertest.NewTestLogger("linux-container-limits-test"),
 		)
 	})
@@ -145,6 +169,44 @@ total_unevictable 28
 				}))
 
 			})
+
+			It("includes the swap limit", func() {
+				metrics, err := container.DetailedMetrics()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(metrics.MemoryLimitStat.SwapLimit).To(Equal(uint64(1)))
+			})
+
+			Context("when soft and kernel memory limits have been set", func() {
+				It("includes them", func() {
+					Expect(container.LimitDetailedMemory(linux_backend.MemoryLimits{
+						MemoryLimits:       garden.MemoryLimits{LimitInBytes: 14},
+						SoftLimitInBytes:   10,
+						KernelLimitInBytes: 5,
+					})).To(Succeed())
+
+					metrics, err := container.DetailedMetrics()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(metrics.MemoryLimitStat).To(Equal(linux_backend.ContainerMemoryLimitStat{
+						SwapLimit:   1,
+						SoftLimit:   10,
+						KernelLimit: 5,
+					}))
+				})
+			})
+		})
+
+		Context("when swap is unlimited", func() {
+			BeforeEach(func() {
+				fakeCgroups.WhenGetting("memory", "memory.stat", func() (string, error) {
+					return "hierarchical_memory_limit 14\nhierarchical_memsw_limit 9223372036854771712\n", nil
+				})
+			})
+
+			It("reports no swap limit", func() {
+				metrics, err := container.DetailedMetrics()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(metrics.MemoryLimitStat.SwapLimit).To(BeZero())
+			})
 		})
 
 		Context("when getting memory.stat fails", func() {
@@ -188,6 +250,50 @@ system 2
 			})
 		})
 
+		Describe("cpu throttling info", func() {
+			It("is returned in the detailed response", func() {
+				fakeCgroups.WhenGetting("cpu", "cpu.stat", func() (string, error) {
+					return "nr_periods 100\nnr_throttled 25\nthrottled_time 123456789\n", nil
+				})
+
+				metrics, err := container.DetailedMetrics()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(metrics.CPUThrottlingStat).To(Equal(linux_backend.ContainerCPUThrottlingStat{
+					Periods:          100,
+					ThrottledPeriods: 25,
+					ThrottledTime:    123456789,
+				}))
+			})
+
+			Context("on the unified hierarchy", func() {
+				It("converts the throttled time to nanoseconds", func() {
+					fakeCgroups.WhenGetting("cpu", "cpu.stat", func() (string, error) {
+						return "usage_usec 1000\nuser_usec 600\nsystem_usec 400\nnr_periods 100\nnr_throttled 25\nthrottled_usec 123456\n", nil
+					})
+
+					metrics, err := container.DetailedMetrics()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(metrics.CPUThrottlingStat).To(Equal(linux_backend.ContainerCPUThrottlingStat{
+						Periods:          100,
+						ThrottledPeriods: 25,
+						ThrottledTime:    123456000,
+					}))
+				})
+			})
+
+			Context("when cpu.stat cannot be read", func() {
+				It("returns zeroed throttling stats", func() {
+					fakeCgroups.WhenGetting("cpu", "cpu.stat", func() (string, error) {
+						return "", errors.New("no such file or directory")
+					})
+
+					metrics, err := container.DetailedMetrics()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(metrics.CPUThrottlingStat).To(BeZero())
+				})
+			})
+		})
+
 		Context("when getting cpuacct/cpuacct.usage fails", func() {
 			disaster := errors.New("oh no!")
 
@@ -218,6 +324,130 @@ system 2
 			})
 		})
 
+		Describe("block io info", func() {
+			BeforeEach(func() {
+				fakeCgroups.WhenGetting("blkio", "blkio.throttle.io_service_bytes", testAsset("blkio.throttle.io_service_bytes"))
+				fakeCgroups.WhenGetting("blkio", "blkio.throttle.io_serviced", testAsset("blkio.throttle.io_serviced"))
+			})
+
+			It("is returned in the detailed response, totalled across devices and per device", func() {
+				metrics, err := container.DetailedMetrics()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(metrics.IOStat).To(Equal(linux_backend.ContainerIOStat{
+					ReadBytes:  4197,
+					WriteBytes: 8394,
+					ReadOps:    11,
+					WriteOps:   22,
+
+					Devices: []linux_backend.ContainerDeviceIOStat{
+						{Device: "8:16", ReadBytes: 4096, WriteBytes: 8192, ReadOps: 1, WriteOps: 2},
+						{Device: "8:0", ReadBytes: 100, WriteBytes: 200, ReadOps: 10, WriteOps: 20},
+						{Device: "253:0", ReadBytes: 1, WriteBytes: 2},
+					},
+				}))
+			})
+		})
+
+		Context("when the blkio controller is unavailable", func() {
+			BeforeEach(func() {
+				fakeCgroups.WhenGetting("blkio", "blkio.throttle.io_service_bytes", func() (string, error) {
+					return "", errors.New("no such file or directory")
+				})
+			})
+
+			It("returns zeroed io stats", func() {
+				metrics, err := container.DetailedMetrics()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(metrics.IOStat).To(BeZero())
+			})
+		})
+
+		Context("when getting blkio/blkio.throttle.io_serviced fails", func() {
+			BeforeEach(func() {
+				fakeCgroups.WhenGetting("blkio", "blkio.throttle.io_service_bytes", testAsset("blkio.throttle.io_service_bytes"))
+				fakeCgroups.WhenGetting("blkio", "blkio.throttle.io_serviced", func() (string, error) {
+					return "", errors.New("no such file or directory")
+				})
+			})
+
+			It("returns zeroed io stats", func() {
+				metrics, err := container.DetailedMetrics()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(metrics.IOStat).To(BeZero())
+			})
+		})
+
+		Describe("pid info", func() {
+			BeforeEach(func() {
+				fakeCgroups.WhenGetting("pids", "pids.current", func() (string, error) {
+					return "12", nil
+				})
+
+				fakeCgroups.WhenGetting("pids", "pids.max", func() (string, error) {
+					return "1024", nil
+				})
+			})
+
+			It("is returned in the detailed response", func() {
+				metrics, err := container.DetailedMetrics()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(metrics.PidStat).To(Equal(linux_backend.ContainerPidStat{
+					Current: 12,
+					Max:     1024,
+				}))
+			})
+		})
+
+		Context("when the pids controller is unavailable", func() {
+			BeforeEach(func() {
+				fakeCgroups.WhenGetting("pids", "pids.current", func() (string, error) {
+					return "", errors.New("no such file or directory")
+				})
+			})
+
+			It("returns zeroed pid stats", func() {
+				metrics, err := container.DetailedMetrics()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(metrics.PidStat).To(BeZero())
+			})
+		})
+
+		Describe("process info", func() {
+			BeforeEach(func() {
+				fakeCgroups.WhenGetting("memory", "tasks", testAsset("tasks"))
+				fakeCgroups.WhenGetting("memory", "cgroup.procs", testAsset("cgroup.procs"))
+
+				fakeFDCounter.CountReturns(42, nil)
+			})
+
+			It("counts the tasks in the container's cgroup", func() {
+				metrics, err := container.DetailedMetrics()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(metrics.ProcessStat.Tasks).To(Equal(uint64(4)))
+			})
+
+			It("counts the file descriptors held by the container's processes", func() {
+				metrics, err := container.DetailedMetrics()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(metrics.ProcessStat.FileDescriptors).To(Equal(uint64(42)))
+
+				Expect(fakeFDCounter.CountCallCount()).To(Equal(1))
+				Expect(fakeFDCounter.CountArgsForCall(0)).To(Equal([]int{1235, 1302}))
+			})
+
+			Context("when counting the file descriptors fails", func() {
+				BeforeEach(func() {
+					fakeFDCounter.CountReturns(0, errors.New("permission denied"))
+				})
+
+				It("returns zeroed process stats", func() {
+					metrics, err := container.DetailedMetrics()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(metrics.ProcessStat).To(BeZero())
+				})
+			})
+		})
+
 		Describe("disk usage info", func() {
 			It("is returned in the response", func() {
 				fakeQuotaManager.GetUsageReturns(garden.ContainerDiskStat{
@@ -255,12 +485,21 @@ system 2
 
 		Describe("Getting network info", func() {
 			Context("on existing interface", func() {
-				It("it returns container statistics, which are the inverse of the returned values", func() {
-					fakeNetStats.StatisticsReturns(garden.ContainerNetworkStat{
-						RxBytes: 2,
-						TxBytes: 1,
+				BeforeEach(func() {
+					fakeNetStats.StatisticsReturns(devices.LinkStatistics{
+						RxBytes:   2,
+						TxBytes:   1,
+						RxPackets: 20,
+						TxPackets: 10,
+						RxErrors:  4,
+						TxErrors:  3,
+						RxDropped: 6,
+						TxDropped: 5,
+						Multicast: 7,
 					}, nil)
+				})
 
+				It("it returns container statistics, which are the inverse of the returned values", func() {
 					metrics, err := container.Metrics()
 					Expect(err).ToNot(HaveOccurred())
 
@@ -269,11 +508,26 @@ system 2
 						TxBytes: 2, // therefore the container should have reversed them
 					}))
 				})
+
+				It("returns the container's packet statistics in the detailed response", func() {
+					metrics, err := container.DetailedMetrics()
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(metrics.NetworkDetailStat).To(Equal(linux_backend.ContainerNetworkDetailStat{
+						RxPackets:   10,
+						TxPackets:   20,
+						RxErrors:    3,
+						TxErrors:    4,
+						RxDropped:   5,
+						TxDropped:   6,
+						TxMulticast: 7,
+					}))
+				})
 			})
 
 			Context("on non-existent interface", func() {
-				JustBeforeEach(func() {
-					fakeNetStats.StatisticsReturns(garden.ContainerNetworkStat{}, errors.New("link does not exist"))
+				BeforeEach(func() {
+					fakeNetStats.StatisticsReturns(devices.LinkStatistics{}, errors.New("link does not exist"))
 				})
 
 				It("returns zero-ed out network stats", func() {
@@ -284,5 +538,55 @@ system 2
 				})
 			})
 		})
+
+		Describe("conntrack info", func() {
+			It("counts the connections tracked for the container's IP", func() {
+				fakeConntrackCounter.CountReturns(12, nil)
+
+				metrics, err := container.DetailedMetrics()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(metrics.NetworkDetailStat.ConntrackEntries).To(Equal(uint64(12)))
+
+				Expect(fakeConntrackCounter.CountArgsForCall(0)).To(Equal(net.ParseIP("1.2.3.4")))
+			})
+
+			Context("when counting the connections fails", func() {
+				It("reports no connections", func() {
+					fakeConntrackCounter.CountReturns(0, errors.New("permission denied"))
+
+					metrics, err := container.DetailedMetrics()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(metrics.NetworkDetailStat.ConntrackEntries).To(BeZero())
+				})
+			})
+		})
+
+		Describe("bandwidth info", func() {
+			It("reports the usage counted by the bandwidth manager", func() {
+				fakeBandwidthManager.GetUsageResult = linux_backend.ContainerBandwidthUsageStat{
+					InBytes:   1024,
+					InDropped: 3,
+					OutBytes:  2048,
+				}
+
+				metrics, err := container.DetailedMetrics()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(metrics.BandwidthStat).To(Equal(linux_backend.ContainerBandwidthUsageStat{
+					InBytes:   1024,
+					InDropped: 3,
+					OutBytes:  2048,
+				}))
+			})
+
+			Context("when getting the usage fails", func() {
+				It("reports no usage", func() {
+					fakeBandwidthManager.GetUsageError = errors.New("no such device")
+
+					metrics, err := container.DetailedMetrics()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(metrics.BandwidthStat).To(BeZero())
+				})
+			})
+		})
 	})
 })
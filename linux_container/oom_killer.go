@@ -0,0 +1,65 @@
+package linux_container
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+type OOMVictim struct {
+	Pid     int
+	Command string
+}
+
+// ProcOOMKiller kills the process which the kernel's OOM killer would have
+// chosen, i.e. the one with the highest oom_score.
+type ProcOOMKiller struct {
+	ProcPath string
+}
+
+func (k *ProcOOMKiller) Kill(pids []int) (OOMVictim, error) {
+	victim := OOMVictim{Pid: -1}
+	highestScore := -1
+
+	for _, pid := range pids {
+		score, err := k.readProcFile(pid, "oom_score")
+		if err != nil {
+			// the process has already exited
+			continue
+		}
+
+		numericScore, err := strconv.Atoi(score)
+		if err != nil {
+			return OOMVictim{}, fmt.Errorf("linux_container: oom kill: invalid oom_score for %d: %s", pid, err)
+		}
+
+		if numericScore > highestScore {
+			victim.Pid = pid
+			highestScore = numericScore
+		}
+	}
+
+	if victim.Pid == -1 {
+		return OOMVictim{}, fmt.Errorf("linux_container: oom kill: no processes to kill")
+	}
+
+	victim.Command, _ = k.readProcFile(victim.Pid, "comm")
+
+	if err := syscall.Kill(victim.Pid, syscall.SIGKILL); err != nil {
+		return OOMVictim{}, fmt.Errorf("linux_container: oom kill: %s", err)
+	}
+
+	return victim, nil
+}
+
+func (k *ProcOOMKiller) readProcFile(pid int, name string) (string, error) {
+	contents, err := ioutil.ReadFile(path.Join(k.ProcPath, strconv.Itoa(pid), name))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(contents)), nil
+}
@@ -0,0 +1,79 @@
+package linux_container_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ProcOOMKiller", func() {
+	var (
+		procPath string
+		killer   *linux_container.ProcOOMKiller
+
+		small *exec.Cmd
+		large *exec.Cmd
+	)
+
+	writeProc := func(pid int, oomScore, comm string) {
+		pidPath := path.Join(procPath, strconv.Itoa(pid))
+		Expect(os.MkdirAll(pidPath, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(path.Join(pidPath, "oom_score"), []byte(oomScore+"\n"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(path.Join(pidPath, "comm"), []byte(comm+"\n"), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		procPath, err = ioutil.TempDir("", "fake-proc")
+		Expect(err).ToNot(HaveOccurred())
+
+		killer = &linux_container.ProcOOMKiller{ProcPath: procPath}
+
+		small = exec.Command("sleep", "1000")
+		Expect(small.Start()).To(Succeed())
+
+		large = exec.Command("sleep", "1000")
+		Expect(large.Start()).To(Succeed())
+
+		writeProc(small.Process.Pid, "10", "small")
+		writeProc(large.Process.Pid, "900", "large")
+	})
+
+	AfterEach(func() {
+		small.Process.Kill()
+		large.Process.Kill()
+
+		os.RemoveAll(procPath)
+	})
+
+	It("kills the process with the highest oom_score", func() {
+		victim, err := killer.Kill([]int{small.Process.Pid, large.Process.Pid})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(victim).To(Equal(linux_container.OOMVictim{
+			Pid:     large.Process.Pid,
+			Command: "large",
+		}))
+
+		Expect(large.Wait()).To(MatchError("signal: killed"))
+	})
+
+	It("skips processes which have already exited", func() {
+		victim, err := killer.Kill([]int{999999, small.Process.Pid})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(victim.Pid).To(Equal(small.Process.Pid))
+	})
+
+	Context("when there are no processes", func() {
+		It("returns an error", func() {
+			_, err := killer.Kill([]int{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
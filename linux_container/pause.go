@@ -0,0 +1,104 @@
+package linux_container
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+)
+
+const (
+	freezerFrozen = "FROZEN"
+	freezerThawed = "THAWED"
+
+	freezerPollInterval = 10 * time.Millisecond
+	freezerTimeout      = 10 * time.Second
+)
+
+// Pause and Resume hold freezerMutex from checking the state until it is
+// updated, so that concurrent calls cannot leave a thawed container marked
+// paused or the reverse.
+func (c *LinuxContainer) Pause() error {
+	c.freezerMutex.Lock()
+	defer c.freezerMutex.Unlock()
+
+	cLog := c.logger.Session("pause")
+
+	state := c.State()
+	if state == linux_backend.StatePaused {
+		return nil
+	}
+
+	if state != linux_backend.StateActive {
+		return fmt.Errorf("container: pause: cannot pause a container in state %s", state)
+	}
+
+	cLog.Debug("freezing")
+
+	if err := c.setFreezerState(freezerFrozen); err != nil {
+		cLog.Error("failed-to-freeze", err)
+
+		// leave the container usable rather than half-frozen
+		c.cgroupsManager.Set("freezer", "freezer.state", freezerThawed)
+		return fmt.Errorf("container: pause: %s", err)
+	}
+
+	c.setState(linux_backend.StatePaused)
+
+	cLog.Info("paused")
+	return nil
+}
+
+func (c *LinuxContainer) Resume() error {
+	c.freezerMutex.Lock()
+	defer c.freezerMutex.Unlock()
+
+	return c.resume()
+}
+
+func (c *LinuxContainer) resume() error {
+	cLog := c.logger.Session("resume")
+
+	state := c.State()
+	if state != linux_backend.StatePaused {
+		return fmt.Errorf("container: resume: cannot resume a container in state %s", state)
+	}
+
+	cLog.Debug("thawing")
+
+	if err := c.setFreezerState(freezerThawed); err != nil {
+		cLog.Error("failed-to-thaw", err)
+		return fmt.Errorf("container: resume: %s", err)
+	}
+
+	c.setState(linux_backend.StateActive)
+
+	cLog.Info("resumed")
+	return nil
+}
+
+// setFreezerState writes the desired state and waits for the kernel to finish
+// the transition, as freezing passes through FREEZING while tasks are stopped.
+func (c *LinuxContainer) setFreezerState(desired string) error {
+	if err := c.cgroupsManager.Set("freezer", "freezer.state", desired); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(freezerTimeout)
+	for {
+		current, err := c.cgroupsManager.Get("freezer", "freezer.state")
+		if err != nil {
+			return err
+		}
+
+		if current == desired {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for freezer state %s (currently %s)", desired, current)
+		}
+
+		time.Sleep(freezerPollInterval)
+	}
+}
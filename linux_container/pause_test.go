@@ -0,0 +1,270 @@
+package linux_container_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend/fakes"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/bandwidth_manager/fake_bandwidth_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager/fake_cgroups_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_conntrack_counter"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_fd_counter"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_iptables_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_network_statisticser"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_oom_killer"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_quota_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_watcher"
+	networkFakes "github.com/cloudfoundry-incubator/garden-linux/network/fakes"
+	"github.com/cloudfoundry-incubator/garden-linux/port_pool/fake_port_pool"
+	"github.com/cloudfoundry-incubator/garden-linux/process_tracker/fake_process_tracker"
+	"github.com/cloudfoundry/gunk/command_runner/fake_command_runner"
+	. "github.com/cloudfoundry/gunk/command_runner/fake_command_runner/matchers"
+)
+
+var _ = Describe("Pausing and resuming", func() {
+	var fakeCgroups *fake_cgroups_manager.FakeCgroupsManager
+	var fakeRunner *fake_command_runner.FakeCommandRunner
+	var container *linux_container.LinuxContainer
+	var containerDir string
+
+	BeforeEach(func() {
+		fakeRunner = fake_command_runner.New()
+		fakeCgroups = fake_cgroups_manager.New("/cgroups", "some-id")
+
+		var err error
+		containerDir, err = ioutil.TempDir("", "depot")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		_, subnet, _ := net.ParseCIDR("2.3.4.0/30")
+
+		container = linux_container.NewLinuxContainer(
+			linux_backend.LinuxContainerSpec{
+				ID:                  "some-id",
+				ContainerPath:       containerDir,
+				ContainerRootFSPath: "some-volume-path",
+				Resources: linux_backend.NewResources(
+					1235,
+					&linux_backend.Network{
+						IP:     net.ParseIP("1.2.3.4"),
+						Subnet: subnet,
+					},
+					"some-bridge",
+					[]uint32{},
+					nil,
+				),
+				State: linux_backend.StateBorn,
+				ContainerSpec: garden.ContainerSpec{
+					Handle:    "some-handle",
+					GraceTime: time.Second * 1,
+				},
+			},
+			fake_port_pool.New(1000),
+			fakeRunner,
+			fakeCgroups,
+			new(fake_quota_manager.FakeQuotaManager),
+			fake_bandwidth_manager.New(),
+			new(fake_process_tracker.FakeProcessTracker),
+			new(networkFakes.FakeFilter),
+			new(fake_iptables_manager.FakeIPTablesManager),
+			new(fake_network_statisticser.FakeNetworkStatisticser),
+			new(fake_watcher.FakeWatcher),
+			new(fake_oom_killer.FakeOOMKiller),
+			new(fake_fd_counter.FakeFileDescriptorCounter),
+			new(fake_conntrack_counter.FakeConntrackCounter),
+			new(fakes.FakeEventEmitter),
+			lagertest.NewTestLogger("linux-container-pause-test"),
+		)
+	})
+
+	Describe("Pause", func() {
+		Context("when the container is active", func() {
+			JustBeforeEach(func() {
+				Expect(container.Start()).To(Succeed())
+			})
+
+			It("freezes the container's freezer cgroup", func() {
+				Expect(container.Pause()).To(Succeed())
+
+				Expect(fakeCgroups.SetValues()).To(ContainElement(fake_cgroups_manager.SetValue{
+					Subsystem: "freezer",
+					Name:      "freezer.state",
+					Value:     "FROZEN",
+				}))
+			})
+
+			It("changes the container's state to paused", func() {
+				Expect(container.Pause()).To(Succeed())
+				Expect(container.State()).To(Equal(linux_backend.StatePaused))
+
+				info, err := container.Info()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.State).To(Equal("paused"))
+			})
+
+			Context("when resumed while pausing", func() {
+				var freezing, release chan struct{}
+
+				BeforeEach(func() {
+					freezing = make(chan struct{})
+					release = make(chan struct{})
+
+					var once sync.Once
+					fakeCgroups.WhenGetting("freezer", "freezer.state", func() (string, error) {
+						once.Do(func() {
+							close(freezing)
+							<-release
+						})
+
+						state := ""
+						for _, value := range fakeCgroups.SetValues() {
+							if value.Subsystem == "freezer" && value.Name == "freezer.state" {
+								state = value.Value
+							}
+						}
+
+						return state, nil
+					})
+				})
+
+				It("waits for the pause to finish before resuming", func() {
+					paused := make(chan error, 1)
+					go func() { paused <- container.Pause() }()
+					Eventually(freezing).Should(BeClosed())
+
+					resumed := make(chan error, 1)
+					go func() { resumed <- container.Resume() }()
+					Consistently(resumed).ShouldNot(Receive())
+
+					close(release)
+					Eventually(paused).Should(Receive(BeNil()))
+					Eventually(resumed).Should(Receive(BeNil()))
+					Expect(container.State()).To(Equal(linux_backend.StateActive))
+				})
+			})
+
+			Context("when the freezer is still freezing", func() {
+				BeforeEach(func() {
+					reads := 0
+					fakeCgroups.WhenGetting("freezer", "freezer.state", func() (string, error) {
+						reads++
+						if reads < 3 {
+							return "FREEZING", nil
+						}
+
+						return "FROZEN", nil
+					})
+				})
+
+				It("waits until it is frozen", func() {
+					Expect(container.Pause()).To(Succeed())
+					Expect(container.State()).To(Equal(linux_backend.StatePaused))
+				})
+			})
+
+			Context("when writing the freezer state fails", func() {
+				BeforeEach(func() {
+					fakeCgroups.WhenSetting("freezer", "freezer.state", func() error {
+						return errors.New("banana")
+					})
+				})
+
+				It("returns an error and stays active", func() {
+					Expect(container.Pause()).To(MatchError("container: pause: banana"))
+					Expect(container.State()).To(Equal(linux_backend.StateActive))
+				})
+			})
+
+			Context("when running a process while paused", func() {
+				It("returns an error", func() {
+					Expect(container.Pause()).To(Succeed())
+
+					_, err := container.Run(garden.ProcessSpec{User: "alice", Path: "true"}, garden.ProcessIO{})
+					Expect(err).To(HaveOccurred())
+				})
+			})
+		})
+
+		Context("when the container is not active", func() {
+			It("returns an error", func() {
+				Expect(container.Pause()).To(HaveOccurred())
+				Expect(fakeCgroups.SetValues()).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("Resume", func() {
+		JustBeforeEach(func() {
+			Expect(container.Start()).To(Succeed())
+			Expect(container.Pause()).To(Succeed())
+		})
+
+		It("thaws the container's freezer cgroup", func() {
+			Expect(container.Resume()).To(Succeed())
+
+			Expect(fakeCgroups.SetValues()).To(ContainElement(fake_cgroups_manager.SetValue{
+				Subsystem: "freezer",
+				Name:      "freezer.state",
+				Value:     "THAWED",
+			}))
+		})
+
+		It("changes the container's state back to active", func() {
+			Expect(container.Resume()).To(Succeed())
+			Expect(container.State()).To(Equal(linux_backend.StateActive))
+		})
+
+		Context("when the container is stopped while paused", func() {
+			It("thaws it before running stop.sh", func() {
+				Expect(container.Stop(false)).To(Succeed())
+
+				Expect(fakeCgroups.SetValues()).To(ContainElement(fake_cgroups_manager.SetValue{
+					Subsystem: "freezer",
+					Name:      "freezer.state",
+					Value:     "THAWED",
+				}))
+
+				Expect(fakeRunner).To(HaveExecutedSerially(
+					fake_command_runner.CommandSpec{
+						Path: containerDir + "/stop.sh",
+					},
+				))
+				Expect(container.State()).To(Equal(linux_backend.StateStopped))
+			})
+		})
+	})
+
+	Describe("Restoring a paused container", func() {
+		It("re-freezes the container and reports it as paused", func() {
+			Expect(container.Restore(linux_backend.LinuxContainerSpec{
+				ID:    "some-id",
+				State: linux_backend.StatePaused,
+				Resources: linux_backend.NewResources(
+					1235,
+					&linux_backend.Network{IP: net.ParseIP("1.2.3.4")},
+					"some-bridge",
+					[]uint32{},
+					nil,
+				),
+			})).To(Succeed())
+
+			Expect(fakeCgroups.SetValues()).To(ContainElement(fake_cgroups_manager.SetValue{
+				Subsystem: "freezer",
+				Name:      "freezer.state",
+				Value:     "FROZEN",
+			}))
+			Expect(container.State()).To(Equal(linux_backend.StatePaused))
+		})
+	})
+})
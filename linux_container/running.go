@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"os/exec"
 	"path"
+	"strconv"
 
 	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
 	"github.com/cloudfoundry-incubator/garden-linux/process"
 	"github.com/pivotal-golang/lager"
 )
@@ -15,6 +17,10 @@ func (c *LinuxContainer) Run(spec garden.ProcessSpec, processIO garden.ProcessIO
 	wshPath := path.Join(c.ContainerPath, "bin", "wsh")
 	sockPath := path.Join(c.ContainerPath, "run", "wshd.sock")
 
+	if c.State() == linux_backend.StatePaused {
+		return nil, errors.New("container: run: cannot run a process in a paused container")
+	}
+
 	if spec.User == "" {
 		c.logger.Error("linux_container: Run:", errors.New("linux_container: Run: A User for the process to run as must be specified."))
 		return nil, errors.New("A User for the process to run as must be specified.")
@@ -56,7 +62,28 @@ func (c *LinuxContainer) Run(spec garden.ProcessSpec, processIO garden.ProcessIO
 
 	setRLimitsEnv(wsh, spec.Limits)
 
-	return c.processTracker.Run(fmt.Sprintf("%d", processID), wsh, processIO, spec.TTY, c.processSignaller())
+	process, err := c.processTracker.Run(fmt.Sprintf("%d", processID), wsh, processIO, spec.TTY, c.processSignaller())
+	if err != nil {
+		return nil, err
+	}
+
+	c.emitEvent(linux_backend.EventProcessSpawned, map[string]string{"process_id": process.ID()})
+	go c.emitProcessExited(process)
+
+	return process, nil
+}
+
+func (c *LinuxContainer) emitProcessExited(process garden.Process) {
+	data := map[string]string{"process_id": process.ID()}
+
+	exitStatus, err := process.Wait()
+	if err != nil {
+		data["error"] = err.Error()
+	} else {
+		data["exit_status"] = strconv.Itoa(exitStatus)
+	}
+
+	c.emitEvent(linux_backend.EventProcessExited, data)
 }
 
 func (c *LinuxContainer) Attach(processID string, processIO garden.ProcessIO) (garden.Process, error) {
@@ -18,11 +18,15 @@ import (
 
 	"github.com/cloudfoundry-incubator/garden"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend/fakes"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/bandwidth_manager/fake_bandwidth_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager/fake_cgroups_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_conntrack_counter"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_fd_counter"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_iptables_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_network_statisticser"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_oom_killer"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_quota_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_watcher"
 	networkFakes "github.com/cloudfoundry-incubator/garden-linux/network/fakes"
@@ -40,9 +44,12 @@ var _ = Describe("Linux containers", func() {
 	var logger *lagertest.TestLogger
 	var containerDir string
 	var containerVersion semver.Version
+	var fakeEvents *fakes.FakeEventEmitter
 
 	BeforeEach(func() {
 		fakeProcessTracker = new(fake_process_tracker.FakeProcessTracker)
+		fakeProcessTracker.RunReturns(new(wfakes.FakeProcess), nil)
+		fakeEvents = new(fakes.FakeEventEmitter)
 		containerVersion = semver.Version{Major: 1, Minor: 0, Patch: 0}
 
 		var err error
@@ -87,6 +94,10 @@ var _ = Describe("Linux containers", func() {
 			new(fake_iptables_manager.FakeIPTablesManager),
 			new(fake_network_statisticser.FakeNetworkStatisticser),
 			new(fake_watcher.FakeWatcher),
+			new(fake_oom_killer.FakeOOMKiller),
+			new(fake_fd_counter.FakeFileDescriptorCounter),
+			new(fake_conntrack_counter.FakeConntrackCounter),
+			fakeEvents,
 			logger,
 		)
 	})
@@ -423,6 +434,35 @@ var _ = Describe("Linux containers", func() {
 			}))
 		})
 
+		Describe("events", func() {
+			BeforeEach(func() {
+				process := new(wfakes.FakeProcess)
+				process.IDReturns("1")
+				process.WaitReturns(42, nil)
+
+				fakeProcessTracker.RunReturns(process, nil)
+			})
+
+			It("emits process-spawned and process-exited events", func() {
+				_, err := container.Run(garden.ProcessSpec{
+					User: "alice",
+					Path: "/some/script",
+				}, garden.ProcessIO{})
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(fakeEvents.EmitCallCount).Should(Equal(2))
+
+				spawned := fakeEvents.EmitArgsForCall(0)
+				Expect(spawned.Type).To(Equal(linux_backend.EventProcessSpawned))
+				Expect(spawned.Handle).To(Equal("some-handle"))
+				Expect(spawned.Data).To(Equal(map[string]string{"process_id": "1"}))
+
+				exited := fakeEvents.EmitArgsForCall(1)
+				Expect(exited.Type).To(Equal(linux_backend.EventProcessExited))
+				Expect(exited.Data).To(Equal(map[string]string{"process_id": "1", "exit_status": "42"}))
+			})
+		})
+
 		Context("when the user is not set", func() {
 			It("returns an error", func() {
 				_, err := container.Run(garden.ProcessSpec{
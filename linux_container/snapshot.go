@@ -29,13 +29,16 @@ type ContainerSnapshot struct {
 
 	Properties garden.Properties
 
+	OOMPolicy linux_backend.OOMPolicy
+
 	EnvVars []string
 }
 
 type ResourcesSnapshot struct {
-	UserUID int
-	RootUID int
-	Network *linux_backend.Network
-	Bridge  string
-	Ports   []uint32
+	UserUID  int
+	RootUID  int
+	Network  *linux_backend.Network
+	Bridge   string
+	Ports    []uint32
+	UDPPorts []uint32
 }
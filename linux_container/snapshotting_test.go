@@ -6,6 +6,7 @@ import (
 	"errors"
 	"io/ioutil"
 	"net"
+	"os"
 	"os/exec"
 	"strconv"
 	"time"
@@ -17,11 +18,15 @@ import (
 
 	"github.com/cloudfoundry-incubator/garden"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend/fakes"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/bandwidth_manager/fake_bandwidth_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager/fake_cgroups_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_conntrack_counter"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_fd_counter"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_iptables_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_network_statisticser"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_oom_killer"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_quota_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_watcher"
 	networkFakes "github.com/cloudfoundry-incubator/garden-linux/network/fakes"
@@ -48,7 +53,10 @@ var _ = Describe("Linux containers", func() {
 		containerDir         string
 		containerProps       map[string]string
 		containerVersion     semver.Version
+		oomPolicy            linux_backend.OOMPolicy
 		fakeIPTablesManager  *fake_iptables_manager.FakeIPTablesManager
+		containerNetIns      []linux_backend.NetInSpec
+		containerNetOuts     []garden.NetOutRule
 	)
 
 	netOutRule1 := garden.NetOutRule{
@@ -71,6 +79,9 @@ var _ = Describe("Linux containers", func() {
 		fakeRunner = fake_command_runner.New()
 
 		fakeCgroups = fake_cgroups_manager.New("/cgroups", "some-id")
+		fakeCgroups.WhenGetting("cpu", "cpu.cfs_period_us", func() (string, error) {
+			return "100000", nil
+		})
 
 		fakeQuotaManager = new(fake_quota_manager.FakeQuotaManager)
 		fakeBandwidthManager = fake_bandwidth_manager.New()
@@ -84,6 +95,7 @@ var _ = Describe("Linux containers", func() {
 		Expect(err).ToNot(HaveOccurred())
 
 		containerVersion = semver.Version{Major: 1, Minor: 0, Patch: 0}
+		oomPolicy = linux_backend.OOMPolicyFreeze
 
 		_, subnet, err := net.ParseCIDR("2.3.4.0/30")
 		containerResources = linux_backend.NewResources(
@@ -102,6 +114,9 @@ var _ = Describe("Linux containers", func() {
 		}
 
 		fakeIPTablesManager = new(fake_iptables_manager.FakeIPTablesManager)
+
+		containerNetIns = nil
+		containerNetOuts = nil
 	})
 
 	fakeOomWatcher = new(fake_watcher.FakeWatcher)
@@ -120,7 +135,10 @@ var _ = Describe("Linux containers", func() {
 					Env:        []string{"env1=env1Value", "env2=env2Value"},
 					Properties: containerProps,
 				},
-				Version: containerVersion,
+				Version:   containerVersion,
+				OOMPolicy: oomPolicy,
+				NetIns:    containerNetIns,
+				NetOuts:   containerNetOuts,
 			},
 			fakePortPool,
 			fakeRunner,
@@ -132,13 +150,19 @@ var _ = Describe("Linux containers", func() {
 			fakeIPTablesManager,
 			new(fake_network_statisticser.FakeNetworkStatisticser),
 			fakeOomWatcher,
+			new(fake_oom_killer.FakeOOMKiller),
+			new(fake_fd_counter.FakeFileDescriptorCounter),
+			new(fake_conntrack_counter.FakeConntrackCounter),
+			new(fakes.FakeEventEmitter),
 			lagertest.NewTestLogger("linux-container-limits-test"),
 		)
 	})
 
 	Describe("Snapshotting", func() {
-		memoryLimits := garden.MemoryLimits{
-			LimitInBytes: 1,
+		memoryLimits := linux_backend.MemoryLimits{
+			MemoryLimits:     garden.MemoryLimits{LimitInBytes: 1},
+			SwapInBytes:      2,
+			SoftLimitInBytes: 1,
 		}
 
 		diskLimits := garden.DiskLimits{
@@ -149,15 +173,39 @@ var _ = Describe("Linux containers", func() {
 			ByteHard: 24,
 		}
 
-		bandwidthLimits := garden.BandwidthLimits{
-			RateInBytesPerSecond:      1,
-			BurstRateInBytesPerSecond: 2,
+		bandwidthLimits := linux_backend.BandwidthLimits{
+			InRateInBytesPerSecond:  1,
+			InBurstInBytes:          2,
+			OutRateInBytesPerSecond: 3,
+			OutBurstInBytes:         4,
 		}
 
 		cpuLimits := garden.CPULimits{
 			LimitInShares: 1,
 		}
 
+		cpuQuotaLimits := linux_backend.CPUQuotaLimits{
+			PeriodInMicroseconds: 100000,
+			QuotaInMicroseconds:  50000,
+		}
+
+		cpusetLimits := linux_backend.CPUSetLimits{
+			CPUs:      "0-1",
+			Mems:      "0",
+			Exclusive: true,
+		}
+
+		pidLimits := linux_backend.PidLimits{
+			Max: 1024,
+		}
+
+		ioLimits := linux_backend.IOLimits{
+			Weight: 500,
+			Devices: []linux_backend.DeviceIOLimits{
+				{Device: "8:0", ReadBytesPerSecond: 1048576},
+			},
+		}
+
 		JustBeforeEach(func() {
 			var err error
 
@@ -222,10 +270,12 @@ var _ = Describe("Linux containers", func() {
 					{
 						HostPort:      1,
 						ContainerPort: 2,
+						Protocol:      linux_backend.NetInProtocolTCP,
 					},
 					{
 						HostPort:      3,
 						ContainerPort: 4,
+						Protocol:      linux_backend.NetInProtocolTCP,
 					},
 				},
 			))
@@ -257,16 +307,22 @@ var _ = Describe("Linux containers", func() {
 			})))
 
 			Expect(snapshot.EnvVars).To(Equal([]string{"env1=env1Value", "env2=env2Value"}))
+
+			Expect(snapshot.OOMPolicy).To(Equal(linux_backend.OOMPolicyFreeze))
 		})
 
 		Context("with limits set", func() {
+			BeforeEach(func() {
+				oomPolicy = linux_backend.OOMPolicyStop
+			})
+
 			JustBeforeEach(func() {
 				fakeOomWatcher.WatchStub = func(onOom func()) error {
 					onOom()
 					return nil
 				}
 
-				err := container.LimitMemory(memoryLimits)
+				err := container.LimitDetailedMemory(memoryLimits)
 				Expect(err).ToNot(HaveOccurred())
 
 				Eventually(container.Events).Should(ContainElement("out of memory"))
@@ -275,11 +331,23 @@ var _ = Describe("Linux containers", func() {
 				err = container.LimitDisk(diskLimits)
 				Expect(err).ToNot(HaveOccurred())
 
-				err = container.LimitBandwidth(bandwidthLimits)
+				err = container.LimitDetailedBandwidth(bandwidthLimits)
 				Expect(err).ToNot(HaveOccurred())
 
 				err = container.LimitCPU(cpuLimits)
 				Expect(err).ToNot(HaveOccurred())
+
+				err = container.LimitCPUQuota(cpuQuotaLimits)
+				Expect(err).ToNot(HaveOccurred())
+
+				err = container.LimitCPUSet(cpusetLimits)
+				Expect(err).ToNot(HaveOccurred())
+
+				err = container.LimitIO(ioLimits)
+				Expect(err).ToNot(HaveOccurred())
+
+				err = container.LimitPids(pidLimits)
+				Expect(err).ToNot(HaveOccurred())
 			})
 
 			It("saves them", func() {
@@ -302,6 +370,10 @@ var _ = Describe("Linux containers", func() {
 						Disk:      &diskLimits,
 						Bandwidth: &bandwidthLimits,
 						CPU:       &cpuLimits,
+						CPUQuota:  &cpuQuotaLimits,
+						CPUSet:    &cpusetLimits,
+						IO:        &ioLimits,
+						Pids:      &pidLimits,
 					},
 				))
 			})
@@ -376,6 +448,8 @@ var _ = Describe("Linux containers", func() {
 		})
 
 		It("makes the next process ID be higher than the highest restored ID", func() {
+			fakeProcessTracker.RunReturns(new(wfakes.FakeProcess), nil)
+
 			err := container.Restore(linux_backend.LinuxContainerSpec{
 				State:     "active",
 				Events:    []string{},
@@ -454,6 +528,24 @@ var _ = Describe("Linux containers", func() {
 			Expect(fakeFilter.NetOutArgsForCall(1)).To(Equal(netOutRule2))
 		})
 
+		Context("when the container is created from the snapshot", func() {
+			BeforeEach(func() {
+				containerNetOuts = []garden.NetOutRule{netOutRule1}
+			})
+
+			It("does not record its net-outs twice", func() {
+				Expect(container.Restore(linux_backend.LinuxContainerSpec{
+					NetOuts:   containerNetOuts,
+					Resources: containerResources,
+				})).To(Succeed())
+
+				Expect(container.CurrentNetOuts()).To(Equal([]garden.NetOutRule{netOutRule1}))
+
+				Expect(container.RemoveNetOut(netOutRule1)).To(Succeed())
+				Expect(container.CurrentNetOuts()).To(BeEmpty())
+			})
+		})
+
 		Context("when applying a netout rule fails", func() {
 			It("returns an error", func() {
 				fakeFilter.NetOutReturns(errors.New("didn't work"))
@@ -497,6 +589,70 @@ var _ = Describe("Linux containers", func() {
 			))
 		})
 
+		Context("when the container is created from the snapshot with a net-in", func() {
+			BeforeEach(func() {
+				containerResources.AddPort(1234)
+				containerNetIns = []linux_backend.NetInSpec{{HostPort: 1234, ContainerPort: 5678, Protocol: linux_backend.NetInProtocolTCP}}
+			})
+
+			It("releases the host port when the net-in is removed", func() {
+				Expect(container.Restore(linux_backend.LinuxContainerSpec{
+					NetIns:    containerNetIns,
+					Resources: containerResources,
+				})).To(Succeed())
+
+				Expect(container.NetIns).To(HaveLen(1))
+
+				Expect(container.RemoveNetIn(linux_backend.NetInSpec{HostPort: 1234, ContainerPort: 5678})).To(Succeed())
+				Expect(container.NetIns).To(BeEmpty())
+				Expect(fakePortPool.Released).To(Equal([]uint32{1234}))
+			})
+		})
+
+		It("redoes net-ins with their protocol, defaulting to tcp for older snapshots", func() {
+			err := container.Restore(linux_backend.LinuxContainerSpec{
+				State:     "active",
+				Events:    []string{},
+				Resources: containerResources,
+
+				NetIns: []linux_backend.NetInSpec{
+					{
+						HostPort:      1234,
+						ContainerPort: 5678,
+					},
+					{
+						HostPort:      1235,
+						ContainerPort: 5679,
+						Protocol:      linux_backend.NetInProtocolUDP,
+					},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeRunner).To(HaveExecutedSerially(
+				fake_command_runner.CommandSpec{
+					Path: containerDir + "/net.sh",
+					Args: []string{"in"},
+					Env: []string{
+						"HOST_PORT=1234",
+						"CONTAINER_PORT=5678",
+						"PROTOCOL=tcp",
+						"PATH=" + os.Getenv("PATH"),
+					},
+				},
+				fake_command_runner.CommandSpec{
+					Path: containerDir + "/net.sh",
+					Args: []string{"in"},
+					Env: []string{
+						"HOST_PORT=1235",
+						"CONTAINER_PORT=5679",
+						"PROTOCOL=udp",
+						"PATH=" + os.Getenv("PATH"),
+					},
+				},
+			))
+		})
+
 		It("should redo iptables setup", func() {
 			err := container.Restore(linux_backend.LinuxContainerSpec{
 				ID:        "test-container",
@@ -595,8 +751,9 @@ var _ = Describe("Linux containers", func() {
 				Resources: containerResources,
 
 				Limits: linux_backend.Limits{
-					Memory: &garden.MemoryLimits{
-						LimitInBytes: 1024,
+					Memory: &linux_backend.MemoryLimits{
+						MemoryLimits: garden.MemoryLimits{LimitInBytes: 1024},
+						SwapInBytes:  512,
 					},
 				},
 			})
@@ -614,7 +771,7 @@ var _ = Describe("Linux containers", func() {
 				fake_cgroups_manager.SetValue{
 					Subsystem: "memory",
 					Name:      "memory.memsw.limit_in_bytes",
-					Value:     "1024",
+					Value:     "1536",
 				},
 			))
 
@@ -622,6 +779,112 @@ var _ = Describe("Linux containers", func() {
 			Eventually(container.Events).Should(ContainElement("out of memory"))
 		})
 
+		It("re-enforces the CPU limits", func() {
+			err := container.Restore(linux_backend.LinuxContainerSpec{
+				State:     "active",
+				Events:    []string{},
+				Resources: containerResources,
+
+				Limits: linux_backend.Limits{
+					CPU: &garden.CPULimits{
+						LimitInShares: 512,
+					},
+					CPUQuota: &linux_backend.CPUQuotaLimits{
+						PeriodInMicroseconds: 100000,
+						QuotaInMicroseconds:  50000,
+					},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCgroups.SetValues()).To(ContainElement(
+				fake_cgroups_manager.SetValue{
+					Subsystem: "cpu",
+					Name:      "cpu.shares",
+					Value:     "512",
+				},
+			))
+
+			Expect(fakeCgroups.SetValues()).To(ContainElement(
+				fake_cgroups_manager.SetValue{
+					Subsystem: "cpu",
+					Name:      "cpu.cfs_quota_us",
+					Value:     "50000",
+				},
+			))
+		})
+
+		It("re-enforces the cpuset limits", func() {
+			err := container.Restore(linux_backend.LinuxContainerSpec{
+				State:     "active",
+				Events:    []string{},
+				Resources: containerResources,
+
+				Limits: linux_backend.Limits{
+					CPUSet: &linux_backend.CPUSetLimits{
+						CPUs: "2-3",
+						Mems: "0",
+					},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCgroups.SetValues()).To(ContainElement(
+				fake_cgroups_manager.SetValue{
+					Subsystem: "cpuset",
+					Name:      "cpuset.cpus",
+					Value:     "2-3",
+				},
+			))
+		})
+
+		It("re-enforces the pid limits", func() {
+			err := container.Restore(linux_backend.LinuxContainerSpec{
+				State:     "active",
+				Events:    []string{},
+				Resources: containerResources,
+
+				Limits: linux_backend.Limits{
+					Pids: &linux_backend.PidLimits{Max: 256},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCgroups.SetValues()).To(ContainElement(
+				fake_cgroups_manager.SetValue{
+					Subsystem: "pids",
+					Name:      "pids.max",
+					Value:     "256",
+				},
+			))
+		})
+
+		It("re-enforces the block io limits", func() {
+			err := container.Restore(linux_backend.LinuxContainerSpec{
+				State:     "active",
+				Events:    []string{},
+				Resources: containerResources,
+
+				Limits: linux_backend.Limits{
+					IO: &linux_backend.IOLimits{
+						Weight: 200,
+						Devices: []linux_backend.DeviceIOLimits{
+							{Device: "8:0", WriteIOPerSecond: 50},
+						},
+					},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCgroups.SetValues()).To(ContainElement(
+				fake_cgroups_manager.SetValue{
+					Subsystem: "blkio",
+					Name:      "blkio.throttle.write_iops_device",
+					Value:     "8:0 50",
+				},
+			))
+		})
+
 		Context("when no memory limit is present", func() {
 			It("does not set a limit", func() {
 				err := container.Restore(linux_backend.LinuxContainerSpec{
@@ -651,8 +914,8 @@ var _ = Describe("Linux containers", func() {
 					Resources: containerResources,
 
 					Limits: linux_backend.Limits{
-						Memory: &garden.MemoryLimits{
-							LimitInBytes: 1024,
+						Memory: &linux_backend.MemoryLimits{
+							MemoryLimits: garden.MemoryLimits{LimitInBytes: 1024},
 						},
 					},
 				})
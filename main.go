@@ -24,6 +24,7 @@ import (
 	"github.com/cloudfoundry-incubator/cf-debug-server"
 	"github.com/cloudfoundry-incubator/cf-lager"
 	"github.com/cloudfoundry-incubator/garden-linux/container_repository"
+	"github.com/cloudfoundry-incubator/garden-linux/cpuset_pool"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/bandwidth_manager"
@@ -86,6 +87,12 @@ var snapshotsPath = flag.String(
 	"directory in which to store container state to persist through restarts",
 )
 
+var journalPath = flag.String(
+	"journal",
+	"",
+	"directory in which to journal container state on every change, so that containers survive a crash",
+)
+
 var binPath = flag.String(
 	"bin",
 	"",
@@ -186,6 +193,36 @@ var metricsEmissionInterval = flag.Duration(
 	"Interval in which to emit metrics to the metron agent",
 )
 
+var prometheusPropertyLabels = flag.String(
+	"prometheusPropertyLabels",
+	"",
+	"comma-separated container properties to label the container series on the debug server's /metrics endpoint with",
+)
+
+var metronContainerMetrics = flag.String(
+	"metronContainerMetrics",
+	"",
+	"comma-separated per-container metrics to emit to the metron agent, from ContainerCPUUsage, ContainerMemoryUsage, ContainerDiskUsage, ContainerRxBytes and ContainerTxBytes, each named <metric>.<handle> as the metron value metrics cannot be tagged (default: none)",
+)
+
+var metricsSampleInterval = flag.Duration(
+	"metricsSampleInterval",
+	10*time.Second,
+	"Interval in which to sample container metrics for computing rates",
+)
+
+var cpuThrottlingEventThreshold = flag.Float64(
+	"cpuThrottlingEventThreshold",
+	0,
+	"fraction of CFS periods between metrics samples in which a container must be throttled for a cpu-throttled event to be emitted (default: 0, no events)",
+)
+
+var metricsRateWindows = flag.String(
+	"metricsRateWindows",
+	"1m,5m,15m",
+	"comma-separated windows over which to compute container metric rates",
+)
+
 var allowHostAccess = flag.Bool(
 	"allowHostAccess",
 	false,
@@ -198,6 +235,18 @@ var iptablesLogMethod = flag.String(
 	"type of iptable logging to use, one of 'kernel' or 'nflog' (default: kernel)",
 )
 
+var firewall = flag.String(
+	"firewall",
+	"iptables",
+	"tool used to program container filtering and NAT, one of 'iptables' or 'nftables' (default: iptables)",
+)
+
+var nftPath = flag.String(
+	"nftPath",
+	"/usr/sbin/nft",
+	"path to the nft binary used with -firewall=nftables",
+)
+
 var mtu = flag.Int(
 	"mtu",
 	DefaultMTUSize,
@@ -216,12 +265,24 @@ var maxContainers = flag.Uint(
 	"Maximum number of containers that can be created",
 )
 
+var maxContainerPids = flag.Uint64(
+	"maxContainerPids",
+	0,
+	"Maximum number of processes and threads in each container (0 means no limit)",
+)
+
 var graphDriverName = flag.String(
 	"graphDriver",
 	"auto",
 	"Docker graph driver to use. Only aufs is officially supported, but others may work.",
 )
 
+var eventBufferSize = flag.Int(
+	"eventBufferSize",
+	1024,
+	"number of container lifecycle events buffered for each subscriber before events are dropped",
+)
+
 func main() {
 	if reexec.Init() {
 		return
@@ -297,6 +358,22 @@ func main() {
 		logger.Fatal("invalid pool range", err)
 	}
 
+	onlineCPUs, err := cpuset_pool.ReadOnline(cpuset_pool.OnlineCPUsPath)
+	if err != nil {
+		logger.Fatal("failed-to-read-online-cpus", err)
+	}
+
+	// hosts without NUMA support do not expose any nodes
+	onlineMems, err := cpuset_pool.ReadOnline(cpuset_pool.OnlineMemsPath)
+	if err != nil {
+		onlineMems = []int{0}
+	}
+
+	cpusetPool := cpuset_pool.New(onlineCPUs, onlineMems)
+
+	unifiedCgroups := cgroups_manager.IsUnified(cgroups_manager.HostCgroupsPath)
+	logger.Info("cgroups", lager.Data{"unified": unifiedCgroups})
+
 	useKernelLogging := true
 	switch *iptablesLogMethod {
 	case "nflog":
@@ -310,10 +387,40 @@ func main() {
 		return
 	}
 
-	config := sysconfig.NewConfig(*tag, *allowHostAccess, dnsServers.List)
+	switch sysconfig.Firewall(*firewall) {
+	case sysconfig.FirewallIPTables, sysconfig.FirewallNFTables:
+		/* noop */
+	default:
+		println("-firewall value not recognized")
+		println()
+		flag.Usage()
+		return
+	}
+
+	if *metricsSampleInterval <= 0 {
+		println("-metricsSampleInterval must be positive")
+		println()
+		flag.Usage()
+		return
+	}
+
+	rateWindows := parseRateWindows(logger, *metricsRateWindows)
+	if err := metrics.ValidateRateWindows(*metricsSampleInterval, rateWindows); err != nil {
+		println("-metricsRateWindows must each be at least -metricsSampleInterval:", err.Error())
+		println()
+		flag.Usage()
+		return
+	}
+
+	config := sysconfig.NewConfig(*tag, *allowHostAccess, dnsServers.List, sysconfig.Firewall(*firewall))
+	config.NFTables.Path = *nftPath
 
 	runner := sysconfig.NewRunner(config, linux_command_runner.New())
 
+	if config.Firewall == sysconfig.FirewallIPTables {
+		logger.Info("iptables-restore", lager.Data{"wait": iptables.ProbeRestoreWait(runner)})
+	}
+
 	if err := os.MkdirAll(*graphRoot, 0755); err != nil {
 		logger.Fatal("failed-to-create-graph-directory", err)
 	}
@@ -346,10 +453,6 @@ func main() {
 
 	metricsProvider := metrics.NewMetrics(logger, backingStoresPath, *depotPath)
 
-	if dbgAddr := cf_debug_server.DebugAddress(flag.CommandLine); dbgAddr != "" {
-		metrics.StartDebugServer(dbgAddr, reconfigurableSink, metricsProvider)
-	}
-
 	dockerGraph, err := graph.NewGraph(*graphRoot, quotaedGraphDriver)
 	if err != nil {
 		logger.Fatal("failed-to-construct-graph", err)
@@ -368,7 +471,27 @@ func main() {
 		}
 	}
 
-	repo := container_repository.New()
+	var repo linux_backend.ContainerRepository = container_repository.New()
+	var journal *container_repository.PersistentContainerRepository
+	if *journalPath != "" {
+		journal, err = container_repository.NewPersistent(*journalPath, logger)
+		if err != nil {
+			logger.Fatal("failed-to-create-container-journal", err)
+		}
+
+		repo = journal
+	}
+
+	if dbgAddr := cf_debug_server.DebugAddress(flag.CommandLine); dbgAddr != "" {
+		propertyLabels, err := metrics.ParsePropertyLabels(*prometheusPropertyLabels)
+		if err != nil {
+			logger.Fatal("failed-to-parse-prometheus-property-labels", err)
+		}
+
+		prometheus := metrics.NewPrometheusHandler(logger, metricsProvider, repo, propertyLabels)
+		metrics.StartDebugServer(dbgAddr, reconfigurableSink, metricsProvider, prometheus)
+	}
+
 	retainer := cleaner.NewRetainer()
 
 	repoFetcher := &repository_fetcher.CompositeFetcher{
@@ -457,6 +580,15 @@ func main() {
 		DiffSizer: &quota_manager.AUFSDiffSizer{quotaedGraphDriver},
 	}
 
+	events := linux_backend.NewEventBus(logger, *eventBufferSize)
+
+	// containers journal the state changes they make themselves as they
+	// emit them
+	var containerEvents linux_backend.EventEmitter = events
+	if journal != nil {
+		containerEvents = journal.EventEmitter(events)
+	}
+
 	ipTablesMgr := createIPTablesManager(config, runner, logger)
 	injector := &provider{
 		useKernelLogging: useKernelLogging,
@@ -467,6 +599,14 @@ func main() {
 		ipTablesMgr:      ipTablesMgr,
 		sysconfig:        config,
 		quotaManager:     quotaManager,
+		events:           containerEvents,
+		unifiedCgroups:   unifiedCgroups,
+
+		// every sampling pass reads the conntrack table afresh, but only once
+		conntrackCounter: &linux_container.ProcConntrackCounter{
+			Path:   "/proc/net/nf_conntrack",
+			MaxAge: *metricsSampleInterval / 2,
+		},
 	}
 
 	currentContainerVersion, err := semver.Make(CurrentContainerVersion)
@@ -488,7 +628,7 @@ func main() {
 		bridgemgr.New("w"+config.Tag+"b-", &devices.Bridge{}, &devices.Link{}),
 		ipTablesMgr,
 		injector,
-		iptables.NewGlobalChain(config.IPTables.Filter.DefaultChain, runner, logger.Session("global-chain")),
+		createGlobalChain(config, runner, logger.Session("global-chain")),
 		portPool,
 		strings.Split(*denyNetworks, ","),
 		strings.Split(*allowNetworks, ","),
@@ -500,7 +640,13 @@ func main() {
 
 	systemInfo := sysinfo.NewProvider(*depotPath)
 
-	backend := linux_backend.New(logger, pool, repo, injector, systemInfo, layercake.GraphPath(*graphRoot), *snapshotsPath, int(*maxContainers))
+	clock := clock.NewClock()
+	containerSampler, err := metrics.NewContainerSampler(logger, repo, *metricsSampleInterval, rateWindows, clock, events, *cpuThrottlingEventThreshold)
+	if err != nil {
+		logger.Fatal("failed-to-create-container-sampler", err)
+	}
+
+	backend := linux_backend.New(logger, pool, repo, injector, cpusetPool, containerSampler, events, systemInfo, layercake.GraphPath(*graphRoot), *snapshotsPath, int(*maxContainers), *maxContainerPids)
 
 	err = backend.Setup()
 	if err != nil {
@@ -516,8 +662,19 @@ func main() {
 		logger.Fatal("failed-to-start-server", err)
 	}
 
-	clock := clock.NewClock()
-	metronNotifier := metrics.NewPeriodicMetronNotifier(logger, metricsProvider, *metricsEmissionInterval, clock)
+	containerSampler.Start()
+
+	var containerMetricNames []string
+	if *metronContainerMetrics != "" {
+		containerMetricNames = strings.Split(*metronContainerMetrics, ",")
+	}
+
+	containerMetrics, err := metrics.ParseContainerMetrics(containerMetricNames)
+	if err != nil {
+		logger.Fatal("failed-to-parse-metron-container-metrics", err)
+	}
+
+	metronNotifier := metrics.NewPeriodicMetronNotifier(logger, metricsProvider, repo, containerMetrics, *metricsEmissionInterval, clock)
 	metronNotifier.Start()
 
 	signals := make(chan os.Signal, 1)
@@ -530,6 +687,7 @@ func main() {
 
 		gardenServer.Stop()
 		metronNotifier.Stop()
+		containerSampler.Stop()
 
 		os.Exit(0)
 	}()
@@ -551,6 +709,20 @@ func missing(flagName string) {
 	os.Exit(1)
 }
 
+func parseRateWindows(logger lager.Logger, windows string) []time.Duration {
+	parsed := []time.Duration{}
+	for _, window := range strings.Split(windows, ",") {
+		duration, err := time.ParseDuration(strings.TrimSpace(window))
+		if err != nil {
+			logger.Fatal("failed-to-parse-metrics-rate-window", err, lager.Data{"window": window})
+		}
+
+		parsed = append(parsed, duration)
+	}
+
+	return parsed
+}
+
 func initializeDropsonde(logger lager.Logger) {
 	err := dropsonde.Initialize(*dropsondeDestination, *dropsondeOrigin)
 	if err != nil {
@@ -558,9 +730,23 @@ func initializeDropsonde(logger lager.Logger) {
 	}
 }
 
-func createIPTablesManager(sysconfig sysconfig.Config, runner command_runner.CommandRunner, log lager.Logger) linux_container.IPTablesManager {
-	filterChain := iptables_manager.NewFilterChain(&sysconfig.IPTables.Filter, runner, log.Session("iptables-manager-filter"))
-	natChain := iptables_manager.NewNATChain(&sysconfig.IPTables.NAT, runner, log.Session("iptables-manager-nat"))
+func createGlobalChain(config sysconfig.Config, runner command_runner.CommandRunner, log lager.Logger) iptables.Chain {
+	if config.Firewall == sysconfig.FirewallNFTables {
+		return iptables.NewNFTablesGlobalChain(config.NFTables.Path, config.NFTables.FilterTable, config.IPTables.Filter.DefaultChain, runner, log)
+	}
+
+	return iptables.NewRestoringGlobalChain(config.IPTables.Filter.DefaultChain, runner, log)
+}
+
+func createIPTablesManager(config sysconfig.Config, runner command_runner.CommandRunner, log lager.Logger) linux_container.IPTablesManager {
+	if config.Firewall == sysconfig.FirewallNFTables {
+		filterChain := iptables_manager.NewNFTablesFilterChain(config.NFTables.Path, config.NFTables.FilterTable, &config.IPTables.Filter, runner, log.Session("iptables-manager-filter"))
+		natChain := iptables_manager.NewNFTablesNATChain(config.NFTables.Path, config.NFTables.NATTable, &config.IPTables.NAT, runner, log.Session("iptables-manager-nat"))
+		return iptables_manager.New().AddChain(filterChain).AddChain(natChain)
+	}
+
+	filterChain := iptables_manager.NewFilterChain(&config.IPTables.Filter, runner, log.Session("iptables-manager-filter"))
+	natChain := iptables_manager.NewNATChain(&config.IPTables.NAT, runner, log.Session("iptables-manager-nat"))
 	return iptables_manager.New().AddChain(filterChain).AddChain(natChain)
 }
 
@@ -573,22 +759,39 @@ type provider struct {
 	ipTablesMgr      linux_container.IPTablesManager
 	quotaManager     linux_container.QuotaManager
 	sysconfig        sysconfig.Config
+	events           linux_backend.EventEmitter
+	unifiedCgroups   bool
+	conntrackCounter *linux_container.ProcConntrackCounter
 }
 
 func (p *provider) ProvideFilter(containerId string) network.Filter {
-	return network.NewFilter(iptables.NewLoggingChain(p.chainPrefix+containerId, p.useKernelLogging, p.runner, p.log.Session(containerId).Session("filter")))
+	if p.sysconfig.Firewall == sysconfig.FirewallNFTables {
+		return network.NewFilter(iptables.NewNFTablesLoggingChain(p.sysconfig.NFTables.Path, p.sysconfig.NFTables.FilterTable, p.chainPrefix+containerId, p.useKernelLogging, p.runner, p.log.Session(containerId).Session("filter")))
+	}
+
+	return network.NewFilter(iptables.NewRestoringLoggingChain(p.chainPrefix+containerId, p.useKernelLogging, p.runner, p.log.Session(containerId).Session("filter")))
 }
 
 func (p *provider) ProvideContainer(spec linux_backend.LinuxContainerSpec) linux_backend.Container {
-	cgroupReader := &cgroups_manager.LinuxCgroupReader{
-		Path: p.sysconfig.CgroupNodeFilePath,
-	}
+	containerLog := p.log.Session("container", lager.Data{"handle": spec.Handle})
 
-	cgroupsManager := cgroups_manager.New(p.sysconfig.CgroupPath, spec.ID, cgroupReader)
+	var cgroupsManager linux_container.CgroupsManager
+	var oomWatcher linux_container.Watcher
 
-	oomWatcher := linux_container.NewOomNotifier(
-		p.runner, spec.ContainerPath, cgroupsManager,
-	)
+	if p.unifiedCgroups {
+		cgroupsManager = cgroups_manager.NewUnified(path.Join(p.sysconfig.CgroupPath, cgroups_manager.UnifiedParent), spec.ID)
+		oomWatcher = linux_container.NewMemoryEventsWatcher(cgroupsManager, time.Second, containerLog)
+	} else {
+		cgroupReader := &cgroups_manager.LinuxCgroupReader{
+			Path: p.sysconfig.CgroupNodeFilePath,
+		}
+
+		cgroupsManager = cgroups_manager.New(p.sysconfig.CgroupPath, spec.ID, cgroupReader)
+		oomWatcher = linux_container.NewMemoryNotifier(cgroupsManager, containerLog)
+	}
+
+	hostIfc := devices.Link{Name: p.sysconfig.NetworkInterfacePrefix + spec.ID + "-0"}
+	ifbIfc := devices.Link{Name: p.sysconfig.NetworkInterfacePrefix + spec.ID + "-i"}
 
 	return linux_container.NewLinuxContainer(
 		spec,
@@ -596,16 +799,24 @@ func (p *provider) ProvideContainer(spec linux_backend.LinuxContainerSpec) linux
 		p.runner,
 		cgroupsManager,
 		p.quotaManager,
-		bandwidth_manager.New(spec.ContainerPath, spec.ID, p.runner),
+		bandwidth_manager.NewNetlink(hostIfc, ifbIfc.Name, ifbIfc, devices.IFB{}),
 		process_tracker.New(spec.ContainerPath, p.runner),
 		p.ProvideFilter(spec.ID),
 		p.ipTablesMgr,
-		devices.Link{Name: p.sysconfig.NetworkInterfacePrefix + spec.ID + "-0"},
+		hostIfc,
 		oomWatcher,
-		p.log.Session("container", lager.Data{"handle": spec.Handle}),
+		&linux_container.ProcOOMKiller{ProcPath: "/proc"},
+		&linux_container.ProcFileDescriptorCounter{ProcPath: "/proc"},
+		p.conntrackCounter,
+		p.events,
+		containerLog,
 	)
 }
 
+func (p *provider) ValidateOOMPolicy(policy linux_backend.OOMPolicy) error {
+	return linux_container.ValidateOOMPolicy(policy, p.unifiedCgroups)
+}
+
 func selectGraphDriver(logger lager.Logger, name string, graphRoot string) (graphdriver.Driver, error) {
 	// silence docker graph debug logging; we'll do our own warning for non-aufs
 	// driver selection
@@ -0,0 +1,348 @@
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	"github.com/pivotal-golang/clock"
+	"github.com/pivotal-golang/lager"
+)
+
+type ContainerLister interface {
+	All() []linux_backend.Container
+}
+
+type NotEnoughSamplesError struct {
+	Handle string
+}
+
+func (e NotEnoughSamplesError) Error() string {
+	return fmt.Sprintf("not enough samples of %s to compute rates", e.Handle)
+}
+
+// Sample is a point-in-time reading of the container counters which rates are
+// computed from.
+type Sample struct {
+	Time time.Time
+
+	CPUUsage    uint64
+	RxBytes     uint64
+	TxBytes     uint64
+	MemoryUsage uint64
+
+	CPUPeriods          uint64
+	CPUThrottledPeriods uint64
+}
+
+// ContainerSampler periodically samples the metrics of every container,
+// keeping enough samples to cover the longest of its windows, and computes
+// rates from them. A container's samples are discarded once it is gone.
+//
+// If a container was throttled in more than throttlingThreshold of the CFS
+// periods since its previous sample, a cpu-throttled event is emitted. A zero
+// threshold disables the events.
+type ContainerSampler struct {
+	logger     lager.Logger
+	containers ContainerLister
+	interval   time.Duration
+	windows    []time.Duration
+	clock      clock.Clock
+
+	events              linux_backend.EventEmitter
+	throttlingThreshold float64
+
+	capacity int
+
+	historiesMutex sync.RWMutex
+	histories      map[string]*sampleRing
+
+	stopped chan struct{}
+}
+
+type InvalidSampleIntervalError struct {
+	Interval time.Duration
+}
+
+func (e InvalidSampleIntervalError) Error() string {
+	return fmt.Sprintf("sample interval must be positive: %s", e.Interval)
+}
+
+type InvalidRateWindowError struct {
+	Window   time.Duration
+	Interval time.Duration
+}
+
+func (e InvalidRateWindowError) Error() string {
+	return fmt.Sprintf("rate window must be at least the sample interval of %s: %s", e.Interval, e.Window)
+}
+
+// ValidateRateWindows checks that every window spans at least one sample
+// interval, as a rate needs two samples.
+func ValidateRateWindows(interval time.Duration, windows []time.Duration) error {
+	for _, window := range windows {
+		if window < interval {
+			return InvalidRateWindowError{Window: window, Interval: interval}
+		}
+	}
+
+	return nil
+}
+
+func NewContainerSampler(
+	logger lager.Logger,
+	containers ContainerLister,
+	interval time.Duration,
+	windows []time.Duration,
+	clock clock.Clock,
+	events linux_backend.EventEmitter,
+	throttlingThreshold float64,
+) (*ContainerSampler, error) {
+	if interval <= 0 {
+		return nil, InvalidSampleIntervalError{Interval: interval}
+	}
+
+	if err := ValidateRateWindows(interval, windows); err != nil {
+		return nil, err
+	}
+
+	var longest time.Duration
+	for _, window := range windows {
+		if window > longest {
+			longest = window
+		}
+	}
+
+	// a rate needs at least two samples
+	capacity := int(longest/interval) + 1
+	if capacity < 2 {
+		capacity = 2
+	}
+
+	return &ContainerSampler{
+		logger:     logger.Session("container-sampler", lager.Data{"interval": interval.String()}),
+		containers: containers,
+		interval:   interval,
+		windows:    windows,
+		clock:      clock,
+
+		events:              events,
+		throttlingThreshold: throttlingThreshold,
+
+		capacity:  capacity,
+		histories: map[string]*sampleRing{},
+
+		stopped: make(chan struct{}),
+	}, nil
+}
+
+func (s *ContainerSampler) Start() {
+	s.logger.Info("starting")
+	ticker := s.clock.NewTicker(s.interval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C():
+				s.SampleAll()
+			case <-s.stopped:
+				s.logger.Info("finished")
+				return
+			}
+		}
+	}()
+}
+
+func (s *ContainerSampler) Stop() {
+	close(s.stopped)
+}
+
+// SampleAll takes a sample of each container and forgets those which no
+// longer exist.
+func (s *ContainerSampler) SampleAll() {
+	now := s.clock.Now()
+
+	samples := map[string]Sample{}
+	for _, container := range s.containers.All() {
+		metrics, err := container.DetailedMetrics()
+		if err != nil {
+			s.logger.Error("failed-to-get-metrics", err, lager.Data{"handle": container.Handle()})
+			continue
+		}
+
+		samples[container.Handle()] = Sample{
+			Time:        now,
+			CPUUsage:    metrics.CPUStat.Usage,
+			RxBytes:     metrics.NetworkStat.RxBytes,
+			TxBytes:     metrics.NetworkStat.TxBytes,
+			MemoryUsage: metrics.MemoryStat.TotalUsageTowardLimit,
+
+			CPUPeriods:          metrics.CPUThrottlingStat.Periods,
+			CPUThrottledPeriods: metrics.CPUThrottlingStat.ThrottledPeriods,
+		}
+	}
+
+	s.historiesMutex.Lock()
+
+	for handle := range s.histories {
+		if _, found := samples[handle]; !found {
+			delete(s.histories, handle)
+		}
+	}
+
+	throttled := []linux_backend.Event{}
+	for handle, sample := range samples {
+		history, found := s.histories[handle]
+		if !found {
+			history = newSampleRing(s.capacity)
+			s.histories[handle] = history
+		}
+
+		if previous, ok := history.Latest(); ok {
+			if event, over := s.throttlingEvent(handle, previous, sample); over {
+				throttled = append(throttled, event)
+			}
+		}
+
+		history.Add(sample)
+	}
+
+	s.historiesMutex.Unlock()
+
+	for _, event := range throttled {
+		s.events.Emit(event)
+	}
+}
+
+func (s *ContainerSampler) throttlingEvent(handle string, previous, latest Sample) (linux_backend.Event, bool) {
+	if s.throttlingThreshold <= 0 {
+		return linux_backend.Event{}, false
+	}
+
+	periods := counterDelta(previous.CPUPeriods, latest.CPUPeriods)
+	if periods == 0 {
+		return linux_backend.Event{}, false
+	}
+
+	throttledPeriods := counterDelta(previous.CPUThrottledPeriods, latest.CPUThrottledPeriods)
+
+	ratio := float64(throttledPeriods) / float64(periods)
+	if ratio <= s.throttlingThreshold {
+		return linux_backend.Event{}, false
+	}
+
+	return linux_backend.Event{
+		Type:      linux_backend.EventCPUThrottled,
+		Handle:    handle,
+		Timestamp: latest.Time,
+		Data: map[string]string{
+			"ratio":             strconv.FormatFloat(ratio, 'f', 2, 64),
+			"periods":           strconv.FormatUint(periods, 10),
+			"throttled_periods": strconv.FormatUint(throttledPeriods, 10),
+		},
+	}, true
+}
+
+func (s *ContainerSampler) Windows() []time.Duration {
+	return s.windows
+}
+
+// Samples returns the container's samples, oldest first.
+func (s *ContainerSampler) Samples(handle string) []Sample {
+	s.historiesMutex.RLock()
+	defer s.historiesMutex.RUnlock()
+
+	history, found := s.histories[handle]
+	if !found {
+		return []Sample{}
+	}
+
+	return history.All()
+}
+
+// Rates computes rates between the latest sample and the oldest sample no
+// more than window before it.
+func (s *ContainerSampler) Rates(handle string, window time.Duration) (linux_backend.MetricRates, error) {
+	samples := s.Samples(handle)
+	if len(samples) < 2 {
+		return linux_backend.MetricRates{}, NotEnoughSamplesError{Handle: handle}
+	}
+
+	latest := samples[len(samples)-1]
+
+	earliest := latest
+	for _, sample := range samples {
+		if latest.Time.Sub(sample.Time) <= window {
+			earliest = sample
+			break
+		}
+	}
+
+	elapsed := latest.Time.Sub(earliest.Time)
+	if elapsed <= 0 {
+		return linux_backend.MetricRates{}, NotEnoughSamplesError{Handle: handle}
+	}
+
+	seconds := elapsed.Seconds()
+
+	return linux_backend.MetricRates{
+		Window: elapsed,
+
+		// cpuacct.usage is in nanoseconds of CPU time
+		CPUPercent:                 float64(counterDelta(earliest.CPUUsage, latest.CPUUsage)) / float64(elapsed.Nanoseconds()) * 100,
+		RxBytesPerSecond:           float64(counterDelta(earliest.RxBytes, latest.RxBytes)) / seconds,
+		TxBytesPerSecond:           float64(counterDelta(earliest.TxBytes, latest.TxBytes)) / seconds,
+		MemoryGrowthBytesPerSecond: (float64(latest.MemoryUsage) - float64(earliest.MemoryUsage)) / seconds,
+	}, nil
+}
+
+// counterDelta treats a counter which went backwards, e.g. as the interface
+// was recreated, as having been reset to zero.
+func counterDelta(earlier, later uint64) uint64 {
+	if later < earlier {
+		return later
+	}
+
+	return later - earlier
+}
+
+// sampleRing is a bounded buffer of samples which overwrites the oldest once
+// full.
+type sampleRing struct {
+	samples []Sample
+	next    int
+	full    bool
+}
+
+func newSampleRing(capacity int) *sampleRing {
+	return &sampleRing{samples: make([]Sample, capacity)}
+}
+
+func (r *sampleRing) Add(sample Sample) {
+	r.samples[r.next] = sample
+
+	r.next = (r.next + 1) % len(r.samples)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+func (r *sampleRing) Latest() (Sample, bool) {
+	if !r.full && r.next == 0 {
+		return Sample{}, false
+	}
+
+	return r.samples[(r.next+len(r.samples)-1)%len(r.samples)], true
+}
+
+func (r *sampleRing) All() []Sample {
+	if !r.full {
+		return append([]Sample{}, r.samples[:r.next]...)
+	}
+
+	return append(append([]Sample{}, r.samples[r.next:]...), r.samples[:r.next]...)
+}
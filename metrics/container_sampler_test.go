@@ -0,0 +1,264 @@
+package metrics_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden-linux/container_repository"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend/fakes"
+	"github.com/cloudfoundry-incubator/garden-linux/metrics"
+	"github.com/pivotal-golang/clock/fakeclock"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ContainerSampler", func() {
+	var (
+		containers *container_repository.InMemoryContainerRepository
+		container  *fakes.FakeContainer
+		fakeClock  *fakeclock.FakeClock
+		events     *fakes.FakeEventEmitter
+
+		throttlingThreshold float64
+
+		sampler *metrics.ContainerSampler
+	)
+
+	const interval = 10 * time.Second
+
+	containerMetrics := func(cpuUsage, rxBytes, txBytes, memoryUsage uint64) linux_backend.ContainerMetrics {
+		return linux_backend.ContainerMetrics{
+			Metrics: garden.Metrics{
+				CPUStat:     garden.ContainerCPUStat{Usage: cpuUsage},
+				NetworkStat: garden.ContainerNetworkStat{RxBytes: rxBytes, TxBytes: txBytes},
+				MemoryStat:  garden.ContainerMemoryStat{TotalUsageTowardLimit: memoryUsage},
+			},
+		}
+	}
+
+	// sample takes a sample with the given metrics and then moves the clock on
+	sample := func(metrics linux_backend.ContainerMetrics) {
+		container.DetailedMetricsReturns(metrics, nil)
+		sampler.SampleAll()
+		fakeClock.Increment(interval)
+	}
+
+	BeforeEach(func() {
+		containers = container_repository.New()
+
+		container = new(fakes.FakeContainer)
+		container.HandleReturns("some-handle")
+		containers.Add(container)
+
+		fakeClock = fakeclock.NewFakeClock(time.Unix(123, 456))
+		events = new(fakes.FakeEventEmitter)
+		throttlingThreshold = 0
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		sampler, err = metrics.NewContainerSampler(
+			lagertest.NewTestLogger("test"),
+			containers,
+			interval,
+			[]time.Duration{20 * time.Second, 40 * time.Second},
+			fakeClock,
+			events,
+			throttlingThreshold,
+		)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("rejects intervals which are not positive", func() {
+		for _, interval := range []time.Duration{0, -time.Second} {
+			_, err := metrics.NewContainerSampler(
+				lagertest.NewTestLogger("test"),
+				containers,
+				interval,
+				[]time.Duration{20 * time.Second},
+				fakeClock,
+				events,
+				throttlingThreshold,
+			)
+			Expect(err).To(MatchError(metrics.InvalidSampleIntervalError{Interval: interval}))
+		}
+	})
+
+	It("rejects windows shorter than the interval", func() {
+		for _, window := range []time.Duration{0, -time.Minute, interval / 2} {
+			_, err := metrics.NewContainerSampler(
+				lagertest.NewTestLogger("test"),
+				containers,
+				interval,
+				[]time.Duration{20 * time.Second, window},
+				fakeClock,
+				events,
+				throttlingThreshold,
+			)
+			Expect(err).To(MatchError(metrics.InvalidRateWindowError{Window: window, Interval: interval}))
+		}
+	})
+
+	It("has the configured windows", func() {
+		Expect(sampler.Windows()).To(Equal([]time.Duration{20 * time.Second, 40 * time.Second}))
+	})
+
+	It("keeps only enough samples to cover the longest window", func() {
+		for i := uint64(0); i < 10; i++ {
+			sample(containerMetrics(i, 0, 0, 0))
+		}
+
+		samples := sampler.Samples("some-handle")
+		Expect(samples).To(HaveLen(5))
+		Expect(samples[0].CPUUsage).To(Equal(uint64(5)))
+		Expect(samples[4].CPUUsage).To(Equal(uint64(9)))
+	})
+
+	It("computes rates over the window", func() {
+		sample(containerMetrics(0, 0, 0, 1000))
+		sample(containerMetrics(0, 0, 0, 1000))
+		sample(containerMetrics(5*uint64(time.Second), 100, 300, 1200))
+		sample(containerMetrics(10*uint64(time.Second), 200, 600, 1400))
+
+		rates, err := sampler.Rates("some-handle", 20*time.Second)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rates).To(Equal(linux_backend.MetricRates{
+			Window:                     20 * time.Second,
+			CPUPercent:                 50,
+			RxBytesPerSecond:           10,
+			TxBytesPerSecond:           30,
+			MemoryGrowthBytesPerSecond: 20,
+		}))
+
+		rates, err = sampler.Rates("some-handle", 40*time.Second)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rates.Window).To(Equal(30 * time.Second))
+		Expect(rates.MemoryGrowthBytesPerSecond).To(BeNumerically("~", 400.0/30))
+	})
+
+	It("reports shrinking memory as negative growth", func() {
+		sample(containerMetrics(0, 0, 0, 1000))
+		sample(containerMetrics(0, 0, 0, 800))
+
+		rates, err := sampler.Rates("some-handle", 20*time.Second)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rates.MemoryGrowthBytesPerSecond).To(Equal(-20.0))
+	})
+
+	It("treats counters which go backwards as reset", func() {
+		sample(containerMetrics(0, 1000, 0, 0))
+		sample(containerMetrics(0, 100, 0, 0))
+
+		rates, err := sampler.Rates("some-handle", 20*time.Second)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rates.RxBytesPerSecond).To(Equal(10.0))
+	})
+
+	Context("when there are not enough samples", func() {
+		It("returns an error", func() {
+			sample(containerMetrics(0, 0, 0, 0))
+
+			_, err := sampler.Rates("some-handle", 20*time.Second)
+			Expect(err).To(Equal(metrics.NotEnoughSamplesError{Handle: "some-handle"}))
+		})
+	})
+
+	Context("when the container is destroyed", func() {
+		It("discards its samples", func() {
+			sample(containerMetrics(0, 0, 0, 0))
+			sample(containerMetrics(0, 0, 0, 0))
+
+			containers.Delete(container)
+			sampler.SampleAll()
+
+			Expect(sampler.Samples("some-handle")).To(BeEmpty())
+		})
+	})
+
+	Context("when getting a container's metrics fails", func() {
+		It("skips the sample", func() {
+			sample(containerMetrics(0, 0, 0, 0))
+
+			container.DetailedMetricsReturns(linux_backend.ContainerMetrics{}, errors.New("banana"))
+			sampler.SampleAll()
+
+			Expect(sampler.Samples("some-handle")).To(HaveLen(1))
+		})
+	})
+
+	Context("when started", func() {
+		AfterEach(func() {
+			sampler.Stop()
+		})
+
+		It("samples every interval", func() {
+			sampler.Start()
+
+			Eventually(fakeClock.WatcherCount).Should(Equal(1))
+
+			fakeClock.Increment(interval)
+			Eventually(func() []metrics.Sample { return sampler.Samples("some-handle") }).Should(HaveLen(1))
+
+			fakeClock.Increment(interval)
+			Eventually(func() []metrics.Sample { return sampler.Samples("some-handle") }).Should(HaveLen(2))
+		})
+	})
+
+	Describe("CPU throttling", func() {
+		throttlingMetrics := func(periods, throttledPeriods uint64) linux_backend.ContainerMetrics {
+			return linux_backend.ContainerMetrics{
+				CPUThrottlingStat: linux_backend.ContainerCPUThrottlingStat{
+					Periods:          periods,
+					ThrottledPeriods: throttledPeriods,
+				},
+			}
+		}
+
+		Context("when no threshold is configured", func() {
+			It("does not emit events", func() {
+				sample(throttlingMetrics(0, 0))
+				sample(throttlingMetrics(100, 100))
+
+				Expect(events.EmitCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when a threshold is configured", func() {
+			BeforeEach(func() {
+				throttlingThreshold = 0.5
+			})
+
+			It("emits an event when the container was throttled in more of the periods since the last sample", func() {
+				sample(throttlingMetrics(100, 10))
+				sample(throttlingMetrics(200, 60))
+				Expect(events.EmitCallCount()).To(Equal(0))
+
+				throttledAt := fakeClock.Now()
+				sample(throttlingMetrics(300, 135))
+				Expect(events.EmitCallCount()).To(Equal(1))
+
+				Expect(events.EmitArgsForCall(0)).To(Equal(linux_backend.Event{
+					Type:      linux_backend.EventCPUThrottled,
+					Handle:    "some-handle",
+					Timestamp: throttledAt,
+					Data: map[string]string{
+						"ratio":             "0.75",
+						"periods":           "100",
+						"throttled_periods": "75",
+					},
+				}))
+			})
+
+			It("does not emit events when no periods have elapsed", func() {
+				sample(throttlingMetrics(100, 100))
+				sample(throttlingMetrics(100, 100))
+
+				Expect(events.EmitCallCount()).To(Equal(0))
+			})
+		})
+	})
+})
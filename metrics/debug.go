@@ -11,7 +11,7 @@ import (
 	"github.com/tedsuo/ifrit/http_server"
 )
 
-func StartDebugServer(address string, sink *lager.ReconfigurableSink, metrics Metrics) (ifrit.Process, error) {
+func StartDebugServer(address string, sink *lager.ReconfigurableSink, metrics Metrics, prometheus http.Handler) (ifrit.Process, error) {
 	expvar.Publish("numCPUS", expvar.Func(func() interface{} {
 		return metrics.NumCPU()
 	}))
@@ -32,7 +32,7 @@ func StartDebugServer(address string, sink *lager.ReconfigurableSink, metrics Me
 		return metrics.DepotDirs()
 	}))
 
-	server := http_server.New(address, handler(sink))
+	server := http_server.New(address, handler(sink, prometheus))
 	p := ifrit.Invoke(server)
 	select {
 	case <-p.Ready():
@@ -42,9 +42,13 @@ func StartDebugServer(address string, sink *lager.ReconfigurableSink, metrics Me
 	return p, nil
 }
 
-func handler(sink *lager.ReconfigurableSink) http.Handler {
+func handler(sink *lager.ReconfigurableSink, prometheus http.Handler) http.Handler {
 	pprofHandler := cf_debug_server.Handler(sink)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/metrics" {
+			prometheus.ServeHTTP(w, r)
+			return
+		}
 		if strings.HasPrefix(r.URL.Path, "/debug/vars") {
 			http.DefaultServeMux.ServeHTTP(w, r)
 			return
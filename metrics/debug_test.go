@@ -2,6 +2,7 @@ package metrics_test
 
 import (
 	"expvar"
+	"io/ioutil"
 	"net/http"
 	"os"
 
@@ -31,7 +32,11 @@ var _ = Describe("Debug", func() {
 		fakeMetrics.DepotDirsReturns(3)
 
 		sink := lager.NewReconfigurableSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG), lager.DEBUG)
-		serverProc, err = metrics.StartDebugServer("127.0.0.1:5123", sink, fakeMetrics)
+		prometheus := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("some-metrics"))
+		})
+
+		serverProc, err = metrics.StartDebugServer("127.0.0.1:5123", sink, fakeMetrics, prometheus)
 		Expect(err).ToNot(HaveOccurred())
 	})
 
@@ -51,5 +56,13 @@ var _ = Describe("Debug", func() {
 		Expect(expvar.Get("depotDirs").String()).To(Equal("3"))
 		Expect(expvar.Get("numCPUS").String()).To(Equal("11"))
 		Expect(expvar.Get("numGoRoutines").String()).To(Equal("888"))
+
+		By("serving the prometheus metrics")
+		metricsResp, err := http.Get("http://127.0.0.1:5123/metrics")
+		Expect(err).ToNot(HaveOccurred())
+
+		defer metricsResp.Body.Close()
+		Expect(metricsResp.StatusCode).To(Equal(http.StatusOK))
+		Expect(ioutil.ReadAll(metricsResp.Body)).To(Equal([]byte("some-metrics")))
 	})
 })
@@ -1,8 +1,10 @@
 package metrics
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
 	dropsonde_metrics "github.com/cloudfoundry/dropsonde/metrics"
 	"github.com/pivotal-golang/clock"
 	"github.com/pivotal-golang/lager"
@@ -14,6 +16,17 @@ func (name Metric) Send(value int) {
 	dropsonde_metrics.SendValue(string(name), float64(value), "Metric")
 }
 
+// ContainerMetric is a per-container metric. The value metrics sent by this
+// version of dropsonde cannot carry tags, so rather than tagging it with the
+// handle, the handle is appended to the name, e.g.
+// "ContainerCPUUsage.some-handle". Consumers of the firehose must match on the
+// name's prefix until dropsonde is upgraded to one with envelope tags.
+type ContainerMetric string
+
+func (name ContainerMetric) Send(handle string, value uint64, unit string) {
+	dropsonde_metrics.SendValue(fmt.Sprintf("%s.%s", name, handle), float64(value), unit)
+}
+
 type Duration string
 
 func (name Duration) Send(duration time.Duration) {
@@ -28,26 +41,95 @@ const (
 	metricsReportingDuration = Duration("MetricsReporting")
 )
 
+const (
+	ContainerCPUUsage    = ContainerMetric("ContainerCPUUsage")
+	ContainerMemoryUsage = ContainerMetric("ContainerMemoryUsage")
+	ContainerDiskUsage   = ContainerMetric("ContainerDiskUsage")
+	ContainerRxBytes     = ContainerMetric("ContainerRxBytes")
+	ContainerTxBytes     = ContainerMetric("ContainerTxBytes")
+)
+
+var containerMetrics = []ContainerMetric{
+	ContainerCPUUsage,
+	ContainerMemoryUsage,
+	ContainerDiskUsage,
+	ContainerRxBytes,
+	ContainerTxBytes,
+}
+
+type UnknownContainerMetricError struct {
+	Name string
+}
+
+func (e UnknownContainerMetricError) Error() string {
+	return fmt.Sprintf("unknown container metric: %s", e.Name)
+}
+
+// ParseContainerMetrics looks up the per-container metrics with the given
+// names.
+func ParseContainerMetrics(names []string) ([]ContainerMetric, error) {
+	parsed := []ContainerMetric{}
+	for _, name := range names {
+		found := false
+		for _, metric := range containerMetrics {
+			if string(metric) == name {
+				parsed = append(parsed, metric)
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, UnknownContainerMetricError{Name: name}
+		}
+	}
+
+	return parsed, nil
+}
+
+// containerStates are reported as a count of containers in each, even when
+// there are none.
+var containerStates = map[linux_backend.State]Metric{
+	linux_backend.StateBorn:    Metric("ContainersBorn"),
+	linux_backend.StateActive:  Metric("ContainersActive"),
+	linux_backend.StatePaused:  Metric("ContainersPaused"),
+	linux_backend.StateStopped: Metric("ContainersStopped"),
+}
+
 type PeriodicMetronNotifier struct {
 	Interval time.Duration
 	Logger   lager.Logger
 	Clock    clock.Clock
 
-	metrics Metrics
-	stopped chan struct{}
+	metrics          Metrics
+	containers       ContainerLister
+	containerMetrics map[ContainerMetric]bool
+	stopped          chan struct{}
 }
 
+// NewPeriodicMetronNotifier emits the daemon metrics and a count of the
+// containers in each state every interval. Only the per-container metrics in
+// containerMetrics are emitted, as each is sent once per container.
 func NewPeriodicMetronNotifier(
 	logger lager.Logger,
 	metrics Metrics,
+	containers ContainerLister,
+	containerMetrics []ContainerMetric,
 	interval time.Duration,
 	clock clock.Clock,
 ) *PeriodicMetronNotifier {
+	allowed := map[ContainerMetric]bool{}
+	for _, metric := range containerMetrics {
+		allowed[metric] = true
+	}
+
 	return &PeriodicMetronNotifier{
 		Interval: interval,
 		Logger:   logger,
 		Clock:    clock,
-		metrics:  metrics,
+
+		metrics:          metrics,
+		containers:       containers,
+		containerMetrics: allowed,
 
 		stopped: make(chan struct{}),
 	}
@@ -73,6 +155,8 @@ func (notifier PeriodicMetronNotifier) Start() {
 				backingStores.Send(notifier.metrics.BackingStores())
 				depotDirs.Send(notifier.metrics.DepotDirs())
 
+				notifier.sendContainerMetrics(logger)
+
 				finishedAt := notifier.Clock.Now()
 				metricsReportingDuration.Send(finishedAt.Sub(startedAt))
 			case <-notifier.stopped:
@@ -85,3 +169,38 @@ func (notifier PeriodicMetronNotifier) Start() {
 func (notifier PeriodicMetronNotifier) Stop() {
 	close(notifier.stopped)
 }
+
+func (notifier PeriodicMetronNotifier) sendContainerMetrics(logger lager.Logger) {
+	counts := map[linux_backend.State]int{}
+
+	for _, container := range notifier.containers.All() {
+		counts[container.State()]++
+
+		if len(notifier.containerMetrics) == 0 {
+			continue
+		}
+
+		metrics, err := container.DetailedMetrics()
+		if err != nil {
+			logger.Error("failed-to-get-container-metrics", err, lager.Data{"handle": container.Handle()})
+			continue
+		}
+
+		handle := container.Handle()
+		notifier.sendContainerMetric(ContainerCPUUsage, handle, metrics.CPUStat.Usage, "nanos")
+		notifier.sendContainerMetric(ContainerMemoryUsage, handle, metrics.MemoryStat.TotalUsageTowardLimit, "bytes")
+		notifier.sendContainerMetric(ContainerDiskUsage, handle, metrics.DiskStat.TotalBytesUsed, "bytes")
+		notifier.sendContainerMetric(ContainerRxBytes, handle, metrics.NetworkStat.RxBytes, "bytes")
+		notifier.sendContainerMetric(ContainerTxBytes, handle, metrics.NetworkStat.TxBytes, "bytes")
+	}
+
+	for state, metric := range containerStates {
+		metric.Send(counts[state])
+	}
+}
+
+func (notifier PeriodicMetronNotifier) sendContainerMetric(metric ContainerMetric, handle string, value uint64, unit string) {
+	if notifier.containerMetrics[metric] {
+		metric.Send(handle, value, unit)
+	}
+}
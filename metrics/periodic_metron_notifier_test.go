@@ -1,8 +1,13 @@
 package metrics_test
 
 import (
+	"errors"
 	"time"
 
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden-linux/container_repository"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	linux_backend_fakes "github.com/cloudfoundry-incubator/garden-linux/linux_backend/fakes"
 	"github.com/cloudfoundry-incubator/garden-linux/metrics"
 	"github.com/cloudfoundry-incubator/garden-linux/metrics/fakes"
 	"github.com/cloudfoundry/dropsonde/metric_sender/fake"
@@ -18,9 +23,11 @@ var _ = Describe("PeriodicMetronNotifier", func() {
 	var (
 		sender *fake.FakeMetricSender
 
-		fakeMetrics    *fakes.FakeMetrics
-		reportInterval time.Duration
-		fakeClock      *fakeclock.FakeClock
+		fakeMetrics      *fakes.FakeMetrics
+		containers       *container_repository.InMemoryContainerRepository
+		containerMetrics []metrics.ContainerMetric
+		reportInterval   time.Duration
+		fakeClock        *fakeclock.FakeClock
 
 		pmn *metrics.PeriodicMetronNotifier
 	)
@@ -33,6 +40,9 @@ var _ = Describe("PeriodicMetronNotifier", func() {
 		fakeMetrics.BackingStoresReturns(12)
 		fakeMetrics.DepotDirsReturns(3)
 
+		containers = container_repository.New()
+		containerMetrics = nil
+
 		fakeClock = fakeclock.NewFakeClock(time.Unix(123, 456))
 
 		sender = fake.NewFakeMetricSender()
@@ -43,6 +53,8 @@ var _ = Describe("PeriodicMetronNotifier", func() {
 		pmn = metrics.NewPeriodicMetronNotifier(
 			lagertest.NewTestLogger("test"),
 			fakeMetrics,
+			containers,
+			containerMetrics,
 			reportInterval,
 			fakeClock,
 		)
@@ -78,5 +90,128 @@ var _ = Describe("PeriodicMetronNotifier", func() {
 				Unit:  "Metric",
 			}))
 		})
+
+		Context("when there are containers", func() {
+			var container1, container2 *linux_backend_fakes.FakeContainer
+
+			BeforeEach(func() {
+				container1 = new(linux_backend_fakes.FakeContainer)
+				container1.HandleReturns("handle-1")
+				container1.StateReturns(linux_backend.StateActive)
+				container1.DetailedMetricsReturns(linux_backend.ContainerMetrics{
+					Metrics: garden.Metrics{
+						CPUStat:     garden.ContainerCPUStat{Usage: 1000},
+						MemoryStat:  garden.ContainerMemoryStat{TotalUsageTowardLimit: 2000},
+						DiskStat:    garden.ContainerDiskStat{TotalBytesUsed: 3000},
+						NetworkStat: garden.ContainerNetworkStat{RxBytes: 4000, TxBytes: 5000},
+					},
+				}, nil)
+				containers.Add(container1)
+
+				container2 = new(linux_backend_fakes.FakeContainer)
+				container2.HandleReturns("handle-2")
+				container2.StateReturns(linux_backend.StateActive)
+				containers.Add(container2)
+
+				container3 := new(linux_backend_fakes.FakeContainer)
+				container3.HandleReturns("handle-3")
+				container3.StateReturns(linux_backend.StateStopped)
+				containers.Add(container3)
+			})
+
+			It("emits the number of containers in each state", func() {
+				fakeClock.Increment(reportInterval)
+
+				Eventually(func() fake.Metric {
+					return sender.GetValue("ContainersActive")
+				}).Should(Equal(fake.Metric{Value: 2, Unit: "Metric"}))
+
+				Eventually(func() fake.Metric {
+					return sender.GetValue("ContainersStopped")
+				}).Should(Equal(fake.Metric{Value: 1, Unit: "Metric"}))
+
+				Eventually(func() fake.Metric {
+					return sender.GetValue("ContainersPaused")
+				}).Should(Equal(fake.Metric{Value: 0, Unit: "Metric"}))
+			})
+
+			Context("and no per-container metrics are allowed", func() {
+				It("does not get the containers' metrics", func() {
+					fakeClock.Increment(reportInterval)
+
+					Eventually(func() fake.Metric {
+						return sender.GetValue("ContainersActive")
+					}).Should(Equal(fake.Metric{Value: 2, Unit: "Metric"}))
+
+					Expect(container1.DetailedMetricsCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("and per-container metrics are allowed", func() {
+				BeforeEach(func() {
+					containerMetrics = []metrics.ContainerMetric{
+						metrics.ContainerCPUUsage,
+						metrics.ContainerMemoryUsage,
+						metrics.ContainerRxBytes,
+					}
+				})
+
+				It("emits the allowed metrics of each container, named with its handle", func() {
+					fakeClock.Increment(reportInterval)
+
+					Eventually(func() fake.Metric {
+						return sender.GetValue("ContainerCPUUsage.handle-1")
+					}).Should(Equal(fake.Metric{Value: 1000, Unit: "nanos"}))
+
+					Eventually(func() fake.Metric {
+						return sender.GetValue("ContainerMemoryUsage.handle-1")
+					}).Should(Equal(fake.Metric{Value: 2000, Unit: "bytes"}))
+
+					Eventually(func() fake.Metric {
+						return sender.GetValue("ContainerRxBytes.handle-1")
+					}).Should(Equal(fake.Metric{Value: 4000, Unit: "bytes"}))
+
+					Eventually(func() fake.Metric {
+						return sender.GetValue("ContainerCPUUsage.handle-2")
+					}).Should(Equal(fake.Metric{Value: 0, Unit: "nanos"}))
+
+					Expect(sender.GetValue("ContainerDiskUsage.handle-1")).To(Equal(fake.Metric{}))
+					Expect(sender.GetValue("ContainerTxBytes.handle-1")).To(Equal(fake.Metric{}))
+				})
+
+				Context("when getting a container's metrics fails", func() {
+					BeforeEach(func() {
+						container2.DetailedMetricsReturns(linux_backend.ContainerMetrics{}, errors.New("banana"))
+					})
+
+					It("still emits the other containers' metrics", func() {
+						fakeClock.Increment(reportInterval)
+
+						Eventually(func() fake.Metric {
+							return sender.GetValue("ContainerCPUUsage.handle-1")
+						}).Should(Equal(fake.Metric{Value: 1000, Unit: "nanos"}))
+
+						Consistently(func() fake.Metric {
+							return sender.GetValue("ContainerCPUUsage.handle-2")
+						}).Should(Equal(fake.Metric{}))
+					})
+				})
+			})
+		})
+	})
+})
+
+var _ = Describe("ParseContainerMetrics", func() {
+	It("looks up the metrics by name", func() {
+		parsed, err := metrics.ParseContainerMetrics([]string{"ContainerDiskUsage", "ContainerTxBytes"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed).To(Equal([]metrics.ContainerMetric{metrics.ContainerDiskUsage, metrics.ContainerTxBytes}))
+	})
+
+	Context("when a metric is unknown", func() {
+		It("returns an error", func() {
+			_, err := metrics.ParseContainerMetrics([]string{"ContainerDiskUsage", "Bananas"})
+			Expect(err).To(Equal(metrics.UnknownContainerMetricError{Name: "Bananas"}))
+		})
 	})
 })
@@ -0,0 +1,228 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	"github.com/pivotal-golang/lager"
+)
+
+// PrometheusContentType is the version of the Prometheus text exposition
+// format which the handler writes.
+const PrometheusContentType = "text/plain; version=0.0.4"
+
+type prometheusHandler struct {
+	logger         lager.Logger
+	metrics        Metrics
+	containers     ContainerLister
+	propertyLabels []string
+}
+
+// NewPrometheusHandler serves the daemon gauges and the metrics of every
+// container in the Prometheus text format. Container series are labelled by
+// handle and by each of the given properties, as property_<name>, where the
+// container has it.
+func NewPrometheusHandler(logger lager.Logger, metrics Metrics, containers ContainerLister, propertyLabels []string) http.Handler {
+	return &prometheusHandler{
+		logger:         logger.Session("prometheus"),
+		metrics:        metrics,
+		containers:     containers,
+		propertyLabels: propertyLabels,
+	}
+}
+
+type PropertyLabelCollisionError struct {
+	Property      string
+	OtherProperty string
+}
+
+func (e PropertyLabelCollisionError) Error() string {
+	return fmt.Sprintf("properties %s and %s would both be labelled property_%s", e.OtherProperty, e.Property, labelName(e.Property))
+}
+
+// ParsePropertyLabels parses a comma-separated list of the properties to
+// label container series with. Properties whose label names would collide,
+// such as app.id and app-id, are rejected, as a scrape with a repeated label
+// is rejected as a whole.
+func ParsePropertyLabels(list string) ([]string, error) {
+	var properties []string
+	named := map[string]string{}
+
+	for _, property := range strings.Split(list, ",") {
+		property = strings.TrimSpace(property)
+		if property == "" {
+			continue
+		}
+
+		if other, found := named[labelName(property)]; found {
+			return nil, PropertyLabelCollisionError{Property: property, OtherProperty: other}
+		}
+
+		named[labelName(property)] = property
+		properties = append(properties, property)
+	}
+
+	return properties, nil
+}
+
+type metricFamily struct {
+	name       string
+	help       string
+	metricType string
+	samples    []metricSample
+}
+
+type metricSample struct {
+	labels string
+	value  float64
+}
+
+func (f *metricFamily) add(labels string, value float64) {
+	f.samples = append(f.samples, metricSample{labels: labels, value: value})
+}
+
+func (h *prometheusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	families := h.daemonFamilies()
+	families = append(families, h.containerFamilies()...)
+
+	buf := new(bytes.Buffer)
+	for _, family := range families {
+		if len(family.samples) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(buf, "# HELP %s %s\n", family.name, family.help)
+		fmt.Fprintf(buf, "# TYPE %s %s\n", family.name, family.metricType)
+		for _, sample := range family.samples {
+			fmt.Fprintf(buf, "%s%s %s\n", family.name, sample.labels, strconv.FormatFloat(sample.value, 'g', -1, 64))
+		}
+	}
+
+	w.Header().Set("Content-Type", PrometheusContentType)
+	w.Write(buf.Bytes())
+}
+
+func (h *prometheusHandler) daemonFamilies() []*metricFamily {
+	gauge := func(name, help string, value int) *metricFamily {
+		family := &metricFamily{name: name, help: help, metricType: "gauge"}
+		family.add("", float64(value))
+		return family
+	}
+
+	return []*metricFamily{
+		gauge("garden_cpus", "Number of CPUs on the host.", h.metrics.NumCPU()),
+		gauge("garden_goroutines", "Number of goroutines in the daemon.", h.metrics.NumGoroutine()),
+		gauge("garden_loop_devices", "Number of loop devices in use.", h.metrics.LoopDevices()),
+		gauge("garden_backing_stores", "Number of container backing store files.", h.metrics.BackingStores()),
+		gauge("garden_depot_dirs", "Number of container depot directories.", h.metrics.DepotDirs()),
+	}
+}
+
+func (h *prometheusHandler) containerFamilies() []*metricFamily {
+	family := func(name, metricType, help string) *metricFamily {
+		return &metricFamily{name: name, help: help, metricType: metricType}
+	}
+
+	var (
+		cpuUsage      = family("garden_container_cpu_usage_seconds_total", "counter", "CPU time consumed by the container.")
+		memoryUsage   = family("garden_container_memory_usage_bytes", "gauge", "Memory counted towards the container's limit.")
+		memoryRSS     = family("garden_container_memory_rss_bytes", "gauge", "Anonymous and swap cache memory of the container.")
+		memoryCache   = family("garden_container_memory_cache_bytes", "gauge", "Page cache memory of the container.")
+		memorySwap    = family("garden_container_memory_swap_bytes", "gauge", "Swap used by the container.")
+		diskUsage     = family("garden_container_disk_usage_bytes", "gauge", "Disk used by the container, including its image layers.")
+		diskExclusive = family("garden_container_disk_exclusive_bytes", "gauge", "Disk used only by the container.")
+		diskInodes    = family("garden_container_disk_inodes", "gauge", "Inodes used by the container, including its image layers.")
+		ioRead        = family("garden_container_io_read_bytes_total", "counter", "Bytes read from block devices by the container.")
+		ioWrite       = family("garden_container_io_write_bytes_total", "counter", "Bytes written to block devices by the container.")
+		rxBytes       = family("garden_container_network_receive_bytes_total", "counter", "Bytes received by the container.")
+		txBytes       = family("garden_container_network_transmit_bytes_total", "counter", "Bytes transmitted by the container.")
+		processes     = family("garden_container_processes", "gauge", "Number of tasks in the container.")
+		processLimit  = family("garden_container_processes_limit", "gauge", "Maximum number of tasks in the container, where limited.")
+	)
+
+	containers := h.containers.All()
+	sort.Sort(byHandle(containers))
+
+	for _, container := range containers {
+		metrics, err := container.DetailedMetrics()
+		if err != nil {
+			h.logger.Error("failed-to-get-metrics", err, lager.Data{"handle": container.Handle()})
+			continue
+		}
+
+		labels := h.containerLabels(container)
+
+		cpuUsage.add(labels, float64(metrics.CPUStat.Usage)/1e9)
+		memoryUsage.add(labels, float64(metrics.MemoryStat.TotalUsageTowardLimit))
+		memoryRSS.add(labels, float64(metrics.MemoryStat.TotalRss))
+		memoryCache.add(labels, float64(metrics.MemoryStat.TotalCache))
+		memorySwap.add(labels, float64(metrics.MemoryStat.TotalSwap))
+		diskUsage.add(labels, float64(metrics.DiskStat.TotalBytesUsed))
+		diskExclusive.add(labels, float64(metrics.DiskStat.ExclusiveBytesUsed))
+		diskInodes.add(labels, float64(metrics.DiskStat.TotalInodesUsed))
+		ioRead.add(labels, float64(metrics.IOStat.ReadBytes))
+		ioWrite.add(labels, float64(metrics.IOStat.WriteBytes))
+		rxBytes.add(labels, float64(metrics.NetworkStat.RxBytes))
+		txBytes.add(labels, float64(metrics.NetworkStat.TxBytes))
+		processes.add(labels, float64(metrics.PidStat.Current))
+
+		if metrics.PidStat.Max != 0 {
+			processLimit.add(labels, float64(metrics.PidStat.Max))
+		}
+	}
+
+	return []*metricFamily{
+		cpuUsage,
+		memoryUsage, memoryRSS, memoryCache, memorySwap,
+		diskUsage, diskExclusive, diskInodes,
+		ioRead, ioWrite,
+		rxBytes, txBytes,
+		processes, processLimit,
+	}
+}
+
+func (h *prometheusHandler) containerLabels(container linux_backend.Container) string {
+	labels := []string{fmt.Sprintf(`handle="%s"`, escapeLabelValue(container.Handle()))}
+
+	if len(h.propertyLabels) > 0 {
+		properties, err := container.Properties()
+		if err != nil {
+			h.logger.Error("failed-to-get-properties", err, lager.Data{"handle": container.Handle()})
+		}
+
+		for _, name := range h.propertyLabels {
+			if value, found := properties[name]; found {
+				labels = append(labels, fmt.Sprintf(`property_%s="%s"`, labelName(name), escapeLabelValue(value)))
+			}
+		}
+	}
+
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+// labelName replaces the characters which are not allowed in label names,
+// such as the dots and dashes in property names, with underscores.
+func labelName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+
+		return '_'
+	}, name)
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+type byHandle []linux_backend.Container
+
+func (c byHandle) Len() int           { return len(c) }
+func (c byHandle) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c byHandle) Less(i, j int) bool { return c[i].Handle() < c[j].Handle() }
@@ -0,0 +1,204 @@
+package metrics_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden-linux/container_repository"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	linux_backend_fakes "github.com/cloudfoundry-incubator/garden-linux/linux_backend/fakes"
+	"github.com/cloudfoundry-incubator/garden-linux/metrics"
+	"github.com/cloudfoundry-incubator/garden-linux/metrics/fakes"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Prometheus", func() {
+	var (
+		fakeMetrics    *fakes.FakeMetrics
+		containers     *container_repository.InMemoryContainerRepository
+		propertyLabels []string
+
+		recorder *httptest.ResponseRecorder
+	)
+
+	newContainer := func(handle string, properties garden.Properties, containerMetrics linux_backend.ContainerMetrics) *linux_backend_fakes.FakeContainer {
+		container := new(linux_backend_fakes.FakeContainer)
+		container.HandleReturns(handle)
+		container.PropertiesReturns(properties, nil)
+		container.DetailedMetricsReturns(containerMetrics, nil)
+		return container
+	}
+
+	BeforeEach(func() {
+		fakeMetrics = new(fakes.FakeMetrics)
+		fakeMetrics.NumCPUReturns(11)
+		fakeMetrics.NumGoroutineReturns(888)
+		fakeMetrics.LoopDevicesReturns(33)
+		fakeMetrics.BackingStoresReturns(12)
+		fakeMetrics.DepotDirsReturns(3)
+
+		containers = container_repository.New()
+		propertyLabels = nil
+
+		recorder = httptest.NewRecorder()
+	})
+
+	JustBeforeEach(func() {
+		handler := metrics.NewPrometheusHandler(lagertest.NewTestLogger("test"), fakeMetrics, containers, propertyLabels)
+
+		request, err := http.NewRequest("GET", "/metrics", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		handler.ServeHTTP(recorder, request)
+	})
+
+	It("serves the text exposition format", func() {
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.HeaderMap.Get("Content-Type")).To(Equal("text/plain; version=0.0.4"))
+	})
+
+	It("exports the daemon gauges", func() {
+		body := recorder.Body.String()
+
+		Expect(body).To(ContainSubstring("# HELP garden_cpus Number of CPUs on the host.\n# TYPE garden_cpus gauge\ngarden_cpus 11\n"))
+		Expect(body).To(ContainSubstring("garden_goroutines 888\n"))
+		Expect(body).To(ContainSubstring("garden_loop_devices 33\n"))
+		Expect(body).To(ContainSubstring("garden_backing_stores 12\n"))
+		Expect(body).To(ContainSubstring("garden_depot_dirs 3\n"))
+	})
+
+	Context("when there are no containers", func() {
+		It("does not export the container series", func() {
+			Expect(recorder.Body.String()).ToNot(ContainSubstring("garden_container_"))
+		})
+	})
+
+	Context("when there are containers", func() {
+		BeforeEach(func() {
+			containers.Add(newContainer("handle-b", garden.Properties{}, linux_backend.ContainerMetrics{
+				Metrics: garden.Metrics{
+					CPUStat: garden.ContainerCPUStat{Usage: 2500000000},
+				},
+			}))
+
+			containers.Add(newContainer("handle-a", garden.Properties{
+				"app.id":   "some-app",
+				"app.name": `some "quoted" name`,
+			}, linux_backend.ContainerMetrics{
+				Metrics: garden.Metrics{
+					CPUStat: garden.ContainerCPUStat{Usage: 1000000000},
+					MemoryStat: garden.ContainerMemoryStat{
+						TotalUsageTowardLimit: 1024,
+						TotalRss:              512,
+						TotalCache:            768,
+						TotalSwap:             64,
+					},
+					DiskStat: garden.ContainerDiskStat{
+						TotalBytesUsed:     4096,
+						ExclusiveBytesUsed: 2048,
+						TotalInodesUsed:    10,
+					},
+					NetworkStat: garden.ContainerNetworkStat{
+						RxBytes: 100,
+						TxBytes: 200,
+					},
+				},
+				IOStat: linux_backend.ContainerIOStat{
+					ReadBytes:  300,
+					WriteBytes: 400,
+				},
+				PidStat: linux_backend.ContainerPidStat{
+					Current: 5,
+					Max:     50,
+				},
+			}))
+		})
+
+		It("exports a series per container, labelled by handle", func() {
+			body := recorder.Body.String()
+
+			Expect(body).To(ContainSubstring(
+				"# HELP garden_container_cpu_usage_seconds_total CPU time consumed by the container.\n" +
+					"# TYPE garden_container_cpu_usage_seconds_total counter\n" +
+					"garden_container_cpu_usage_seconds_total{handle=\"handle-a\"} 1\n" +
+					"garden_container_cpu_usage_seconds_total{handle=\"handle-b\"} 2.5\n",
+			))
+
+			Expect(body).To(ContainSubstring("garden_container_memory_usage_bytes{handle=\"handle-a\"} 1024\n"))
+			Expect(body).To(ContainSubstring("garden_container_memory_rss_bytes{handle=\"handle-a\"} 512\n"))
+			Expect(body).To(ContainSubstring("garden_container_memory_cache_bytes{handle=\"handle-a\"} 768\n"))
+			Expect(body).To(ContainSubstring("garden_container_memory_swap_bytes{handle=\"handle-a\"} 64\n"))
+			Expect(body).To(ContainSubstring("garden_container_disk_usage_bytes{handle=\"handle-a\"} 4096\n"))
+			Expect(body).To(ContainSubstring("garden_container_disk_exclusive_bytes{handle=\"handle-a\"} 2048\n"))
+			Expect(body).To(ContainSubstring("garden_container_disk_inodes{handle=\"handle-a\"} 10\n"))
+			Expect(body).To(ContainSubstring("garden_container_io_read_bytes_total{handle=\"handle-a\"} 300\n"))
+			Expect(body).To(ContainSubstring("garden_container_io_write_bytes_total{handle=\"handle-a\"} 400\n"))
+			Expect(body).To(ContainSubstring("garden_container_network_receive_bytes_total{handle=\"handle-a\"} 100\n"))
+			Expect(body).To(ContainSubstring("garden_container_network_transmit_bytes_total{handle=\"handle-a\"} 200\n"))
+			Expect(body).To(ContainSubstring("garden_container_processes{handle=\"handle-a\"} 5\n"))
+		})
+
+		It("exports the process limit only for limited containers", func() {
+			body := recorder.Body.String()
+
+			Expect(body).To(ContainSubstring("garden_container_processes_limit{handle=\"handle-a\"} 50\n"))
+			Expect(body).ToNot(ContainSubstring("garden_container_processes_limit{handle=\"handle-b\"}"))
+		})
+
+		Context("when properties are selected as labels", func() {
+			BeforeEach(func() {
+				propertyLabels = []string{"app.id", "app.name"}
+			})
+
+			It("labels the series with the properties the container has", func() {
+				body := recorder.Body.String()
+
+				Expect(body).To(ContainSubstring(
+					`garden_container_processes{handle="handle-a",property_app_id="some-app",property_app_name="some \"quoted\" name"} 5` + "\n",
+				))
+				Expect(body).To(ContainSubstring(`garden_container_processes{handle="handle-b"} 0` + "\n"))
+			})
+		})
+
+		Context("when getting the metrics of a container fails", func() {
+			BeforeEach(func() {
+				container := newContainer("handle-c", garden.Properties{}, linux_backend.ContainerMetrics{})
+				container.DetailedMetricsReturns(linux_backend.ContainerMetrics{}, errors.New("banana"))
+				containers.Add(container)
+			})
+
+			It("leaves the container out", func() {
+				body := recorder.Body.String()
+
+				Expect(body).To(ContainSubstring(`handle="handle-a"`))
+				Expect(body).ToNot(ContainSubstring(`handle="handle-c"`))
+			})
+		})
+	})
+})
+
+var _ = Describe("Parsing property labels", func() {
+	It("splits the list, ignoring blanks", func() {
+		Expect(metrics.ParsePropertyLabels("app.id, space_id,,")).To(Equal([]string{"app.id", "space_id"}))
+	})
+
+	It("returns no properties for an empty list", func() {
+		Expect(metrics.ParsePropertyLabels("")).To(BeEmpty())
+	})
+
+	It("rejects properties whose label names collide", func() {
+		_, err := metrics.ParsePropertyLabels("app.id,app-id")
+		Expect(err).To(MatchError(metrics.PropertyLabelCollisionError{Property: "app-id", OtherProperty: "app.id"}))
+		Expect(err.Error()).To(ContainSubstring("property_app_id"))
+	})
+
+	It("rejects repeated properties", func() {
+		_, err := metrics.ParsePropertyLabels("app.id,app.id")
+		Expect(err).To(HaveOccurred())
+	})
+})
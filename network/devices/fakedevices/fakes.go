@@ -1,7 +1,7 @@
 package fakedevices
 
 import "net"
-import "github.com/cloudfoundry-incubator/garden"
+import "github.com/cloudfoundry-incubator/garden-linux/network/devices"
 
 type FaveVethCreator struct {
 	CreateCalledWith struct {
@@ -95,12 +95,12 @@ func (f *FakeLink) InterfaceByName(name string) (*net.Interface, bool, error) {
 	return nil, false, nil
 }
 
-func (f *FakeLink) Statistics() (garden.ContainerNetworkStat, error) {
+func (f *FakeLink) Statistics() (devices.LinkStatistics, error) {
 	if f.StatisticsReturns != nil {
-		return garden.ContainerNetworkStat{}, f.StatisticsReturns
+		return devices.LinkStatistics{}, f.StatisticsReturns
 	}
 
-	return garden.ContainerNetworkStat{
+	return devices.LinkStatistics{
 		RxBytes: 1,
 		TxBytes: 2,
 	}, nil
@@ -0,0 +1,65 @@
+package devices
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/docker/libcontainer/netlink"
+)
+
+// IFB manages intermediate functional block devices, to whose egress traffic
+// received by another interface can be redirected and shaped.
+type IFB struct{}
+
+// Create creates an IFB device, brings it up and returns the interface.
+// If the device already exists, returns the existing interface.
+func (IFB) Create(name string) (*net.Interface, error) {
+	netlinkMu.Lock()
+	defer netlinkMu.Unlock()
+
+	if intf, err := net.InterfaceByName(name); err == nil {
+		return intf, nil
+	}
+
+	if err := netlink.NetworkLinkAdd(name, "ifb"); err != nil {
+		return nil, fmt.Errorf("devices: create ifb: %v", err)
+	}
+
+	intf, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("devices: look up created ifb: %v", err)
+	}
+
+	if err := netlink.NetworkLinkUp(intf); err != nil {
+		return nil, fmt.Errorf("devices: set ifb up: %v", err)
+	}
+
+	return intf, nil
+}
+
+func (IFB) Exists(name string) (bool, error) {
+	intfs, err := net.Interfaces()
+	if err != nil {
+		return false, errF(err)
+	}
+
+	for _, intf := range intfs {
+		if intf.Name == name {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Destroy deletes an IFB device, if it exists.
+func (IFB) Destroy(name string) error {
+	netlinkMu.Lock()
+	defer netlinkMu.Unlock()
+
+	if _, err := net.InterfaceByName(name); err != nil {
+		return nil
+	}
+
+	return errF(netlink.NetworkLinkDel(name))
+}
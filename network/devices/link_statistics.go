@@ -0,0 +1,70 @@
+package devices
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unsafe"
+)
+
+// LinkStatistics are an interface's counters, from its own perspective.
+// Multicast counts the multicast packets it received.
+type LinkStatistics struct {
+	RxBytes   uint64
+	TxBytes   uint64
+	RxPackets uint64
+	TxPackets uint64
+	RxErrors  uint64
+	TxErrors  uint64
+	RxDropped uint64
+	TxDropped uint64
+	Multicast uint64
+}
+
+// the leading fields of struct rtnl_link_stats64, in order
+const (
+	rxPackets = iota
+	txPackets
+	rxBytes
+	txBytes
+	rxErrors
+	txErrors
+	rxDropped
+	txDropped
+	multicast
+
+	linkStats64Fields
+)
+
+var nativeEndian binary.ByteOrder = binary.BigEndian
+
+func init() {
+	one := uint16(1)
+	if *(*byte)(unsafe.Pointer(&one)) == 1 {
+		nativeEndian = binary.LittleEndian
+	}
+}
+
+// ParseLinkStats64 parses the IFLA_STATS64 attribute of a link, which is a
+// struct rtnl_link_stats64 in host byte order. Newer kernels append fields,
+// which are ignored.
+func ParseLinkStats64(data []byte) (LinkStatistics, error) {
+	if len(data) < linkStats64Fields*8 {
+		return LinkStatistics{}, fmt.Errorf("devices: link statistics too short: %d bytes", len(data))
+	}
+
+	field := func(i int) uint64 {
+		return nativeEndian.Uint64(data[i*8 : (i+1)*8])
+	}
+
+	return LinkStatistics{
+		RxBytes:   field(rxBytes),
+		TxBytes:   field(txBytes),
+		RxPackets: field(rxPackets),
+		TxPackets: field(txPackets),
+		RxErrors:  field(rxErrors),
+		TxErrors:  field(txErrors),
+		RxDropped: field(rxDropped),
+		TxDropped: field(txDropped),
+		Multicast: field(multicast),
+	}, nil
+}
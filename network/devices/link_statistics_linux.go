@@ -0,0 +1,50 @@
+package devices
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// IFLA_STATS64 is missing from the syscall package
+const iflaStats64 = 23
+
+// Statistics reads the interface's counters over rtnetlink, asking for the
+// one interface rather than dumping every link on the host.
+func (l Link) Statistics() (LinkStatistics, error) {
+	intf, err := net.InterfaceByName(l.Name)
+	if err != nil {
+		return LinkStatistics{}, errF(err)
+	}
+
+	replies, err := netlinkRequest(syscall.RTM_GETLINK, 0, ifinfomsg(intf.Index))
+	if err != nil {
+		return LinkStatistics{}, errF(err)
+	}
+
+	for _, reply := range replies {
+		if len(reply) < syscall.SizeofIfInfomsg {
+			continue
+		}
+
+		attrs, err := parseNetlinkAttrs(reply[syscall.SizeofIfInfomsg:])
+		if err != nil {
+			return LinkStatistics{}, errF(err)
+		}
+
+		if stats, found := attrs[iflaStats64]; found {
+			return ParseLinkStats64(stats)
+		}
+
+		return LinkStatistics{}, errF(fmt.Errorf("no statistics for %s", l.Name))
+	}
+
+	return LinkStatistics{}, errF(fmt.Errorf("link %s not found", l.Name))
+}
+
+func ifinfomsg(ifindex int) []byte {
+	msg := make([]byte, syscall.SizeofIfInfomsg)
+	msg[0] = syscall.AF_UNSPEC
+	nativeEndian.PutUint32(msg[4:], uint32(ifindex))
+	return msg
+}
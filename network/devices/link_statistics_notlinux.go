@@ -0,0 +1,9 @@
+// +build !linux
+
+package devices
+
+import "errors"
+
+func (l Link) Statistics() (LinkStatistics, error) {
+	return LinkStatistics{}, errors.New("devices: link statistics are not supported on this OS")
+}
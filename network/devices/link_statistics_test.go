@@ -0,0 +1,53 @@
+package devices_test
+
+import (
+	"encoding/binary"
+	"unsafe"
+
+	"github.com/cloudfoundry-incubator/garden-linux/network/devices"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseLinkStats64", func() {
+	var nativeEndian binary.ByteOrder = binary.BigEndian
+
+	one := uint16(1)
+	if *(*byte)(unsafe.Pointer(&one)) == 1 {
+		nativeEndian = binary.LittleEndian
+	}
+
+	linkStats64 := func(fields ...uint64) []byte {
+		data := make([]byte, len(fields)*8)
+		for i, field := range fields {
+			nativeEndian.PutUint64(data[i*8:], field)
+		}
+
+		return data
+	}
+
+	It("parses the leading fields of struct rtnl_link_stats64", func() {
+		// rx/tx packets, bytes, errors and dropped, then multicast and collisions
+		stats, err := devices.ParseLinkStats64(linkStats64(1, 2, 3, 4, 5, 6, 7, 8, 9, 10))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(stats).To(Equal(devices.LinkStatistics{
+			RxPackets: 1,
+			TxPackets: 2,
+			RxBytes:   3,
+			TxBytes:   4,
+			RxErrors:  5,
+			TxErrors:  6,
+			RxDropped: 7,
+			TxDropped: 8,
+			Multicast: 9,
+		}))
+	})
+
+	Context("when the statistics are truncated", func() {
+		It("returns an error", func() {
+			_, err := devices.ParseLinkStats64(linkStats64(1, 2, 3))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
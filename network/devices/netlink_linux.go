@@ -0,0 +1,78 @@
+package devices
+
+import (
+	"fmt"
+	"sync/atomic"
+	"syscall"
+)
+
+var netlinkSeq uint32
+
+// netlinkRequest sends a single rtnetlink request and collects the bodies of
+// the replies to it, until the kernel acknowledges it or ends the dump.
+func netlinkRequest(msgType, flags uint16, body []byte) ([][]byte, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return nil, err
+	}
+
+	defer syscall.Close(fd)
+
+	addr := &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}
+	if err := syscall.Bind(fd, addr); err != nil {
+		return nil, err
+	}
+
+	seq := atomic.AddUint32(&netlinkSeq, 1)
+
+	req := make([]byte, syscall.NLMSG_HDRLEN+len(body))
+	nativeEndian.PutUint32(req[0:], uint32(len(req)))
+	nativeEndian.PutUint16(req[4:], msgType)
+	nativeEndian.PutUint16(req[6:], flags|syscall.NLM_F_REQUEST|syscall.NLM_F_ACK)
+	nativeEndian.PutUint32(req[8:], seq)
+	copy(req[syscall.NLMSG_HDRLEN:], body)
+
+	if err := syscall.Sendto(fd, req, 0, addr); err != nil {
+		return nil, err
+	}
+
+	var replies [][]byte
+
+	buf := make([]byte, 1<<16)
+	for {
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, msg := range msgs {
+			if msg.Header.Seq != seq {
+				continue
+			}
+
+			switch msg.Header.Type {
+			case syscall.NLMSG_DONE:
+				return replies, nil
+
+			case syscall.NLMSG_ERROR:
+				if len(msg.Data) < 4 {
+					return nil, fmt.Errorf("netlink error message too short: %d bytes", len(msg.Data))
+				}
+
+				if errno := int32(nativeEndian.Uint32(msg.Data)); errno != 0 {
+					return nil, syscall.Errno(-errno)
+				}
+
+				return replies, nil
+
+			default:
+				replies = append(replies, append([]byte(nil), msg.Data...))
+			}
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package devices
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// TokenBucket is a rate limit and the burst it tolerates above the rate.
+type TokenBucket struct {
+	RateInBytesPerSecond uint64
+	BurstInBytes         uint64
+}
+
+// QdiscStatistics are the counters of a queueing discipline.
+type QdiscStatistics struct {
+	Bytes      uint64
+	Packets    uint64
+	Drops      uint64
+	Overlimits uint64
+	Backlog    uint64
+}
+
+// TrafficControlStatistics are the counters of an interface's root and
+// ingress qdiscs, i.e. of the traffic it sends and receives respectively.
+type TrafficControlStatistics struct {
+	Root    QdiscStatistics
+	Ingress QdiscStatistics
+}
+
+var ErrZeroRate = errors.New("devices: rate must be greater than zero")
+
+const (
+	tcHRoot         = 0xFFFFFFFF
+	tcHIngress      = 0xFFFFFFF1
+	tcIngressHandle = 0xFFFF0000
+
+	tcaKind    = 1
+	tcaOptions = 2
+	tcaStats2  = 7
+
+	tcaStatsBasic = 1
+	tcaStatsQueue = 3
+
+	tcaTbfParms  = 1
+	tcaTbfRtab   = 2
+	tcaTbfRate64 = 4
+
+	tcaU32Sel = 5
+	tcaU32Act = 7
+
+	tcaActKind    = 1
+	tcaActOptions = 2
+
+	tcaMirredParms = 2
+	tcaEgressRedir = 1
+
+	tcActStolen         = 4
+	tcU32Terminal       = 1
+	tcLinklayerEthernet = 1
+
+	sizeofTcmsg      = 20
+	sizeofTcRatespec = 12
+	sizeofTbfQopt    = 36
+	sizeofTcMirred   = 28
+	sizeofU32Sel     = 16
+	sizeofU32Key     = 16
+
+	ethPAll = 0x0003
+
+	nlaTypeMask = ^uint16(1<<15 | 1<<14)
+
+	timeUnitsPerSec = 1000000
+
+	// how long a packet may wait for tokens in the TBF, as garden has always used
+	tbfLatencyInUsec = 25000
+
+	// the MTU iproute2 assumes when computing rate tables
+	rateTableMTU = 2047
+)
+
+// Psched converts between times and the ticks of the kernel's packet
+// scheduler clock, as described by /proc/net/psched.
+type Psched struct {
+	TicksPerUsec float64
+}
+
+// ParsePsched parses the contents of /proc/net/psched the way iproute2 does.
+func ParsePsched(data []byte) (Psched, error) {
+	var t2us, us2t, clockRes uint32
+	if _, err := fmt.Sscanf(string(data), "%08x %08x %08x", &t2us, &us2t, &clockRes); err != nil {
+		return Psched{}, fmt.Errorf("devices: parse psched: %v", err)
+	}
+
+	if us2t == 0 {
+		return Psched{}, fmt.Errorf("devices: parse psched: invalid clock %q", data)
+	}
+
+	if clockRes == 1000000000 {
+		t2us = us2t
+	}
+
+	clockFactor := float64(clockRes) / timeUnitsPerSec
+
+	return Psched{TicksPerUsec: float64(t2us) / float64(us2t) * clockFactor}, nil
+}
+
+// XmitTime is the number of ticks it takes to send size bytes at rate.
+func (p Psched) XmitTime(rate, size uint64) uint32 {
+	ticks := timeUnitsPerSec * (float64(size) / float64(rate)) * p.TicksPerUsec
+	if ticks > math.MaxUint32 {
+		return math.MaxUint32
+	}
+
+	return uint32(ticks)
+}
+
+// XmitSize is the number of bytes sent at rate within the given ticks.
+func (p Psched) XmitSize(rate uint64, ticks uint32) uint64 {
+	return uint64(math.Floor(float64(rate)*(float64(ticks)/p.TicksPerUsec)/timeUnitsPerSec + 0.5))
+}
+
+func (p Psched) rateTable(rate uint64) (uint8, []byte) {
+	var cellLog uint8
+	for (rateTableMTU >> cellLog) > 255 {
+		cellLog++
+	}
+
+	table := make([]byte, 256*4)
+	for i := 0; i < 256; i++ {
+		nativeEndian.PutUint32(table[i*4:], p.XmitTime(rate, uint64(i+1)<<cellLog))
+	}
+
+	return cellLog, table
+}
+
+// TBFOptions encodes the TCA_OPTIONS of a tbf qdisc enforcing the bucket.
+func (p Psched) TBFOptions(bucket TokenBucket) ([]byte, error) {
+	rate := bucket.RateInBytesPerSecond
+	if rate == 0 {
+		return nil, ErrZeroRate
+	}
+
+	cellLog, rtab := p.rateTable(rate)
+
+	qopt := make([]byte, sizeofTbfQopt)
+	putRatespec(qopt[0:], rate, cellLog)
+	nativeEndian.PutUint32(qopt[2*sizeofTcRatespec:], clampUint32(rate*tbfLatencyInUsec/timeUnitsPerSec+bucket.BurstInBytes))
+	nativeEndian.PutUint32(qopt[2*sizeofTcRatespec+4:], p.XmitTime(rate, bucket.BurstInBytes))
+
+	options := append(netlinkAttr(tcaTbfParms, qopt), netlinkAttr(tcaTbfRtab, rtab)...)
+	if rate > math.MaxUint32 {
+		options = append(options, netlinkAttr(tcaTbfRate64, uint64Bytes(rate))...)
+	}
+
+	return options, nil
+}
+
+// ParseTBFOptions decodes the TCA_OPTIONS of a tbf qdisc.
+func (p Psched) ParseTBFOptions(options []byte) (TokenBucket, error) {
+	attrs, err := parseNetlinkAttrs(options)
+	if err != nil {
+		return TokenBucket{}, err
+	}
+
+	qopt := attrs[tcaTbfParms]
+	if len(qopt) < sizeofTbfQopt {
+		return TokenBucket{}, fmt.Errorf("devices: tbf parameters too short: %d bytes", len(qopt))
+	}
+
+	rate := uint64(nativeEndian.Uint32(qopt[8:]))
+	if rate64 := attrs[tcaTbfRate64]; len(rate64) >= 8 {
+		rate = nativeEndian.Uint64(rate64)
+	}
+
+	buffer := nativeEndian.Uint32(qopt[2*sizeofTcRatespec+4:])
+
+	return TokenBucket{
+		RateInBytesPerSecond: rate,
+		BurstInBytes:         p.XmitSize(rate, buffer),
+	}, nil
+}
+
+// U32RedirectOptions encodes the TCA_OPTIONS of a u32 filter matching every
+// packet and redirecting it to the egress of the interface with the given
+// index, typically an IFB device which can then shape it.
+func U32RedirectOptions(ifindex int) []byte {
+	// match u32 0 0, i.e. a zero mask at offset zero
+	sel := make([]byte, sizeofU32Sel+sizeofU32Key)
+	sel[0] = tcU32Terminal
+	sel[2] = 1
+
+	mirred := make([]byte, sizeofTcMirred)
+	nativeEndian.PutUint32(mirred[8:], tcActStolen)
+	nativeEndian.PutUint32(mirred[20:], tcaEgressRedir)
+	nativeEndian.PutUint32(mirred[24:], uint32(ifindex))
+
+	action := netlinkAttr(tcaActKind, []byte("mirred\x00"))
+	action = append(action, netlinkAttr(tcaActOptions, netlinkAttr(tcaMirredParms, mirred))...)
+
+	options := netlinkAttr(tcaU32Sel, sel)
+	options = append(options, netlinkAttr(tcaU32Act, netlinkAttr(1, action))...)
+
+	return options
+}
+
+// tcObject is a qdisc or filter as dumped by the kernel.
+type tcObject struct {
+	Ifindex int32
+	Handle  uint32
+	Parent  uint32
+	Kind    string
+	Options []byte
+	Stats   QdiscStatistics
+}
+
+func tcmsg(ifindex int, handle, parent, info uint32) []byte {
+	msg := make([]byte, sizeofTcmsg)
+	msg[0] = 0 // AF_UNSPEC
+	nativeEndian.PutUint32(msg[4:], uint32(ifindex))
+	nativeEndian.PutUint32(msg[8:], handle)
+	nativeEndian.PutUint32(msg[12:], parent)
+	nativeEndian.PutUint32(msg[16:], info)
+	return msg
+}
+
+func parseTcObject(data []byte) (tcObject, error) {
+	if len(data) < sizeofTcmsg {
+		return tcObject{}, fmt.Errorf("devices: tc message too short: %d bytes", len(data))
+	}
+
+	attrs, err := parseNetlinkAttrs(data[sizeofTcmsg:])
+	if err != nil {
+		return tcObject{}, err
+	}
+
+	obj := tcObject{
+		Ifindex: int32(nativeEndian.Uint32(data[4:])),
+		Handle:  nativeEndian.Uint32(data[8:]),
+		Parent:  nativeEndian.Uint32(data[12:]),
+		Kind:    cString(attrs[tcaKind]),
+		Options: attrs[tcaOptions],
+	}
+
+	if stats2, found := attrs[tcaStats2]; found {
+		statsAttrs, err := parseNetlinkAttrs(stats2)
+		if err != nil {
+			return tcObject{}, err
+		}
+
+		// struct gnet_stats_basic
+		if basic := statsAttrs[tcaStatsBasic]; len(basic) >= 12 {
+			obj.Stats.Bytes = nativeEndian.Uint64(basic[0:])
+			obj.Stats.Packets = uint64(nativeEndian.Uint32(basic[8:]))
+		}
+
+		// struct gnet_stats_queue
+		if queue := statsAttrs[tcaStatsQueue]; len(queue) >= 20 {
+			obj.Stats.Backlog = uint64(nativeEndian.Uint32(queue[4:]))
+			obj.Stats.Drops = uint64(nativeEndian.Uint32(queue[8:]))
+			obj.Stats.Overlimits = uint64(nativeEndian.Uint32(queue[16:]))
+		}
+	}
+
+	return obj, nil
+}
+
+func putRatespec(buf []byte, rate uint64, cellLog uint8) {
+	buf[0] = cellLog
+	buf[1] = tcLinklayerEthernet
+	nativeEndian.PutUint16(buf[4:], 0xFFFF) // cell_align of -1
+	nativeEndian.PutUint32(buf[8:], clampUint32(rate))
+}
+
+func netlinkAttr(attrType uint16, value []byte) []byte {
+	length := 4 + len(value)
+
+	attr := make([]byte, align4(length))
+	nativeEndian.PutUint16(attr[0:], uint16(length))
+	nativeEndian.PutUint16(attr[2:], attrType)
+	copy(attr[4:], value)
+
+	return attr
+}
+
+func parseNetlinkAttrs(data []byte) (map[uint16][]byte, error) {
+	attrs := make(map[uint16][]byte)
+
+	for len(data) >= 4 {
+		length := int(nativeEndian.Uint16(data[0:]))
+		attrType := nativeEndian.Uint16(data[2:]) & nlaTypeMask
+
+		if length < 4 || length > len(data) {
+			return nil, fmt.Errorf("devices: malformed netlink attribute of type %d", attrType)
+		}
+
+		attrs[attrType] = data[4:length]
+
+		if align4(length) >= len(data) {
+			break
+		}
+
+		data = data[align4(length):]
+	}
+
+	return attrs, nil
+}
+
+func align4(length int) int {
+	return (length + 3) &^ 3
+}
+
+func htons(v uint16) uint16 {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return nativeEndian.Uint16(b)
+}
+
+func uint64Bytes(v uint64) []byte {
+	b := make([]byte, 8)
+	nativeEndian.PutUint64(b, v)
+	return b
+}
+
+func clampUint32(v uint64) uint32 {
+	if v > math.MaxUint32 {
+		return math.MaxUint32
+	}
+
+	return uint32(v)
+}
+
+func cString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+
+	return string(b)
+}
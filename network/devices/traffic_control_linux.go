@@ -0,0 +1,162 @@
+package devices
+
+import (
+	"io/ioutil"
+	"net"
+	"syscall"
+)
+
+const pschedPath = "/proc/net/psched"
+
+// ClearTrafficControl removes the interface's root and ingress qdiscs,
+// along with any limits they enforce.
+func (l Link) ClearTrafficControl() error {
+	intf, err := net.InterfaceByName(l.Name)
+	if err != nil {
+		return errF(err)
+	}
+
+	for _, parent := range []uint32{tcHRoot, tcHIngress} {
+		_, err := netlinkRequest(syscall.RTM_DELQDISC, 0, tcmsg(intf.Index, 0, parent, 0))
+		if err != nil && err != syscall.ENOENT && err != syscall.EINVAL {
+			return errF(err)
+		}
+	}
+
+	return nil
+}
+
+// AddTBF shapes the traffic the interface sends with a root tbf qdisc.
+func (l Link) AddTBF(bucket TokenBucket) error {
+	intf, err := net.InterfaceByName(l.Name)
+	if err != nil {
+		return errF(err)
+	}
+
+	psched, err := readPsched()
+	if err != nil {
+		return err
+	}
+
+	options, err := psched.TBFOptions(bucket)
+	if err != nil {
+		return err
+	}
+
+	body := tcmsg(intf.Index, 0, tcHRoot, 0)
+	body = append(body, netlinkAttr(tcaKind, []byte("tbf\x00"))...)
+	body = append(body, netlinkAttr(tcaOptions, options)...)
+
+	_, err = netlinkRequest(syscall.RTM_NEWQDISC, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL, body)
+	return errF(err)
+}
+
+// RedirectIngress hands the traffic the interface receives to the egress of
+// another interface, using an ingress qdisc and a u32 filter with a mirred
+// action, so that it can be shaped there.
+func (l Link) RedirectIngress(to string) error {
+	intf, err := net.InterfaceByName(l.Name)
+	if err != nil {
+		return errF(err)
+	}
+
+	target, err := net.InterfaceByName(to)
+	if err != nil {
+		return errF(err)
+	}
+
+	qdisc := tcmsg(intf.Index, tcIngressHandle, tcHIngress, 0)
+	qdisc = append(qdisc, netlinkAttr(tcaKind, []byte("ingress\x00"))...)
+	qdisc = append(qdisc, netlinkAttr(tcaOptions, nil)...)
+
+	if _, err := netlinkRequest(syscall.RTM_NEWQDISC, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL, qdisc); err != nil {
+		return errF(err)
+	}
+
+	// prio 1, protocol all
+	filter := tcmsg(intf.Index, 0, tcIngressHandle, 1<<16|uint32(htons(ethPAll)))
+	filter = append(filter, netlinkAttr(tcaKind, []byte("u32\x00"))...)
+	filter = append(filter, netlinkAttr(tcaOptions, U32RedirectOptions(target.Index))...)
+
+	_, err = netlinkRequest(syscall.RTM_NEWTFILTER, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL, filter)
+	return errF(err)
+}
+
+// TBF reads back the limits of the interface's root tbf qdisc, returning
+// false when it has none.
+func (l Link) TBF() (TokenBucket, bool, error) {
+	qdiscs, err := l.qdiscs()
+	if err != nil {
+		return TokenBucket{}, false, err
+	}
+
+	psched, err := readPsched()
+	if err != nil {
+		return TokenBucket{}, false, err
+	}
+
+	for _, qdisc := range qdiscs {
+		if qdisc.Parent == tcHRoot && qdisc.Kind == "tbf" {
+			bucket, err := psched.ParseTBFOptions(qdisc.Options)
+			return bucket, err == nil, err
+		}
+	}
+
+	return TokenBucket{}, false, nil
+}
+
+// TrafficControlStatistics reads the counters of the interface's root and
+// ingress qdiscs.
+func (l Link) TrafficControlStatistics() (TrafficControlStatistics, error) {
+	qdiscs, err := l.qdiscs()
+	if err != nil {
+		return TrafficControlStatistics{}, err
+	}
+
+	stats := TrafficControlStatistics{}
+	for _, qdisc := range qdiscs {
+		switch qdisc.Parent {
+		case tcHRoot:
+			stats.Root = qdisc.Stats
+		case tcHIngress:
+			stats.Ingress = qdisc.Stats
+		}
+	}
+
+	return stats, nil
+}
+
+func (l Link) qdiscs() ([]tcObject, error) {
+	intf, err := net.InterfaceByName(l.Name)
+	if err != nil {
+		return nil, errF(err)
+	}
+
+	replies, err := netlinkRequest(syscall.RTM_GETQDISC, syscall.NLM_F_DUMP, tcmsg(intf.Index, 0, 0, 0))
+	if err != nil {
+		return nil, errF(err)
+	}
+
+	var qdiscs []tcObject
+	for _, reply := range replies {
+		qdisc, err := parseTcObject(reply)
+		if err != nil {
+			return nil, err
+		}
+
+		if int(qdisc.Ifindex) == intf.Index {
+			qdiscs = append(qdiscs, qdisc)
+		}
+	}
+
+	return qdiscs, nil
+}
+
+func readPsched() (Psched, error) {
+	data, err := ioutil.ReadFile(pschedPath)
+	if err != nil {
+		return Psched{}, errF(err)
+	}
+
+	return ParsePsched(data)
+}
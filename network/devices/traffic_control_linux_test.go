@@ -0,0 +1,126 @@
+package devices_test
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+
+	"github.com/cloudfoundry-incubator/garden-linux/network/devices"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Traffic Control", func() {
+	var (
+		hostName, containerName, ifbName string
+		host                             devices.Link
+	)
+
+	BeforeEach(func() {
+		hostName = fmt.Sprintf("gdn-tc-h-%d", GinkgoParallelNode())
+		containerName = fmt.Sprintf("gdn-tc-c-%d", GinkgoParallelNode())
+		ifbName = fmt.Sprintf("gdn-tc-i-%d", GinkgoParallelNode())
+
+		_, _, err := devices.VethCreator{}.Create(hostName, containerName)
+		Expect(err).ToNot(HaveOccurred())
+
+		host = devices.Link{Name: hostName}
+	})
+
+	AfterEach(func() {
+		Expect(cleanup(hostName)).To(Succeed())
+		Expect(cleanup(ifbName)).To(Succeed())
+	})
+
+	tcShow := func(args ...string) string {
+		out, err := exec.Command("tc", args...).CombinedOutput()
+		Expect(err).ToNot(HaveOccurred())
+		return string(out)
+	}
+
+	Describe("AddTBF", func() {
+		It("adds a root tbf qdisc which can be read back", func() {
+			bucket := devices.TokenBucket{RateInBytesPerSecond: 128000, BurstInBytes: 65536}
+			Expect(host.AddTBF(bucket)).To(Succeed())
+
+			Expect(tcShow("qdisc", "show", "dev", hostName)).To(ContainSubstring("qdisc tbf"))
+
+			readBack, found, err := host.TBF()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(readBack).To(Equal(bucket))
+		})
+
+		Context("when the interface has no tbf qdisc", func() {
+			It("reads back nothing", func() {
+				_, found, err := host.TBF()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(found).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("RedirectIngress", func() {
+		It("redirects received traffic to the egress of the target", func() {
+			_, err := devices.IFB{}.Create(ifbName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(host.RedirectIngress(ifbName)).To(Succeed())
+
+			Expect(tcShow("filter", "show", "dev", hostName, "parent", "ffff:")).To(ContainSubstring("Egress Redirect to device " + ifbName))
+		})
+
+		Context("when the target does not exist", func() {
+			It("returns an error", func() {
+				Expect(host.RedirectIngress(ifbName)).ToNot(Succeed())
+			})
+		})
+	})
+
+	Describe("ClearTrafficControl", func() {
+		It("removes the root and ingress qdiscs", func() {
+			_, err := devices.IFB{}.Create(ifbName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(host.AddTBF(devices.TokenBucket{RateInBytesPerSecond: 128000, BurstInBytes: 65536})).To(Succeed())
+			Expect(host.RedirectIngress(ifbName)).To(Succeed())
+
+			Expect(host.ClearTrafficControl()).To(Succeed())
+
+			qdiscs := tcShow("qdisc", "show", "dev", hostName)
+			Expect(qdiscs).ToNot(ContainSubstring("tbf"))
+			Expect(qdiscs).ToNot(ContainSubstring("ingress"))
+		})
+
+		It("succeeds when there is nothing to remove", func() {
+			Expect(host.ClearTrafficControl()).To(Succeed())
+		})
+	})
+
+	Describe("IFB", func() {
+		It("creates an ifb device idempotently and brings it up", func() {
+			created, err := devices.IFB{}.Create(ifbName)
+			Expect(err).ToNot(HaveOccurred())
+
+			again, err := devices.IFB{}.Create(ifbName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(again.Index).To(Equal(created.Index))
+
+			intf, err := net.InterfaceByName(ifbName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(intf.Flags & net.FlagUp).To(Equal(net.FlagUp))
+
+			Expect(devices.IFB{}.Exists(ifbName)).To(BeTrue())
+		})
+
+		It("destroys the ifb device idempotently", func() {
+			_, err := devices.IFB{}.Create(ifbName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(devices.IFB{}.Destroy(ifbName)).To(Succeed())
+			Expect(devices.IFB{}.Destroy(ifbName)).To(Succeed())
+
+			Expect(devices.IFB{}.Exists(ifbName)).To(BeFalse())
+		})
+	})
+})
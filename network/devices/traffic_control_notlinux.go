@@ -0,0 +1,27 @@
+// +build !linux
+
+package devices
+
+import "errors"
+
+var errTrafficControlUnsupported = errors.New("devices: traffic control is not supported on this OS")
+
+func (l Link) ClearTrafficControl() error {
+	return errTrafficControlUnsupported
+}
+
+func (l Link) AddTBF(bucket TokenBucket) error {
+	return errTrafficControlUnsupported
+}
+
+func (l Link) RedirectIngress(to string) error {
+	return errTrafficControlUnsupported
+}
+
+func (l Link) TBF() (TokenBucket, bool, error) {
+	return TokenBucket{}, false, errTrafficControlUnsupported
+}
+
+func (l Link) TrafficControlStatistics() (TrafficControlStatistics, error) {
+	return TrafficControlStatistics{}, errTrafficControlUnsupported
+}
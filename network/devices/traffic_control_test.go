@@ -0,0 +1,82 @@
+package devices_test
+
+import (
+	"github.com/cloudfoundry-incubator/garden-linux/network/devices"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Traffic control", func() {
+	var psched devices.Psched
+
+	BeforeEach(func() {
+		var err error
+		psched, err = devices.ParsePsched([]byte("000003e8 00000040 000f4240 3b9aca00\n"))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("ParsePsched", func() {
+		It("computes the ticks per microsecond of a 64ns clock", func() {
+			Expect(psched.TicksPerUsec).To(Equal(15.625))
+		})
+
+		Context("when the clock resolution is a nanosecond", func() {
+			It("ignores the time to microsecond factor", func() {
+				p, err := devices.ParsePsched([]byte("000003e8 00000040 3b9aca00 3b9aca00\n"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.TicksPerUsec).To(Equal(1000.0))
+			})
+		})
+
+		Context("when the contents are malformed", func() {
+			It("returns an error", func() {
+				_, err := devices.ParsePsched([]byte("potato"))
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("XmitTime and XmitSize", func() {
+		It("convert between bytes and ticks at a rate", func() {
+			ticks := psched.XmitTime(1000000, 65536)
+			Expect(ticks).To(Equal(uint32(1024000)))
+			Expect(psched.XmitSize(1000000, ticks)).To(Equal(uint64(65536)))
+		})
+	})
+
+	Describe("TBF options", func() {
+		It("round-trips the rate and burst", func() {
+			bucket := devices.TokenBucket{RateInBytesPerSecond: 128000, BurstInBytes: 65536}
+
+			options, err := psched.TBFOptions(bucket)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(psched.ParseTBFOptions(options)).To(Equal(bucket))
+		})
+
+		It("carries rates above 32 bits", func() {
+			bucket := devices.TokenBucket{RateInBytesPerSecond: 5 << 32, BurstInBytes: 1 << 30}
+
+			options, err := psched.TBFOptions(bucket)
+			Expect(err).ToNot(HaveOccurred())
+
+			parsed, err := psched.ParseTBFOptions(options)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed.RateInBytesPerSecond).To(Equal(bucket.RateInBytesPerSecond))
+		})
+
+		Context("when the rate is zero", func() {
+			It("returns an error", func() {
+				_, err := psched.TBFOptions(devices.TokenBucket{BurstInBytes: 256})
+				Expect(err).To(Equal(devices.ErrZeroRate))
+			})
+		})
+
+		Context("when the parameters are truncated", func() {
+			It("returns an error", func() {
+				_, err := psched.ParseTBFOptions([]byte{8, 0, 1, 0, 0, 0, 0, 0})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
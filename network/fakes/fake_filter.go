@@ -28,6 +28,14 @@ type FakeFilter struct {
 	netOutReturns struct {
 		result1 error
 	}
+	RemoveNetOutStub        func(garden.NetOutRule) error
+	removeNetOutMutex       sync.RWMutex
+	removeNetOutArgsForCall []struct {
+		arg1 garden.NetOutRule
+	}
+	removeNetOutReturns struct {
+		result1 error
+	}
 }
 
 func (fake *FakeFilter) Setup(logPrefix string) error {
@@ -109,4 +117,36 @@ func (fake *FakeFilter) NetOutReturns(result1 error) {
 	}{result1}
 }
 
+func (fake *FakeFilter) RemoveNetOut(arg1 garden.NetOutRule) error {
+	fake.removeNetOutMutex.Lock()
+	fake.removeNetOutArgsForCall = append(fake.removeNetOutArgsForCall, struct {
+		arg1 garden.NetOutRule
+	}{arg1})
+	fake.removeNetOutMutex.Unlock()
+	if fake.RemoveNetOutStub != nil {
+		return fake.RemoveNetOutStub(arg1)
+	} else {
+		return fake.removeNetOutReturns.result1
+	}
+}
+
+func (fake *FakeFilter) RemoveNetOutCallCount() int {
+	fake.removeNetOutMutex.RLock()
+	defer fake.removeNetOutMutex.RUnlock()
+	return len(fake.removeNetOutArgsForCall)
+}
+
+func (fake *FakeFilter) RemoveNetOutArgsForCall(i int) garden.NetOutRule {
+	fake.removeNetOutMutex.RLock()
+	defer fake.removeNetOutMutex.RUnlock()
+	return fake.removeNetOutArgsForCall[i].arg1
+}
+
+func (fake *FakeFilter) RemoveNetOutReturns(result1 error) {
+	fake.RemoveNetOutStub = nil
+	fake.removeNetOutReturns = struct {
+		result1 error
+	}{result1}
+}
+
 var _ network.Filter = new(FakeFilter)
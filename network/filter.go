@@ -13,6 +13,7 @@ type Filter interface {
 	Setup(logPrefix string) error
 	TearDown()
 	NetOut(garden.NetOutRule) error
+	RemoveNetOut(garden.NetOutRule) error
 }
 
 type filter struct {
@@ -37,3 +38,7 @@ func (fltr *filter) TearDown() {
 func (fltr *filter) NetOut(r garden.NetOutRule) error {
 	return fltr.chain.PrependFilterRule(r)
 }
+
+func (fltr *filter) RemoveNetOut(r garden.NetOutRule) error {
+	return fltr.chain.DeleteFilterRule(r)
+}
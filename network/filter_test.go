@@ -61,4 +61,19 @@ var _ = Describe("Filter", func() {
 			Expect(filter.NetOut(garden.NetOutRule{})).To(MatchError("iptables says no"))
 		})
 	})
+
+	Context("RemoveNetOut", func() {
+		It("deletes the rule from the chain", func() {
+			rule := garden.NetOutRule{Protocol: garden.ProtocolUDP}
+			Expect(filter.RemoveNetOut(rule)).To(Succeed())
+
+			Expect(fakeChain.DeleteFilterRuleCallCount()).To(Equal(1))
+			Expect(fakeChain.DeleteFilterRuleArgsForCall(0)).To(Equal(rule))
+		})
+
+		It("returns an error if one occurs", func() {
+			fakeChain.DeleteFilterRuleReturns(errors.New("iptables says no"))
+			Expect(filter.RemoveNetOut(garden.NetOutRule{})).To(MatchError("iptables says no"))
+		})
+	})
 })
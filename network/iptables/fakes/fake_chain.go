@@ -74,6 +74,14 @@ type FakeChain struct {
 	prependFilterRuleReturns struct {
 		result1 error
 	}
+	DeleteFilterRuleStub        func(rule garden.NetOutRule) error
+	deleteFilterRuleMutex       sync.RWMutex
+	deleteFilterRuleArgsForCall []struct {
+		rule garden.NetOutRule
+	}
+	deleteFilterRuleReturns struct {
+		result1 error
+	}
 }
 
 func (fake *FakeChain) Setup(logPrefix string) error {
@@ -302,4 +310,36 @@ func (fake *FakeChain) PrependFilterRuleReturns(result1 error) {
 	}{result1}
 }
 
+func (fake *FakeChain) DeleteFilterRule(rule garden.NetOutRule) error {
+	fake.deleteFilterRuleMutex.Lock()
+	fake.deleteFilterRuleArgsForCall = append(fake.deleteFilterRuleArgsForCall, struct {
+		rule garden.NetOutRule
+	}{rule})
+	fake.deleteFilterRuleMutex.Unlock()
+	if fake.DeleteFilterRuleStub != nil {
+		return fake.DeleteFilterRuleStub(rule)
+	} else {
+		return fake.deleteFilterRuleReturns.result1
+	}
+}
+
+func (fake *FakeChain) DeleteFilterRuleCallCount() int {
+	fake.deleteFilterRuleMutex.RLock()
+	defer fake.deleteFilterRuleMutex.RUnlock()
+	return len(fake.deleteFilterRuleArgsForCall)
+}
+
+func (fake *FakeChain) DeleteFilterRuleArgsForCall(i int) garden.NetOutRule {
+	fake.deleteFilterRuleMutex.RLock()
+	defer fake.deleteFilterRuleMutex.RUnlock()
+	return fake.deleteFilterRuleArgsForCall[i].rule
+}
+
+func (fake *FakeChain) DeleteFilterRuleReturns(result1 error) {
+	fake.DeleteFilterRuleStub = nil
+	fake.deleteFilterRuleReturns = struct {
+		result1 error
+	}{result1}
+}
+
 var _ iptables.Chain = new(FakeChain)
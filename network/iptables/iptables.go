@@ -65,6 +65,9 @@ type Chain interface {
 	DeleteNatRule(source string, destination string, jump Action, to net.IP) error
 
 	PrependFilterRule(rule garden.NetOutRule) error
+
+	// Delete the rules a previous PrependFilterRule of the same rule added
+	DeleteFilterRule(rule garden.NetOutRule) error
 }
 
 type chain struct {
@@ -187,19 +190,38 @@ type singleRule struct {
 func (ch *chain) PrependFilterRule(r garden.NetOutRule) error {
 	logger := ch.logger.Session("prepend-filter-rule", lager.Data{"rule": r})
 	logger.Debug("started")
-	if len(r.Ports) > 0 && !allowsPort(r.Protocol) {
-		return fmt.Errorf("Ports cannot be specified for Protocol %s", strings.ToUpper(protocols[r.Protocol]))
+	singles, err := expandFilterRule(r)
+	if err != nil {
+		return err
 	}
 
-	single := singleRule{
-		Protocol: r.Protocol,
-		ICMPs:    r.ICMPs,
-		Log:      r.Log,
+	for _, single := range singles {
+		if err := ch.prependSingleRule(single); err != nil {
+			return err
+		}
 	}
 
+	logger.Debug("ending")
+	return nil
+}
+
+// expandFilterRule splits a NetOutRule into one rule per network and port
+// range, as each iptables rule matches at most one of each.
+func expandFilterRule(r garden.NetOutRule) ([]singleRule, error) {
+	if len(r.Ports) > 0 && !allowsPort(r.Protocol) {
+		return nil, fmt.Errorf("Ports cannot be specified for Protocol %s", strings.ToUpper(protocols[r.Protocol]))
+	}
+
+	var singles []singleRule
+
 	// It should still loop once even if there are no networks or ports.
 	for j := 0; j < len(r.Networks) || j == 0; j++ {
 		for i := 0; i < len(r.Ports) || i == 0; i++ {
+			single := singleRule{
+				Protocol: r.Protocol,
+				ICMPs:    r.ICMPs,
+				Log:      r.Log,
+			}
 
 			// Preserve nils unless there are ports specified
 			if len(r.Ports) > 0 {
@@ -211,30 +233,70 @@ func (ch *chain) PrependFilterRule(r garden.NetOutRule) error {
 				single.Networks = &r.Networks[j]
 			}
 
-			if err := ch.prependSingleRule(single); err != nil {
-				return err
-			}
+			singles = append(singles, single)
 		}
 	}
 
-	logger.Debug("ending")
-	return nil
+	return singles, nil
 }
 
 func allowsPort(p garden.Protocol) bool {
 	return p == garden.ProtocolTCP || p == garden.ProtocolUDP
 }
 
+func (ch *chain) DeleteFilterRule(r garden.NetOutRule) error {
+	logger := ch.logger.Session("delete-filter-rule", lager.Data{"rule": r})
+	logger.Debug("started")
+	singles, err := expandFilterRule(r)
+	if err != nil {
+		return err
+	}
+
+	for _, single := range singles {
+		if err := ch.runSingleRule(single, "-D", ch.name); err != nil {
+			return err
+		}
+	}
+
+	logger.Debug("ending")
+	return nil
+}
+
 func (ch *chain) prependSingleRule(r singleRule) error {
-	params := []string{"-w", "-I", ch.name, "1"}
+	return ch.runSingleRule(r, "-I", ch.name, "1")
+}
 
+func (ch *chain) runSingleRule(r singleRule, action ...string) error {
+	spec, err := r.spec(ch.logChainName)
+	if err != nil {
+		return err
+	}
+
+	params := append(append([]string{"-w"}, action...), spec...)
+
+	ch.logger.Debug("run-filter-rule", lager.Data{"parms": params})
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("/sbin/iptables", params...)
+	cmd.Stderr = &stderr
+	if err := ch.runner.Run(cmd); err != nil {
+		return fmt.Errorf("iptables: %v, %v", err, stderr.String())
+	}
+	ch.logger.Debug("runSingleRule-finished")
+
+	return nil
+}
+
+// spec is the rule's match and target, i.e. what follows the chain name in
+// an iptables command. Logged rules go to logChainName.
+func (r singleRule) spec(logChainName string) ([]string, error) {
 	protocolString, ok := protocols[r.Protocol]
 
 	if !ok {
-		return fmt.Errorf("invalid protocol: %d", r.Protocol)
+		return nil, fmt.Errorf("invalid protocol: %d", r.Protocol)
 	}
 
-	params = append(params, "--protocol", protocolString)
+	params := []string{"--protocol", protocolString}
 
 	network := r.Networks
 	if network != nil {
@@ -266,22 +328,12 @@ func (ch *chain) prependSingleRule(r singleRule) error {
 	}
 
 	if r.Log {
-		params = append(params, "--goto", ch.logChainName)
+		params = append(params, "--goto", logChainName)
 	} else {
 		params = append(params, "--jump", "RETURN")
 	}
 
-	ch.logger.Debug("prepend-filter-rule", lager.Data{"parms": params})
-
-	var stderr bytes.Buffer
-	cmd := exec.Command("/sbin/iptables", params...)
-	cmd.Stderr = &stderr
-	if err := ch.runner.Run(cmd); err != nil {
-		return fmt.Errorf("iptables: %v, %v", err, stderr.String())
-	}
-	ch.logger.Debug("prependSingleRule-finished")
-
-	return nil
+	return params, nil
 }
 
 type rule struct {
@@ -309,21 +361,27 @@ func flags(action, chain string, n *rule) []string {
 
 	rule = append(rule, action, chain)
 
+	return append(rule, n.spec()...)
+}
+
+func (n *rule) spec() []string {
+	var spec []string
+
 	if n.source != "" {
-		rule = append(rule, "--source", n.source)
+		spec = append(spec, "--source", n.source)
 	}
 
 	if n.destination != "" {
-		rule = append(rule, "--destination", n.destination)
+		spec = append(spec, "--destination", n.destination)
 	}
 
-	rule = append(rule, "--jump", string(n.jump))
+	spec = append(spec, "--jump", string(n.jump))
 
 	if n.to != nil {
-		rule = append(rule, "--to", string(n.to.String()))
+		spec = append(spec, "--to", string(n.to.String()))
 	}
 
-	return rule
+	return spec
 }
 
 type Destroyable interface {
@@ -697,6 +697,46 @@ var _ = Describe("Iptables", func() {
 					})
 				})
 			})
+
+			Describe("DeleteFilterRule", func() {
+				It("deletes the permutations of the port ranges and networks", func() {
+					Expect(subject.DeleteFilterRule(garden.NetOutRule{
+						Protocol: garden.ProtocolUDP,
+						Networks: []garden.IPRange{
+							{Start: net.ParseIP("1.2.3.4")},
+							{Start: net.ParseIP("2.2.3.4"), End: net.ParseIP("2.2.3.9")},
+						},
+						Ports: []garden.PortRange{{Start: 53, End: 53}},
+						Log:   true,
+					})).To(Succeed())
+
+					Expect(fakeRunner.ExecutedCommands()).To(HaveLen(2))
+					Expect(fakeRunner).To(HaveExecutedSerially(
+						fake_command_runner.CommandSpec{
+							Path: "/sbin/iptables",
+							Args: []string{"-w", "-D", "foo-bar-baz", "--protocol", "udp", "--destination", "1.2.3.4", "--destination-port", "53", "--goto", "foo-bar-baz-log"},
+						},
+						fake_command_runner.CommandSpec{
+							Path: "/sbin/iptables",
+							Args: []string{"-w", "-D", "foo-bar-baz", "--protocol", "udp", "-m", "iprange", "--dst-range", "2.2.3.4-2.2.3.9", "--destination-port", "53", "--goto", "foo-bar-baz-log"},
+						},
+					))
+				})
+
+				Context("when the command returns an error", func() {
+					It("returns a wrapped error, including stderr", func() {
+						fakeRunner.WhenRunning(
+							fake_command_runner.CommandSpec{Path: "/sbin/iptables"},
+							func(cmd *exec.Cmd) error {
+								cmd.Stderr.Write([]byte("Bad rule (does a matching rule exist in that chain?)."))
+								return errors.New("exit status 1")
+							},
+						)
+
+						Expect(subject.DeleteFilterRule(garden.NetOutRule{})).To(MatchError("iptables: exit status 1, Bad rule (does a matching rule exist in that chain?)."))
+					})
+				})
+			})
 		})
 	})
 })
@@ -0,0 +1,424 @@
+package iptables
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden-linux/logging"
+	"github.com/cloudfoundry/gunk/command_runner"
+	"github.com/pivotal-golang/lager"
+)
+
+var nftVerdicts = map[Action]string{
+	Return:    "return",
+	Reject:    "reject",
+	Drop:      "drop",
+	SourceNAT: "snat",
+}
+
+// NewNFTablesGlobalChain is like NewGlobalChain, but programs a chain of the
+// given nftables table with the nft binary at nft. The chain is created by
+// net.sh.
+func NewNFTablesGlobalChain(nft, table, name string, runner command_runner.CommandRunner, logger lager.Logger) Chain {
+	logger = logger.Session("nftables-global-chain", lager.Data{
+		"table": table,
+		"name":  name,
+	})
+	return &nftChain{
+		nft:   nft,
+		table: table,
+		chain: chain{name: name, logChainName: "", runner: &logging.Runner{CommandRunner: runner, Logger: logger}, logger: logger},
+	}
+}
+
+// NewNFTablesLoggingChain is like NewLoggingChain, but programs a chain of the
+// given nftables table with the nft binary at nft. All the rules of a call are
+// committed by a single nft transaction.
+func NewNFTablesLoggingChain(nft, table, name string, useKernelLogging bool, runner command_runner.CommandRunner, logger lager.Logger) Chain {
+	logger = logger.Session("nftables-logging-chain", lager.Data{
+		"table":            table,
+		"name":             name,
+		"useKernelLogging": useKernelLogging,
+	})
+	return &nftChain{
+		nft:   nft,
+		table: table,
+		chain: chain{
+			name:             name,
+			logChainName:     name + "-log",
+			useKernelLogging: useKernelLogging,
+			loglessRunner:    runner,
+			runner:           &logging.Runner{CommandRunner: runner, Logger: logger},
+			logger:           logger,
+		},
+	}
+}
+
+// nftChain reuses the expansion of NetOutRules of chain, but emits nft
+// statements. As nft can only delete rules by handle, every rule it adds is
+// commented with its own statement, so that it can be found again.
+type nftChain struct {
+	chain
+	nft   string
+	table string
+}
+
+func (ch *nftChain) Setup(logPrefix string) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	logger := ch.logger.Session("setup", lager.Data{
+		"logChainName": ch.logChainName,
+	})
+	logger.Debug("started")
+
+	if ch.logChainName == "" {
+		// we still use net.sh to set up global non-logging chains
+		panic("cannot set up chains without associated log chains")
+	}
+
+	// adding an existing chain is not an error, so flush it too
+	txn := NewNFTBatch(ch.nft)
+	txn.Add("add chain ip %s %s", ch.table, ch.logChainName)
+	txn.Add("flush chain ip %s %s", ch.table, ch.logChainName)
+	txn.Add("add rule ip %s %s ct state new,untracked,invalid meta l4proto tcp %s", ch.table, ch.logChainName, ch.nftLogStatement(logPrefix))
+	txn.Add("add rule ip %s %s return", ch.table, ch.logChainName)
+
+	if err := txn.Commit(ch.runner); err != nil {
+		return fmt.Errorf("iptables: log chain setup: %v", err)
+	}
+
+	logger.Debug("ending")
+	return nil
+}
+
+func (ch *nftChain) nftLogStatement(logPrefix string) string {
+	statement := "log prefix " + nftQuote(logPrefix)
+	if !ch.useKernelLogging {
+		statement += " group 1"
+	}
+
+	return statement
+}
+
+func (ch *nftChain) TearDown() error {
+	logger := ch.logger.Session("teardown", lager.Data{
+		"logChainName": ch.logChainName,
+	})
+	logger.Debug("started")
+	if ch.logChainName == "" {
+		// we still use net.sh to tear down global non-logging chains
+		panic("cannot tear down chains without associated log chains")
+	}
+
+	txn := NewNFTBatch(ch.nft)
+	txn.Add("flush chain ip %s %s", ch.table, ch.logChainName)
+	txn.Add("delete chain ip %s %s", ch.table, ch.logChainName)
+
+	// it's ok to skip logs here, we expect this to fail if this is a
+	// pre-creation teardown
+	txn.Commit(ch.loglessRunner)
+	logger.Debug("ending")
+	return nil
+}
+
+func (ch *nftChain) AppendRule(source string, destination string, jump Action) error {
+	return ch.appendRule(&rule{
+		source:      source,
+		destination: destination,
+		jump:        jump,
+	})
+}
+
+func (ch *nftChain) DeleteRule(source string, destination string, jump Action) error {
+	return ch.deleteRule(&rule{
+		source:      source,
+		destination: destination,
+		jump:        jump,
+	})
+}
+
+func (ch *nftChain) AppendNatRule(source string, destination string, jump Action, to net.IP) error {
+	return ch.appendRule(&rule{
+		typ:         Nat,
+		source:      source,
+		destination: destination,
+		jump:        jump,
+		to:          to,
+	})
+}
+
+func (ch *nftChain) DeleteNatRule(source string, destination string, jump Action, to net.IP) error {
+	return ch.deleteRule(&rule{
+		typ:         Nat,
+		source:      source,
+		destination: destination,
+		jump:        jump,
+		to:          to,
+	})
+}
+
+// The type of a rule has no counterpart here: the table of the chain decides
+// whether it is a filter or a NAT chain.
+func (ch *nftChain) appendRule(r *rule) error {
+	statement, err := r.nftStatement()
+	if err != nil {
+		return err
+	}
+
+	txn := NewNFTBatch(ch.nft)
+	txn.Add("add rule ip %s %s %s comment %s", ch.table, ch.name, statement, nftQuote(statement))
+
+	return txn.Commit(ch.runner)
+}
+
+func (ch *nftChain) deleteRule(r *rule) error {
+	statement, err := r.nftStatement()
+	if err != nil {
+		return err
+	}
+
+	handles, err := ch.handles()
+	if err != nil {
+		return err
+	}
+
+	txn := NewNFTBatch(ch.nft)
+	if err := txn.deleteRule(ch.table, ch.name, handles, statement); err != nil {
+		return err
+	}
+
+	return txn.Commit(ch.runner)
+}
+
+// handles maps the comments of the rules of the chain to their handles.
+func (ch *nftChain) handles() (map[string][]string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(ch.nft, "--handle", "list", "chain", "ip", ch.table, ch.name)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := ch.runner.Run(cmd); err != nil {
+		return nil, fmt.Errorf("nft: %v, %v", err, stderr.String())
+	}
+
+	handles := make(map[string][]string)
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		h := strings.LastIndex(line, " # handle ")
+		if h < 0 {
+			continue
+		}
+
+		c := strings.LastIndex(line[:h], " comment ")
+		if c < 0 {
+			continue
+		}
+
+		comment := line[c+len(" comment ") : h]
+		handles[comment] = append(handles[comment], strings.TrimSpace(line[h+len(" # handle "):]))
+	}
+
+	return handles, nil
+}
+
+// PrependFilterRule inserts one rule per network and port range of r, as the
+// iptables chain does. They are deliberately not collapsed into a named set of
+// the container: each NetOutRule keeps its own rules, so that DeleteFilterRule
+// removes exactly what it added even when rules overlap.
+func (ch *nftChain) PrependFilterRule(r garden.NetOutRule) error {
+	logger := ch.logger.Session("prepend-filter-rule", lager.Data{"rule": r})
+	logger.Debug("started")
+
+	singles, err := expandFilterRule(r)
+	if err != nil {
+		return err
+	}
+
+	txn := NewNFTBatch(ch.nft)
+	for _, single := range singles {
+		statement, err := single.nftStatement(ch.logChainName)
+		if err != nil {
+			return err
+		}
+
+		txn.Add("insert rule ip %s %s %s comment %s", ch.table, ch.name, statement, nftQuote(statement))
+	}
+
+	if err := txn.Commit(ch.runner); err != nil {
+		return err
+	}
+
+	logger.Debug("ending")
+	return nil
+}
+
+// DeleteFilterRule deletes all the rules r expands into in a single
+// transaction, so if any of them is missing none are deleted.
+func (ch *nftChain) DeleteFilterRule(r garden.NetOutRule) error {
+	logger := ch.logger.Session("delete-filter-rule", lager.Data{"rule": r})
+	logger.Debug("started")
+
+	singles, err := expandFilterRule(r)
+	if err != nil {
+		return err
+	}
+
+	handles, err := ch.handles()
+	if err != nil {
+		return err
+	}
+
+	txn := NewNFTBatch(ch.nft)
+	for _, single := range singles {
+		statement, err := single.nftStatement(ch.logChainName)
+		if err != nil {
+			return err
+		}
+
+		if err := txn.deleteRule(ch.table, ch.name, handles, statement); err != nil {
+			return err
+		}
+	}
+
+	if err := txn.Commit(ch.runner); err != nil {
+		return err
+	}
+
+	logger.Debug("ending")
+	return nil
+}
+
+// nftStatement is the nft counterpart of spec.
+func (r singleRule) nftStatement(logChainName string) (string, error) {
+	protocolString, ok := protocols[r.Protocol]
+
+	if !ok {
+		return "", fmt.Errorf("invalid protocol: %d", r.Protocol)
+	}
+
+	var params []string
+	if r.Protocol != garden.ProtocolAll {
+		params = append(params, "meta l4proto "+protocolString)
+	}
+
+	network := r.Networks
+	if network != nil {
+		if network.Start != nil && network.End != nil {
+			params = append(params, "ip daddr "+network.Start.String()+"-"+network.End.String())
+		} else if network.Start != nil {
+			params = append(params, "ip daddr "+network.Start.String())
+		} else if network.End != nil {
+			params = append(params, "ip daddr "+network.End.String())
+		}
+	}
+
+	ports := r.Ports
+	if ports != nil {
+		if ports.End != ports.Start {
+			params = append(params, fmt.Sprintf("%s dport %d-%d", protocolString, ports.Start, ports.End))
+		} else {
+			params = append(params, fmt.Sprintf("%s dport %d", protocolString, ports.Start))
+		}
+	}
+
+	if r.ICMPs != nil {
+		params = append(params, fmt.Sprintf("icmp type %d", r.ICMPs.Type))
+		if r.ICMPs.Code != nil {
+			params = append(params, fmt.Sprintf("icmp code %d", *r.ICMPs.Code))
+		}
+	}
+
+	if r.Log {
+		params = append(params, "goto "+logChainName)
+	} else {
+		params = append(params, "return")
+	}
+
+	return strings.Join(params, " "), nil
+}
+
+// nftStatement is the nft counterpart of spec.
+func (n *rule) nftStatement() (string, error) {
+	verdict, ok := nftVerdicts[n.jump]
+	if !ok {
+		return "", fmt.Errorf("invalid action: %s", n.jump)
+	}
+
+	var params []string
+
+	if n.source != "" {
+		params = append(params, "ip saddr "+n.source)
+	}
+
+	if n.destination != "" {
+		params = append(params, "ip daddr "+n.destination)
+	}
+
+	params = append(params, verdict)
+
+	if n.to != nil {
+		params = append(params, "to "+n.to.String())
+	}
+
+	return strings.Join(params, " "), nil
+}
+
+// NFTBatch accumulates nft commands and commits them with a single nft
+// invocation, which applies them in one transaction.
+type NFTBatch struct {
+	nft   string
+	lines []string
+}
+
+func NewNFTBatch(nft string) *NFTBatch {
+	return &NFTBatch{nft: nft}
+}
+
+// Add appends an nft command, formatted as by fmt.Sprintf.
+func (b *NFTBatch) Add(format string, args ...interface{}) {
+	b.lines = append(b.lines, fmt.Sprintf(format, args...))
+}
+
+// deleteRule deletes the first of the rules added with statement, and removes
+// its handle from handles, like iptables -D.
+func (b *NFTBatch) deleteRule(table, chain string, handles map[string][]string, statement string) error {
+	comment := nftQuote(statement)
+	if len(handles[comment]) == 0 {
+		return fmt.Errorf("nft: no rule %q in chain %s", statement, chain)
+	}
+
+	b.Add("delete rule ip %s %s handle %s", table, chain, handles[comment][0])
+	handles[comment] = handles[comment][1:]
+
+	return nil
+}
+
+// String is the input to nft.
+func (b *NFTBatch) String() string {
+	return strings.Join(b.lines, "\n") + "\n"
+}
+
+func (b *NFTBatch) Commit(runner command_runner.CommandRunner) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command(b.nft, "-f", "-")
+	cmd.Stdin = strings.NewReader(b.String())
+	cmd.Stderr = &stderr
+	if err := runner.Run(cmd); err != nil {
+		return fmt.Errorf("nft: %v, %v", err, stderr.String())
+	}
+
+	return nil
+}
+
+// nftQuote makes a string literal. nft has no escape for double quotes inside
+// strings, so they are replaced with single quotes.
+func nftQuote(s string) string {
+	return `"` + strings.Replace(s, `"`, `'`, -1) + `"`
+}
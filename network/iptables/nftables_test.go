@@ -0,0 +1,328 @@
+package iptables_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os/exec"
+
+	"github.com/cloudfoundry-incubator/garden"
+	. "github.com/cloudfoundry-incubator/garden-linux/network/iptables"
+	"github.com/cloudfoundry/gunk/command_runner/fake_command_runner"
+	. "github.com/cloudfoundry/gunk/command_runner/fake_command_runner/matchers"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NFTables chain", func() {
+	var fakeRunner *fake_command_runner.FakeCommandRunner
+	var subject Chain
+	var useKernelLogging bool
+	var batches []string
+	var nftErr error
+
+	nftSpec := fake_command_runner.CommandSpec{
+		Path: "/path/to/nft",
+		Args: []string{"-f", "-"},
+	}
+
+	BeforeEach(func() {
+		useKernelLogging = false
+		batches = nil
+		nftErr = nil
+	})
+
+	JustBeforeEach(func() {
+		fakeRunner = fake_command_runner.New()
+		fakeRunner.WhenRunning(nftSpec, func(cmd *exec.Cmd) error {
+			input, err := ioutil.ReadAll(cmd.Stdin)
+			Expect(err).ToNot(HaveOccurred())
+			batches = append(batches, string(input))
+
+			if nftErr != nil {
+				cmd.Stderr.Write([]byte("stderr contents"))
+			}
+
+			return nftErr
+		})
+
+		subject = NewNFTablesLoggingChain("/path/to/nft", "some-table", "foo-bar-baz", useKernelLogging, fakeRunner, lagertest.NewTestLogger("test"))
+	})
+
+	Describe("Setup", func() {
+		It("creates the log chain in a single transaction", func() {
+			Expect(subject.Setup("logPrefix")).To(Succeed())
+			Expect(fakeRunner).To(HaveExecutedSerially(nftSpec))
+			Expect(fakeRunner.ExecutedCommands()).To(HaveLen(1))
+
+			Expect(batches).To(Equal([]string{
+				"add chain ip some-table foo-bar-baz-log\n" +
+					"flush chain ip some-table foo-bar-baz-log\n" +
+					"add rule ip some-table foo-bar-baz-log ct state new,untracked,invalid meta l4proto tcp log prefix \"logPrefix\" group 1\n" +
+					"add rule ip some-table foo-bar-baz-log return\n",
+			}))
+		})
+
+		Context("when kernel logging is enabled", func() {
+			BeforeEach(func() {
+				useKernelLogging = true
+			})
+
+			It("logs without an nflog group", func() {
+				Expect(subject.Setup("logPrefix")).To(Succeed())
+				Expect(batches[0]).To(ContainSubstring("meta l4proto tcp log prefix \"logPrefix\"\n"))
+			})
+		})
+
+		Context("when the log prefix contains double quotes", func() {
+			It("replaces them, as nft cannot escape them", func() {
+				Expect(subject.Setup(`some "log" prefix`)).To(Succeed())
+				Expect(batches[0]).To(ContainSubstring(`log prefix "some 'log' prefix" group 1`))
+			})
+		})
+
+		Context("when nft fails", func() {
+			It("returns a wrapped error, including stderr", func() {
+				nftErr = errors.New("y")
+				Expect(subject.Setup("logPrefix")).To(MatchError("iptables: log chain setup: nft: y, stderr contents"))
+			})
+		})
+
+		Context("when the chain has no log chain", func() {
+			It("panics", func() {
+				global := NewNFTablesGlobalChain("/path/to/nft", "some-table", "global", fakeRunner, lagertest.NewTestLogger("test"))
+				Expect(func() { global.Setup("logPrefix") }).To(Panic())
+			})
+		})
+	})
+
+	Describe("TearDown", func() {
+		It("flushes and deletes the log chain", func() {
+			Expect(subject.TearDown()).To(Succeed())
+			Expect(batches).To(Equal([]string{
+				"flush chain ip some-table foo-bar-baz-log\n" +
+					"delete chain ip some-table foo-bar-baz-log\n",
+			}))
+		})
+
+		It("ignores failures", func() {
+			nftErr = errors.New("y")
+			Expect(subject.TearDown()).To(Succeed())
+		})
+	})
+
+	Describe("AppendRule", func() {
+		It("appends the rule, commented with its statement", func() {
+			Expect(subject.AppendRule("", "2.0.0.0/11", Return)).To(Succeed())
+			Expect(batches).To(Equal([]string{
+				"add rule ip some-table foo-bar-baz ip daddr 2.0.0.0/11 return comment \"ip daddr 2.0.0.0/11 return\"\n",
+			}))
+		})
+
+		It("rejects", func() {
+			Expect(subject.AppendRule("1.3.5.0/28", "2.0.0.0/11", Reject)).To(Succeed())
+			Expect(batches).To(Equal([]string{
+				"add rule ip some-table foo-bar-baz ip saddr 1.3.5.0/28 ip daddr 2.0.0.0/11 reject comment \"ip saddr 1.3.5.0/28 ip daddr 2.0.0.0/11 reject\"\n",
+			}))
+		})
+
+		Context("when the action has no nft verdict", func() {
+			It("returns an error without running nft", func() {
+				Expect(subject.AppendRule("", "", Action("BANANA"))).To(MatchError("invalid action: BANANA"))
+				Expect(fakeRunner.ExecutedCommands()).To(HaveLen(0))
+			})
+		})
+	})
+
+	Describe("DeleteRule", func() {
+		var listing string
+
+		listSpec := fake_command_runner.CommandSpec{
+			Path: "/path/to/nft",
+			Args: []string{"--handle", "list", "chain", "ip", "some-table", "foo-bar-baz"},
+		}
+
+		BeforeEach(func() {
+			listing = `table ip some-table {
+	chain foo-bar-baz { # handle 3
+		ip daddr 2.0.0.0/11 return comment "ip daddr 2.0.0.0/11 return" # handle 7
+		ip saddr 1.3.5.0/28 ip daddr 2.0.0.0/11 reject comment "ip saddr 1.3.5.0/28 ip daddr 2.0.0.0/11 reject" # handle 8
+	}
+}
+`
+		})
+
+		JustBeforeEach(func() {
+			fakeRunner.WhenRunning(listSpec, func(cmd *exec.Cmd) error {
+				cmd.Stdout.Write([]byte(listing))
+				return nil
+			})
+		})
+
+		It("deletes the rule by its handle", func() {
+			Expect(subject.DeleteRule("1.3.5.0/28", "2.0.0.0/11", Reject)).To(Succeed())
+			Expect(fakeRunner).To(HaveExecutedSerially(listSpec, nftSpec))
+			Expect(batches).To(Equal([]string{
+				"delete rule ip some-table foo-bar-baz handle 8\n",
+			}))
+		})
+
+		Context("when the rule is not in the chain", func() {
+			It("returns an error without deleting anything", func() {
+				Expect(subject.DeleteRule("", "2.0.0.0/11", Reject)).To(MatchError(`nft: no rule "ip daddr 2.0.0.0/11 reject" in chain foo-bar-baz`))
+				Expect(batches).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("AppendNatRule", func() {
+		It("appends a snat rule", func() {
+			Expect(subject.AppendNatRule("1.3.5.0/28", "2.0.0.0/11", SourceNAT, net.ParseIP("1.2.3.4"))).To(Succeed())
+			Expect(batches).To(Equal([]string{
+				"add rule ip some-table foo-bar-baz ip saddr 1.3.5.0/28 ip daddr 2.0.0.0/11 snat to 1.2.3.4 comment \"ip saddr 1.3.5.0/28 ip daddr 2.0.0.0/11 snat to 1.2.3.4\"\n",
+			}))
+		})
+
+		Context("when nft fails", func() {
+			It("returns a wrapped error, including stderr", func() {
+				nftErr = errors.New("badly laid nftable")
+				Expect(subject.AppendNatRule("", "", SourceNAT, nil)).To(MatchError("nft: badly laid nftable, stderr contents"))
+			})
+		})
+	})
+
+	Describe("PrependFilterRule", func() {
+		It("inserts the permutations of the networks and port ranges in a single transaction", func() {
+			Expect(subject.PrependFilterRule(garden.NetOutRule{
+				Protocol: garden.ProtocolTCP,
+				Networks: []garden.IPRange{
+					{
+						Start: net.ParseIP("1.2.3.4"),
+					},
+					{
+						Start: net.ParseIP("2.2.3.4"),
+						End:   net.ParseIP("2.2.3.9"),
+					},
+				},
+				Ports: []garden.PortRange{
+					{12, 24},
+					{64, 64},
+				},
+				Log: true,
+			})).To(Succeed())
+
+			Expect(fakeRunner.ExecutedCommands()).To(HaveLen(1))
+			Expect(batches).To(Equal([]string{
+				"insert rule ip some-table foo-bar-baz meta l4proto tcp ip daddr 1.2.3.4 tcp dport 12-24 goto foo-bar-baz-log comment \"meta l4proto tcp ip daddr 1.2.3.4 tcp dport 12-24 goto foo-bar-baz-log\"\n" +
+					"insert rule ip some-table foo-bar-baz meta l4proto tcp ip daddr 1.2.3.4 tcp dport 64 goto foo-bar-baz-log comment \"meta l4proto tcp ip daddr 1.2.3.4 tcp dport 64 goto foo-bar-baz-log\"\n" +
+					"insert rule ip some-table foo-bar-baz meta l4proto tcp ip daddr 2.2.3.4-2.2.3.9 tcp dport 12-24 goto foo-bar-baz-log comment \"meta l4proto tcp ip daddr 2.2.3.4-2.2.3.9 tcp dport 12-24 goto foo-bar-baz-log\"\n" +
+					"insert rule ip some-table foo-bar-baz meta l4proto tcp ip daddr 2.2.3.4-2.2.3.9 tcp dport 64 goto foo-bar-baz-log comment \"meta l4proto tcp ip daddr 2.2.3.4-2.2.3.9 tcp dport 64 goto foo-bar-baz-log\"\n",
+			}))
+		})
+
+		It("matches any protocol for ProtocolAll", func() {
+			Expect(subject.PrependFilterRule(garden.NetOutRule{
+				Networks: []garden.IPRange{{End: net.ParseIP("1.2.3.4")}},
+			})).To(Succeed())
+
+			Expect(batches).To(Equal([]string{
+				"insert rule ip some-table foo-bar-baz ip daddr 1.2.3.4 return comment \"ip daddr 1.2.3.4 return\"\n",
+			}))
+		})
+
+		It("passes icmp types and codes", func() {
+			Expect(subject.PrependFilterRule(garden.NetOutRule{
+				Protocol: garden.ProtocolICMP,
+				ICMPs:    &garden.ICMPControl{Type: 3, Code: garden.ICMPControlCode(5)},
+			})).To(Succeed())
+
+			Expect(batches).To(Equal([]string{
+				"insert rule ip some-table foo-bar-baz meta l4proto icmp icmp type 3 icmp code 5 return comment \"meta l4proto icmp icmp type 3 icmp code 5 return\"\n",
+			}))
+		})
+
+		Context("when a portrange is specified for ProtocolALL", func() {
+			It("returns a nice error message without running nft", func() {
+				Expect(subject.PrependFilterRule(garden.NetOutRule{
+					Protocol: garden.ProtocolAll,
+					Ports:    []garden.PortRange{{Start: 1, End: 5}},
+				})).To(MatchError("Ports cannot be specified for Protocol ALL"))
+
+				Expect(fakeRunner.ExecutedCommands()).To(HaveLen(0))
+			})
+		})
+
+		Context("when an invalid protocol is specified", func() {
+			It("returns an error without running nft", func() {
+				Expect(subject.PrependFilterRule(garden.NetOutRule{
+					Protocol: garden.Protocol(52),
+				})).To(MatchError("invalid protocol: 52"))
+
+				Expect(fakeRunner.ExecutedCommands()).To(HaveLen(0))
+			})
+		})
+
+		Context("when nft fails", func() {
+			It("returns a wrapped error, including stderr", func() {
+				nftErr = errors.New("badly laid nftable")
+				Expect(subject.PrependFilterRule(garden.NetOutRule{})).To(MatchError("nft: badly laid nftable, stderr contents"))
+			})
+		})
+	})
+
+	Describe("DeleteFilterRule", func() {
+		listSpec := fake_command_runner.CommandSpec{
+			Path: "/path/to/nft",
+			Args: []string{"--handle", "list", "chain", "ip", "some-table", "foo-bar-baz"},
+		}
+
+		JustBeforeEach(func() {
+			fakeRunner.WhenRunning(listSpec, func(cmd *exec.Cmd) error {
+				cmd.Stdout.Write([]byte(`table ip some-table {
+	chain foo-bar-baz { # handle 3
+		meta l4proto udp ip daddr 1.2.3.4 udp dport 53 return comment "meta l4proto udp ip daddr 1.2.3.4 udp dport 53 return" # handle 12
+		meta l4proto udp ip daddr 1.2.3.4 udp dport 53 return comment "meta l4proto udp ip daddr 1.2.3.4 udp dport 53 return" # handle 11
+		meta l4proto udp ip daddr 2.2.3.4-2.2.3.9 udp dport 53 return comment "meta l4proto udp ip daddr 2.2.3.4-2.2.3.9 udp dport 53 return" # handle 10
+		ip daddr 2.0.0.0/11 return comment "ip daddr 2.0.0.0/11 return" # handle 7
+	}
+}
+`))
+				return nil
+			})
+		})
+
+		It("deletes one rule per permutation, by handle, in a single transaction", func() {
+			Expect(subject.DeleteFilterRule(garden.NetOutRule{
+				Protocol: garden.ProtocolUDP,
+				Networks: []garden.IPRange{
+					{Start: net.ParseIP("1.2.3.4")},
+					{Start: net.ParseIP("2.2.3.4"), End: net.ParseIP("2.2.3.9")},
+				},
+				Ports: []garden.PortRange{{Start: 53, End: 53}},
+			})).To(Succeed())
+
+			Expect(fakeRunner).To(HaveExecutedSerially(listSpec, nftSpec))
+			Expect(batches).To(Equal([]string{
+				"delete rule ip some-table foo-bar-baz handle 12\n" +
+					"delete rule ip some-table foo-bar-baz handle 10\n",
+			}))
+		})
+
+		Context("when a permutation is not in the chain", func() {
+			It("returns an error without deleting anything", func() {
+				Expect(subject.DeleteFilterRule(garden.NetOutRule{
+					Protocol: garden.ProtocolUDP,
+					Networks: []garden.IPRange{
+						{Start: net.ParseIP("1.2.3.4")},
+						{Start: net.ParseIP("5.6.7.8")},
+					},
+					Ports: []garden.PortRange{{Start: 53, End: 53}},
+				})).To(MatchError(`nft: no rule "meta l4proto udp ip daddr 5.6.7.8 udp dport 53 return" in chain foo-bar-baz`))
+
+				Expect(batches).To(BeEmpty())
+			})
+		})
+	})
+})
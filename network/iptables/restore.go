@@ -0,0 +1,306 @@
+package iptables
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden-linux/logging"
+	"github.com/cloudfoundry/gunk/command_runner"
+	"github.com/pivotal-golang/lager"
+)
+
+// NewRestoringGlobalChain is like NewGlobalChain, but programs the chain with
+// iptables-restore.
+func NewRestoringGlobalChain(name string, runner command_runner.CommandRunner, logger lager.Logger) Chain {
+	logger = logger.Session("restoring-global-chain", lager.Data{
+		"name": name,
+	})
+	return &restoringChain{
+		chain: chain{name: name, logChainName: "", runner: &logging.Runner{CommandRunner: runner, Logger: logger}, logger: logger},
+	}
+}
+
+// NewRestoringLoggingChain is like NewLoggingChain, but programs the chain
+// with iptables-restore. All the rules of a call, e.g. every network and port
+// range of a NetOutRule, are committed by a single iptables-restore, so either
+// all of them are applied or none are.
+func NewRestoringLoggingChain(name string, useKernelLogging bool, runner command_runner.CommandRunner, logger lager.Logger) Chain {
+	logger = logger.Session("restoring-logging-chain", lager.Data{
+		"name":             name,
+		"useKernelLogging": useKernelLogging,
+	})
+	return &restoringChain{
+		chain: chain{
+			name:             name,
+			logChainName:     name + "-log",
+			useKernelLogging: useKernelLogging,
+			loglessRunner:    runner,
+			runner:           &logging.Runner{CommandRunner: runner, Logger: logger},
+			logger:           logger,
+		},
+	}
+}
+
+type restoringChain struct {
+	chain
+}
+
+func (ch *restoringChain) Setup(logPrefix string) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	logger := ch.logger.Session("setup", lager.Data{
+		"logChainName": ch.logChainName,
+	})
+	logger.Debug("started")
+
+	if ch.logChainName == "" {
+		// we still use net.sh to set up global non-logging chains
+		panic("cannot set up chains without associated log chains")
+	}
+
+	// declaring the chain creates it, or flushes it if it already exists
+	txn := NewRestore()
+	txn.Declare("", ch.logChainName)
+	txn.Add("", append([]string{"-A", ch.logChainName, "-m", "conntrack", "--ctstate", "NEW,UNTRACKED,INVALID", "--protocol", "tcp"}, ch.buildLogParams(logPrefix)...)...)
+	txn.Add("", "-A", ch.logChainName, "--jump", "RETURN")
+
+	if err := txn.Commit(ch.runner); err != nil {
+		return fmt.Errorf("iptables: log chain setup: %v", err)
+	}
+
+	logger.Debug("ending")
+	return nil
+}
+
+func (ch *restoringChain) TearDown() error {
+	logger := ch.logger.Session("teardown", lager.Data{
+		"logChainName": ch.logChainName,
+	})
+	logger.Debug("started")
+	if ch.logChainName == "" {
+		// we still use net.sh to tear down global non-logging chains
+		panic("cannot tear down chains without associated log chains")
+	}
+
+	txn := NewRestore()
+	txn.Add("", "-F", ch.logChainName)
+	txn.Add("", "-X", ch.logChainName)
+
+	// it's ok to skip logs here, we expect this to fail if this is a
+	// pre-creation teardown
+	txn.Commit(ch.loglessRunner)
+	logger.Debug("ending")
+	return nil
+}
+
+func (ch *restoringChain) AppendRule(source string, destination string, jump Action) error {
+	return ch.commitRule("-A", &rule{
+		source:      source,
+		destination: destination,
+		jump:        jump,
+	})
+}
+
+func (ch *restoringChain) DeleteRule(source string, destination string, jump Action) error {
+	return ch.commitRule("-D", &rule{
+		source:      source,
+		destination: destination,
+		jump:        jump,
+	})
+}
+
+func (ch *restoringChain) AppendNatRule(source string, destination string, jump Action, to net.IP) error {
+	return ch.commitRule("-A", &rule{
+		typ:         Nat,
+		source:      source,
+		destination: destination,
+		jump:        jump,
+		to:          to,
+	})
+}
+
+func (ch *restoringChain) DeleteNatRule(source string, destination string, jump Action, to net.IP) error {
+	return ch.commitRule("-D", &rule{
+		typ:         Nat,
+		source:      source,
+		destination: destination,
+		jump:        jump,
+		to:          to,
+	})
+}
+
+func (ch *restoringChain) commitRule(action string, r *rule) error {
+	txn := NewRestore()
+	txn.Add(r.typ, append([]string{action, ch.name}, r.spec()...)...)
+
+	return txn.Commit(ch.runner)
+}
+
+func (ch *restoringChain) PrependFilterRule(r garden.NetOutRule) error {
+	logger := ch.logger.Session("prepend-filter-rule", lager.Data{"rule": r})
+	logger.Debug("started")
+
+	if err := ch.commitFilterRule(r, "-I", ch.name, "1"); err != nil {
+		return err
+	}
+
+	logger.Debug("ending")
+	return nil
+}
+
+// DeleteFilterRule deletes all the rules r expands into with a single
+// iptables-restore, so if any of them is missing none are deleted.
+func (ch *restoringChain) DeleteFilterRule(r garden.NetOutRule) error {
+	logger := ch.logger.Session("delete-filter-rule", lager.Data{"rule": r})
+	logger.Debug("started")
+
+	if err := ch.commitFilterRule(r, "-D", ch.name); err != nil {
+		return err
+	}
+
+	logger.Debug("ending")
+	return nil
+}
+
+func (ch *restoringChain) commitFilterRule(r garden.NetOutRule, action ...string) error {
+	singles, err := expandFilterRule(r)
+	if err != nil {
+		return err
+	}
+
+	txn := NewRestore()
+	for _, single := range singles {
+		spec, err := single.spec(ch.logChainName)
+		if err != nil {
+			return err
+		}
+
+		txn.Add("", append(append([]string{}, action...), spec...)...)
+	}
+
+	return txn.Commit(ch.runner)
+}
+
+// Restore accumulates iptables commands and commits them with a single
+// iptables-restore, which applies each table atomically. It does not flush
+// the tables, so existing rules are left alone.
+type Restore struct {
+	tables []Type
+	chains map[Type][]string
+	lines  map[Type][]string
+}
+
+func NewRestore() *Restore {
+	return &Restore{
+		chains: make(map[Type][]string),
+		lines:  make(map[Type][]string),
+	}
+}
+
+// Declare creates the chain in the table, flushing it if it already exists.
+func (r *Restore) Declare(table Type, chain string) {
+	r.addTable(table)
+	r.chains[table] = append(r.chains[table], chain)
+}
+
+// Add appends an iptables command, without the table, to the table.
+func (r *Restore) Add(table Type, args ...string) {
+	r.addTable(table)
+
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quoteRestoreArg(arg)
+	}
+
+	r.lines[table] = append(r.lines[table], strings.Join(quoted, " "))
+}
+
+func (r *Restore) addTable(table Type) {
+	if _, found := r.lines[table]; found {
+		return
+	}
+
+	r.tables = append(r.tables, table)
+	r.lines[table] = []string{}
+}
+
+// String is the input to iptables-restore.
+func (r *Restore) String() string {
+	var input bytes.Buffer
+
+	for _, table := range r.tables {
+		name := string(table)
+		if name == "" {
+			name = "filter"
+		}
+
+		fmt.Fprintf(&input, "*%s\n", name)
+		for _, chain := range r.chains[table] {
+			fmt.Fprintf(&input, ":%s - [0:0]\n", chain)
+		}
+		for _, line := range r.lines[table] {
+			fmt.Fprintf(&input, "%s\n", line)
+		}
+		fmt.Fprintf(&input, "COMMIT\n")
+	}
+
+	return input.String()
+}
+
+// iptables-restore only waits for the xtables lock, rather than failing when
+// another iptables holds it, with --wait, which needs iptables 1.6.2. Whether
+// it is supported is probed once by ProbeRestoreWait. Either way, the
+// iptables-restores of this process are serialized by restoreMutex.
+var (
+	restoreMutex sync.Mutex
+	restoreWait  bool
+)
+
+// ProbeRestoreWait checks whether iptables-restore supports --wait, and if
+// so passes it to every later iptables-restore. It is meant to be called
+// once, at startup.
+func ProbeRestoreWait(runner command_runner.CommandRunner) bool {
+	restoreMutex.Lock()
+	defer restoreMutex.Unlock()
+
+	cmd := exec.Command("/sbin/iptables-restore", "--wait", "--test", "--noflush")
+	cmd.Stdin = strings.NewReader("")
+	restoreWait = runner.Run(cmd) == nil
+
+	return restoreWait
+}
+
+// Commit applies the commands with a single iptables-restore.
+func (r *Restore) Commit(runner command_runner.CommandRunner) error {
+	restoreMutex.Lock()
+	defer restoreMutex.Unlock()
+
+	args := []string{"--noflush"}
+	if restoreWait {
+		args = append([]string{"--wait"}, args...)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("/sbin/iptables-restore", args...)
+	cmd.Stdin = strings.NewReader(r.String())
+	cmd.Stderr = &stderr
+	if err := runner.Run(cmd); err != nil {
+		return fmt.Errorf("iptables-restore: %v, %v", err, stderr.String())
+	}
+
+	return nil
+}
+
+func quoteRestoreArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'") {
+		return arg
+	}
+
+	return `"` + strings.Replace(arg, `"`, `\"`, -1) + `"`
+}
@@ -0,0 +1,336 @@
+package iptables_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os/exec"
+
+	"github.com/cloudfoundry-incubator/garden"
+	. "github.com/cloudfoundry-incubator/garden-linux/network/iptables"
+	"github.com/cloudfoundry/gunk/command_runner/fake_command_runner"
+	. "github.com/cloudfoundry/gunk/command_runner/fake_command_runner/matchers"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Restoring chain", func() {
+	var fakeRunner *fake_command_runner.FakeCommandRunner
+	var subject Chain
+	var useKernelLogging bool
+	var restored []string
+	var restoreErr error
+
+	restoreSpec := fake_command_runner.CommandSpec{
+		Path: "/sbin/iptables-restore",
+		Args: []string{"--noflush"},
+	}
+
+	BeforeEach(func() {
+		useKernelLogging = false
+		restored = nil
+		restoreErr = nil
+	})
+
+	JustBeforeEach(func() {
+		fakeRunner = fake_command_runner.New()
+		fakeRunner.WhenRunning(restoreSpec, func(cmd *exec.Cmd) error {
+			input, err := ioutil.ReadAll(cmd.Stdin)
+			Expect(err).ToNot(HaveOccurred())
+			restored = append(restored, string(input))
+
+			if restoreErr != nil {
+				cmd.Stderr.Write([]byte("stderr contents"))
+			}
+
+			return restoreErr
+		})
+
+		subject = NewRestoringLoggingChain("foo-bar-baz", useKernelLogging, fakeRunner, lagertest.NewTestLogger("test"))
+	})
+
+	Describe("Setup", func() {
+		It("creates the log chain with a single iptables-restore", func() {
+			Expect(subject.Setup("logPrefix")).To(Succeed())
+			Expect(fakeRunner).To(HaveExecutedSerially(restoreSpec))
+			Expect(fakeRunner.ExecutedCommands()).To(HaveLen(1))
+
+			Expect(restored).To(Equal([]string{
+				"*filter\n" +
+					":foo-bar-baz-log - [0:0]\n" +
+					"-A foo-bar-baz-log -m conntrack --ctstate NEW,UNTRACKED,INVALID --protocol tcp --jump NFLOG --nflog-prefix logPrefix --nflog-group 1\n" +
+					"-A foo-bar-baz-log --jump RETURN\n" +
+					"COMMIT\n",
+			}))
+		})
+
+		Context("when kernel logging is enabled", func() {
+			BeforeEach(func() {
+				useKernelLogging = true
+			})
+
+			It("logs with the LOG target", func() {
+				Expect(subject.Setup("logPrefix")).To(Succeed())
+				Expect(restored[0]).To(ContainSubstring("-A foo-bar-baz-log -m conntrack --ctstate NEW,UNTRACKED,INVALID --protocol tcp --jump LOG --log-prefix logPrefix\n"))
+			})
+		})
+
+		Context("when the log prefix contains spaces", func() {
+			It("quotes it", func() {
+				Expect(subject.Setup(`some "log" prefix`)).To(Succeed())
+				Expect(restored[0]).To(ContainSubstring(`--nflog-prefix "some \"log\" prefix" --nflog-group 1`))
+			})
+		})
+
+		Context("when iptables-restore fails", func() {
+			It("returns a wrapped error, including stderr", func() {
+				restoreErr = errors.New("y")
+				Expect(subject.Setup("logPrefix")).To(MatchError("iptables: log chain setup: iptables-restore: y, stderr contents"))
+			})
+		})
+
+		Context("when the chain has no log chain", func() {
+			It("panics", func() {
+				global := NewRestoringGlobalChain("global", fakeRunner, lagertest.NewTestLogger("test"))
+				Expect(func() { global.Setup("logPrefix") }).To(Panic())
+			})
+		})
+	})
+
+	Describe("TearDown", func() {
+		It("flushes and deletes the log chain with a single iptables-restore", func() {
+			Expect(subject.TearDown()).To(Succeed())
+			Expect(restored).To(Equal([]string{
+				"*filter\n" +
+					"-F foo-bar-baz-log\n" +
+					"-X foo-bar-baz-log\n" +
+					"COMMIT\n",
+			}))
+		})
+
+		It("ignores failures", func() {
+			restoreErr = errors.New("y")
+			Expect(subject.TearDown()).To(Succeed())
+		})
+	})
+
+	Describe("AppendRule", func() {
+		It("appends the rule to the filter table", func() {
+			Expect(subject.AppendRule("", "2.0.0.0/11", Return)).To(Succeed())
+			Expect(restored).To(Equal([]string{
+				"*filter\n" +
+					"-A foo-bar-baz --destination 2.0.0.0/11 --jump RETURN\n" +
+					"COMMIT\n",
+			}))
+		})
+	})
+
+	Describe("DeleteRule", func() {
+		It("deletes the rule from the filter table", func() {
+			Expect(subject.DeleteRule("1.3.5.0/28", "2.0.0.0/11", Reject)).To(Succeed())
+			Expect(restored).To(Equal([]string{
+				"*filter\n" +
+					"-D foo-bar-baz --source 1.3.5.0/28 --destination 2.0.0.0/11 --jump REJECT\n" +
+					"COMMIT\n",
+			}))
+		})
+	})
+
+	Describe("AppendNatRule", func() {
+		It("appends the rule to the nat table", func() {
+			Expect(subject.AppendNatRule("1.3.5.0/28", "2.0.0.0/11", SourceNAT, net.ParseIP("1.2.3.4"))).To(Succeed())
+			Expect(restored).To(Equal([]string{
+				"*nat\n" +
+					"-A foo-bar-baz --source 1.3.5.0/28 --destination 2.0.0.0/11 --jump SNAT --to 1.2.3.4\n" +
+					"COMMIT\n",
+			}))
+		})
+
+		Context("when iptables-restore fails", func() {
+			It("returns a wrapped error, including stderr", func() {
+				restoreErr = errors.New("badly laid iptable")
+				Expect(subject.AppendNatRule("", "", SourceNAT, nil)).To(MatchError("iptables-restore: badly laid iptable, stderr contents"))
+			})
+		})
+	})
+
+	Describe("DeleteNatRule", func() {
+		It("deletes the rule from the nat table", func() {
+			Expect(subject.DeleteNatRule("1.3.5.0/28", "", SourceNAT, net.ParseIP("1.2.3.4"))).To(Succeed())
+			Expect(restored).To(Equal([]string{
+				"*nat\n" +
+					"-D foo-bar-baz --source 1.3.5.0/28 --jump SNAT --to 1.2.3.4\n" +
+					"COMMIT\n",
+			}))
+		})
+	})
+
+	Describe("PrependFilterRule", func() {
+		It("inserts the permutations of the networks and port ranges with a single iptables-restore", func() {
+			Expect(subject.PrependFilterRule(garden.NetOutRule{
+				Protocol: garden.ProtocolTCP,
+				Networks: []garden.IPRange{
+					{
+						Start: net.ParseIP("1.2.3.4"),
+					},
+					{
+						Start: net.ParseIP("2.2.3.4"),
+						End:   net.ParseIP("2.2.3.9"),
+					},
+				},
+				Ports: []garden.PortRange{
+					{12, 24},
+					{64, 942},
+				},
+				Log: true,
+			})).To(Succeed())
+
+			Expect(fakeRunner.ExecutedCommands()).To(HaveLen(1))
+			Expect(restored).To(Equal([]string{
+				"*filter\n" +
+					"-I foo-bar-baz 1 --protocol tcp --destination 1.2.3.4 --destination-port 12:24 --goto foo-bar-baz-log\n" +
+					"-I foo-bar-baz 1 --protocol tcp --destination 1.2.3.4 --destination-port 64:942 --goto foo-bar-baz-log\n" +
+					"-I foo-bar-baz 1 --protocol tcp -m iprange --dst-range 2.2.3.4-2.2.3.9 --destination-port 12:24 --goto foo-bar-baz-log\n" +
+					"-I foo-bar-baz 1 --protocol tcp -m iprange --dst-range 2.2.3.4-2.2.3.9 --destination-port 64:942 --goto foo-bar-baz-log\n" +
+					"COMMIT\n",
+			}))
+		})
+
+		It("passes icmp types and codes", func() {
+			Expect(subject.PrependFilterRule(garden.NetOutRule{
+				Protocol: garden.ProtocolICMP,
+				ICMPs:    &garden.ICMPControl{Type: 3, Code: garden.ICMPControlCode(5)},
+			})).To(Succeed())
+
+			Expect(restored).To(Equal([]string{
+				"*filter\n" +
+					"-I foo-bar-baz 1 --protocol icmp --icmp-type 3/5 --jump RETURN\n" +
+					"COMMIT\n",
+			}))
+		})
+
+		Context("when a portrange is specified for ProtocolALL", func() {
+			It("returns a nice error message without running iptables-restore", func() {
+				Expect(subject.PrependFilterRule(garden.NetOutRule{
+					Protocol: garden.ProtocolAll,
+					Ports:    []garden.PortRange{{Start: 1, End: 5}},
+				})).To(MatchError("Ports cannot be specified for Protocol ALL"))
+
+				Expect(fakeRunner.ExecutedCommands()).To(HaveLen(0))
+			})
+		})
+
+		Context("when an invalid protocol is specified", func() {
+			It("returns an error without running iptables-restore", func() {
+				Expect(subject.PrependFilterRule(garden.NetOutRule{
+					Protocol: garden.Protocol(52),
+				})).To(MatchError("invalid protocol: 52"))
+
+				Expect(fakeRunner.ExecutedCommands()).To(HaveLen(0))
+			})
+		})
+
+		Context("when iptables-restore fails", func() {
+			It("returns a wrapped error, including stderr", func() {
+				restoreErr = errors.New("badly laid iptable")
+				Expect(subject.PrependFilterRule(garden.NetOutRule{})).To(MatchError("iptables-restore: badly laid iptable, stderr contents"))
+			})
+		})
+	})
+
+	Describe("DeleteFilterRule", func() {
+		It("deletes the permutations of the networks and port ranges with a single iptables-restore", func() {
+			Expect(subject.DeleteFilterRule(garden.NetOutRule{
+				Protocol: garden.ProtocolTCP,
+				Networks: []garden.IPRange{{Start: net.ParseIP("1.2.3.4")}},
+				Ports: []garden.PortRange{
+					{Start: 12, End: 24},
+					{Start: 80, End: 80},
+				},
+			})).To(Succeed())
+
+			Expect(fakeRunner.ExecutedCommands()).To(HaveLen(1))
+			Expect(restored).To(Equal([]string{
+				"*filter\n" +
+					"-D foo-bar-baz --protocol tcp --destination 1.2.3.4 --destination-port 12:24 --jump RETURN\n" +
+					"-D foo-bar-baz --protocol tcp --destination 1.2.3.4 --destination-port 80 --jump RETURN\n" +
+					"COMMIT\n",
+			}))
+		})
+
+		Context("when an invalid protocol is specified", func() {
+			It("returns an error without running iptables-restore", func() {
+				Expect(subject.DeleteFilterRule(garden.NetOutRule{
+					Protocol: garden.Protocol(52),
+				})).To(MatchError("invalid protocol: 52"))
+
+				Expect(fakeRunner.ExecutedCommands()).To(HaveLen(0))
+			})
+		})
+
+		Context("when iptables-restore fails", func() {
+			It("returns a wrapped error, including stderr", func() {
+				restoreErr = errors.New("no such rule")
+				Expect(subject.DeleteFilterRule(garden.NetOutRule{})).To(MatchError("iptables-restore: no such rule, stderr contents"))
+			})
+		})
+	})
+})
+
+var _ = Describe("Probing iptables-restore for --wait", func() {
+	var fakeRunner *fake_command_runner.FakeCommandRunner
+
+	probeSpec := fake_command_runner.CommandSpec{
+		Path: "/sbin/iptables-restore",
+		Args: []string{"--wait", "--test", "--noflush"},
+	}
+
+	BeforeEach(func() {
+		fakeRunner = fake_command_runner.New()
+	})
+
+	AfterEach(func() {
+		// later iptables-restores must not wait
+		failing := fake_command_runner.New()
+		failing.WhenRunning(probeSpec, func(*exec.Cmd) error {
+			return errors.New("unrecognized option '--wait'")
+		})
+
+		Expect(ProbeRestoreWait(failing)).To(BeFalse())
+	})
+
+	Context("when iptables-restore supports --wait", func() {
+		It("passes --wait to later iptables-restores", func() {
+			Expect(ProbeRestoreWait(fakeRunner)).To(BeTrue())
+			Expect(fakeRunner).To(HaveExecutedSerially(probeSpec))
+
+			chain := NewRestoringGlobalChain("foo", fakeRunner, lagertest.NewTestLogger("test"))
+			Expect(chain.AppendRule("1.2.3.4", "", Return)).To(Succeed())
+
+			Expect(fakeRunner).To(HaveExecutedSerially(fake_command_runner.CommandSpec{
+				Path: "/sbin/iptables-restore",
+				Args: []string{"--wait", "--noflush"},
+			}))
+		})
+	})
+
+	Context("when iptables-restore does not support --wait", func() {
+		It("leaves it out of later iptables-restores", func() {
+			fakeRunner.WhenRunning(probeSpec, func(*exec.Cmd) error {
+				return errors.New("unrecognized option '--wait'")
+			})
+
+			Expect(ProbeRestoreWait(fakeRunner)).To(BeFalse())
+
+			chain := NewRestoringGlobalChain("foo", fakeRunner, lagertest.NewTestLogger("test"))
+			Expect(chain.AppendRule("1.2.3.4", "", Return)).To(Succeed())
+
+			Expect(fakeRunner).To(HaveExecutedSerially(fake_command_runner.CommandSpec{
+				Path: "/sbin/iptables-restore",
+				Args: []string{"--noflush"},
+			}))
+		})
+	})
+})
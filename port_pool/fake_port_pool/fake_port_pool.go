@@ -1,5 +1,7 @@
 package fake_port_pool
 
+import "github.com/cloudfoundry-incubator/garden-linux/port_pool"
+
 type FakePortPool struct {
 	nextPort uint32
 
@@ -9,6 +11,10 @@ type FakePortPool struct {
 	Acquired []uint32
 	Released []uint32
 	Removed  []uint32
+
+	AcquiredProtocols [][]port_pool.Protocol
+	ReleasedProtocols [][]port_pool.Protocol
+	RemovedProtocols  [][]port_pool.Protocol
 }
 
 func New(start uint32) *FakePortPool {
@@ -17,7 +23,7 @@ func New(start uint32) *FakePortPool {
 	}
 }
 
-func (p *FakePortPool) Acquire() (uint32, error) {
+func (p *FakePortPool) Acquire(protocols ...port_pool.Protocol) (uint32, error) {
 	if p.AcquireError != nil {
 		return 0, p.AcquireError
 	}
@@ -25,19 +31,23 @@ func (p *FakePortPool) Acquire() (uint32, error) {
 	port := p.nextPort
 	p.nextPort++
 
+	p.AcquiredProtocols = append(p.AcquiredProtocols, protocols)
+
 	return port, nil
 }
 
-func (p *FakePortPool) Remove(port uint32) error {
+func (p *FakePortPool) Remove(port uint32, protocols ...port_pool.Protocol) error {
 	if p.RemoveError != nil {
 		return p.RemoveError
 	}
 
 	p.Removed = append(p.Removed, port)
+	p.RemovedProtocols = append(p.RemovedProtocols, protocols)
 
 	return nil
 }
 
-func (p *FakePortPool) Release(port uint32) {
+func (p *FakePortPool) Release(port uint32, protocols ...port_pool.Protocol) {
 	p.Released = append(p.Released, port)
+	p.ReleasedProtocols = append(p.ReleasedProtocols, protocols)
 }
@@ -5,11 +5,24 @@ import (
 	"sync"
 )
 
+// Protocol names the transport protocol a port is acquired for. TCP and UDP
+// ports are allocated independently, so the same port can be acquired once
+// for each.
+type Protocol string
+
+const (
+	TCP Protocol = "tcp"
+	UDP Protocol = "udp"
+)
+
 type PortPool struct {
 	start uint32
 	size  uint32
 
-	pool      []uint32
+	// pools holds the free ports of each protocol in the order they are
+	// acquired, and free the same ports for looking them up
+	pools     map[Protocol][]uint32
+	free      map[Protocol]map[uint32]bool
 	poolMutex sync.Mutex
 
 	state State
@@ -50,75 +63,122 @@ func New(start, size uint32, state State) (*PortPool, error) {
 		i += 1
 	}
 
+	free := map[Protocol]map[uint32]bool{}
+	for _, protocol := range []Protocol{TCP, UDP} {
+		free[protocol] = make(map[uint32]bool, size)
+		for _, port := range pool {
+			free[protocol][port] = true
+		}
+	}
+
 	return &PortPool{
 		start: start,
 		size:  size,
 
-		pool: pool,
+		pools: map[Protocol][]uint32{
+			TCP: pool,
+			UDP: append([]uint32{}, pool...),
+		},
+		free: free,
 	}, nil
 }
 
-func (p *PortPool) Acquire() (uint32, error) {
+// Acquire returns the next port which is available for all of the protocols,
+// and takes it from each of them. Without protocols, it acquires a TCP port.
+func (p *PortPool) Acquire(protocols ...Protocol) (uint32, error) {
+	protocols = defaultProtocols(protocols)
+
 	p.poolMutex.Lock()
 	defer p.poolMutex.Unlock()
 
-	if len(p.pool) == 0 {
-		return 0, PoolExhaustedError{}
+	for _, port := range p.pools[protocols[0]] {
+		if p.available(port, protocols) {
+			p.take(port, protocols)
+			return port, nil
+		}
 	}
 
-	port := p.pool[0]
-
-	p.pool = p.pool[1:]
-
-	return port, nil
+	return 0, PoolExhaustedError{}
 }
 
-func (p *PortPool) Remove(port uint32) error {
-	idx := 0
-	found := false
+// Remove takes a specific port for all of the protocols. If it is already
+// taken for any of them, it is taken for none.
+func (p *PortPool) Remove(port uint32, protocols ...Protocol) error {
+	protocols = defaultProtocols(protocols)
 
 	p.poolMutex.Lock()
 	defer p.poolMutex.Unlock()
 
-	for i, existingPort := range p.pool {
-		if existingPort == port {
-			idx = i
-			found = true
-			break
-		}
-	}
-
-	if !found {
+	if !p.available(port, protocols) {
 		return PortTakenError{port}
 	}
 
-	p.pool = append(p.pool[:idx], p.pool[idx+1:]...)
+	p.take(port, protocols)
 
 	return nil
 }
 
-func (p *PortPool) Release(port uint32) {
+func (p *PortPool) Release(port uint32, protocols ...Protocol) {
 	if port < p.start || port >= p.start+p.size {
 		return
 	}
 
+	protocols = defaultProtocols(protocols)
+
 	p.poolMutex.Lock()
 	defer p.poolMutex.Unlock()
 
-	for _, existingPort := range p.pool {
-		if existingPort == port {
-			return
+	for _, protocol := range protocols {
+		if !p.free[protocol][port] {
+			p.pools[protocol] = append(p.pools[protocol], port)
+			p.free[protocol][port] = true
 		}
 	}
-
-	p.pool = append(p.pool, port)
 }
 
 func (p *PortPool) RefreshState() State {
-	if len(p.pool) == 0 {
+	pool := p.pools[TCP]
+	if len(pool) == 0 {
 		p.state.Offset = 0
 	} else {
-		p.state.Offset = p.pool[0] - p.start
+		p.state.Offset = pool[0] - p.start
 	}
 	return p.state
 }
+
+func (p *PortPool) available(port uint32, protocols []Protocol) bool {
+	for _, protocol := range protocols {
+		if !p.free[protocol][port] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p *PortPool) take(port uint32, protocols []Protocol) {
+	for _, protocol := range protocols {
+		pool := p.pools[protocol]
+		idx := indexOf(pool, port)
+		p.pools[protocol] = append(pool[:idx], pool[idx+1:]...)
+		delete(p.free[protocol], port)
+	}
+}
+
+func defaultProtocols(protocols []Protocol) []Protocol {
+	if len(protocols) == 0 {
+		return []Protocol{TCP}
+	}
+
+	return protocols
+}
+
+func indexOf(pool []uint32, port uint32) int {
+	for i, existingPort := range pool {
+		if existingPort == port {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -100,6 +100,57 @@ var _ = Describe("Port pool", func() {
 				}
 			})
 		})
+
+		Context("when a protocol is given", func() {
+			It("acquires ports for each protocol independently", func() {
+				pool, err := port_pool.New(10000, 5, initialState)
+				Expect(err).ToNot(HaveOccurred())
+
+				tcpPort, err := pool.Acquire(port_pool.TCP)
+				Expect(err).ToNot(HaveOccurred())
+
+				udpPort, err := pool.Acquire(port_pool.UDP)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(tcpPort).To(Equal(uint32(10000)))
+				Expect(udpPort).To(Equal(uint32(10000)))
+			})
+		})
+
+		Context("when several protocols are given", func() {
+			It("returns the next port available for all of them", func() {
+				pool, err := port_pool.New(10000, 5, initialState)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = pool.Acquire(port_pool.UDP)
+				Expect(err).ToNot(HaveOccurred())
+
+				port, err := pool.Acquire(port_pool.TCP, port_pool.UDP)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(port).To(Equal(uint32(10001)))
+
+				tcpPort, err := pool.Acquire(port_pool.TCP)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tcpPort).To(Equal(uint32(10000)))
+			})
+
+			It("returns an error when no port is available for all of them", func() {
+				pool, err := port_pool.New(10000, 2, initialState)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = pool.Acquire(port_pool.TCP)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = pool.Acquire(port_pool.UDP)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = pool.Acquire(port_pool.UDP)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = pool.Acquire(port_pool.TCP, port_pool.UDP)
+				Expect(err).To(Equal(port_pool.PoolExhaustedError{}))
+			})
+		})
 	})
 
 	Describe("removing", func() {
@@ -130,6 +181,23 @@ var _ = Describe("Port pool", func() {
 				Expect(err).To(Equal(port_pool.PortTakenError{port}))
 			})
 		})
+
+		Context("when the port is taken for one of several protocols", func() {
+			It("takes it for none of them", func() {
+				pool, err := port_pool.New(10000, 2, initialState)
+				Expect(err).ToNot(HaveOccurred())
+
+				err = pool.Remove(10000, port_pool.UDP)
+				Expect(err).ToNot(HaveOccurred())
+
+				err = pool.Remove(10000, port_pool.TCP, port_pool.UDP)
+				Expect(err).To(Equal(port_pool.PortTakenError{10000}))
+
+				port, err := pool.Acquire(port_pool.TCP)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(port).To(Equal(uint32(10000)))
+			})
+		})
 	})
 
 	Describe("releasing", func() {
@@ -188,6 +256,25 @@ var _ = Describe("Port pool", func() {
 				Expect(err).To(HaveOccurred())
 			})
 		})
+
+		Context("when protocols are given", func() {
+			It("places the port back only in their pools", func() {
+				pool, err := port_pool.New(10000, 1, initialState)
+				Expect(err).ToNot(HaveOccurred())
+
+				port, err := pool.Acquire(port_pool.TCP, port_pool.UDP)
+				Expect(err).ToNot(HaveOccurred())
+
+				pool.Release(port, port_pool.UDP)
+
+				_, err = pool.Acquire(port_pool.TCP)
+				Expect(err).To(HaveOccurred())
+
+				udpPort, err := pool.Acquire(port_pool.UDP)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(udpPort).To(Equal(port))
+			})
+		})
 	})
 
 	Describe("RefreshState", func() {
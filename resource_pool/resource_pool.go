@@ -28,6 +28,7 @@ import (
 	"github.com/cloudfoundry-incubator/garden-linux/network/bridgemgr"
 	"github.com/cloudfoundry-incubator/garden-linux/network/iptables"
 	"github.com/cloudfoundry-incubator/garden-linux/network/subnets"
+	"github.com/cloudfoundry-incubator/garden-linux/port_pool"
 	"github.com/cloudfoundry-incubator/garden-linux/process"
 	"github.com/cloudfoundry-incubator/garden-linux/sysconfig"
 	"github.com/cloudfoundry-incubator/garden-shed/layercake"
@@ -364,17 +365,9 @@ func (p *LinuxResourcePool) Restore(snapshot io.Reader) (linux_backend.LinuxCont
 		return linux_backend.LinuxContainerSpec{}, err
 	}
 
-	for _, port := range resources.Ports {
-		err = p.portPool.Remove(port)
-		if err != nil {
-			p.subnetPool.Release(resources.Network, subnetLogger)
-
-			for _, port := range resources.Ports {
-				p.portPool.Release(port)
-			}
-
-			return linux_backend.LinuxContainerSpec{}, err
-		}
+	if err = p.removePorts(resources); err != nil {
+		p.subnetPool.Release(resources.Network, subnetLogger)
+		return linux_backend.LinuxContainerSpec{}, err
 	}
 
 	version, err := p.restoreContainerVersion(id)
@@ -395,18 +388,13 @@ func (p *LinuxResourcePool) Restore(snapshot io.Reader) (linux_backend.LinuxCont
 			Properties: containerSnapshot.Properties,
 		},
 
-		Resources: linux_backend.NewResources(
-			resources.RootUID,
-			resources.Network,
-			resources.Bridge,
-			resources.Ports,
-			p.externalIP,
-		),
+		Resources: restoreResources(resources, p.externalIP),
 
 		Limits:    containerSnapshot.Limits,
 		NetIns:    containerSnapshot.NetIns,
 		NetOuts:   containerSnapshot.NetOuts,
 		Processes: containerSnapshot.Processes,
+		OOMPolicy: containerSnapshot.OOMPolicy,
 		Version:   version,
 	}
 
@@ -549,11 +537,52 @@ func (p *LinuxResourcePool) acquireUID(resources *linux_backend.Resources, privi
 	return nil
 }
 
-func (p *LinuxResourcePool) releasePoolResources(resources *linux_backend.Resources, logger lager.Logger) {
+func restoreResources(snapshot linux_container.ResourcesSnapshot, externalIP net.IP) *linux_backend.Resources {
+	resources := linux_backend.NewResources(
+		snapshot.RootUID,
+		snapshot.Network,
+		snapshot.Bridge,
+		snapshot.Ports,
+		externalIP,
+	)
+	resources.UDPPorts = snapshot.UDPPorts
+
+	return resources
+}
+
+// removePorts takes the TCP and UDP ports of restored resources from the port
+// pool. If any of them is taken, all of them are released.
+func (p *LinuxResourcePool) removePorts(resources linux_container.ResourcesSnapshot) error {
 	for _, port := range resources.Ports {
-		p.portPool.Release(port)
+		if err := p.portPool.Remove(port, port_pool.TCP); err != nil {
+			p.releasePorts(resources.Ports, resources.UDPPorts)
+			return err
+		}
 	}
 
+	for _, port := range resources.UDPPorts {
+		if err := p.portPool.Remove(port, port_pool.UDP); err != nil {
+			p.releasePorts(resources.Ports, resources.UDPPorts)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *LinuxResourcePool) releasePorts(tcpPorts, udpPorts []uint32) {
+	for _, port := range tcpPorts {
+		p.portPool.Release(port, port_pool.TCP)
+	}
+
+	for _, port := range udpPorts {
+		p.portPool.Release(port, port_pool.UDP)
+	}
+}
+
+func (p *LinuxResourcePool) releasePoolResources(resources *linux_backend.Resources, logger lager.Logger) {
+	p.releasePorts(resources.Ports, resources.UDPPorts)
+
 	if resources.Network != nil {
 		p.subnetPool.Release(resources.Network, logger.Session("subnet-pool"))
 	}
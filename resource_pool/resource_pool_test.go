@@ -31,6 +31,7 @@ import (
 	"github.com/cloudfoundry-incubator/garden-linux/network/fakes"
 	"github.com/cloudfoundry-incubator/garden-linux/network/iptables"
 	"github.com/cloudfoundry-incubator/garden-linux/network/subnets"
+	"github.com/cloudfoundry-incubator/garden-linux/port_pool"
 	"github.com/cloudfoundry-incubator/garden-linux/port_pool/fake_port_pool"
 	"github.com/cloudfoundry-incubator/garden-linux/resource_pool"
 	"github.com/cloudfoundry-incubator/garden-linux/resource_pool/fake_filter_provider"
@@ -102,7 +103,7 @@ var _ = Describe("Container pool", func() {
 		currentContainerVersion, err := semver.Make("1.0.0")
 		Expect(err).ToNot(HaveOccurred())
 
-		config = sysconfig.NewConfig("0", false, nil)
+		config = sysconfig.NewConfig("0", false, nil, sysconfig.FirewallIPTables)
 		logger = lagertest.NewTestLogger("test")
 		fakeMkdirChowner = new(fake_mkdir_chowner.FakeMkdirChowner)
 		pool = resource_pool.New(
@@ -1175,15 +1176,18 @@ var _ = Describe("Container pool", func() {
 					},
 
 					Resources: linux_container.ResourcesSnapshot{
-						RootUID: rootUID,
-						Network: containerNetwork,
-						Bridge:  bridgeName,
-						Ports:   []uint32{61001, 61002, 61003},
+						RootUID:  rootUID,
+						Network:  containerNetwork,
+						Bridge:   bridgeName,
+						Ports:    []uint32{61001, 61002, 61003},
+						UDPPorts: []uint32{61002, 61004},
 					},
 
 					Properties: map[string]string{
 						"foo": "bar",
 					},
+
+					OOMPolicy: linux_backend.OOMPolicyKillProcess,
 				},
 			)
 			Expect(err).ToNot(HaveOccurred())
@@ -1208,6 +1212,7 @@ var _ = Describe("Container pool", func() {
 
 			Expect(containerSpec.Resources.Network).To(Equal(containerNetwork))
 			Expect(containerSpec.Resources.Bridge).To(Equal("some-bridge"))
+			Expect(containerSpec.OOMPolicy).To(Equal(linux_backend.OOMPolicyKillProcess))
 		})
 
 		Context("when a version file exists in the container", func() {
@@ -1282,6 +1287,17 @@ var _ = Describe("Container pool", func() {
 			Expect(fakePortPool.Removed).To(ContainElement(uint32(61003)))
 		})
 
+		It("removes its udp ports from the udp pool", func() {
+			containerSpec, err := pool.Restore(snapshot)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakePortPool.Removed).To(Equal([]uint32{61001, 61002, 61003, 61002, 61004}))
+			Expect(fakePortPool.RemovedProtocols).To(Equal([][]port_pool.Protocol{
+				{port_pool.TCP}, {port_pool.TCP}, {port_pool.TCP}, {port_pool.UDP}, {port_pool.UDP},
+			}))
+			Expect(containerSpec.Resources.UDPPorts).To(Equal([]uint32{61002, 61004}))
+		})
+
 		It("rereserves the bridge for the subnet from the pool", func() {
 			_, err := pool.Restore(snapshot)
 			Expect(err).ToNot(HaveOccurred())
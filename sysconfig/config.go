@@ -12,11 +12,21 @@ type Config struct {
 	CgroupPath             string
 	CgroupNodeFilePath     string
 	NetworkInterfacePrefix string
+	Firewall               Firewall
 	IPTables               IPTablesConfig
+	NFTables               NFTablesConfig
 	Tag                    string
 	DNSServers             []string
 }
 
+// Firewall selects the tool used to program container filtering and NAT.
+type Firewall string
+
+const (
+	FirewallIPTables Firewall = "iptables"
+	FirewallNFTables Firewall = "nftables"
+)
+
 type IPTablesConfig struct {
 	Filter IPTablesFilterConfig
 	NAT    IPTablesNATConfig
@@ -36,7 +46,17 @@ type IPTablesNATConfig struct {
 	InstancePrefix   string
 }
 
-func NewConfig(tag string, allowHostAccess bool, dnsServers []string) Config {
+// NFTablesConfig names the nftables tables holding the chains of
+// IPTablesConfig when the nftables firewall is used. Filter and NAT chains get
+// a table each, as their instance chains share a name. Path is the nft binary
+// used by both garden and its scripts.
+type NFTablesConfig struct {
+	Path        string
+	FilterTable string
+	NATTable    string
+}
+
+func NewConfig(tag string, allowHostAccess bool, dnsServers []string, firewall Firewall) Config {
 	return Config{
 		NetworkInterfacePrefix: fmt.Sprintf("w%s", tag),
 		Tag:        tag,
@@ -59,6 +79,13 @@ func NewConfig(tag string, allowHostAccess bool, dnsServers []string) Config {
 				InstancePrefix:   fmt.Sprintf("w-%s-instance-", tag),
 			},
 		},
+
+		Firewall: firewall,
+		NFTables: NFTablesConfig{
+			Path:        "/usr/sbin/nft",
+			FilterTable: fmt.Sprintf("w-%s-filter", tag),
+			NATTable:    fmt.Sprintf("w-%s-nat", tag),
+		},
 	}
 }
 
@@ -80,5 +107,10 @@ func (config Config) Environ() process.Env {
 		"GARDEN_IPTABLES_NAT_PREROUTING_CHAIN":  config.IPTables.NAT.PreroutingChain,
 		"GARDEN_IPTABLES_NAT_POSTROUTING_CHAIN": config.IPTables.NAT.PostroutingChain,
 		"GARDEN_IPTABLES_NAT_INSTANCE_PREFIX":   config.IPTables.NAT.InstancePrefix,
+
+		"GARDEN_FIREWALL":              string(config.Firewall),
+		"GARDEN_NFTABLES_PATH":         config.NFTables.Path,
+		"GARDEN_NFTABLES_FILTER_TABLE": config.NFTables.FilterTable,
+		"GARDEN_NFTABLES_NAT_TABLE":    config.NFTables.NATTable,
 	}
 }